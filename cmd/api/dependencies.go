@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"os"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/auth"
+	"ticket-purchase/internal/db/repositories"
+	"ticket-purchase/internal/events"
+	"ticket-purchase/internal/mail"
+	"ticket-purchase/internal/payments"
+	"ticket-purchase/internal/ratelimit"
+	"ticket-purchase/internal/services"
+	"ticket-purchase/internal/webhooks"
+	"ticket-purchase/internal/workers"
+)
+
+// Config is the configuration of the API and its background workers
+type Config struct {
+	Auth        config.AuthConfig
+	Idempotency config.IdempotencyConfig
+	Hold        config.HoldConfig
+	Cache       config.CacheConfig
+	Inventory   config.InventoryConfig
+	RateLimit   config.RateLimitConfig
+	WaitingRoom config.WaitingRoomConfig
+	Payment     config.PaymentConfig
+	Webhook     config.WebhookConfig
+	Outbox      config.OutboxConfig
+	Mail        config.MailConfig
+}
+
+// Dependencies are the repositories and services of the API and its background workers. They are built once by
+// NewDependencies, so that the routes and the workers share the same instances.
+type Dependencies struct {
+	Config Config
+
+	Verifier              auth.Verifier
+	IdempotencyRepository repositories.IdempotencyRepository
+	RateLimiter           ratelimit.Limiter
+
+	TicketService              services.TicketService
+	HoldService                services.HoldService
+	PurchaseService            services.PurchaseService
+	OrderService               services.OrderService
+	AuthService                services.AuthService
+	WebhookService             services.WebhookService
+	WebhookSubscriptionService services.WebhookSubscriptionService
+	IdempotencyService         services.IdempotencyService
+	OutboxService              services.OutboxService
+	// WaitingRoomService is nil without Redis, tickets are then sold without a queue
+	WaitingRoomService services.WaitingRoomService
+	// InventoryService is nil unless the Redis inventory is enabled
+	InventoryService services.InventoryService
+
+	MailDispatcher *workers.MailDispatcher
+}
+
+// NewDependencies builds the dependency graph. The Redis client is optional, without it the features kept in Redis
+// fall back to the database or to every instance on its own.
+func NewDependencies(connection *gorm.DB, redisClient *redis.Client, conf Config) (*Dependencies, error) {
+	verifier, err := auth.NewVerifier(conf.Auth)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := auth.NewSigner(conf.Auth)
+	if err != nil {
+		return nil, err
+	}
+	paymentProvider, err := payments.NewProvider(conf.Payment, redisClient)
+	if err != nil {
+		return nil, err
+	}
+
+	// Repositories
+	dbTicketRepository := repositories.NewTicketRepository(connection)
+	dbPurchaseRepository := repositories.NewPurchaseRepository(connection)
+	dbHoldRepository := repositories.NewHoldRepository(connection)
+	orderRepository := repositories.NewOrderRepository(connection)
+
+	// Tickets are cached in Redis. Every write that changes the allocation in the database drops the cached ticket.
+	if redisClient != nil {
+		dbPurchaseRepository = repositories.NewTicketCachePurchaseRepository(dbPurchaseRepository, redisClient)
+		dbHoldRepository = repositories.NewTicketCacheHoldRepository(dbHoldRepository, redisClient)
+		orderRepository = repositories.NewTicketCacheOrderRepository(orderRepository, redisClient)
+	}
+	ticketRepository := dbTicketRepository
+	purchaseRepository := dbPurchaseRepository
+	holdRepository := dbHoldRepository
+
+	// Purchases take allocation from the Redis counters in flash sale mode, the persister writes them to Postgres.
+	// Every other change of the allocation goes through the counters as well.
+	redisInventory := redisClient != nil && conf.Inventory.Backend == config.InventoryBackendRedis
+	var inventoryRepository repositories.InventoryRepository
+	if redisInventory {
+		inventoryRepository = repositories.NewInventoryRepository(redisClient)
+		ticketRepository = repositories.NewInventoryTicketRepository(dbTicketRepository, inventoryRepository)
+		purchaseRepository = repositories.NewInventoryPurchaseRepository(purchaseRepository, inventoryRepository, dbTicketRepository)
+		holdRepository = repositories.NewInventoryHoldRepository(holdRepository, inventoryRepository, dbTicketRepository)
+		orderRepository = repositories.NewInventoryOrderRepository(orderRepository, inventoryRepository, dbTicketRepository)
+	}
+	if redisClient != nil {
+		ticketRepository = repositories.NewCachedTicketRepository(ticketRepository, redisClient, conf.Cache.TicketTTL)
+	}
+	userRepository := repositories.NewUserRepository(connection)
+	paymentRepository := repositories.NewPaymentRepository(connection)
+
+	// Limits are shared between instances through Redis, without it every instance counts on its own
+	limiter := ratelimit.NewMemoryLimiter()
+	if redisClient != nil {
+		limiter = ratelimit.NewRedisLimiter(redisClient)
+	}
+
+	deps := &Dependencies{
+		Config:                conf,
+		Verifier:              verifier,
+		IdempotencyRepository: repositories.NewIdempotencyRepository(connection),
+		RateLimiter:           limiter,
+		MailDispatcher:        workers.NewMailDispatcher(mail.NewSender(conf.Mail), conf.Mail),
+	}
+
+	// Services
+	paymentService := services.NewPaymentService(paymentRepository, paymentProvider, conf.Payment)
+	deps.TicketService = services.NewTicketService(ticketRepository, purchaseRepository, holdRepository, paymentService)
+	deps.HoldService = services.NewHoldService(holdRepository, ticketRepository, paymentService, conf.Hold)
+	deps.PurchaseService = services.NewPurchaseService(purchaseRepository, ticketRepository, paymentService)
+	deps.OrderService = services.NewOrderService(orderRepository, ticketRepository, paymentService)
+	deps.AuthService = services.NewAuthService(userRepository, repositories.NewRefreshTokenRepository(connection), signer, conf.Auth)
+	deps.WebhookService = services.NewWebhookService(repositories.NewWebhookEventRepository(connection), paymentRepository, purchaseRepository, conf.Payment)
+	deps.IdempotencyService = services.NewIdempotencyService(deps.IdempotencyRepository)
+
+	// Webhook payloads are read from the database, a cached ticket may not have caught up with the event yet
+	deps.WebhookSubscriptionService = services.NewWebhookSubscriptionService(
+		repositories.NewWebhookSubscriptionRepository(connection),
+		repositories.NewWebhookDeliveryRepository(connection),
+		dbTicketRepository,
+		dbHoldRepository,
+		dbPurchaseRepository,
+		webhooks.NewHTTPSender(conf.Webhook.Timeout),
+		conf.Webhook,
+	)
+
+	// The relay hands every event to the ticket cache, the webhooks and the emails of this process, and to a Redis
+	// stream for other consumers when Redis is configured. The emails come last, a failure before them publishes the
+	// event again.
+	notificationService := services.NewNotificationService(ticketRepository, userRepository, deps.MailDispatcher)
+	local := events.NewMemoryBus()
+	if redisClient != nil {
+		local.Subscribe(repositories.NewTicketCacheInvalidator(redisClient))
+	}
+	local.Subscribe(deps.WebhookSubscriptionService.HandleEvent)
+	local.Subscribe(notificationService.HandleEvent)
+
+	var bus events.Bus = local
+	if redisClient != nil {
+		bus = events.NewMultiBus(events.NewRedisBus(redisClient, conf.Outbox.Stream, conf.Outbox.StreamMaxLen), local)
+	}
+	deps.OutboxService = services.NewOutboxService(repositories.NewOutboxRepository(connection), bus, conf.Outbox)
+
+	if redisClient != nil {
+		deps.WaitingRoomService = services.NewWaitingRoomService(repositories.NewWaitingRoomRepository(redisClient), ticketRepository, conf.WaitingRoom)
+	}
+
+	// The persister writes the sales already taken from the counters, so it uses the database repository
+	if redisInventory {
+		deps.InventoryService = services.NewInventoryService(inventoryRepository, dbTicketRepository, dbPurchaseRepository, paymentService, conf.Inventory)
+	}
+
+	return deps, nil
+}
+
+// RunWorkers starts the background workers. They stop when ctx is cancelled.
+func (d *Dependencies) RunWorkers(ctx context.Context) error {
+	go d.MailDispatcher.Run(ctx)
+	go workers.NewIdempotencySweeper(d.IdempotencyService, d.Config.Idempotency.CleanupInterval).Run(ctx)
+	go workers.NewWebhookDispatcher(d.WebhookSubscriptionService, d.Config.Webhook.Interval).Run(ctx)
+	go workers.NewOutboxRelay(d.OutboxService, d.Config.Outbox.RelayInterval).Run(ctx)
+	go workers.NewHoldSweeper(d.HoldService, d.Config.Hold.SweepInterval).Run(ctx)
+
+	if d.InventoryService != nil {
+		// Every instance needs its own consumer name, the container hostname is unique
+		consumer, err := os.Hostname()
+		if err != nil {
+			return err
+		}
+
+		go workers.NewInventoryPersister(d.InventoryService, consumer).Run(ctx)
+		go workers.NewInventoryReconciler(d.InventoryService, d.Config.Inventory.ReconcileInterval).Run(ctx)
+	}
+
+	if d.WaitingRoomService != nil {
+		go workers.NewWaitingRoomAdmitter(d.WaitingRoomService).Run(ctx)
+	}
+	return nil
+}
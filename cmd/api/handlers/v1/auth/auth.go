@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/dto"
+	"ticket-purchase/internal/services"
+	"ticket-purchase/internal/validation"
+	"ticket-purchase/pkg/cresponse"
+)
+
+type Handler interface {
+	Register(ctx *fiber.Ctx) error
+	Login(ctx *fiber.Ctx) error
+	Refresh(ctx *fiber.Ctx) error
+	UpdateUserRole(ctx *fiber.Ctx) error
+}
+
+type handler struct {
+	authService services.AuthService
+}
+
+func New(authService services.AuthService) Handler {
+	return &handler{
+		authService: authService,
+	}
+}
+
+// AuthRegister godoc
+// @Summary Register a user
+// @Description Create a user account and return its tokens
+// @Tags Auth
+// @Accept application/json
+// @Produce application/json
+// @Param user body dto.RegisterRequest true "User data"
+// @Success 201 {object} dto.TokenResponse
+// @Router /auth/register [post]
+func (h *handler) Register(ctx *fiber.Ctx) error {
+	var request dto.RegisterRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	if fieldErrors := validation.Struct(ctx, &request); fieldErrors != nil {
+		return apperrors.ErrValidation.WithData(fieldErrors)
+	}
+
+	response, err := h.authService.Register(ctx.Context(), &request)
+	if err != nil {
+		return err
+	}
+
+	return cresponse.SuccessResponse(ctx, fiber.StatusCreated, response)
+}
+
+// AuthLogin godoc
+// @Summary Log in
+// @Description Check the credentials of a user and return new tokens
+// @Tags Auth
+// @Accept application/json
+// @Produce application/json
+// @Param credentials body dto.LoginRequest true "Credentials"
+// @Success 200 {object} dto.TokenResponse
+// @Router /auth/login [post]
+func (h *handler) Login(ctx *fiber.Ctx) error {
+	var request dto.LoginRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	if fieldErrors := validation.Struct(ctx, &request); fieldErrors != nil {
+		return apperrors.ErrValidation.WithData(fieldErrors)
+	}
+
+	response, err := h.authService.Login(ctx.Context(), &request)
+	if err != nil {
+		return err
+	}
+
+	return cresponse.SuccessResponse(ctx, fiber.StatusOK, response)
+}
+
+// AuthRefresh godoc
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for new tokens. Each refresh token can be used once.
+// @Tags Auth
+// @Accept application/json
+// @Produce application/json
+// @Param refresh body dto.RefreshRequest true "Refresh token"
+// @Success 200 {object} dto.TokenResponse
+// @Router /auth/refresh [post]
+func (h *handler) Refresh(ctx *fiber.Ctx) error {
+	var request dto.RefreshRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	if fieldErrors := validation.Struct(ctx, &request); fieldErrors != nil {
+		return apperrors.ErrValidation.WithData(fieldErrors)
+	}
+
+	response, err := h.authService.Refresh(ctx.Context(), &request)
+	if err != nil {
+		return err
+	}
+
+	return cresponse.SuccessResponse(ctx, fiber.StatusOK, response)
+}
+
+// UserRoleUpdate godoc
+// @Summary Change the role of a user
+// @Description Change the role of a user. The new role is applied to the next access token of the user.
+// @Tags Auth
+// @Accept application/json
+// @Produce application/json
+// @Param userId path string true "User ID"
+// @Param role body dto.UserRoleRequest true "Role"
+// @Success 200 {object} dto.UserResponse
+// @Security BearerAuth
+// @Router /users/{userId}/role [put]
+func (h *handler) UpdateUserRole(ctx *fiber.Ctx) error {
+	var request dto.UserRoleRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	if fieldErrors := validation.Struct(ctx, &request); fieldErrors != nil {
+		return apperrors.ErrValidation.WithData(fieldErrors)
+	}
+
+	request.UserId = ctx.Params("userId")
+
+	response, err := h.authService.UpdateRole(ctx.Context(), &request)
+	if err != nil {
+		return err
+	}
+
+	return cresponse.SuccessResponse(ctx, fiber.StatusOK, response)
+}
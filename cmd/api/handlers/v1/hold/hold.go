@@ -0,0 +1,80 @@
+package hold
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/auth"
+	"ticket-purchase/internal/dto"
+	"ticket-purchase/internal/services"
+	"ticket-purchase/internal/validation"
+	"ticket-purchase/pkg/cresponse"
+)
+
+type Handler interface {
+	CreateHold(ctx *fiber.Ctx) error
+	ConfirmHold(ctx *fiber.Ctx) error
+}
+
+type handler struct {
+	holdService services.HoldService
+}
+
+func New(holdService services.HoldService) Handler {
+	return &handler{
+		holdService: holdService,
+	}
+}
+
+// HoldCreate godoc
+// @Summary Hold tickets
+// @Description Reserve ticket allocation for a limited time before checkout
+// @Tags Hold
+// @Accept application/json
+// @Produce application/json
+// @Param id path string true "Ticket ID"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Param X-Queue-Token header string false "Admitted queue token, required for tickets with a waiting room"
+// @Param hold body dto.HoldCreateRequest true "Hold data"
+// @Success 201 {object} dto.HoldResponse
+// @Security BearerAuth
+// @Router /tickets/{id}/holds [post]
+func (h *handler) CreateHold(ctx *fiber.Ctx) error {
+	var request dto.HoldCreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	if fieldErrors := validation.Struct(ctx, &request); fieldErrors != nil {
+		return apperrors.ErrValidation.WithData(fieldErrors)
+	}
+
+	request.TicketId = ctx.Params("id")
+	request.UserId = auth.UserId(ctx)
+
+	response, err := h.holdService.Create(ctx.Context(), &request)
+	if err != nil {
+		return err
+	}
+
+	return cresponse.SuccessResponse(ctx, fiber.StatusCreated, response)
+}
+
+// HoldConfirm godoc
+// @Summary Confirm a hold
+// @Description Turn an active hold into a purchase
+// @Tags Hold
+// @Accept application/json
+// @Produce application/json
+// @Param id path string true "Hold ID"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 200 {object} dto.HoldResponse
+// @Security BearerAuth
+// @Router /holds/{id}/confirm [post]
+func (h *handler) ConfirmHold(ctx *fiber.Ctx) error {
+	response, err := h.holdService.Confirm(ctx.Context(), ctx.Params("id"), auth.UserId(ctx))
+	if err != nil {
+		return err
+	}
+
+	return cresponse.SuccessResponse(ctx, fiber.StatusOK, response)
+}
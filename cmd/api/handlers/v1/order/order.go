@@ -0,0 +1,166 @@
+package order
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/auth"
+	"ticket-purchase/internal/dto"
+	"ticket-purchase/internal/services"
+	"ticket-purchase/internal/validation"
+	"ticket-purchase/pkg/cresponse"
+)
+
+type Handler interface {
+	GetOrder(ctx *fiber.Ctx) error
+	GetCart(ctx *fiber.Ctx) error
+	AddCartLine(ctx *fiber.Ctx) error
+	UpdateCartLine(ctx *fiber.Ctx) error
+	RemoveCartLine(ctx *fiber.Ctx) error
+	Checkout(ctx *fiber.Ctx) error
+}
+
+type handler struct {
+	orderService services.OrderService
+}
+
+func New(orderService services.OrderService) Handler {
+	return &handler{
+		orderService: orderService,
+	}
+}
+
+// OrderGet godoc
+// @Summary Get order by ID
+// @Description Get an order with its lines
+// @Tags Order
+// @Accept application/json
+// @Produce application/json
+// @Param id path string true "Order ID"
+// @Success 200 {object} dto.OrderResponse
+// @Security BearerAuth
+// @Router /orders/{id} [get]
+func (h *handler) GetOrder(ctx *fiber.Ctx) error {
+	response, err := h.orderService.FindById(ctx.Context(), ctx.Params("id"), auth.ActorFrom(ctx))
+	if err != nil {
+		return err
+	}
+
+	return cresponse.SuccessResponse(ctx, fiber.StatusOK, response)
+}
+
+// CartGet godoc
+// @Summary Get the cart
+// @Description Get the cart of the user with its lines priced at the current ticket prices
+// @Tags Order
+// @Accept application/json
+// @Produce application/json
+// @Success 200 {object} dto.OrderResponse
+// @Security BearerAuth
+// @Router /cart [get]
+func (h *handler) GetCart(ctx *fiber.Ctx) error {
+	response, err := h.orderService.Cart(ctx.Context(), auth.UserId(ctx))
+	if err != nil {
+		return err
+	}
+
+	return cresponse.SuccessResponse(ctx, fiber.StatusOK, response)
+}
+
+// CartLineAdd godoc
+// @Summary Add tickets to the cart
+// @Description Add a line to the cart, or add to the quantity of the line of the same ticket
+// @Tags Order
+// @Accept application/json
+// @Produce application/json
+// @Param line body dto.CartLineCreateRequest true "Line data"
+// @Success 200 {object} dto.OrderResponse
+// @Security BearerAuth
+// @Router /cart/lines [post]
+func (h *handler) AddCartLine(ctx *fiber.Ctx) error {
+	var request dto.CartLineCreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	if fieldErrors := validation.Struct(ctx, &request); fieldErrors != nil {
+		return apperrors.ErrValidation.WithData(fieldErrors)
+	}
+
+	request.UserId = auth.UserId(ctx)
+
+	response, err := h.orderService.AddLine(ctx.Context(), &request)
+	if err != nil {
+		return err
+	}
+
+	return cresponse.SuccessResponse(ctx, fiber.StatusOK, response)
+}
+
+// CartLineUpdate godoc
+// @Summary Change the quantity of a cart line
+// @Description Set the quantity of a line of the cart
+// @Tags Order
+// @Accept application/json
+// @Produce application/json
+// @Param id path string true "Line ID"
+// @Param line body dto.CartLineUpdateRequest true "Line data"
+// @Success 200 {object} dto.OrderResponse
+// @Security BearerAuth
+// @Router /cart/lines/{id} [patch]
+func (h *handler) UpdateCartLine(ctx *fiber.Ctx) error {
+	var request dto.CartLineUpdateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	if fieldErrors := validation.Struct(ctx, &request); fieldErrors != nil {
+		return apperrors.ErrValidation.WithData(fieldErrors)
+	}
+
+	request.UserId = auth.UserId(ctx)
+
+	response, err := h.orderService.UpdateLine(ctx.Context(), ctx.Params("id"), &request)
+	if err != nil {
+		return err
+	}
+
+	return cresponse.SuccessResponse(ctx, fiber.StatusOK, response)
+}
+
+// CartLineRemove godoc
+// @Summary Remove a cart line
+// @Description Remove a line from the cart
+// @Tags Order
+// @Accept application/json
+// @Produce application/json
+// @Param id path string true "Line ID"
+// @Success 200 {object} dto.OrderResponse
+// @Security BearerAuth
+// @Router /cart/lines/{id} [delete]
+func (h *handler) RemoveCartLine(ctx *fiber.Ctx) error {
+	response, err := h.orderService.RemoveLine(ctx.Context(), auth.UserId(ctx), ctx.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return cresponse.SuccessResponse(ctx, fiber.StatusOK, response)
+}
+
+// CartCheckout godoc
+// @Summary Check out the cart
+// @Description Purchase every line of the cart or none of them. A rejected checkout answers 409 with the order, whose rejected lines carry the reason.
+// @Tags Order
+// @Accept application/json
+// @Produce application/json
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 201 {object} dto.OrderResponse
+// @Security BearerAuth
+// @Router /cart/checkout [post]
+func (h *handler) Checkout(ctx *fiber.Ctx) error {
+	response, err := h.orderService.Checkout(ctx.Context(), auth.UserId(ctx))
+	if err != nil {
+		return err
+	}
+
+	return cresponse.SuccessResponse(ctx, fiber.StatusCreated, response)
+}
@@ -0,0 +1,180 @@
+package purchase
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/auth"
+	"ticket-purchase/internal/dto"
+	"ticket-purchase/internal/services"
+	"ticket-purchase/internal/validation"
+	"ticket-purchase/pkg/cresponse"
+)
+
+type Handler interface {
+	GetPurchase(ctx *fiber.Ctx) error
+	ListUserPurchases(ctx *fiber.Ctx) error
+	ListTicketPurchases(ctx *fiber.Ctx) error
+	CancelPurchase(ctx *fiber.Ctx) error
+	RefundPurchase(ctx *fiber.Ctx) error
+}
+
+type handler struct {
+	purchaseService services.PurchaseService
+}
+
+func New(purchaseService services.PurchaseService) Handler {
+	return &handler{
+		purchaseService: purchaseService,
+	}
+}
+
+// PurchaseGet godoc
+// @Summary Get purchase by ID
+// @Description Get purchase by ID with its ticket summary
+// @Tags Purchase
+// @Accept application/json
+// @Produce application/json
+// @Param id path string true "Purchase ID"
+// @Success 200 {object} dto.PurchaseResponse
+// @Security BearerAuth
+// @Router /purchases/{id} [get]
+func (h *handler) GetPurchase(ctx *fiber.Ctx) error {
+	response, err := h.purchaseService.FindById(ctx.Context(), ctx.Params("id"), auth.ActorFrom(ctx))
+	if err != nil {
+		return err
+	}
+
+	return cresponse.SuccessResponse(ctx, fiber.StatusOK, response)
+}
+
+// PurchaseListByUser godoc
+// @Summary List purchases of a user
+// @Description List purchases of a user with cursor pagination
+// @Tags Purchase
+// @Accept application/json
+// @Produce application/json
+// @Param userId path string true "User ID"
+// @Param cursor query string false "Cursor of the next page"
+// @Param limit query int false "Page size"
+// @Param sort query string false "Sort column" Enums(created_at, quantity)
+// @Param order query string false "Sort order" Enums(asc, desc)
+// @Param from query string false "Created at or after (RFC 3339)"
+// @Param to query string false "Created before (RFC 3339)"
+// @Success 200 {object} dto.PurchaseListResponse
+// @Security BearerAuth
+// @Router /users/{userId}/purchases [get]
+func (h *handler) ListUserPurchases(ctx *fiber.Ctx) error {
+	var request dto.PurchaseListRequest
+	if err := ctx.QueryParser(&request); err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	if fieldErrors := validation.Struct(ctx, &request); fieldErrors != nil {
+		return apperrors.ErrValidation.WithData(fieldErrors)
+	}
+
+	request.UserId = ctx.Params("userId")
+
+	response, err := h.purchaseService.FindAll(ctx.Context(), &request, auth.ActorFrom(ctx))
+	if err != nil {
+		return err
+	}
+
+	return cresponse.SuccessResponse(ctx, fiber.StatusOK, response)
+}
+
+// PurchaseListByTicket godoc
+// @Summary List purchases of a ticket
+// @Description List purchases of a ticket with cursor pagination
+// @Tags Purchase
+// @Accept application/json
+// @Produce application/json
+// @Param id path string true "Ticket ID"
+// @Param cursor query string false "Cursor of the next page"
+// @Param limit query int false "Page size"
+// @Param sort query string false "Sort column" Enums(created_at, quantity)
+// @Param order query string false "Sort order" Enums(asc, desc)
+// @Param from query string false "Created at or after (RFC 3339)"
+// @Param to query string false "Created before (RFC 3339)"
+// @Success 200 {object} dto.PurchaseListResponse
+// @Security BearerAuth
+// @Router /tickets/{id}/purchases [get]
+func (h *handler) ListTicketPurchases(ctx *fiber.Ctx) error {
+	var request dto.PurchaseListRequest
+	if err := ctx.QueryParser(&request); err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	if fieldErrors := validation.Struct(ctx, &request); fieldErrors != nil {
+		return apperrors.ErrValidation.WithData(fieldErrors)
+	}
+
+	request.TicketId = ctx.Params("id")
+
+	response, err := h.purchaseService.FindAll(ctx.Context(), &request, auth.ActorFrom(ctx))
+	if err != nil {
+		return err
+	}
+
+	return cresponse.SuccessResponse(ctx, fiber.StatusOK, response)
+}
+
+// PurchaseCancel godoc
+// @Summary Cancel a purchase
+// @Description Cancel a purchase and return its remaining quantity to the ticket allocation
+// @Tags Purchase
+// @Accept application/json
+// @Produce application/json
+// @Param id path string true "Purchase ID"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Param cancel body dto.PurchaseCancelRequest true "Cancellation data"
+// @Success 200 {object} dto.PurchaseResponse
+// @Security BearerAuth
+// @Router /purchases/{id}/cancel [post]
+func (h *handler) CancelPurchase(ctx *fiber.Ctx) error {
+	var request dto.PurchaseCancelRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	if fieldErrors := validation.Struct(ctx, &request); fieldErrors != nil {
+		return apperrors.ErrValidation.WithData(fieldErrors)
+	}
+
+	response, err := h.purchaseService.Cancel(ctx.Context(), ctx.Params("id"), &request, auth.ActorFrom(ctx))
+	if err != nil {
+		return err
+	}
+
+	return cresponse.SuccessResponse(ctx, fiber.StatusOK, response)
+}
+
+// PurchaseRefund godoc
+// @Summary Refund a purchase
+// @Description Refund part of a purchase and return the quantity to the ticket allocation
+// @Tags Purchase
+// @Accept application/json
+// @Produce application/json
+// @Param id path string true "Purchase ID"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Param refund body dto.PurchaseRefundRequest true "Refund data"
+// @Success 200 {object} dto.PurchaseResponse
+// @Security BearerAuth
+// @Router /purchases/{id}/refund [post]
+func (h *handler) RefundPurchase(ctx *fiber.Ctx) error {
+	var request dto.PurchaseRefundRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	if fieldErrors := validation.Struct(ctx, &request); fieldErrors != nil {
+		return apperrors.ErrValidation.WithData(fieldErrors)
+	}
+
+	response, err := h.purchaseService.Refund(ctx.Context(), ctx.Params("id"), &request, auth.ActorFrom(ctx))
+	if err != nil {
+		return err
+	}
+
+	return cresponse.SuccessResponse(ctx, fiber.StatusOK, response)
+}
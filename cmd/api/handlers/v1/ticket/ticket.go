@@ -2,17 +2,23 @@ package ticket
 
 import (
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/log"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/auth"
 	"ticket-purchase/internal/dto"
 	"ticket-purchase/internal/i18n"
-	"ticket-purchase/internal/i18n/messages"
 	"ticket-purchase/internal/services"
+	"ticket-purchase/internal/validation"
 	"ticket-purchase/pkg/cresponse"
+	"ticket-purchase/pkg/money"
 )
 
 type Handler interface {
 	CreateTicket(ctx *fiber.Ctx) error
 	GetTicket(ctx *fiber.Ctx) error
+	ListTickets(ctx *fiber.Ctx) error
+	UpdateTicket(ctx *fiber.Ctx) error
+	DeleteTicket(ctx *fiber.Ctx) error
+	RestoreTicket(ctx *fiber.Ctx) error
 	PurchaseTicket(ctx *fiber.Ctx) error
 }
 
@@ -32,31 +38,29 @@ func New(ticketService services.TicketService) Handler {
 // @Tags Ticket
 // @Accept application/json
 // @Produce application/json
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
 // @Param ticket body dto.TicketCreateRequest true "Ticket data"
 // @Success 201 {object} dto.TicketResponse
+// @Security BearerAuth
 // @Router /tickets [post]
 func (h *handler) CreateTicket(ctx *fiber.Ctx) error {
 	var request dto.TicketCreateRequest
 	if err := ctx.BodyParser(&request); err != nil {
-		return cresponse.ErrorResponse(ctx, fiber.StatusBadRequest, i18n.CreateMsg(ctx, messages.BadRequest))
+		return apperrors.ErrBadRequest.Wrap(err)
 	}
 
+	if fieldErrors := validation.Struct(ctx, &request); fieldErrors != nil {
+		return apperrors.ErrValidation.WithData(fieldErrors)
+	}
+
+	request.UserId = auth.UserId(ctx)
+
 	response, err := h.ticketService.Create(ctx.Context(), &request)
 	if err != nil {
-		var status int
-		var message string
-		if err.Error() == messages.ErrorTicketCreate {
-			status = fiber.StatusInternalServerError
-			message = i18n.CreateMsg(ctx, messages.ErrorTicketCreate)
-		} else {
-			status = fiber.StatusInternalServerError
-			message = i18n.CreateMsg(ctx, messages.UnexpectedError)
-		}
-
-		log.Error("Error creating ticket: ", err)
-		return cresponse.ErrorResponse(ctx, status, message)
+		return err
 	}
 
+	formatPrice(ctx, response)
 	return cresponse.SuccessResponse(ctx, fiber.StatusCreated, response)
 }
 
@@ -67,26 +71,67 @@ func (h *handler) CreateTicket(ctx *fiber.Ctx) error {
 // @Accept application/json
 // @Produce application/json
 // @Param id path string true "Ticket ID"
+// @Param include_inactive query bool false "Return the ticket even if it is soft deleted"
 // @Success 200 {object} dto.TicketResponse
 // @Router /tickets/{id} [get]
 func (h *handler) GetTicket(ctx *fiber.Ctx) error {
+	includeInactive := ctx.QueryBool("include_inactive")
+	if includeInactive && !auth.ActorFrom(ctx).Can(auth.PermTicketReadInactive) {
+		return apperrors.ErrForbidden
+	}
+
 	id := ctx.Params("id")
-	response, err := h.ticketService.FindById(ctx.Context(), id)
+	response, err := h.ticketService.FindById(ctx.Context(), id, includeInactive)
 	if err != nil {
-		var status int
-		var message string
-		if err.Error() == messages.NotFound {
-			status = fiber.StatusNotFound
-			message = i18n.CreateMsg(ctx, messages.NotFound)
-		} else {
-			status = fiber.StatusInternalServerError
-			message = i18n.CreateMsg(ctx, messages.UnexpectedError)
-		}
+		return err
+	}
+
+	formatPrice(ctx, response)
+	return cresponse.SuccessResponse(ctx, fiber.StatusOK, response)
+}
+
+// TicketList godoc
+// @Summary List tickets
+// @Description List the ticket catalog with filters and cursor pagination
+// @Tags Ticket
+// @Accept application/json
+// @Produce application/json
+// @Param name query string false "Name contains"
+// @Param min_allocation query int false "Minimum remaining allocation"
+// @Param max_allocation query int false "Maximum remaining allocation"
+// @Param active query bool false "Active flag"
+// @Param sale query string false "Sale status" Enums(upcoming, on_sale, ended)
+// @Param from query string false "Created at or after (RFC 3339)"
+// @Param to query string false "Created before (RFC 3339)"
+// @Param cursor query string false "Cursor of the next page"
+// @Param limit query int false "Page size"
+// @Param sort query string false "Sort column" Enums(created_at, name, allocation)
+// @Param order query string false "Sort order" Enums(asc, desc)
+// @Param include_inactive query bool false "List soft deleted tickets too"
+// @Success 200 {object} dto.TicketListResponse
+// @Router /tickets [get]
+func (h *handler) ListTickets(ctx *fiber.Ctx) error {
+	var request dto.TicketListRequest
+	if err := ctx.QueryParser(&request); err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	if fieldErrors := validation.Struct(ctx, &request); fieldErrors != nil {
+		return apperrors.ErrValidation.WithData(fieldErrors)
+	}
+
+	if request.IncludeInactive && !auth.ActorFrom(ctx).Can(auth.PermTicketReadInactive) {
+		return apperrors.ErrForbidden
+	}
 
-		log.Error("Error getting ticket: ", err)
-		return cresponse.ErrorResponse(ctx, status, message)
+	response, err := h.ticketService.FindAll(ctx.Context(), &request)
+	if err != nil {
+		return err
 	}
 
+	for i := range response.Items {
+		formatPrice(ctx, &response.Items[i])
+	}
 	return cresponse.SuccessResponse(ctx, fiber.StatusOK, response)
 }
 
@@ -97,38 +142,104 @@ func (h *handler) GetTicket(ctx *fiber.Ctx) error {
 // @Accept application/json
 // @Produce application/json
 // @Param id path string true "Ticket ID"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Param X-Queue-Token header string false "Admitted queue token, required for tickets with a waiting room"
 // @Param purchase body dto.TicketPurchaseRequest true "Purchase data"
 // @Success 200 {object} interface{}
+// @Security BearerAuth
 // @Router /tickets/{id}/purchase [post]
 func (h *handler) PurchaseTicket(ctx *fiber.Ctx) error {
 	id := ctx.Params("id")
 	var request dto.TicketPurchaseRequest
 	if err := ctx.BodyParser(&request); err != nil {
-		return cresponse.ErrorResponse(ctx, fiber.StatusBadRequest, i18n.CreateMsg(ctx, messages.BadRequest))
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	if fieldErrors := validation.Struct(ctx, &request); fieldErrors != nil {
+		return apperrors.ErrValidation.WithData(fieldErrors)
 	}
+
 	request.TicketId = id
+	request.UserId = auth.UserId(ctx)
 
 	err := h.ticketService.TicketPurchase(ctx.Context(), &request)
 	if err != nil {
-		var status int
-		var message string
-		if err.Error() == messages.ErrorPurchase {
-			status = fiber.StatusInternalServerError
-			message = i18n.CreateMsg(ctx, messages.ErrorPurchase)
-		} else if err.Error() == messages.NotFound {
-			status = fiber.StatusNotFound
-			message = i18n.CreateMsg(ctx, messages.NotFound)
-		} else if err.Error() == messages.ErrorTicketAllocations {
-			status = fiber.StatusBadRequest
-			message = i18n.CreateMsg(ctx, messages.ErrorTicketAllocations)
-		} else {
-			status = fiber.StatusInternalServerError
-			message = i18n.CreateMsg(ctx, messages.UnexpectedError)
-		}
-
-		log.Error("Error purchasing ticket: ", err)
-		return cresponse.ErrorResponse(ctx, status, message)
+		return err
+	}
+
+	return cresponse.SuccessResponse(ctx, fiber.StatusOK, nil)
+}
+
+// TicketUpdate godoc
+// @Summary Update a ticket
+// @Description Update the name, description or total allocation of a ticket. The allocation cannot be lower than what is already sold or held.
+// @Tags Ticket
+// @Accept application/json
+// @Produce application/json
+// @Param id path string true "Ticket ID"
+// @Param ticket body dto.TicketUpdateRequest true "Ticket changes"
+// @Success 200 {object} dto.TicketResponse
+// @Security BearerAuth
+// @Router /tickets/{id} [patch]
+func (h *handler) UpdateTicket(ctx *fiber.Ctx) error {
+	var request dto.TicketUpdateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	if fieldErrors := validation.Struct(ctx, &request); fieldErrors != nil {
+		return apperrors.ErrValidation.WithData(fieldErrors)
+	}
+
+	response, err := h.ticketService.Update(ctx.Context(), ctx.Params("id"), &request, auth.ActorFrom(ctx))
+	if err != nil {
+		return err
+	}
+
+	formatPrice(ctx, response)
+	return cresponse.SuccessResponse(ctx, fiber.StatusOK, response)
+}
+
+// TicketDelete godoc
+// @Summary Delete a ticket
+// @Description Soft delete a ticket. Deleted tickets cannot be purchased.
+// @Tags Ticket
+// @Accept application/json
+// @Produce application/json
+// @Param id path string true "Ticket ID"
+// @Success 200 {object} interface{}
+// @Security BearerAuth
+// @Router /tickets/{id} [delete]
+func (h *handler) DeleteTicket(ctx *fiber.Ctx) error {
+	err := h.ticketService.Delete(ctx.Context(), ctx.Params("id"), auth.ActorFrom(ctx))
+	if err != nil {
+		return err
 	}
 
 	return cresponse.SuccessResponse(ctx, fiber.StatusOK, nil)
 }
+
+// TicketRestore godoc
+// @Summary Restore a ticket
+// @Description Restore a soft deleted ticket
+// @Tags Ticket
+// @Accept application/json
+// @Produce application/json
+// @Param id path string true "Ticket ID"
+// @Success 200 {object} dto.TicketResponse
+// @Security BearerAuth
+// @Router /tickets/{id}/restore [post]
+func (h *handler) RestoreTicket(ctx *fiber.Ctx) error {
+	response, err := h.ticketService.Restore(ctx.Context(), ctx.Params("id"), auth.ActorFrom(ctx))
+	if err != nil {
+		return err
+	}
+
+	formatPrice(ctx, response)
+	return cresponse.SuccessResponse(ctx, fiber.StatusOK, response)
+}
+
+// formatPrice sets the price of the ticket formatted for the language of the request
+func formatPrice(ctx *fiber.Ctx, ticket *dto.TicketResponse) {
+	ticket.FormattedPrice = i18n.FormatMoney(i18n.GetLanguage(ctx), money.New(ticket.Price, ticket.Currency))
+}
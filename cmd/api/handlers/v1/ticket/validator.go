@@ -1 +1,46 @@
 package ticket
+
+import (
+	"github.com/go-playground/validator/v10"
+	"ticket-purchase/internal/dto"
+	"ticket-purchase/internal/validation"
+	"time"
+)
+
+func init() {
+	validation.RegisterStructValidation(validateTicketCreate, dto.TicketCreateRequest{})
+	validation.RegisterStructValidation(validateTicketUpdate, dto.TicketUpdateRequest{})
+	validation.RegisterStructValidation(validateTicketList, dto.TicketListRequest{})
+}
+
+// validateTicketCreate rejects sale windows that end before they start
+func validateTicketCreate(sl validator.StructLevel) {
+	request := sl.Current().Interface().(dto.TicketCreateRequest)
+	validateSaleWindow(sl, request.SaleStartsAt, request.SaleEndsAt)
+}
+
+// validateTicketUpdate rejects updates that do not change anything. Sale windows are checked against the stored
+// bounds by the service.
+func validateTicketUpdate(sl validator.StructLevel) {
+	request := sl.Current().Interface().(dto.TicketUpdateRequest)
+	if request.Name == nil && request.Description == nil && request.Allocation == nil && request.Price == nil &&
+		request.Currency == nil && request.EventStartsAt == nil && request.SaleStartsAt == nil &&
+		request.SaleEndsAt == nil && request.MaxPerOrder == nil && request.MaxPerUser == nil && request.WaitingRoom == nil {
+		sl.ReportError(request, "", "", "at_least_one", "")
+	}
+	validateSaleWindow(sl, request.SaleStartsAt, request.SaleEndsAt)
+}
+
+func validateSaleWindow(sl validator.StructLevel, startsAt *time.Time, endsAt *time.Time) {
+	if startsAt != nil && endsAt != nil && !endsAt.After(*startsAt) {
+		sl.ReportError(endsAt, "sale_ends_at", "SaleEndsAt", "gtfield", "sale_starts_at")
+	}
+}
+
+// validateTicketList rejects allocation ranges that cannot match any ticket
+func validateTicketList(sl validator.StructLevel) {
+	request := sl.Current().Interface().(dto.TicketListRequest)
+	if request.MinAllocation != nil && request.MaxAllocation != nil && *request.MaxAllocation < *request.MinAllocation {
+		sl.ReportError(request.MaxAllocation, "max_allocation", "MaxAllocation", "gtefield", "min_allocation")
+	}
+}
@@ -0,0 +1,62 @@
+package waitingroom
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"ticket-purchase/internal/auth"
+	"ticket-purchase/internal/services"
+	"ticket-purchase/pkg/cresponse"
+)
+
+type Handler interface {
+	JoinQueue(ctx *fiber.Ctx) error
+	GetQueueStatus(ctx *fiber.Ctx) error
+}
+
+type handler struct {
+	waitingRoomService services.WaitingRoomService
+}
+
+func New(waitingRoomService services.WaitingRoomService) Handler {
+	return &handler{
+		waitingRoomService: waitingRoomService,
+	}
+}
+
+// QueueJoin godoc
+// @Summary Join the waiting room of a ticket
+// @Description Get a queue token with a position. Users who are already queued get their current token back.
+// @Tags Waiting Room
+// @Accept application/json
+// @Produce application/json
+// @Param id path string true "Ticket ID"
+// @Success 200 {object} dto.QueueTokenResponse
+// @Security BearerAuth
+// @Router /tickets/{id}/queue [post]
+func (h *handler) JoinQueue(ctx *fiber.Ctx) error {
+	response, err := h.waitingRoomService.Join(ctx.Context(), ctx.Params("id"), auth.ActorFrom(ctx))
+	if err != nil {
+		return err
+	}
+
+	return cresponse.SuccessResponse(ctx, fiber.StatusOK, response)
+}
+
+// QueueStatus godoc
+// @Summary Get the position of a queue token
+// @Description Poll until the status is admitted, then buy with the token in the X-Queue-Token header before admitted_until
+// @Tags Waiting Room
+// @Accept application/json
+// @Produce application/json
+// @Param id path string true "Ticket ID"
+// @Param token path string true "Queue token"
+// @Success 200 {object} dto.QueueTokenResponse
+// @Security BearerAuth
+// @Router /tickets/{id}/queue/{token} [get]
+func (h *handler) GetQueueStatus(ctx *fiber.Ctx) error {
+	response, err := h.waitingRoomService.Status(ctx.Context(), ctx.Params("id"), ctx.Params("token"), auth.ActorFrom(ctx))
+	if err != nil {
+		return err
+	}
+
+	return cresponse.SuccessResponse(ctx, fiber.StatusOK, response)
+}
@@ -0,0 +1,168 @@
+package webhook
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/auth"
+	"ticket-purchase/internal/dto"
+	"ticket-purchase/internal/payments"
+	"ticket-purchase/internal/services"
+	"ticket-purchase/internal/validation"
+	"ticket-purchase/pkg/cresponse"
+)
+
+type Handler interface {
+	ReceivePayment(ctx *fiber.Ctx) error
+	CreateSubscription(ctx *fiber.Ctx) error
+	ListSubscriptions(ctx *fiber.Ctx) error
+	DeleteSubscription(ctx *fiber.Ctx) error
+	ListDeliveries(ctx *fiber.Ctx) error
+	Redeliver(ctx *fiber.Ctx) error
+}
+
+type handler struct {
+	webhookService      services.WebhookService
+	subscriptionService services.WebhookSubscriptionService
+}
+
+func New(webhookService services.WebhookService, subscriptionService services.WebhookSubscriptionService) Handler {
+	return &handler{
+		webhookService:      webhookService,
+		subscriptionService: subscriptionService,
+	}
+}
+
+// PaymentWebhook godoc
+// @Summary Receive a payment callback
+// @Description Callback of the payment provider. The body is signed with the webhook secret in X-Payment-Signature as "t=<unix seconds>,v1=<hex HMAC-SHA256 of t.body>".
+// @Tags Webhook
+// @Accept application/json
+// @Produce application/json
+// @Param X-Payment-Signature header string true "Signature of the callback"
+// @Param event body payments.Event true "Payment event"
+// @Success 200 {object} cresponse.BaseResponse
+// @Router /webhooks/payments [post]
+func (h *handler) ReceivePayment(ctx *fiber.Ctx) error {
+	// The signature covers the raw body, so it is verified before the body is parsed
+	if err := h.webhookService.ReceivePayment(ctx.Context(), ctx.Get(payments.SignatureHeader), ctx.Body()); err != nil {
+		return err
+	}
+
+	return cresponse.SuccessResponse(ctx, fiber.StatusOK, nil)
+}
+
+// WebhookSubscriptionCreate godoc
+// @Summary Subscribe to events
+// @Description Subscribe an endpoint to ticket and purchase events. Deliveries are signed with the returned secret in X-Webhook-Signature as "t=<unix seconds>,v1=<hex HMAC-SHA256 of t.body>". The secret is only returned here. The url has to resolve to public addresses only.
+// @Tags Webhook
+// @Accept application/json
+// @Produce application/json
+// @Param subscription body dto.WebhookSubscriptionCreateRequest true "Subscription data, events are ticket.created, ticket.updated, ticket.sold_out, purchase.completed and purchase.cancelled"
+// @Success 201 {object} dto.WebhookSubscriptionResponse
+// @Security BearerAuth
+// @Router /webhooks/subscriptions [post]
+func (h *handler) CreateSubscription(ctx *fiber.Ctx) error {
+	var request dto.WebhookSubscriptionCreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	if fieldErrors := validation.Struct(ctx, &request); fieldErrors != nil {
+		return apperrors.ErrValidation.WithData(fieldErrors)
+	}
+
+	request.UserId = auth.UserId(ctx)
+
+	response, err := h.subscriptionService.Create(ctx.Context(), &request)
+	if err != nil {
+		return err
+	}
+
+	return cresponse.SuccessResponse(ctx, fiber.StatusCreated, response)
+}
+
+// WebhookSubscriptionList godoc
+// @Summary List webhook subscriptions
+// @Description List webhook subscriptions without their secrets
+// @Tags Webhook
+// @Accept application/json
+// @Produce application/json
+// @Success 200 {object} dto.WebhookSubscriptionListResponse
+// @Security BearerAuth
+// @Router /webhooks/subscriptions [get]
+func (h *handler) ListSubscriptions(ctx *fiber.Ctx) error {
+	response, err := h.subscriptionService.FindAll(ctx.Context())
+	if err != nil {
+		return err
+	}
+
+	return cresponse.SuccessResponse(ctx, fiber.StatusOK, response)
+}
+
+// WebhookSubscriptionDelete godoc
+// @Summary Delete a webhook subscription
+// @Description Delete a webhook subscription with its deliveries
+// @Tags Webhook
+// @Accept application/json
+// @Produce application/json
+// @Param id path string true "Subscription ID"
+// @Success 200 {object} interface{}
+// @Security BearerAuth
+// @Router /webhooks/subscriptions/{id} [delete]
+func (h *handler) DeleteSubscription(ctx *fiber.Ctx) error {
+	if err := h.subscriptionService.Delete(ctx.Context(), ctx.Params("id")); err != nil {
+		return err
+	}
+
+	return cresponse.SuccessResponse(ctx, fiber.StatusOK, nil)
+}
+
+// WebhookDeliveryList godoc
+// @Summary List webhook deliveries
+// @Description List webhook deliveries newest first with cursor pagination. Deliveries that ran out of attempts have the dead status.
+// @Tags Webhook
+// @Accept application/json
+// @Produce application/json
+// @Param subscription_id query string false "Subscription ID"
+// @Param status query string false "Delivery status" Enums(pending, delivered, dead)
+// @Param cursor query string false "Cursor of the next page"
+// @Param limit query int false "Page size"
+// @Success 200 {object} dto.WebhookDeliveryListResponse
+// @Security BearerAuth
+// @Router /webhooks/deliveries [get]
+func (h *handler) ListDeliveries(ctx *fiber.Ctx) error {
+	var request dto.WebhookDeliveryListRequest
+	if err := ctx.QueryParser(&request); err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	if fieldErrors := validation.Struct(ctx, &request); fieldErrors != nil {
+		return apperrors.ErrValidation.WithData(fieldErrors)
+	}
+
+	response, err := h.subscriptionService.FindDeliveries(ctx.Context(), &request)
+	if err != nil {
+		return err
+	}
+
+	return cresponse.SuccessResponse(ctx, fiber.StatusOK, response)
+}
+
+// WebhookRedeliver godoc
+// @Summary Redeliver a webhook delivery
+// @Description Queue a delivered or dead delivery again with a fresh set of attempts
+// @Tags Webhook
+// @Accept application/json
+// @Produce application/json
+// @Param id path string true "Delivery ID"
+// @Success 200 {object} dto.WebhookDeliveryResponse
+// @Security BearerAuth
+// @Router /webhooks/deliveries/{id}/redeliver [post]
+func (h *handler) Redeliver(ctx *fiber.Ctx) error {
+	response, err := h.subscriptionService.Redeliver(ctx.Context(), ctx.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return cresponse.SuccessResponse(ctx, fiber.StatusOK, response)
+}
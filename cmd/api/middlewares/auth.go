@@ -0,0 +1,53 @@
+package middlewares
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"strings"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/auth"
+)
+
+const bearerScheme = "Bearer "
+
+// Authenticate rejects requests without a valid bearer token and stores the token claims for the handlers
+func Authenticate(verifier auth.Verifier) fiber.Handler {
+	return authenticate(verifier, false)
+}
+
+// OptionalAuthenticate lets anonymous requests through, but still rejects requests with an invalid token
+func OptionalAuthenticate(verifier auth.Verifier) fiber.Handler {
+	return authenticate(verifier, true)
+}
+
+// Authorize rejects requests of users whose role does not have the permission. It runs after Authenticate.
+func Authorize(permission auth.Permission) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if !auth.ActorFrom(ctx).Can(permission) {
+			return apperrors.ErrForbidden
+		}
+		return ctx.Next()
+	}
+}
+
+func authenticate(verifier auth.Verifier, optional bool) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		header := ctx.Get(fiber.HeaderAuthorization)
+		if header == "" && optional {
+			return ctx.Next()
+		}
+
+		if len(header) <= len(bearerScheme) || !strings.EqualFold(header[:len(bearerScheme)], bearerScheme) {
+			ctx.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+			return apperrors.ErrUnauthorized
+		}
+
+		claims, err := verifier.Verify(strings.TrimSpace(header[len(bearerScheme):]))
+		if err != nil {
+			ctx.Set(fiber.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+			return apperrors.ErrUnauthorized.Wrap(err)
+		}
+
+		auth.SetClaims(ctx, claims)
+		return ctx.Next()
+	}
+}
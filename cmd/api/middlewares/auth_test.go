@@ -0,0 +1,123 @@
+package middlewares
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/auth"
+	"ticket-purchase/internal/i18n"
+	"time"
+)
+
+const authTestSecret = "secret"
+
+func setupAuthTest(t *testing.T) *fiber.App {
+	i18n.InitBundle("./../../../internal/i18n/languages")
+
+	verifier, err := auth.NewVerifier(config.AuthConfig{Algorithm: auth.AlgorithmHS256, Secret: authTestSecret})
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	authApp := fiber.New(config.FiberConfig)
+	authApp.Get("/me", Authenticate(verifier), func(ctx *fiber.Ctx) error {
+		return ctx.SendString(auth.UserId(ctx))
+	})
+	authApp.Get("/users", Authenticate(verifier), Authorize(auth.PermUserManage), func(ctx *fiber.Ctx) error {
+		return ctx.SendString(auth.ActorFrom(ctx).Role)
+	})
+	return authApp
+}
+
+func doAuthRequest(t *testing.T, authApp *fiber.App, authorization string, language string) (int, string, string) {
+	return doAuthRequestTo(t, authApp, "/me", authorization, language)
+}
+
+func doAuthRequestTo(t *testing.T, authApp *fiber.App, path string, authorization string, language string) (int, string, string) {
+	req := httptest.NewRequest(fiber.MethodGet, path, nil)
+	if authorization != "" {
+		req.Header.Set(fiber.HeaderAuthorization, authorization)
+	}
+	if language != "" {
+		req.Header.Set(fiber.HeaderAcceptLanguage, language)
+	}
+
+	resp, err := authApp.Test(req)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data), resp.Header.Get(fiber.HeaderWWWAuthenticate)
+}
+
+func accessToken(t *testing.T, subject string, expiresAt time.Time) string {
+	return roleAccessToken(t, subject, "", expiresAt)
+}
+
+func roleAccessToken(t *testing.T, subject string, role string, expiresAt time.Time) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		Role: role,
+	}).SignedString([]byte(authTestSecret))
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	return token
+}
+
+func TestAuthenticate_Valid_Token(t *testing.T) {
+	authApp := setupAuthTest(t)
+
+	status, body, _ := doAuthRequest(t, authApp, "Bearer "+accessToken(t, "user-1", time.Now().Add(time.Hour)), "")
+
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "user-1", body)
+}
+
+func TestAuthenticate_Missing_Token(t *testing.T) {
+	authApp := setupAuthTest(t)
+
+	status, body, challenge := doAuthRequest(t, authApp, "", "")
+
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+	assert.Equal(t, "Bearer", challenge)
+	assert.Contains(t, body, "Missing or invalid access token")
+}
+
+func TestAuthenticate_Invalid_Token_Localized(t *testing.T) {
+	authApp := setupAuthTest(t)
+
+	status, body, challenge := doAuthRequest(t, authApp, "Bearer "+accessToken(t, "user-1", time.Now().Add(-time.Hour)), "tr")
+
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+	assert.Equal(t, `Bearer error="invalid_token"`, challenge)
+	assert.Contains(t, body, "Erişim anahtarı eksik veya geçersiz")
+}
+
+func TestAuthorize_Allowed_Role(t *testing.T) {
+	authApp := setupAuthTest(t)
+
+	token := roleAccessToken(t, "admin-1", auth.RoleAdmin, time.Now().Add(time.Hour))
+	status, body, _ := doAuthRequestTo(t, authApp, "/users", "Bearer "+token, "")
+
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, auth.RoleAdmin, body)
+}
+
+func TestAuthorize_Missing_Permission_Localized(t *testing.T) {
+	authApp := setupAuthTest(t)
+
+	token := roleAccessToken(t, "user-1", auth.RoleOrganizer, time.Now().Add(time.Hour))
+	status, body, _ := doAuthRequestTo(t, authApp, "/users", "Bearer "+token, "tr")
+
+	assert.Equal(t, fiber.StatusForbidden, status)
+	assert.Contains(t, body, "Bu işlemi yapma yetkiniz yok")
+}
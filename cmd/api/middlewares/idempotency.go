@@ -0,0 +1,140 @@
+package middlewares
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"gorm.io/gorm"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/auth"
+	"ticket-purchase/internal/db/models"
+	"ticket-purchase/internal/db/repositories"
+	"time"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+var timeNow = time.Now
+
+// Idempotency makes a route safe to retry. The first response for an Idempotency-Key is stored
+// and replayed for later requests with the same key and body until the key expires. Keys are scoped to the
+// authenticated user, so a key chosen by one user never collides with the same key of another.
+func Idempotency(repo repositories.IdempotencyRepository, conf config.IdempotencyConfig) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		key := ctx.Get(IdempotencyKeyHeader)
+		if key == "" {
+			return ctx.Next()
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			return apperrors.ErrIdempotencyKeyInvalid
+		}
+
+		userId := auth.UserId(ctx)
+		fingerprint := requestFingerprint(ctx)
+
+		record, err := repo.FindByKey(ctx.Context(), userId, key)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperrors.ErrUnexpected.Wrap(err)
+		}
+
+		// Expired keys are taken over and the request is processed as a new one
+		now := timeNow()
+		if record != nil && !record.ExpiresAt.Before(now) {
+			if record.Fingerprint != fingerprint {
+				return apperrors.ErrIdempotencyKeyReused
+			}
+
+			if record.IsCompleted() {
+				return replay(ctx, record)
+			}
+
+			// The request holding the key is still running. Once its lease is over the key is taken over, the request
+			// stopped without releasing it, e.g. because its instance crashed.
+			if record.LockedUntil.After(now) {
+				return apperrors.ErrIdempotencyKeyInProgress
+			}
+		}
+
+		acquired, err := repo.Acquire(ctx.Context(), &models.IdempotencyKey{
+			UserId:      userId,
+			Key:         key,
+			Fingerprint: fingerprint,
+			ExpiresAt:   now.Add(conf.TTL),
+			LockedUntil: now.Add(conf.Lease),
+		}, now)
+		if err != nil {
+			return apperrors.ErrUnexpected.Wrap(err)
+		}
+
+		// Another request with the same key won the race and is still running
+		if !acquired {
+			return apperrors.ErrIdempotencyKeyInProgress
+		}
+
+		// A panicking handler releases the key like a server error before the panic goes on to the recover middleware
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				releaseKey(ctx, repo, userId, key)
+				panic(recovered)
+			}
+		}()
+
+		// Errors returned by the handler are rendered here so that client errors are stored like any other response
+		if err := ctx.Next(); err != nil {
+			if handlerErr := ctx.App().ErrorHandler(ctx, err); handlerErr != nil {
+				return handlerErr
+			}
+		}
+		status := ctx.Response().StatusCode()
+
+		// Server errors are not stored so that the client can safely retry with the same key
+		if status >= fiber.StatusInternalServerError {
+			releaseKey(ctx, repo, userId, key)
+			return nil
+		}
+
+		contentType := string(ctx.Response().Header.ContentType())
+		if saveErr := repo.SaveResponse(ctx.Context(), userId, key, status, contentType, ctx.Response().Body()); saveErr != nil {
+			log.Error("Error saving idempotent response: ", saveErr)
+		}
+
+		return nil
+	}
+}
+
+// releaseKey deletes the key of a request that failed, so that the client can retry with it right away
+func releaseKey(ctx *fiber.Ctx, repo repositories.IdempotencyRepository, userId string, key string) {
+	if err := repo.Delete(ctx.Context(), userId, key); err != nil {
+		log.Error("Error deleting idempotency key: ", err)
+	}
+}
+
+func replay(ctx *fiber.Ctx, record *models.IdempotencyKey) error {
+	ctx.Set(IdempotentReplayedHeader, "true")
+	if record.ContentType != "" {
+		ctx.Set(fiber.HeaderContentType, record.ContentType)
+	}
+	return ctx.Status(record.StatusCode).Send(record.ResponseBody)
+}
+
+// requestFingerprint identifies a request by its user, method, path and body. Including the user keeps
+// one user from replaying the stored response of another.
+func requestFingerprint(ctx *fiber.Ctx) string {
+	hash := sha256.New()
+	hash.Write([]byte(auth.UserId(ctx)))
+	hash.Write([]byte{0})
+	hash.Write([]byte(ctx.Method()))
+	hash.Write([]byte{0})
+	hash.Write([]byte(ctx.Path()))
+	hash.Write([]byte{0})
+	hash.Write(ctx.Body())
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,308 @@
+package middlewares
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/auth"
+	"ticket-purchase/internal/db/models"
+	"ticket-purchase/internal/i18n"
+	"ticket-purchase/internal/mocks/repositories"
+	"time"
+)
+
+const testUserHeader = "X-Test-User"
+
+var idempotencyRepo *repositories.MockIdempotencyRepository
+var app *fiber.App
+var handlerCalls int
+
+func setupIdempotencyTest(t *testing.T) func() {
+	ct := gomock.NewController(t)
+
+	i18n.InitBundle("./../../../internal/i18n/languages")
+	idempotencyRepo = repositories.NewMockIdempotencyRepository(ct)
+
+	mockTime := time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time {
+		return mockTime
+	}
+
+	handlerCalls = 0
+	app = fiber.New(config.FiberConfig)
+	app.Use(recover.New())
+	app.Use(func(ctx *fiber.Ctx) error {
+		if userId := ctx.Get(testUserHeader); userId != "" {
+			auth.SetClaims(ctx, &auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: userId}})
+		}
+		return ctx.Next()
+	})
+	app.Post("/tickets/:id/purchase", Idempotency(idempotencyRepo, config.IdempotencyConfig{TTL: time.Hour, Lease: time.Minute}), func(ctx *fiber.Ctx) error {
+		handlerCalls++
+		if ctx.Params("id") == "panic" {
+			panic("handler failed")
+		}
+		if ctx.Params("id") == "broken" {
+			return ctx.Status(fiber.StatusInternalServerError).SendString("broken")
+		}
+		if ctx.Params("id") == "sold-out" {
+			return apperrors.ErrTicketAllocations
+		}
+		return ctx.Status(fiber.StatusOK).JSON(fiber.Map{"success": true})
+	})
+
+	return func() {
+		timeNow = time.Now
+		ct.Finish()
+	}
+}
+
+func doRequest(t *testing.T, path string, key string, body string) (int, string, string) {
+	return doUserRequest(t, "", path, key, body)
+}
+
+func doUserRequest(t *testing.T, userId string, path string, key string, body string) (int, string, string) {
+	req := httptest.NewRequest(fiber.MethodPost, path, strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	if userId != "" {
+		req.Header.Set(testUserHeader, userId)
+	}
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data), resp.Header.Get(IdempotentReplayedHeader)
+}
+
+func TestIdempotency_Without_Key(t *testing.T) {
+	teardown := setupIdempotencyTest(t)
+	defer teardown()
+
+	status, _, _ := doRequest(t, "/tickets/1/purchase", "", `{"quantity":1}`)
+
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, 1, handlerCalls)
+}
+
+func TestIdempotency_First_Request_Stores_Response(t *testing.T) {
+	teardown := setupIdempotencyTest(t)
+	defer teardown()
+
+	idempotencyRepo.EXPECT().FindByKey(gomock.Any(), "", "key-1").Return(nil, gorm.ErrRecordNotFound)
+	idempotencyRepo.EXPECT().Acquire(gomock.Any(), gomock.Any(), timeNow()).DoAndReturn(
+		func(_ any, record *models.IdempotencyKey, _ time.Time) (bool, error) {
+			assert.Equal(t, "key-1", record.Key)
+			assert.Equal(t, timeNow().Add(time.Hour), record.ExpiresAt)
+			assert.Equal(t, timeNow().Add(time.Minute), record.LockedUntil)
+			return true, nil
+		})
+	idempotencyRepo.EXPECT().SaveResponse(gomock.Any(), "", "key-1", fiber.StatusOK, fiber.MIMEApplicationJSON, []byte(`{"success":true}`)).Return(nil)
+
+	status, body, replayed := doRequest(t, "/tickets/1/purchase", "key-1", `{"quantity":1}`)
+
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, `{"success":true}`, body)
+	assert.Empty(t, replayed)
+	assert.Equal(t, 1, handlerCalls)
+}
+
+func TestIdempotency_Replays_Stored_Response(t *testing.T) {
+	teardown := setupIdempotencyTest(t)
+	defer teardown()
+
+	idempotencyRepo.EXPECT().FindByKey(gomock.Any(), "", "key-1").Return(&models.IdempotencyKey{
+		Key:          "key-1",
+		Fingerprint:  fingerprintOf(t, "/tickets/1/purchase", `{"quantity":1}`),
+		StatusCode:   fiber.StatusOK,
+		ContentType:  fiber.MIMEApplicationJSON,
+		ResponseBody: []byte(`{"success":true}`),
+		ExpiresAt:    timeNow().Add(time.Minute),
+	}, nil)
+
+	status, body, replayed := doRequest(t, "/tickets/1/purchase", "key-1", `{"quantity":1}`)
+
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, `{"success":true}`, body)
+	assert.Equal(t, "true", replayed)
+	assert.Equal(t, 0, handlerCalls)
+}
+
+func TestIdempotency_Rejects_Different_Body(t *testing.T) {
+	teardown := setupIdempotencyTest(t)
+	defer teardown()
+
+	idempotencyRepo.EXPECT().FindByKey(gomock.Any(), "", "key-1").Return(&models.IdempotencyKey{
+		Key:         "key-1",
+		Fingerprint: fingerprintOf(t, "/tickets/1/purchase", `{"quantity":1}`),
+		StatusCode:  fiber.StatusOK,
+		ExpiresAt:   timeNow().Add(time.Minute),
+	}, nil)
+
+	status, _, _ := doRequest(t, "/tickets/1/purchase", "key-1", `{"quantity":2}`)
+
+	assert.Equal(t, fiber.StatusUnprocessableEntity, status)
+	assert.Equal(t, 0, handlerCalls)
+}
+
+func TestIdempotency_In_Progress(t *testing.T) {
+	teardown := setupIdempotencyTest(t)
+	defer teardown()
+
+	idempotencyRepo.EXPECT().FindByKey(gomock.Any(), "", "key-1").Return(nil, gorm.ErrRecordNotFound)
+	idempotencyRepo.EXPECT().Acquire(gomock.Any(), gomock.Any(), timeNow()).Return(false, nil)
+
+	status, _, _ := doRequest(t, "/tickets/1/purchase", "key-1", `{"quantity":1}`)
+
+	assert.Equal(t, fiber.StatusConflict, status)
+	assert.Equal(t, 0, handlerCalls)
+}
+
+func TestIdempotency_Expired_Key_Is_Processed_Again(t *testing.T) {
+	teardown := setupIdempotencyTest(t)
+	defer teardown()
+
+	idempotencyRepo.EXPECT().FindByKey(gomock.Any(), "", "key-1").Return(&models.IdempotencyKey{
+		Key:         "key-1",
+		Fingerprint: "other",
+		StatusCode:  fiber.StatusOK,
+		ExpiresAt:   timeNow().Add(-time.Minute),
+	}, nil)
+	idempotencyRepo.EXPECT().Acquire(gomock.Any(), gomock.Any(), timeNow()).Return(true, nil)
+	idempotencyRepo.EXPECT().SaveResponse(gomock.Any(), "", "key-1", fiber.StatusOK, gomock.Any(), gomock.Any()).Return(nil)
+
+	status, _, _ := doRequest(t, "/tickets/1/purchase", "key-1", `{"quantity":2}`)
+
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, 1, handlerCalls)
+}
+
+func TestIdempotency_Server_Error_Releases_Key(t *testing.T) {
+	teardown := setupIdempotencyTest(t)
+	defer teardown()
+
+	idempotencyRepo.EXPECT().FindByKey(gomock.Any(), "", "key-1").Return(nil, gorm.ErrRecordNotFound)
+	idempotencyRepo.EXPECT().Acquire(gomock.Any(), gomock.Any(), timeNow()).Return(true, nil)
+	idempotencyRepo.EXPECT().Delete(gomock.Any(), "", "key-1").Return(nil)
+
+	status, _, _ := doRequest(t, "/tickets/broken/purchase", "key-1", `{"quantity":1}`)
+
+	assert.Equal(t, fiber.StatusInternalServerError, status)
+	assert.Equal(t, 1, handlerCalls)
+}
+
+func TestIdempotency_Panic_Releases_Key(t *testing.T) {
+	teardown := setupIdempotencyTest(t)
+	defer teardown()
+
+	idempotencyRepo.EXPECT().FindByKey(gomock.Any(), "", "key-1").Return(nil, gorm.ErrRecordNotFound)
+	idempotencyRepo.EXPECT().Acquire(gomock.Any(), gomock.Any(), timeNow()).Return(true, nil)
+	idempotencyRepo.EXPECT().Delete(gomock.Any(), "", "key-1").Return(nil)
+
+	status, _, _ := doRequest(t, "/tickets/panic/purchase", "key-1", `{"quantity":1}`)
+
+	assert.Equal(t, fiber.StatusInternalServerError, status)
+	assert.Equal(t, 1, handlerCalls)
+}
+
+func TestIdempotency_Running_Request_Holds_Key(t *testing.T) {
+	teardown := setupIdempotencyTest(t)
+	defer teardown()
+
+	idempotencyRepo.EXPECT().FindByKey(gomock.Any(), "", "key-1").Return(&models.IdempotencyKey{
+		Key:         "key-1",
+		Fingerprint: fingerprintOf(t, "/tickets/1/purchase", `{"quantity":1}`),
+		ExpiresAt:   timeNow().Add(time.Hour),
+		LockedUntil: timeNow().Add(30 * time.Second),
+	}, nil)
+	idempotencyRepo.EXPECT().Acquire(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	status, _, _ := doRequest(t, "/tickets/1/purchase", "key-1", `{"quantity":1}`)
+
+	assert.Equal(t, fiber.StatusConflict, status)
+	assert.Equal(t, 0, handlerCalls)
+}
+
+func TestIdempotency_Lapsed_Lease_Is_Taken_Over(t *testing.T) {
+	teardown := setupIdempotencyTest(t)
+	defer teardown()
+
+	// The request holding the key crashed a while ago without releasing it
+	idempotencyRepo.EXPECT().FindByKey(gomock.Any(), "", "key-1").Return(&models.IdempotencyKey{
+		Key:         "key-1",
+		Fingerprint: fingerprintOf(t, "/tickets/1/purchase", `{"quantity":1}`),
+		ExpiresAt:   timeNow().Add(time.Hour),
+		LockedUntil: timeNow().Add(-time.Second),
+	}, nil)
+	idempotencyRepo.EXPECT().Acquire(gomock.Any(), gomock.Any(), timeNow()).Return(true, nil)
+	idempotencyRepo.EXPECT().SaveResponse(gomock.Any(), "", "key-1", fiber.StatusOK, gomock.Any(), gomock.Any()).Return(nil)
+
+	status, _, _ := doRequest(t, "/tickets/1/purchase", "key-1", `{"quantity":1}`)
+
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, 1, handlerCalls)
+}
+
+func TestIdempotency_Client_Error_Is_Stored(t *testing.T) {
+	teardown := setupIdempotencyTest(t)
+	defer teardown()
+
+	idempotencyRepo.EXPECT().FindByKey(gomock.Any(), "", "key-1").Return(nil, gorm.ErrRecordNotFound)
+	idempotencyRepo.EXPECT().Acquire(gomock.Any(), gomock.Any(), timeNow()).Return(true, nil)
+	idempotencyRepo.EXPECT().SaveResponse(gomock.Any(), "", "key-1", fiber.StatusBadRequest, fiber.MIMEApplicationJSON, gomock.Any()).Return(nil)
+
+	status, body, _ := doRequest(t, "/tickets/sold-out/purchase", "key-1", `{"quantity":1}`)
+
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	assert.Contains(t, body, `"success":false`)
+	assert.Equal(t, 1, handlerCalls)
+}
+
+func TestIdempotency_Keys_Are_Scoped_To_User(t *testing.T) {
+	teardown := setupIdempotencyTest(t)
+	defer teardown()
+
+	idempotencyRepo.EXPECT().FindByKey(gomock.Any(), "user-2", "key-1").Return(nil, gorm.ErrRecordNotFound)
+	idempotencyRepo.EXPECT().Acquire(gomock.Any(), gomock.Any(), timeNow()).DoAndReturn(
+		func(_ any, record *models.IdempotencyKey, _ time.Time) (bool, error) {
+			assert.Equal(t, "user-2", record.UserId)
+			assert.Equal(t, "key-1", record.Key)
+			return true, nil
+		})
+	idempotencyRepo.EXPECT().SaveResponse(gomock.Any(), "user-2", "key-1", fiber.StatusOK, gomock.Any(), gomock.Any()).Return(nil)
+
+	status, _, replayed := doUserRequest(t, "user-2", "/tickets/1/purchase", "key-1", `{"quantity":1}`)
+
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "", replayed)
+	assert.Equal(t, 1, handlerCalls)
+}
+
+// fingerprintOf captures the fingerprint the middleware computes for a purchase request
+func fingerprintOf(t *testing.T, path string, body string) string {
+	var fingerprint string
+	fingerprintApp := fiber.New()
+	fingerprintApp.Post("/tickets/:id/purchase", func(ctx *fiber.Ctx) error {
+		fingerprint = requestFingerprint(ctx)
+		return nil
+	})
+
+	if _, err := fingerprintApp.Test(httptest.NewRequest(fiber.MethodPost, path, strings.NewReader(body))); err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	return fingerprint
+}
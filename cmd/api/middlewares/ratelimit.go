@@ -0,0 +1,98 @@
+package middlewares
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"math"
+	"strconv"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/auth"
+	"ticket-purchase/internal/ratelimit"
+	"time"
+)
+
+const (
+	APIKeyHeader             = "X-API-Key"
+	RateLimitLimitHeader     = "X-RateLimit-Limit"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	RateLimitResetHeader     = "X-RateLimit-Reset"
+)
+
+// RateLimit limits the requests of every client to a route by the policy the configuration gives it. Every route has
+// its own buckets. Clients are identified by a known API key, the authenticated user or their IP, so it runs after
+// Authenticate on routes with a user. Requests are let through when the limiter is unavailable.
+func RateLimit(limiter ratelimit.Limiter, conf config.RateLimitConfig, route string) fiber.Handler {
+	if !conf.Enabled {
+		return func(ctx *fiber.Ctx) error {
+			return ctx.Next()
+		}
+	}
+
+	policyName, ok := conf.Routes[route]
+	if !ok {
+		panic("rate limit route " + route + " is not configured")
+	}
+	policy, ok := conf.Policies[policyName]
+	if !ok {
+		panic("rate limit policy " + policyName + " of route " + route + " is not configured")
+	}
+
+	apiKeys := make(map[string]bool, len(conf.APIKeys))
+	for _, key := range conf.APIKeys {
+		apiKeys[hashAPIKey(key)] = true
+	}
+
+	return func(ctx *fiber.Ctx) error {
+		result, err := limiter.Allow(ctx.Context(), route+":"+clientKey(ctx, apiKeys), policy)
+		if err != nil {
+			log.Warn("Rate limiter is unavailable, allowing the request: ", err)
+			return ctx.Next()
+		}
+
+		ctx.Set(RateLimitLimitHeader, strconv.Itoa(result.Limit))
+		ctx.Set(RateLimitRemainingHeader, strconv.Itoa(result.Remaining))
+		ctx.Set(RateLimitResetHeader, strconv.Itoa(seconds(result.Reset)))
+
+		if !result.Allowed {
+			retryAfter := seconds(result.RetryAfter)
+			ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+			return apperrors.ErrTooManyRequests.WithData(fiber.Map{"retry_after": retryAfter})
+		}
+		return ctx.Next()
+	}
+}
+
+// clientKey identifies the client of a request. API keys are hashed so that they are not stored in Redis.
+func clientKey(ctx *fiber.Ctx, apiKeys map[string]bool) string {
+	if key := ctx.Get(APIKeyHeader); key != "" {
+		if hashed := hashAPIKey(key); apiKeys[hashed] {
+			return "key:" + hashed
+		}
+	}
+
+	if userId := auth.UserId(ctx); userId != "" {
+		return "user:" + userId
+	}
+	return "ip:" + clientIP(ctx)
+}
+
+// clientIP is the address nginx puts into X-Forwarded-For. Earlier entries come from the client and are not trusted.
+func clientIP(ctx *fiber.Ctx) string {
+	if ips := ctx.IPs(); len(ips) > 0 {
+		return ips[len(ips)-1]
+	}
+	return ctx.IP()
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:16])
+}
+
+// seconds rounds up, so that a client waiting this long is never rejected again
+func seconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
@@ -0,0 +1,172 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/auth"
+	"ticket-purchase/internal/i18n"
+	"ticket-purchase/internal/ratelimit"
+	"time"
+)
+
+const rateLimitTestAPIKey = "partner-key"
+
+var rateLimitTestConf = config.RateLimitConfig{
+	Enabled: true,
+	Policies: map[string]config.RateLimitPolicy{
+		config.RateLimitPolicyPurchase: {Limit: 2, Period: time.Minute, Burst: 2},
+	},
+	Routes: map[string]string{
+		config.RateLimitRoutePurchase: config.RateLimitPolicyPurchase,
+		config.RateLimitRouteCheckout: config.RateLimitPolicyPurchase,
+	},
+	APIKeys: []string{rateLimitTestAPIKey},
+}
+
+// recordingLimiter remembers the keys it was asked for and delegates to an in-memory limiter
+type recordingLimiter struct {
+	next ratelimit.Limiter
+	keys []string
+	err  error
+}
+
+func (l *recordingLimiter) Allow(ctx context.Context, key string, policy config.RateLimitPolicy) (ratelimit.Result, error) {
+	l.keys = append(l.keys, key)
+	if l.err != nil {
+		return ratelimit.Result{}, l.err
+	}
+	return l.next.Allow(ctx, key, policy)
+}
+
+func setupRateLimitTest(t *testing.T, conf config.RateLimitConfig) (*fiber.App, *recordingLimiter) {
+	i18n.InitBundle("./../../../internal/i18n/languages")
+	limiter := &recordingLimiter{next: ratelimit.NewMemoryLimiter()}
+
+	rateLimitApp := fiber.New(config.FiberConfig)
+	rateLimitApp.Post("/purchase", RateLimit(limiter, conf, config.RateLimitRoutePurchase), func(ctx *fiber.Ctx) error {
+		return ctx.SendString("ok")
+	})
+	rateLimitApp.Post("/checkout", RateLimit(limiter, conf, config.RateLimitRouteCheckout), func(ctx *fiber.Ctx) error {
+		return ctx.SendString("ok")
+	})
+	return rateLimitApp, limiter
+}
+
+func doRateLimitRequest(t *testing.T, rateLimitApp *fiber.App, headers map[string]string) (*http.Response, string) {
+	return doRateLimitRouteRequest(t, rateLimitApp, "/purchase", headers)
+}
+
+func doRateLimitRouteRequest(t *testing.T, rateLimitApp *fiber.App, path string, headers map[string]string) (*http.Response, string) {
+	req := httptest.NewRequest(fiber.MethodPost, path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := rateLimitApp.Test(req)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	data, _ := io.ReadAll(resp.Body)
+	return resp, string(data)
+}
+
+func TestRateLimit_Headers_And_Localized_429(t *testing.T) {
+	rateLimitApp, _ := setupRateLimitTest(t, rateLimitTestConf)
+
+	resp, _ := doRateLimitRequest(t, rateLimitApp, nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "2", resp.Header.Get(RateLimitLimitHeader))
+	assert.Equal(t, "1", resp.Header.Get(RateLimitRemainingHeader))
+	assert.Equal(t, "30", resp.Header.Get(RateLimitResetHeader))
+
+	_, _ = doRateLimitRequest(t, rateLimitApp, nil)
+	resp, body := doRateLimitRequest(t, rateLimitApp, map[string]string{fiber.HeaderAcceptLanguage: "tr"})
+
+	assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "30", resp.Header.Get(fiber.HeaderRetryAfter))
+	assert.Equal(t, "0", resp.Header.Get(RateLimitRemainingHeader))
+	assert.Contains(t, body, "Çok fazla istek gönderildi")
+	assert.Contains(t, body, `"retry_after":30`)
+}
+
+func TestRateLimit_Client_Keys(t *testing.T) {
+	rateLimitApp, limiter := setupRateLimitTest(t, rateLimitTestConf)
+
+	// The address appended by nginx is used, the one sent by the client is not trusted
+	_, _ = doRateLimitRequest(t, rateLimitApp, map[string]string{fiber.HeaderXForwardedFor: "10.0.0.1, 192.168.1.5"})
+	_, _ = doRateLimitRequest(t, rateLimitApp, map[string]string{APIKeyHeader: rateLimitTestAPIKey})
+	_, _ = doRateLimitRequest(t, rateLimitApp, map[string]string{APIKeyHeader: "unknown", fiber.HeaderXForwardedFor: "192.168.1.6"})
+
+	assert.Equal(t, "purchase:ip:192.168.1.5", limiter.keys[0])
+	assert.Equal(t, "purchase:key:"+hashAPIKey(rateLimitTestAPIKey), limiter.keys[1])
+	assert.Equal(t, "purchase:ip:192.168.1.6", limiter.keys[2])
+}
+
+func TestRateLimit_Routes_Have_Own_Buckets(t *testing.T) {
+	rateLimitApp, limiter := setupRateLimitTest(t, rateLimitTestConf)
+
+	// Both routes use the purchase policy, using up the purchases leaves the checkouts of the client alone
+	for i := 0; i < 2; i++ {
+		_, _ = doRateLimitRequest(t, rateLimitApp, nil)
+	}
+	resp, _ := doRateLimitRequest(t, rateLimitApp, nil)
+	assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+
+	resp, _ = doRateLimitRouteRequest(t, rateLimitApp, "/checkout", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get(RateLimitRemainingHeader))
+	assert.Equal(t, "checkout:ip:0.0.0.0", limiter.keys[3])
+}
+
+func TestRateLimit_Unknown_Route(t *testing.T) {
+	assert.Panics(t, func() {
+		RateLimit(ratelimit.NewMemoryLimiter(), rateLimitTestConf, config.RateLimitRouteHold)
+	})
+}
+
+func TestRateLimit_Limiter_Unavailable(t *testing.T) {
+	rateLimitApp, limiter := setupRateLimitTest(t, rateLimitTestConf)
+	limiter.err = errors.New("connection refused")
+
+	for i := 0; i < 3; i++ {
+		resp, _ := doRateLimitRequest(t, rateLimitApp, nil)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	}
+}
+
+func TestRateLimit_Disabled(t *testing.T) {
+	rateLimitApp, limiter := setupRateLimitTest(t, config.RateLimitConfig{})
+
+	for i := 0; i < 3; i++ {
+		resp, _ := doRateLimitRequest(t, rateLimitApp, nil)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	}
+	assert.Empty(t, limiter.keys)
+}
+
+func TestRateLimit_Authenticated_User(t *testing.T) {
+	i18n.InitBundle("./../../../internal/i18n/languages")
+	verifier, err := auth.NewVerifier(config.AuthConfig{Algorithm: auth.AlgorithmHS256, Secret: authTestSecret})
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	limiter := &recordingLimiter{next: ratelimit.NewMemoryLimiter()}
+
+	rateLimitApp := fiber.New(config.FiberConfig)
+	rateLimitApp.Post("/purchase", Authenticate(verifier), RateLimit(limiter, rateLimitTestConf, config.RateLimitRoutePurchase), func(ctx *fiber.Ctx) error {
+		return ctx.SendString("ok")
+	})
+
+	token := accessToken(t, "user-1", time.Now().Add(time.Hour))
+	_, _ = doRateLimitRequest(t, rateLimitApp, map[string]string{fiber.HeaderAuthorization: "Bearer " + token})
+
+	assert.Equal(t, []string{"purchase:user:user-1"}, limiter.keys)
+}
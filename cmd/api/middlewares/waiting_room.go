@@ -0,0 +1,33 @@
+package middlewares
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"ticket-purchase/internal/auth"
+	"ticket-purchase/internal/services"
+)
+
+const QueueTokenHeader = "X-Queue-Token"
+
+// WaitingRoom lets requests for a ticket with a waiting room through only with an admitted queue token.
+// It runs after Authenticate on routes with the ticket id in the path. An admission allows a single purchase:
+// the token is used up by the request and given back only when the request fails.
+func WaitingRoom(waitingRoomService services.WaitingRoomService) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		ticketId := ctx.Params("id")
+		token := ctx.Get(QueueTokenHeader)
+
+		used, err := waitingRoomService.CheckAdmission(ctx.Context(), ticketId, token, auth.ActorFrom(ctx))
+		if err != nil {
+			return err
+		}
+
+		err = ctx.Next()
+		if used && (err != nil || ctx.Response().StatusCode() >= fiber.StatusBadRequest) {
+			if releaseErr := waitingRoomService.ReleaseAdmission(ctx.Context(), ticketId, token); releaseErr != nil {
+				log.Error("Error releasing queue token: ", releaseErr)
+			}
+		}
+		return err
+	}
+}
@@ -0,0 +1,152 @@
+package middlewares
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/auth"
+	"ticket-purchase/internal/db/models"
+	dbRepositories "ticket-purchase/internal/db/repositories"
+	"ticket-purchase/internal/i18n"
+	"ticket-purchase/internal/mocks/repositories"
+	"ticket-purchase/internal/services"
+	"time"
+)
+
+const waitingRoomTestTicketId = "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b"
+
+// setupWaitingRoomTest serves a purchase route behind the waiting room of a ticket. Purchases of quantity 0 fail.
+func setupWaitingRoomTest(t *testing.T) (*fiber.App, dbRepositories.WaitingRoomRepository, *int) {
+	i18n.InitBundle("./../../../internal/i18n/languages")
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	ticketRepo := repositories.NewMockTicketRepository(gomock.NewController(t))
+	ticketRepo.EXPECT().FindById(gomock.Any(), waitingRoomTestTicketId).Return(&models.Ticket{
+		Id:          waitingRoomTestTicketId,
+		IsActive:    true,
+		WaitingRoom: true,
+	}, nil).AnyTimes()
+
+	waitingRoomRepo := dbRepositories.NewWaitingRoomRepository(client)
+	waitingRoomService := services.NewWaitingRoomService(waitingRoomRepo, ticketRepo, config.WaitingRoomConfig{
+		AdmissionsPerSecond: 1,
+		AdmissionWindow:     time.Minute,
+	})
+
+	purchases := 0
+	waitingRoomApp := fiber.New(config.FiberConfig)
+	waitingRoomApp.Post("/tickets/:id/purchase", func(ctx *fiber.Ctx) error {
+		auth.SetClaims(ctx, &auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: ctx.Get(testUserHeader)}})
+		return ctx.Next()
+	}, WaitingRoom(waitingRoomService), func(ctx *fiber.Ctx) error {
+		if ctx.Query("quantity") == "0" {
+			return ctx.Status(fiber.StatusBadRequest).SendString("invalid quantity")
+		}
+		purchases++
+		return ctx.SendString("ok")
+	})
+	return waitingRoomApp, waitingRoomRepo, &purchases
+}
+
+func doWaitingRoomRequest(t *testing.T, waitingRoomApp *fiber.App, token string, query string) (int, string) {
+	req := httptest.NewRequest(fiber.MethodPost, "/tickets/"+waitingRoomTestTicketId+"/purchase"+query, nil)
+	req.Header.Set(testUserHeader, "buyer")
+	if token != "" {
+		req.Header.Set(QueueTokenHeader, token)
+	}
+
+	resp, err := waitingRoomApp.Test(req)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data)
+}
+
+// joinQueue queues the buyer and admits them when admit is set
+func joinQueue(t *testing.T, waitingRoomRepo dbRepositories.WaitingRoomRepository, admit bool) string {
+	entry, err := waitingRoomRepo.Join(context.Background(), waitingRoomTestTicketId, "buyer", "token-1", time.Now())
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	if admit {
+		if _, err := waitingRoomRepo.Admit(context.Background(), waitingRoomTestTicketId, time.Now(), 1, time.Minute); err != nil {
+			t.Fatalf("Expected error to be nil, got %v", err)
+		}
+	}
+	return entry.Token
+}
+
+func TestWaitingRoom_Missing_Token(t *testing.T) {
+	waitingRoomApp, _, purchases := setupWaitingRoomTest(t)
+
+	status, body := doWaitingRoomRequest(t, waitingRoomApp, "", "")
+
+	assert.Equal(t, fiber.StatusForbidden, status)
+	assert.Contains(t, body, "join the queue first")
+	assert.Equal(t, 0, *purchases)
+}
+
+func TestWaitingRoom_Not_Admitted(t *testing.T) {
+	waitingRoomApp, waitingRoomRepo, purchases := setupWaitingRoomTest(t)
+	token := joinQueue(t, waitingRoomRepo, false)
+
+	status, body := doWaitingRoomRequest(t, waitingRoomApp, token, "")
+
+	assert.Equal(t, fiber.StatusForbidden, status)
+	assert.Contains(t, body, "has not come yet")
+	assert.Equal(t, 0, *purchases)
+}
+
+func TestWaitingRoom_Admitted(t *testing.T) {
+	waitingRoomApp, waitingRoomRepo, purchases := setupWaitingRoomTest(t)
+	token := joinQueue(t, waitingRoomRepo, true)
+
+	status, body := doWaitingRoomRequest(t, waitingRoomApp, token, "")
+
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "ok", body)
+	assert.Equal(t, 1, *purchases)
+}
+
+func TestWaitingRoom_Reused_Token(t *testing.T) {
+	waitingRoomApp, waitingRoomRepo, purchases := setupWaitingRoomTest(t)
+	token := joinQueue(t, waitingRoomRepo, true)
+
+	status, _ := doWaitingRoomRequest(t, waitingRoomApp, token, "")
+	assert.Equal(t, fiber.StatusOK, status)
+
+	status, body := doWaitingRoomRequest(t, waitingRoomApp, token, "")
+
+	assert.Equal(t, fiber.StatusForbidden, status)
+	assert.Contains(t, body, "already used")
+	assert.Equal(t, 1, *purchases)
+}
+
+func TestWaitingRoom_Failed_Purchase_Keeps_Token(t *testing.T) {
+	waitingRoomApp, waitingRoomRepo, purchases := setupWaitingRoomTest(t)
+	token := joinQueue(t, waitingRoomRepo, true)
+
+	status, _ := doWaitingRoomRequest(t, waitingRoomApp, token, "?quantity=0")
+	assert.Equal(t, fiber.StatusBadRequest, status)
+
+	status, _ = doWaitingRoomRequest(t, waitingRoomApp, token, "")
+
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, 1, *purchases)
+}
@@ -3,10 +3,16 @@ package api
 import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/swagger"
-	"gorm.io/gorm"
+	authapi "ticket-purchase/cmd/api/handlers/v1/auth"
+	"ticket-purchase/cmd/api/handlers/v1/hold"
+	"ticket-purchase/cmd/api/handlers/v1/order"
+	"ticket-purchase/cmd/api/handlers/v1/purchase"
 	"ticket-purchase/cmd/api/handlers/v1/ticket"
-	"ticket-purchase/internal/db/repositories"
-	"ticket-purchase/internal/services"
+	"ticket-purchase/cmd/api/handlers/v1/waitingroom"
+	"ticket-purchase/cmd/api/handlers/v1/webhook"
+	"ticket-purchase/cmd/api/middlewares"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/auth"
 )
 
 // HealthCheck godoc
@@ -23,17 +29,30 @@ func health(ctx *fiber.Ctx) error {
 	})
 }
 
-func InitializeRouters(app *fiber.App, connection *gorm.DB) {
-
-	// Repositories
-	ticketRepository := repositories.NewTicketRepository(connection)
-	purchaseRepository := repositories.NewPurchaseRepository(connection)
+// InitializeRouters registers the routes of the API on app with the services of deps
+func InitializeRouters(app *fiber.App, deps *Dependencies) {
+	// Handlers
+	ticketHandler := ticket.New(deps.TicketService)
+	holdHandler := hold.New(deps.HoldService)
+	purchaseHandler := purchase.New(deps.PurchaseService)
+	orderHandler := order.New(deps.OrderService)
+	authHandler := authapi.New(deps.AuthService)
+	webhookHandler := webhook.New(deps.WebhookService, deps.WebhookSubscriptionService)
 
-	// Services
-	ticketService := services.NewTicketService(ticketRepository, purchaseRepository)
+	// Middlewares
+	authenticate := middlewares.Authenticate(deps.Verifier)
+	optionalAuthenticate := middlewares.OptionalAuthenticate(deps.Verifier)
+	idempotency := middlewares.Idempotency(deps.IdempotencyRepository, deps.Config.Idempotency)
+	waitingRoom := func(ctx *fiber.Ctx) error {
+		return ctx.Next()
+	}
+	if deps.WaitingRoomService != nil {
+		waitingRoom = middlewares.WaitingRoom(deps.WaitingRoomService)
+	}
 
-	// Handlers
-	ticketHandler := ticket.New(ticketService)
+	rateLimit := func(route string) fiber.Handler {
+		return middlewares.RateLimit(deps.RateLimiter, deps.Config.RateLimit, route)
+	}
 
 	// Initialize the routes for the application here
 	v1 := app.Group("/v1")
@@ -44,9 +63,66 @@ func InitializeRouters(app *fiber.App, connection *gorm.DB) {
 	// Health check
 	v1.Get("/health", health)
 
+	// Every route below is limited per client, sales routes also per user
+	v1.Use(rateLimit(config.RateLimitRouteDefault))
+
+	authRouter := v1.Group("/auth", rateLimit(config.RateLimitRouteAuth))
+	authRouter.Post("/register", authHandler.Register)
+	authRouter.Post("/login", authHandler.Login)
+	authRouter.Post("/refresh", authHandler.Refresh)
+
 	// Initialize the routes for the application here
 	ticketRouter := v1.Group("/tickets")
-	ticketRouter.Post("/", ticketHandler.CreateTicket)
-	ticketRouter.Get("/:id", ticketHandler.GetTicket)
-	ticketRouter.Post("/:id/purchase", ticketHandler.PurchaseTicket)
+	// The catalog is public, everything else needs an access token.
+	// Authentication runs before idempotency so that stored responses are bound to the user.
+	// Ownership of tickets and purchases is checked by the services.
+	ticketRouter.Get("/", optionalAuthenticate, ticketHandler.ListTickets)
+	ticketRouter.Post("/", authenticate, middlewares.Authorize(auth.PermTicketCreate), idempotency, ticketHandler.CreateTicket)
+	ticketRouter.Get("/:id", optionalAuthenticate, ticketHandler.GetTicket)
+	ticketRouter.Patch("/:id", authenticate, middlewares.Authorize(auth.PermTicketManage), ticketHandler.UpdateTicket)
+	ticketRouter.Delete("/:id", authenticate, middlewares.Authorize(auth.PermTicketManage), ticketHandler.DeleteTicket)
+	ticketRouter.Post("/:id/restore", authenticate, middlewares.Authorize(auth.PermTicketManage), ticketHandler.RestoreTicket)
+	ticketRouter.Post("/:id/purchase", authenticate, middlewares.Authorize(auth.PermTicketPurchase), rateLimit(config.RateLimitRoutePurchase), idempotency, waitingRoom, ticketHandler.PurchaseTicket)
+	ticketRouter.Post("/:id/holds", authenticate, middlewares.Authorize(auth.PermTicketPurchase), rateLimit(config.RateLimitRouteHold), idempotency, waitingRoom, holdHandler.CreateHold)
+	ticketRouter.Get("/:id/purchases", authenticate, purchaseHandler.ListTicketPurchases)
+
+	if deps.WaitingRoomService != nil {
+		waitingRoomHandler := waitingroom.New(deps.WaitingRoomService)
+		ticketRouter.Post("/:id/queue", authenticate, middlewares.Authorize(auth.PermTicketPurchase), waitingRoomHandler.JoinQueue)
+		ticketRouter.Get("/:id/queue/:token", authenticate, waitingRoomHandler.GetQueueStatus)
+	}
+
+	holdRouter := v1.Group("/holds", authenticate)
+	holdRouter.Post("/:id/confirm", middlewares.Authorize(auth.PermTicketPurchase), rateLimit(config.RateLimitRouteHoldConfirm), idempotency, holdHandler.ConfirmHold)
+
+	purchaseRouter := v1.Group("/purchases", authenticate)
+	purchaseRouter.Get("/:id", purchaseHandler.GetPurchase)
+	purchaseRouter.Post("/:id/cancel", idempotency, purchaseHandler.CancelPurchase)
+	purchaseRouter.Post("/:id/refund", idempotency, purchaseHandler.RefundPurchase)
+
+	// The cart spans several tickets, so it is not behind the waiting room. Tickets with a waiting room are refused.
+	cartRouter := v1.Group("/cart", authenticate, middlewares.Authorize(auth.PermTicketPurchase))
+	cartRouter.Get("/", orderHandler.GetCart)
+	cartRouter.Post("/lines", orderHandler.AddCartLine)
+	cartRouter.Patch("/lines/:id", orderHandler.UpdateCartLine)
+	cartRouter.Delete("/lines/:id", orderHandler.RemoveCartLine)
+	cartRouter.Post("/checkout", rateLimit(config.RateLimitRouteCheckout), idempotency, orderHandler.Checkout)
+
+	orderRouter := v1.Group("/orders", authenticate)
+	orderRouter.Get("/:id", orderHandler.GetOrder)
+
+	// Provider callbacks are authenticated by their signature
+	webhookRouter := v1.Group("/webhooks")
+	webhookRouter.Post("/payments", webhookHandler.ReceivePayment)
+
+	manageWebhooks := middlewares.Authorize(auth.PermWebhookManage)
+	webhookRouter.Post("/subscriptions", authenticate, manageWebhooks, webhookHandler.CreateSubscription)
+	webhookRouter.Get("/subscriptions", authenticate, manageWebhooks, webhookHandler.ListSubscriptions)
+	webhookRouter.Delete("/subscriptions/:id", authenticate, manageWebhooks, webhookHandler.DeleteSubscription)
+	webhookRouter.Get("/deliveries", authenticate, manageWebhooks, webhookHandler.ListDeliveries)
+	webhookRouter.Post("/deliveries/:id/redeliver", authenticate, manageWebhooks, webhookHandler.Redeliver)
+
+	userRouter := v1.Group("/users", authenticate)
+	userRouter.Get("/:userId/purchases", purchaseHandler.ListUserPurchases)
+	userRouter.Put("/:userId/role", middlewares.Authorize(auth.PermUserManage), authHandler.UpdateUserRole)
 }
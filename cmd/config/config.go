@@ -1,10 +1,13 @@
 package config
 
 import (
-	"errors"
 	"github.com/gofiber/fiber/v2"
+	"strconv"
+	"strings"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/i18n"
 	"ticket-purchase/pkg/cresponse"
-	"ticket-purchase/pkg/enum"
+	"time"
 
 	"github.com/gofiber/fiber/v2/log"
 )
@@ -14,27 +17,285 @@ type ServerConfig struct {
 	Port string
 }
 
+type HoldConfig struct {
+	// DefaultDuration is used when a hold request does not ask for a duration
+	DefaultDuration time.Duration
+	// MaxDuration is the longest a hold can reserve inventory
+	MaxDuration time.Duration
+	// SweepInterval is how often expired holds are released back to inventory
+	SweepInterval time.Duration
+}
+
+type IdempotencyConfig struct {
+	// TTL is how long a stored idempotency key and its response are kept
+	TTL time.Duration
+	// Lease is how long a request holds its key while it runs. A key whose request stopped without releasing it, e.g.
+	// because its instance crashed, can be taken over by a retry once the lease is over.
+	Lease time.Duration
+	// CleanupInterval is how often expired keys are deleted
+	CleanupInterval time.Duration
+}
+
+type AuthConfig struct {
+	// Algorithm is the signing algorithm of access tokens, HS256 or RS256
+	Algorithm string
+	// Secret is the shared HS256 key
+	Secret string
+	// PublicKey is the PEM encoded RS256 verification key. PublicKeyFile is read when it is empty.
+	PublicKey     string
+	PublicKeyFile string
+	// PrivateKey is the PEM encoded RS256 signing key. PrivateKeyFile is read when it is empty.
+	PrivateKey     string
+	PrivateKeyFile string
+	// Issuer and Audience are checked only when they are set
+	Issuer   string
+	Audience string
+	// Leeway tolerates clock skew between the token issuer and the API
+	Leeway time.Duration
+	// AccessTokenTTL and RefreshTokenTTL are the lifetimes of issued tokens
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
+type CacheConfig struct {
+	// TicketTTL is how long a ticket is served from Redis. Availability changed by purchases and holds can lag by up to this long.
+	TicketTTL time.Duration
+}
+
+// InventoryBackendRedis sells tickets from Redis counters, the default backend locks the ticket row in Postgres
+const InventoryBackendRedis = "redis"
+
+type InventoryConfig struct {
+	// Backend is where purchases take ticket allocation from, "postgres" or "redis"
+	Backend string
+	// BatchSize is how many queued sales the persister writes per round
+	BatchSize int
+	// ClaimIdle is how long a queued sale can stay unacknowledged before another instance takes it over
+	ClaimIdle time.Duration
+	// ReconcileInterval is how often the Redis counters are compared with the ticket allocations
+	ReconcileInterval time.Duration
+}
+
+// RateLimitPolicy allows Limit requests per Period on average. Burst is how many requests can be made at once
+// before the rate applies.
+type RateLimitPolicy struct {
+	Limit  int
+	Period time.Duration
+	Burst  int
+}
+
+// Rate limit policies used by the routes
+const (
+	RateLimitPolicyDefault  = "default"
+	RateLimitPolicyAuth     = "auth"
+	RateLimitPolicyPurchase = "purchase"
+)
+
+// Routes that are rate limited. Every route has buckets of its own, even when it shares a policy with another route.
+const (
+	RateLimitRouteDefault     = "default"
+	RateLimitRouteAuth        = "auth"
+	RateLimitRoutePurchase    = "purchase"
+	RateLimitRouteHold        = "hold"
+	RateLimitRouteHoldConfirm = "hold_confirm"
+	RateLimitRouteCheckout    = "checkout"
+)
+
+type RateLimitConfig struct {
+	// Enabled turns rate limiting on
+	Enabled bool
+	// Policies are the limits by name
+	Policies map[string]RateLimitPolicy
+	// Routes are the names of the policies by route
+	Routes map[string]string
+	// APIKeys are the keys of known clients sent in the X-API-Key header. Each one is limited on its own instead of by IP.
+	APIKeys []string
+}
+
+type WaitingRoomConfig struct {
+	// AdmissionsPerSecond is how many users of a waiting room are admitted per second across all instances
+	AdmissionsPerSecond int
+	// AdmissionWindow is how long an admitted user can buy
+	AdmissionWindow time.Duration
+}
+
+// PaymentProviderFake is the fake payment provider, it is the default until a real gateway is configured
+const PaymentProviderFake = "fake"
+
+type PaymentConfig struct {
+	// Provider is the payment gateway, only "fake" is available yet
+	Provider string
+	// FakeOutcome is what the fake provider answers: "succeed", "decline" or "timeout"
+	FakeOutcome string
+	// Timeout is how long a call to the provider can take
+	Timeout time.Duration
+	// WebhookSecrets verify the signatures of provider callbacks. All of them are accepted, so a new secret can be
+	// added before the provider switches to it and the old one removed afterwards.
+	WebhookSecrets []string
+	// WebhookTolerance is how far the timestamp of a callback can be from now before it is refused as a replay
+	WebhookTolerance time.Duration
+}
+
+type WebhookConfig struct {
+	// Interval is how often due deliveries are sent and BatchSize how many are sent per round
+	Interval  time.Duration
+	BatchSize int
+	// Timeout is how long a subscriber can take to answer a delivery
+	Timeout time.Duration
+	// MaxAttempts is how many times a delivery is tried before it becomes a dead letter. RetryDelay doubles after
+	// every failed attempt up to MaxRetryDelay.
+	MaxAttempts   int
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+}
+
+type OutboxConfig struct {
+	// RelayInterval is how often unpublished events are relayed to the event bus and BatchSize how many per round
+	RelayInterval time.Duration
+	BatchSize     int
+	// MaxAttempts is how often an event is published before it is parked and the events after it go on
+	MaxAttempts int
+	// Retention is how long published events are kept in the outbox
+	Retention time.Duration
+	// Stream is the Redis stream events are published to and StreamMaxLen about how many entries it keeps
+	Stream       string
+	StreamMaxLen int64
+}
+
+type MailConfig struct {
+	// Host and Port of the SMTP server. Emails are only logged when Host is empty.
+	Host string
+	Port string
+	// Username and Password authenticate to the SMTP server. The username is also the sender address.
+	Username string
+	Password string
+	// Workers is the number of concurrent deliveries and QueueSize how many emails can wait for a worker
+	Workers   int
+	QueueSize int
+	// MaxAttempts is how many times a delivery is tried. RetryDelay doubles after every failed attempt.
+	MaxAttempts int
+	RetryDelay  time.Duration
+}
+
 var FiberConfig = fiber.Config{
 	AppName:   "Ticket Purchase API",
 	BodyLimit: 1024 * 1024 * 50, // 50 MB
 
 	// Override default error handlers
+	// Handlers return domain errors, they are logged with their cause and the client gets the localized message
 	ErrorHandler: func(ctx *fiber.Ctx, err error) error {
-		// Status code defaults to 500
-		var code int = fiber.StatusInternalServerError
+		appErr := apperrors.From(err)
+		if appErr.Status >= fiber.StatusInternalServerError {
+			log.Error("Error occurred: ", err)
+		} else {
+			log.Warn("Request failed: ", err)
+		}
+
+		return cresponse.ErrorResponse(ctx, appErr.Status, i18n.CreateMsg(ctx, appErr.Code), appErr.Data)
+	},
+}
+
+// GetDuration parses a duration such as "24h" and returns the fallback when it is empty or invalid
+func GetDuration(value string, fallback time.Duration) time.Duration {
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return fallback
+	}
+	return duration
+}
+
+// GetInt parses a positive integer and returns the fallback when it is empty or invalid
+func GetInt(value string, fallback int) int {
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		return fallback
+	}
+	return number
+}
+
+// GetRateLimitPolicies parses policies such as "default=300/1m,purchase=5/1m:10", where the optional value after the
+// colon is the burst. The parsed policies override the defaults, invalid entries are logged and skipped.
+func GetRateLimitPolicies(value string, defaults map[string]RateLimitPolicy) map[string]RateLimitPolicy {
+	policies := make(map[string]RateLimitPolicy, len(defaults))
+	for name, policy := range defaults {
+		policies[name] = policy
+	}
 
-		// Retrieve the custom status code if it's a fiber.*Error
-		var e *fiber.Error
-		if errors.As(err, &e) {
-			code = e.Code
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
 		}
 
-		log.Error("Error occurred: ", err)
+		name, policy, ok := parseRateLimitPolicy(entry)
+		if !ok {
+			log.Warn("Ignoring invalid rate limit policy: ", entry)
+			continue
+		}
+		policies[name] = policy
+	}
+	return policies
+}
 
-		return cresponse.ErrorResponse(ctx, code, "Unexpected error occurred")
-	},
+// GetRateLimitRoutes parses the policies of routes such as "purchase=purchase,checkout=checkout". The parsed routes
+// override the defaults, invalid entries are logged and skipped.
+func GetRateLimitRoutes(value string, defaults map[string]string) map[string]string {
+	routes := make(map[string]string, len(defaults))
+	for route, policy := range defaults {
+		routes[route] = policy
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		route, policy, ok := strings.Cut(entry, "=")
+		route, policy = strings.TrimSpace(route), strings.TrimSpace(policy)
+		if !ok || route == "" || policy == "" {
+			log.Warn("Ignoring invalid rate limit route: ", entry)
+			continue
+		}
+		routes[route] = policy
+	}
+	return routes
 }
 
-func GetLanguage(ctx *fiber.Ctx) string {
-	return ctx.Get("Accept-Language", enum.DefaultLanguage)
+func parseRateLimitPolicy(entry string) (string, RateLimitPolicy, bool) {
+	name, rate, ok := strings.Cut(entry, "=")
+	if !ok || strings.TrimSpace(name) == "" {
+		return "", RateLimitPolicy{}, false
+	}
+
+	rate, burst, hasBurst := strings.Cut(rate, ":")
+	limit, period, ok := strings.Cut(rate, "/")
+	if !ok {
+		return "", RateLimitPolicy{}, false
+	}
+
+	policy := RateLimitPolicy{
+		Limit:  GetInt(strings.TrimSpace(limit), 0),
+		Period: GetDuration(strings.TrimSpace(period), 0),
+	}
+	policy.Burst = policy.Limit
+	if hasBurst {
+		policy.Burst = GetInt(strings.TrimSpace(burst), 0)
+	}
+
+	if policy.Limit == 0 || policy.Period == 0 || policy.Burst == 0 {
+		return "", RateLimitPolicy{}, false
+	}
+	return strings.TrimSpace(name), policy, true
+}
+
+// GetList splits a comma separated value and drops empty entries
+func GetList(value string) []string {
+	var list []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
 }
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestGetRateLimitPolicies(t *testing.T) {
+	defaults := map[string]RateLimitPolicy{
+		"default":  {Limit: 300, Period: time.Minute, Burst: 300},
+		"purchase": {Limit: 5, Period: time.Minute, Burst: 5},
+	}
+
+	policies := GetRateLimitPolicies(" purchase=10/30s:20, auth=3/1h, broken=5, zero=0/1m, nameless=/1m ", defaults)
+
+	assert.Equal(t, map[string]RateLimitPolicy{
+		"default":  {Limit: 300, Period: time.Minute, Burst: 300},
+		"purchase": {Limit: 10, Period: 30 * time.Second, Burst: 20},
+		"auth":     {Limit: 3, Period: time.Hour, Burst: 3},
+	}, policies)
+
+	// The defaults are not changed
+	assert.Equal(t, 5, defaults["purchase"].Limit)
+}
+
+func TestGetRateLimitRoutes(t *testing.T) {
+	defaults := map[string]string{"purchase": "purchase", "checkout": "purchase"}
+
+	routes := GetRateLimitRoutes(" checkout=checkout, hold=purchase, broken, =purchase, empty= ", defaults)
+
+	assert.Equal(t, map[string]string{
+		"purchase": "purchase",
+		"checkout": "checkout",
+		"hold":     "purchase",
+	}, routes)
+	assert.Equal(t, "purchase", defaults["checkout"])
+}
+
+func TestGetList(t *testing.T) {
+	assert.Equal(t, []string{"a", "b"}, GetList(" a, ,b,"))
+	assert.Nil(t, GetList(""))
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 	"os"
 	"os/signal"
@@ -17,15 +18,32 @@ import (
 	"ticket-purchase/cmd/api"
 	"ticket-purchase/cmd/config"
 	"ticket-purchase/docs"
+	"ticket-purchase/internal/auth"
 	"ticket-purchase/internal/db/connection"
+	"ticket-purchase/internal/events"
 	"ticket-purchase/internal/i18n"
 	"time"
 )
 
 var once sync.Once
 var conn *gorm.DB
+var redisClient *redis.Client
 
 var serverConf config.ServerConfig
+var idempotencyConf config.IdempotencyConfig
+var holdConf config.HoldConfig
+var authConf config.AuthConfig
+var mailConf config.MailConfig
+var cacheConf config.CacheConfig
+var inventoryConf config.InventoryConfig
+var rateLimitConf config.RateLimitConfig
+var waitingRoomConf config.WaitingRoomConfig
+var paymentConf config.PaymentConfig
+var webhookConf config.WebhookConfig
+var outboxConf config.OutboxConfig
+
+// stopWorkers cancels the background workers on shutdown
+var stopWorkers context.CancelFunc = func() {}
 
 func init() {
 	once.Do(func() {
@@ -40,6 +58,11 @@ func init() {
 			Timezone: os.Getenv("DB_TIMEZONE"),
 		})
 
+		redisClient = connection.RedisConnection(connection.RedisConfig{
+			Addr:     os.Getenv("REDIS_ADDR"),
+			Password: os.Getenv("REDIS_PASSWORD"),
+			DB:       config.GetInt(os.Getenv("REDIS_DB"), 0),
+		})
 	})
 
 	// Initialize the config configuration
@@ -48,6 +71,109 @@ func init() {
 		Port: os.Getenv("APP_PORT"),
 	}
 
+	idempotencyConf = config.IdempotencyConfig{
+		TTL:             config.GetDuration(os.Getenv("IDEMPOTENCY_KEY_TTL"), 24*time.Hour),
+		Lease:           config.GetDuration(os.Getenv("IDEMPOTENCY_KEY_LEASE"), time.Minute),
+		CleanupInterval: config.GetDuration(os.Getenv("IDEMPOTENCY_CLEANUP_INTERVAL"), time.Hour),
+	}
+
+	holdConf = config.HoldConfig{
+		DefaultDuration: config.GetDuration(os.Getenv("HOLD_DEFAULT_DURATION"), 10*time.Minute),
+		MaxDuration:     config.GetDuration(os.Getenv("HOLD_MAX_DURATION"), 30*time.Minute),
+		SweepInterval:   config.GetDuration(os.Getenv("HOLD_SWEEP_INTERVAL"), 30*time.Second),
+	}
+
+	authConf = config.AuthConfig{
+		Algorithm:       os.Getenv("JWT_ALGORITHM"),
+		Secret:          os.Getenv("JWT_SECRET"),
+		PublicKey:       os.Getenv("JWT_PUBLIC_KEY"),
+		PublicKeyFile:   os.Getenv("JWT_PUBLIC_KEY_FILE"),
+		PrivateKey:      os.Getenv("JWT_PRIVATE_KEY"),
+		PrivateKeyFile:  os.Getenv("JWT_PRIVATE_KEY_FILE"),
+		Issuer:          os.Getenv("JWT_ISSUER"),
+		Audience:        os.Getenv("JWT_AUDIENCE"),
+		Leeway:          config.GetDuration(os.Getenv("JWT_LEEWAY"), 30*time.Second),
+		AccessTokenTTL:  config.GetDuration(os.Getenv("JWT_ACCESS_TOKEN_TTL"), 15*time.Minute),
+		RefreshTokenTTL: config.GetDuration(os.Getenv("JWT_REFRESH_TOKEN_TTL"), 30*24*time.Hour),
+	}
+	if authConf.Algorithm == "" {
+		authConf.Algorithm = auth.AlgorithmHS256
+	}
+
+	cacheConf = config.CacheConfig{
+		TicketTTL: config.GetDuration(os.Getenv("TICKET_CACHE_TTL"), 30*time.Second),
+	}
+
+	inventoryConf = config.InventoryConfig{
+		Backend:           os.Getenv("INVENTORY_BACKEND"),
+		BatchSize:         config.GetInt(os.Getenv("INVENTORY_BATCH_SIZE"), 100),
+		ClaimIdle:         config.GetDuration(os.Getenv("INVENTORY_CLAIM_IDLE"), 30*time.Second),
+		ReconcileInterval: config.GetDuration(os.Getenv("INVENTORY_RECONCILE_INTERVAL"), time.Minute),
+	}
+
+	rateLimitConf = config.RateLimitConfig{
+		Enabled: os.Getenv("RATE_LIMIT_ENABLED") != "false",
+		Policies: config.GetRateLimitPolicies(os.Getenv("RATE_LIMIT_POLICIES"), map[string]config.RateLimitPolicy{
+			config.RateLimitPolicyDefault:  {Limit: 300, Period: time.Minute, Burst: 300},
+			config.RateLimitPolicyAuth:     {Limit: 10, Period: time.Minute, Burst: 10},
+			config.RateLimitPolicyPurchase: {Limit: 5, Period: time.Minute, Burst: 5},
+		}),
+		Routes: config.GetRateLimitRoutes(os.Getenv("RATE_LIMIT_ROUTES"), map[string]string{
+			config.RateLimitRouteDefault:     config.RateLimitPolicyDefault,
+			config.RateLimitRouteAuth:        config.RateLimitPolicyAuth,
+			config.RateLimitRoutePurchase:    config.RateLimitPolicyPurchase,
+			config.RateLimitRouteHold:        config.RateLimitPolicyPurchase,
+			config.RateLimitRouteHoldConfirm: config.RateLimitPolicyPurchase,
+			config.RateLimitRouteCheckout:    config.RateLimitPolicyPurchase,
+		}),
+		APIKeys: config.GetList(os.Getenv("RATE_LIMIT_API_KEYS")),
+	}
+
+	waitingRoomConf = config.WaitingRoomConfig{
+		AdmissionsPerSecond: config.GetInt(os.Getenv("WAITING_ROOM_ADMISSIONS_PER_SECOND"), 50),
+		AdmissionWindow:     config.GetDuration(os.Getenv("WAITING_ROOM_ADMISSION_WINDOW"), 5*time.Minute),
+	}
+
+	paymentConf = config.PaymentConfig{
+		Provider:         os.Getenv("PAYMENT_PROVIDER"),
+		FakeOutcome:      os.Getenv("PAYMENT_FAKE_OUTCOME"),
+		Timeout:          config.GetDuration(os.Getenv("PAYMENT_TIMEOUT"), 10*time.Second),
+		WebhookSecrets:   config.GetList(os.Getenv("PAYMENT_WEBHOOK_SECRETS")),
+		WebhookTolerance: config.GetDuration(os.Getenv("PAYMENT_WEBHOOK_TOLERANCE"), 5*time.Minute),
+	}
+
+	webhookConf = config.WebhookConfig{
+		Interval:      config.GetDuration(os.Getenv("WEBHOOK_INTERVAL"), 5*time.Second),
+		BatchSize:     config.GetInt(os.Getenv("WEBHOOK_BATCH_SIZE"), 50),
+		Timeout:       config.GetDuration(os.Getenv("WEBHOOK_TIMEOUT"), 10*time.Second),
+		MaxAttempts:   config.GetInt(os.Getenv("WEBHOOK_MAX_ATTEMPTS"), 8),
+		RetryDelay:    config.GetDuration(os.Getenv("WEBHOOK_RETRY_DELAY"), 30*time.Second),
+		MaxRetryDelay: config.GetDuration(os.Getenv("WEBHOOK_MAX_RETRY_DELAY"), time.Hour),
+	}
+
+	outboxConf = config.OutboxConfig{
+		RelayInterval: config.GetDuration(os.Getenv("OUTBOX_RELAY_INTERVAL"), time.Second),
+		BatchSize:     config.GetInt(os.Getenv("OUTBOX_BATCH_SIZE"), 100),
+		MaxAttempts:   config.GetInt(os.Getenv("OUTBOX_MAX_ATTEMPTS"), 10),
+		Retention:     config.GetDuration(os.Getenv("OUTBOX_RETENTION"), 24*time.Hour),
+		Stream:        os.Getenv("OUTBOX_STREAM"),
+		StreamMaxLen:  int64(config.GetInt(os.Getenv("OUTBOX_STREAM_MAX_LEN"), 100000)),
+	}
+	if outboxConf.Stream == "" {
+		outboxConf.Stream = events.DefaultStream
+	}
+
+	mailConf = config.MailConfig{
+		Host:        os.Getenv("SMTP_HOST"),
+		Port:        os.Getenv("SMTP_PORT"),
+		Username:    os.Getenv("SMTP_USERNAME"),
+		Password:    os.Getenv("SMTP_PASSWORD"),
+		Workers:     config.GetInt(os.Getenv("MAIL_WORKERS"), 2),
+		QueueSize:   config.GetInt(os.Getenv("MAIL_QUEUE_SIZE"), 1000),
+		MaxAttempts: config.GetInt(os.Getenv("MAIL_MAX_ATTEMPTS"), 5),
+		RetryDelay:  config.GetDuration(os.Getenv("MAIL_RETRY_DELAY"), 2*time.Second),
+	}
+
 	//Swagger Info configuration
 	docs.SwaggerInfo.Host = fmt.Sprint(serverConf.Host + ":" + serverConf.Port)
 
@@ -64,6 +190,10 @@ func init() {
 // @license.name Apache 2.0
 // @license.url http://www.apache.org/licenses/LICENSE-2.0.html
 // @BasePath /v1
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Access token as "Bearer <token>"
 func main() {
 	app := fiber.New(config.FiberConfig)
 
@@ -75,8 +205,31 @@ func main() {
 		TimeZone:   "Europe/Istanbul",
 	}))
 
-	// Initialize routes
-	api.InitializeRouters(app, conn)
+	// The routes and the background workers share one set of repositories and services
+	deps, err := api.NewDependencies(conn, redisClient, api.Config{
+		Auth:        authConf,
+		Idempotency: idempotencyConf,
+		Hold:        holdConf,
+		Cache:       cacheConf,
+		Inventory:   inventoryConf,
+		RateLimit:   rateLimitConf,
+		WaitingRoom: waitingRoomConf,
+		Payment:     paymentConf,
+		Webhook:     webhookConf,
+		Outbox:      outboxConf,
+		Mail:        mailConf,
+	})
+	if err != nil {
+		panic(err)
+	}
+	api.InitializeRouters(app, deps)
+
+	// Start background workers
+	var workerCtx context.Context
+	workerCtx, stopWorkers = context.WithCancel(context.Background())
+	if err := deps.RunWorkers(workerCtx); err != nil {
+		panic(err)
+	}
 
 	// Start listening on port 8000
 	go func() {
@@ -86,7 +239,7 @@ func main() {
 	}()
 
 	// Graceful shutdown
-	err := GracefulShutdown(app, 5*time.Second)
+	err = GracefulShutdown(app, 5*time.Second)
 	if err != nil {
 		log.Error("Graceful shutdown error", err)
 	}
@@ -101,6 +254,8 @@ func GracefulShutdown(app *fiber.App, timeout time.Duration) error {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
+	stopWorkers()
+
 	db, err := conn.DB()
 	if err != nil {
 		return err
@@ -110,6 +265,12 @@ func GracefulShutdown(app *fiber.App, timeout time.Duration) error {
 		return err
 	}
 
+	if redisClient != nil {
+		if err := redisClient.Close(); err != nil {
+			log.Error("Redis close error", err)
+		}
+	}
+
 	if err := app.Shutdown(); err != nil {
 		return err
 	}
@@ -0,0 +1,44 @@
+// Command webhooksign signs a payment callback body the way the provider does, for testing the webhook locally:
+//
+//	echo '{"id":"evt_1","type":"payment.captured","payment_id":"..."}' > event.json
+//	curl -X POST localhost:8000/v1/webhooks/payments -H "Content-Type: application/json" \
+//		-H "X-Payment-Signature: $(go run ./cmd/webhooksign -secret dev-secret < event.json)" --data-binary @event.json
+//
+// The secret defaults to the first of PAYMENT_WEBHOOK_SECRETS.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/payments"
+	"time"
+)
+
+func main() {
+	var secret string
+	flag.StringVar(&secret, "secret", "", "webhook secret, defaults to the first of PAYMENT_WEBHOOK_SECRETS")
+	age := flag.Duration("age", 0, "how long ago the callback was sent, to test stale timestamps")
+	flag.Parse()
+
+	if secret == "" {
+		if secrets := config.GetList(os.Getenv("PAYMENT_WEBHOOK_SECRETS")); len(secrets) > 0 {
+			secret = secrets[0]
+		}
+	}
+
+	if secret == "" {
+		fmt.Fprintln(os.Stderr, "webhooksign: no secret given")
+		os.Exit(2)
+	}
+
+	body, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "webhooksign:", err)
+		os.Exit(1)
+	}
+
+	fmt.Println(payments.SignWebhook(secret, time.Now().Add(-*age), body))
+}
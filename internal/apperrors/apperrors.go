@@ -0,0 +1,141 @@
+package apperrors
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"ticket-purchase/internal/i18n/messages"
+)
+
+// Error is a domain error. Code is the i18n message key shown to the client, Status is the HTTP status
+// it maps to and Err keeps the original cause for logging.
+type Error struct {
+	Code   string
+	Status int
+	Data   interface{}
+	Err    error
+}
+
+// New creates a sentinel error
+func New(code string, status int) *Error {
+	return &Error{Code: code, Status: status}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Code + ": " + e.Err.Error()
+	}
+	return e.Code
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the target is an Error with the same code, so wrapped copies match their sentinel
+func (e *Error) Is(target error) bool {
+	var t *Error
+	if !errors.As(target, &t) {
+		return false
+	}
+	return e.Code == t.Code
+}
+
+// Wrap returns a copy of the error that keeps the cause
+func (e *Error) Wrap(cause error) *Error {
+	wrapped := *e
+	wrapped.Err = cause
+	return &wrapped
+}
+
+// WithData returns a copy of the error that carries details for the response body
+func (e *Error) WithData(data interface{}) *Error {
+	wrapped := *e
+	wrapped.Data = data
+	return &wrapped
+}
+
+// From maps any error to a domain error. Fiber errors keep their status and unknown errors become ErrUnexpected.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		code := messages.UnexpectedError
+		if fiberErr.Code == fiber.StatusNotFound {
+			code = messages.NotFound
+		} else if fiberErr.Code < fiber.StatusInternalServerError {
+			code = messages.BadRequest
+		}
+		return New(code, fiberErr.Code).Wrap(err)
+	}
+
+	return ErrUnexpected.Wrap(err)
+}
+
+var (
+	ErrBadRequest = New(messages.BadRequest, fiber.StatusBadRequest)
+	ErrValidation = New(messages.ValidationFailed, fiber.StatusBadRequest)
+	ErrNotFound   = New(messages.NotFound, fiber.StatusNotFound)
+	ErrUnexpected = New(messages.UnexpectedError, fiber.StatusInternalServerError)
+
+	ErrUnauthorized = New(messages.Unauthorized, fiber.StatusUnauthorized)
+	ErrForbidden    = New(messages.Forbidden, fiber.StatusForbidden)
+
+	ErrTooManyRequests = New(messages.TooManyRequests, fiber.StatusTooManyRequests)
+
+	ErrEmailTaken          = New(messages.EmailTaken, fiber.StatusConflict)
+	ErrInvalidCredentials  = New(messages.InvalidCredentials, fiber.StatusUnauthorized)
+	ErrUserDisabled        = New(messages.UserDisabled, fiber.StatusForbidden)
+	ErrRefreshTokenInvalid = New(messages.RefreshTokenInvalid, fiber.StatusUnauthorized)
+
+	ErrInvalidCursor = New(messages.InvalidCursor, fiber.StatusBadRequest)
+
+	ErrIdempotencyKeyInvalid    = New(messages.InvalidIdempotencyKey, fiber.StatusBadRequest)
+	ErrIdempotencyKeyReused     = New(messages.IdempotencyKeyReused, fiber.StatusUnprocessableEntity)
+	ErrIdempotencyKeyInProgress = New(messages.IdempotencyKeyInProgress, fiber.StatusConflict)
+
+	ErrTicketCreate            = New(messages.ErrorTicketCreate, fiber.StatusInternalServerError)
+	ErrTicketUpdate            = New(messages.ErrorTicketUpdate, fiber.StatusInternalServerError)
+	ErrTicketInactive          = New(messages.TicketInactive, fiber.StatusConflict)
+	ErrTicketAllocations       = New(messages.ErrorTicketAllocations, fiber.StatusBadRequest)
+	ErrAllocationBelowSold     = New(messages.AllocationBelowSold, fiber.StatusConflict)
+	ErrSaleNotStarted          = New(messages.SaleNotStarted, fiber.StatusConflict)
+	ErrSaleEnded               = New(messages.SaleEnded, fiber.StatusConflict)
+	ErrSaleWindowInvalid       = New(messages.SaleWindowInvalid, fiber.StatusBadRequest)
+	ErrMaxPerOrderExceeded     = New(messages.MaxPerOrderExceeded, fiber.StatusBadRequest)
+	ErrMaxPerUserExceeded      = New(messages.MaxPerUserExceeded, fiber.StatusConflict)
+	ErrPaymentDeclined         = New(messages.PaymentDeclined, fiber.StatusPaymentRequired)
+	ErrPaymentUnavailable      = New(messages.PaymentUnavailable, fiber.StatusServiceUnavailable)
+	ErrPaymentRefund           = New(messages.ErrorPaymentRefund, fiber.StatusBadGateway)
+	ErrInvalidWebhookSignature = New(messages.InvalidWebhookSignature, fiber.StatusUnauthorized)
+	ErrStaleWebhook            = New(messages.StaleWebhook, fiber.StatusBadRequest)
+	ErrPurchase                = New(messages.ErrorPurchase, fiber.StatusInternalServerError)
+	ErrPurchaseNotActive       = New(messages.PurchaseNotActive, fiber.StatusConflict)
+	ErrPurchaseRefund          = New(messages.ErrorPurchaseRefund, fiber.StatusInternalServerError)
+	ErrRefundExceedsQuantity   = New(messages.RefundExceedsQuantity, fiber.StatusBadRequest)
+	ErrRefundWindowClosed      = New(messages.RefundWindowClosed, fiber.StatusUnprocessableEntity)
+
+	ErrHoldCreate    = New(messages.ErrorHoldCreate, fiber.StatusInternalServerError)
+	ErrHoldNotActive = New(messages.HoldNotActive, fiber.StatusConflict)
+	ErrHoldExpired   = New(messages.HoldExpired, fiber.StatusGone)
+
+	ErrWaitingRoomDisabled = New(messages.WaitingRoomDisabled, fiber.StatusConflict)
+	ErrQueueTokenRequired  = New(messages.QueueTokenRequired, fiber.StatusForbidden)
+	ErrQueueNotAdmitted    = New(messages.QueueNotAdmitted, fiber.StatusForbidden)
+	ErrQueueTokenUsed      = New(messages.QueueTokenUsed, fiber.StatusForbidden)
+
+	ErrWebhookEventUnknown    = New(messages.WebhookEventUnknown, fiber.StatusBadRequest)
+	ErrWebhookUrlInvalid      = New(messages.WebhookUrlInvalid, fiber.StatusBadRequest)
+	ErrWebhookUrlNotPublic    = New(messages.WebhookUrlNotPublic, fiber.StatusBadRequest)
+	ErrWebhookDeliveryPending = New(messages.WebhookDeliveryPending, fiber.StatusConflict)
+
+	ErrCartEmpty             = New(messages.CartEmpty, fiber.StatusBadRequest)
+	ErrCartChanged           = New(messages.CartChanged, fiber.StatusConflict)
+	ErrCheckoutRejected      = New(messages.CheckoutRejected, fiber.StatusConflict)
+	ErrOrderCheckout         = New(messages.ErrorOrderCheckout, fiber.StatusInternalServerError)
+	ErrOrderCurrencyMismatch = New(messages.OrderCurrencyMismatch, fiber.StatusBadRequest)
+	ErrTicketWaitingRoom     = New(messages.TicketWaitingRoom, fiber.StatusConflict)
+)
@@ -0,0 +1,38 @@
+package apperrors
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"ticket-purchase/internal/i18n/messages"
+)
+
+func TestError_Wrap_Keeps_Cause(t *testing.T) {
+	cause := errors.New("record not found")
+
+	err := ErrNotFound.Wrap(cause)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, err, cause)
+	assert.NotErrorIs(t, err, ErrBadRequest)
+	assert.Equal(t, "not_found: record not found", err.Error())
+	assert.Nil(t, ErrNotFound.Err)
+}
+
+func TestFrom(t *testing.T) {
+	wrapped := ErrTicketInactive.Wrap(errors.New("inactive"))
+	assert.Same(t, wrapped, From(wrapped))
+
+	notFound := From(fiber.ErrNotFound)
+	assert.Equal(t, fiber.StatusNotFound, notFound.Status)
+	assert.Equal(t, messages.NotFound, notFound.Code)
+
+	tooLarge := From(fiber.ErrRequestEntityTooLarge)
+	assert.Equal(t, fiber.StatusRequestEntityTooLarge, tooLarge.Status)
+	assert.Equal(t, messages.BadRequest, tooLarge.Code)
+
+	unknown := From(errors.New("boom"))
+	assert.ErrorIs(t, unknown, ErrUnexpected)
+	assert.Equal(t, fiber.StatusInternalServerError, unknown.Status)
+}
@@ -0,0 +1,32 @@
+package auth
+
+import "github.com/gofiber/fiber/v2"
+
+const claimsKey = "auth.claims"
+
+// SetClaims stores the claims of the authenticated request
+func SetClaims(ctx *fiber.Ctx, claims *Claims) {
+	ctx.Locals(claimsKey, claims)
+}
+
+// ClaimsFrom returns the claims of the authenticated request, or nil when the request is anonymous
+func ClaimsFrom(ctx *fiber.Ctx) *Claims {
+	claims, _ := ctx.Locals(claimsKey).(*Claims)
+	return claims
+}
+
+// UserId returns the id of the authenticated user, or an empty string when the request is anonymous
+func UserId(ctx *fiber.Ctx) string {
+	if claims := ClaimsFrom(ctx); claims != nil {
+		return claims.UserId()
+	}
+	return ""
+}
+
+// ActorFrom returns the authenticated user, or an anonymous actor when the request is anonymous
+func ActorFrom(ctx *fiber.Ctx) Actor {
+	if claims := ClaimsFrom(ctx); claims != nil {
+		return claims.Actor()
+	}
+	return Actor{}
+}
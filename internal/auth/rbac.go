@@ -0,0 +1,70 @@
+package auth
+
+const (
+	RoleAdmin     = "admin"
+	RoleOrganizer = "organizer"
+	RoleSupport   = "support"
+	RoleCustomer  = "customer"
+)
+
+// Roles lists every role a user can have
+var Roles = []string{RoleAdmin, RoleOrganizer, RoleSupport, RoleCustomer}
+
+type Permission string
+
+const (
+	// PermTicketCreate allows creating tickets, the creator becomes the owner of the ticket
+	PermTicketCreate Permission = "tickets:create"
+	// PermTicketManage allows updating, deleting and restoring owned tickets
+	PermTicketManage Permission = "tickets:manage"
+	// PermTicketManageAny allows managing tickets of any owner
+	PermTicketManageAny Permission = "tickets:manage_any"
+	// PermTicketReadInactive allows reading soft deleted tickets
+	PermTicketReadInactive Permission = "tickets:read_inactive"
+	// PermTicketPurchase allows holding and purchasing tickets
+	PermTicketPurchase Permission = "tickets:purchase"
+	// PermPurchaseRead allows looking up purchases of any user
+	PermPurchaseRead Permission = "purchases:read"
+	// PermPurchaseRefund allows cancelling and refunding purchases of any user
+	PermPurchaseRefund Permission = "purchases:refund"
+	// PermUserManage allows changing the role of users
+	PermUserManage Permission = "users:manage"
+	// PermWebhookManage allows managing webhook subscriptions and their deliveries
+	PermWebhookManage Permission = "webhooks:manage"
+)
+
+// permissions is the permission matrix of the roles
+var permissions = map[string][]Permission{
+	RoleAdmin: {
+		PermTicketCreate, PermTicketManage, PermTicketManageAny, PermTicketReadInactive, PermTicketPurchase,
+		PermPurchaseRead, PermPurchaseRefund, PermUserManage, PermWebhookManage,
+	},
+	RoleOrganizer: {PermTicketCreate, PermTicketManage, PermTicketPurchase},
+	RoleSupport:   {PermTicketPurchase, PermPurchaseRead, PermPurchaseRefund},
+	RoleCustomer:  {PermTicketPurchase},
+}
+
+// Actor is the user a request is made by. The zero value is an anonymous actor without permissions.
+type Actor struct {
+	UserId string
+	Role   string
+}
+
+// Can reports whether the role of the actor has the permission
+func (a Actor) Can(permission Permission) bool {
+	if a.UserId == "" {
+		return false
+	}
+
+	for _, granted := range permissions[a.Role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Owns reports whether the actor is the owner of a resource
+func (a Actor) Owns(ownerId string) bool {
+	return a.UserId != "" && a.UserId == ownerId
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestActor_Can(t *testing.T) {
+	tests := []struct {
+		role       string
+		permission Permission
+		expected   bool
+	}{
+		{RoleAdmin, PermUserManage, true},
+		{RoleAdmin, PermTicketManageAny, true},
+		{RoleAdmin, PermWebhookManage, true},
+		{RoleOrganizer, PermTicketCreate, true},
+		{RoleOrganizer, PermTicketManage, true},
+		{RoleOrganizer, PermTicketManageAny, false},
+		{RoleOrganizer, PermPurchaseRefund, false},
+		{RoleSupport, PermPurchaseRead, true},
+		{RoleSupport, PermPurchaseRefund, true},
+		{RoleSupport, PermTicketCreate, false},
+		{RoleSupport, PermWebhookManage, false},
+		{RoleCustomer, PermTicketPurchase, true},
+		{RoleCustomer, PermTicketCreate, false},
+		{RoleCustomer, PermPurchaseRead, false},
+		{"unknown", PermTicketPurchase, false},
+	}
+
+	for _, tt := range tests {
+		actor := Actor{UserId: "user-1", Role: tt.role}
+		assert.Equal(t, tt.expected, actor.Can(tt.permission), "%s %s", tt.role, tt.permission)
+	}
+}
+
+func TestActor_Anonymous(t *testing.T) {
+	var actor Actor
+
+	assert.False(t, actor.Can(PermTicketPurchase))
+	assert.False(t, actor.Owns(""))
+}
+
+func TestClaims_Actor_Defaults_To_Customer(t *testing.T) {
+	claims := Claims{}
+	claims.Subject = "user-1"
+
+	assert.Equal(t, Actor{UserId: "user-1", Role: RoleCustomer}, claims.Actor())
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"ticket-purchase/cmd/config"
+	"time"
+)
+
+type Signer interface {
+	// Sign issues an access token for the user and returns it with its expiry time
+	Sign(userId string, role string) (string, time.Time, error)
+}
+
+type signer struct {
+	method   jwt.SigningMethod
+	key      interface{}
+	issuer   string
+	audience string
+	ttl      time.Duration
+}
+
+// NewSigner creates a signer for the configured algorithm and key source
+func NewSigner(conf config.AuthConfig) (Signer, error) {
+	method, key, err := signingKey(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &signer{
+		method:   method,
+		key:      key,
+		issuer:   conf.Issuer,
+		audience: conf.Audience,
+		ttl:      conf.AccessTokenTTL,
+	}, nil
+}
+
+func (s *signer) Sign(userId string, role string) (string, time.Time, error) {
+	now := timeNow()
+	expiresAt := now.Add(s.ttl)
+
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Subject:   userId,
+			Issuer:    s.issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		Role: role,
+	}
+	if s.audience != "" {
+		claims.Audience = jwt.ClaimStrings{s.audience}
+	}
+
+	token, err := jwt.NewWithClaims(s.method, claims).SignedString(s.key)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// signingKey loads the key that tokens are signed with
+func signingKey(conf config.AuthConfig) (jwt.SigningMethod, interface{}, error) {
+	if conf.AccessTokenTTL <= 0 {
+		return nil, nil, errors.New("auth: access token TTL must be positive")
+	}
+
+	switch conf.Algorithm {
+	case AlgorithmHS256:
+		if conf.Secret == "" {
+			return nil, nil, errors.New("auth: HS256 requires a secret")
+		}
+		return jwt.SigningMethodHS256, []byte(conf.Secret), nil
+	case AlgorithmRS256:
+		data, err := readPEM(conf.PrivateKey, conf.PrivateKeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("auth: private key: %w", err)
+		}
+
+		key, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("auth: parsing private key: %w", err)
+		}
+		return jwt.SigningMethodRS256, key, nil
+	default:
+		return nil, nil, fmt.Errorf("auth: unsupported algorithm %q", conf.Algorithm)
+	}
+}
@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"os"
+	"ticket-purchase/cmd/config"
+	"time"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+)
+
+var timeNow = time.Now
+
+// ErrMissingSubject is returned for tokens that do not identify a user
+var ErrMissingSubject = errors.New("token has no subject")
+
+// Claims are the access token claims. The subject is the id of the authenticated user.
+type Claims struct {
+	jwt.RegisteredClaims
+	Role string `json:"role,omitempty"`
+}
+
+// UserId returns the id of the authenticated user
+func (c *Claims) UserId() string {
+	return c.Subject
+}
+
+// Actor returns the authenticated user. Tokens without a role are treated as customer tokens.
+func (c *Claims) Actor() Actor {
+	role := c.Role
+	if role == "" {
+		role = RoleCustomer
+	}
+	return Actor{UserId: c.Subject, Role: role}
+}
+
+type Verifier interface {
+	// Verify checks the signature and the registered claims of a token and returns its claims
+	Verify(token string) (*Claims, error)
+}
+
+type verifier struct {
+	algorithm string
+	key       interface{}
+	parser    *jwt.Parser
+	issuer    string
+	audience  string
+	leeway    time.Duration
+}
+
+// NewVerifier creates a verifier for the configured algorithm and key source
+func NewVerifier(conf config.AuthConfig) (Verifier, error) {
+	key, err := verificationKey(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &verifier{
+		algorithm: conf.Algorithm,
+		key:       key,
+		// Claims are validated in Verify so that the leeway is applied
+		parser:   jwt.NewParser(jwt.WithValidMethods([]string{conf.Algorithm}), jwt.WithoutClaimsValidation()),
+		issuer:   conf.Issuer,
+		audience: conf.Audience,
+		leeway:   conf.Leeway,
+	}, nil
+}
+
+func (v *verifier) Verify(tokenString string) (*Claims, error) {
+	var claims Claims
+	_, err := v.parser.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		return v.key, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := v.validate(&claims); err != nil {
+		return nil, err
+	}
+
+	return &claims, nil
+}
+
+func (v *verifier) validate(claims *Claims) error {
+	now := timeNow()
+	if claims.ExpiresAt == nil || !claims.VerifyExpiresAt(now.Add(-v.leeway), true) {
+		return jwt.ErrTokenExpired
+	}
+
+	if !claims.VerifyNotBefore(now.Add(v.leeway), false) || !claims.VerifyIssuedAt(now.Add(v.leeway), false) {
+		return jwt.ErrTokenNotValidYet
+	}
+
+	if v.issuer != "" && !claims.VerifyIssuer(v.issuer, true) {
+		return jwt.ErrTokenInvalidIssuer
+	}
+
+	if v.audience != "" && !claims.VerifyAudience(v.audience, true) {
+		return jwt.ErrTokenInvalidAudience
+	}
+
+	if claims.Subject == "" {
+		return ErrMissingSubject
+	}
+
+	return nil
+}
+
+// verificationKey loads the key that token signatures are checked with
+func verificationKey(conf config.AuthConfig) (interface{}, error) {
+	switch conf.Algorithm {
+	case AlgorithmHS256:
+		if conf.Secret == "" {
+			return nil, errors.New("auth: HS256 requires a secret")
+		}
+		return []byte(conf.Secret), nil
+	case AlgorithmRS256:
+		data, err := readPEM(conf.PublicKey, conf.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("auth: public key: %w", err)
+		}
+
+		key, err := jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("auth: parsing public key: %w", err)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("auth: unsupported algorithm %q", conf.Algorithm)
+	}
+}
+
+// readPEM returns the inline PEM data, or the content of the file when there is no inline data
+func readPEM(inline string, file string) ([]byte, error) {
+	if inline != "" {
+		return []byte(inline), nil
+	}
+
+	if file == "" {
+		return nil, errors.New("no key configured")
+	}
+
+	return os.ReadFile(file)
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"ticket-purchase/cmd/config"
+	"time"
+)
+
+var authMockTime = time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+func setupAuthTest(t *testing.T) func() {
+	timeNow = func() time.Time {
+		return authMockTime
+	}
+
+	return func() {
+		timeNow = time.Now
+	}
+}
+
+func claimsFor(subject string, expiresAt time.Time) jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		Subject:   subject,
+		Issuer:    "ticket-purchase",
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		IssuedAt:  jwt.NewNumericDate(authMockTime),
+	}
+}
+
+func signHS256(t *testing.T, secret string, claims jwt.Claims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	return token
+}
+
+func TestVerifier_HS256(t *testing.T) {
+	teardown := setupAuthTest(t)
+	defer teardown()
+
+	verifier, err := NewVerifier(config.AuthConfig{Algorithm: AlgorithmHS256, Secret: "secret", Issuer: "ticket-purchase"})
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	claims, err := verifier.Verify(signHS256(t, "secret", claimsFor("user-1", authMockTime.Add(time.Hour))))
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	assert.Equal(t, "user-1", claims.UserId())
+
+	_, err = verifier.Verify(signHS256(t, "other-secret", claimsFor("user-1", authMockTime.Add(time.Hour))))
+	assert.Error(t, err)
+}
+
+func TestVerifier_Rejects_Invalid_Claims(t *testing.T) {
+	teardown := setupAuthTest(t)
+	defer teardown()
+
+	verifier, err := NewVerifier(config.AuthConfig{
+		Algorithm: AlgorithmHS256,
+		Secret:    "secret",
+		Issuer:    "ticket-purchase",
+		Leeway:    30 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	// Expired tokens are accepted only within the leeway
+	_, err = verifier.Verify(signHS256(t, "secret", claimsFor("user-1", authMockTime.Add(-10*time.Second))))
+	assert.NoError(t, err)
+
+	_, err = verifier.Verify(signHS256(t, "secret", claimsFor("user-1", authMockTime.Add(-time.Minute))))
+	assert.True(t, errors.Is(err, jwt.ErrTokenExpired))
+
+	wrongIssuer := claimsFor("user-1", authMockTime.Add(time.Hour))
+	wrongIssuer.Issuer = "someone-else"
+	_, err = verifier.Verify(signHS256(t, "secret", wrongIssuer))
+	assert.True(t, errors.Is(err, jwt.ErrTokenInvalidIssuer))
+
+	_, err = verifier.Verify(signHS256(t, "secret", claimsFor("", authMockTime.Add(time.Hour))))
+	assert.True(t, errors.Is(err, ErrMissingSubject))
+
+	noExpiry := claimsFor("user-1", authMockTime)
+	noExpiry.ExpiresAt = nil
+	_, err = verifier.Verify(signHS256(t, "secret", noExpiry))
+	assert.True(t, errors.Is(err, jwt.ErrTokenExpired))
+}
+
+func TestVerifier_RS256(t *testing.T) {
+	teardown := setupAuthTest(t)
+	defer teardown()
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	publicKey, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	verifier, err := NewVerifier(config.AuthConfig{
+		Algorithm: AlgorithmRS256,
+		PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})),
+	})
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claimsFor("user-1", authMockTime.Add(time.Hour))).SignedString(privateKey)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	claims, err := verifier.Verify(token)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	assert.Equal(t, "user-1", claims.UserId())
+
+	// HS256 tokens are rejected by an RS256 verifier
+	_, err = verifier.Verify(signHS256(t, "secret", claimsFor("user-1", authMockTime.Add(time.Hour))))
+	assert.Error(t, err)
+}
+
+func TestNewVerifier_Invalid_Config(t *testing.T) {
+	_, err := NewVerifier(config.AuthConfig{Algorithm: AlgorithmHS256})
+	assert.Error(t, err)
+
+	_, err = NewVerifier(config.AuthConfig{Algorithm: AlgorithmRS256, PublicKeyFile: "./missing.pem"})
+	assert.Error(t, err)
+
+	_, err = NewVerifier(config.AuthConfig{Algorithm: "none", Secret: "secret"})
+	assert.Error(t, err)
+}
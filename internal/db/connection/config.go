@@ -10,3 +10,9 @@ type DatabaseConfig struct {
 	SSLMode  string
 	Timezone string
 }
+
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+}
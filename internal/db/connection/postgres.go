@@ -44,8 +44,18 @@ func migration(connection *gorm.DB) {
 		log.Info("Migrating the database...")
 
 		err := connection.AutoMigrate(
+			models.User{},
 			models.Ticket{},
 			models.Purchase{},
+			models.Order{},
+			models.IdempotencyKey{},
+			models.Hold{},
+			models.RefreshToken{},
+			models.Payment{},
+			models.WebhookEvent{},
+			models.WebhookSubscription{},
+			models.WebhookDelivery{},
+			models.OutboxEvent{},
 		)
 		if err != nil {
 			log.Error("Error migrating the database: ", err)
@@ -0,0 +1,32 @@
+package connection
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// RedisConnection returns a Redis client, or nil when no address is configured.
+// An unreachable server is only logged, the client reconnects when it comes back.
+func RedisConnection(config RedisConfig) *redis.Client {
+	if config.Addr == "" {
+		log.Info("REDIS_ADDR is not set, running without Redis")
+		return nil
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     config.Addr,
+		Password: config.Password,
+		DB:       config.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		log.Warn("Error connecting to Redis: ", err)
+	}
+
+	return client
+}
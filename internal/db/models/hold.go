@@ -0,0 +1,54 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"ticket-purchase/pkg/money"
+	"time"
+)
+
+// Hold statuses
+const (
+	HoldStatusActive    = "active"
+	HoldStatusConfirmed = "confirmed"
+	HoldStatusExpired   = "expired"
+)
+
+type Hold struct {
+	Id         string    `gorm:"primaryKey"`
+	TicketId   string    `gorm:"not null;index"`
+	UserId     string    `gorm:"not null"`
+	Quantity   int       `gorm:"not null"`
+	Status     string    `gorm:"not null;default:active;index"`
+	ExpiresAt  time.Time `gorm:"not null;index"`
+	PurchaseId *string
+
+	// Price of the ticket when the hold was made, it is kept when the hold is confirmed
+	UnitPrice int64  `gorm:"not null;default:0"`
+	Currency  string `gorm:"size:3;not null;default:USD"`
+
+	// Relationships
+	Ticket Ticket `gorm:"foreignKey:TicketId;references:Id"`
+
+	// Audit fields
+	CreatedBy string    `gorm:"not null"`
+	UpdatedBy string    `gorm:"not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// TableName specifies the table name for the Hold model
+func (Hold) TableName() string {
+	return "public.holds"
+}
+
+// BeforeCreate is a GORM hook that is triggered before creating a new record
+func (h *Hold) BeforeCreate(tx *gorm.DB) error {
+	h.Id = uuid.New().String()
+	return nil
+}
+
+// Price returns the unit price of the held tickets
+func (h *Hold) Price() money.Money {
+	return money.New(h.UnitPrice, h.Currency)
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// IdempotencyKey is scoped to the user who sent it, so two users can pick the same key
+type IdempotencyKey struct {
+	UserId       string `gorm:"primaryKey;size:255"`
+	Key          string `gorm:"primaryKey;size:255"`
+	Fingerprint  string `gorm:"not null"`
+	StatusCode   int    `gorm:"not null;default:0"`
+	ContentType  string
+	ResponseBody []byte
+
+	// Audit fields
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	// LockedUntil is the end of the lease of the request that is running with the key
+	LockedUntil time.Time `gorm:"not null;default:'epoch'"`
+}
+
+// TableName specifies the table name for the IdempotencyKey model
+func (IdempotencyKey) TableName() string {
+	return "public.idempotency_keys"
+}
+
+// IsCompleted reports whether the original request has finished and its response was stored
+func (k *IdempotencyKey) IsCompleted() bool {
+	return k.StatusCode != 0
+}
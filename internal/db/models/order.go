@@ -0,0 +1,68 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Order statuses. A user has at most one cart, which becomes a completed order once all of its lines are reserved at
+// checkout.
+const (
+	OrderStatusCart      = "cart"
+	OrderStatusCompleted = "completed"
+)
+
+// Order groups purchases of several tickets. Its lines are purchases, which stay in the cart without taking any
+// allocation until the order is checked out.
+type Order struct {
+	Id     string `gorm:"primaryKey"`
+	UserId string `gorm:"not null;index;uniqueIndex:idx_orders_user_cart,where:status = 'cart'"`
+	Status string `gorm:"not null;default:cart"`
+
+	// Total is the sum of the line totals in Currency. Lines are priced again at checkout.
+	Total        int64  `gorm:"not null;default:0"`
+	Currency     string `gorm:"size:3"`
+	CheckedOutAt *time.Time
+
+	// Relationships
+	Lines []Purchase `gorm:"foreignKey:OrderId;references:Id"`
+	User  User       `gorm:"foreignKey:UserId;references:Id"`
+
+	// Audit fields
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// TableName specifies the table name for the Order model
+func (Order) TableName() string {
+	return "public.orders"
+}
+
+// BeforeCreate is a GORM hook that is triggered before creating a new record
+func (o *Order) BeforeCreate(tx *gorm.DB) error {
+	if o.Id == "" {
+		o.Id = uuid.New().String()
+	}
+	return nil
+}
+
+// Line returns the line of the order with the given id
+func (o *Order) Line(id string) *Purchase {
+	for i := range o.Lines {
+		if o.Lines[i].Id == id {
+			return &o.Lines[i]
+		}
+	}
+	return nil
+}
+
+// LineOf returns the line of the order for the given ticket
+func (o *Order) LineOf(ticketId string) *Purchase {
+	for i := range o.Lines {
+		if o.Lines[i].TicketId == ticketId {
+			return &o.Lines[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// OutboxEvent is a domain event written in the same transaction as the change it describes. The relay publishes
+// unpublished events to the event bus in the order of their position. Events that keep failing are parked, the relay
+// leaves them until they are released by hand.
+type OutboxEvent struct {
+	// Position orders the events, events of a ticket are published in this order
+	Position int64  `gorm:"primaryKey;autoIncrement"`
+	Id       string `gorm:"not null;uniqueIndex"`
+	TicketId string `gorm:"not null;index"`
+	Type     string `gorm:"not null"`
+	// Payload is the JSON body of the event
+	Payload     string     `gorm:"type:text;not null"`
+	PublishedAt *time.Time `gorm:"index"`
+	// Attempts counts the failed publishes and LastError is the error of the latest one
+	Attempts  int    `gorm:"not null;default:0"`
+	LastError string `gorm:"type:text"`
+	// ParkedAt is set when the event failed too often to be tried again
+	ParkedAt *time.Time `gorm:"index"`
+
+	// Audit fields
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for the OutboxEvent model
+func (OutboxEvent) TableName() string {
+	return "public.outbox"
+}
+
+// BeforeCreate is a GORM hook that is triggered before creating a new record
+func (e *OutboxEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.Id == "" {
+		e.Id = uuid.New().String()
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"ticket-purchase/pkg/money"
+	"time"
+)
+
+// Payment statuses. A payment is authorized before the purchase takes allocation, then captured once the purchase
+// is committed or voided when it is not. Refunds keep a payment captured until the whole amount is refunded.
+// Providers can report an authorized payment as failed when its capture fails.
+const (
+	PaymentStatusPending    = "pending"
+	PaymentStatusAuthorized = "authorized"
+	PaymentStatusCaptured   = "captured"
+	PaymentStatusVoided     = "voided"
+	PaymentStatusRefunded   = "refunded"
+	PaymentStatusDeclined   = "declined"
+	PaymentStatusFailed     = "failed"
+)
+
+// paymentTransitions are the statuses a payment can move to from each status
+var paymentTransitions = map[string][]string{
+	PaymentStatusPending:    {PaymentStatusAuthorized, PaymentStatusDeclined, PaymentStatusFailed},
+	PaymentStatusAuthorized: {PaymentStatusCaptured, PaymentStatusVoided, PaymentStatusFailed},
+	PaymentStatusCaptured:   {PaymentStatusRefunded},
+}
+
+type Payment struct {
+	Id string `gorm:"primaryKey"`
+	// PurchaseId is the purchase the payment is for. It is not a foreign key, because the purchase is only written
+	// after the payment is authorized, and never for declined payments.
+	PurchaseId string `gorm:"not null;index"`
+	UserId     string `gorm:"not null"`
+
+	// Amounts are in the minor unit of Currency
+	Amount         int64  `gorm:"not null"`
+	RefundedAmount int64  `gorm:"not null;default:0"`
+	Currency       string `gorm:"size:3;not null"`
+
+	Status          string `gorm:"not null;default:pending;index"`
+	Provider        string `gorm:"not null"`
+	AuthorizationId string
+	FailureReason   string
+
+	// Audit fields
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// TableName specifies the table name for the Payment model
+func (Payment) TableName() string {
+	return "public.payments"
+}
+
+// BeforeCreate is a GORM hook that is triggered before creating a new record
+func (p *Payment) BeforeCreate(tx *gorm.DB) error {
+	if p.Id == "" {
+		p.Id = uuid.New().String()
+	}
+	return nil
+}
+
+// Total returns the authorized amount
+func (p *Payment) Total() money.Money {
+	return money.New(p.Amount, p.Currency)
+}
+
+// CanTransition reports whether the payment can move from its status to the given one
+func (p *Payment) CanTransition(status string) bool {
+	for _, next := range paymentTransitions[p.Status] {
+		if next == status {
+			return true
+		}
+	}
+	return false
+}
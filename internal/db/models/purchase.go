@@ -3,17 +3,40 @@ package models
 import (
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"ticket-purchase/pkg/money"
 	"time"
 )
 
+// Purchase statuses. Lines of an order stay in the cart until the order is checked out, purchases made on their own
+// are completed right away.
+const (
+	PurchaseStatusCart      = "cart"
+	PurchaseStatusCompleted = "completed"
+)
+
 type Purchase struct {
 	Id       string `gorm:"primaryKey"`
-	TicketId string `gorm:"not null"`
+	TicketId string `gorm:"not null;uniqueIndex:idx_purchases_order_ticket,priority:2"`
 	UserId   string `gorm:"not null"`
 	Quantity int    `gorm:"not null"`
 
+	// OrderId is the order the purchase is a line of, an order has one line per ticket. Lines in the cart are not
+	// active, they take allocation at checkout.
+	OrderId *string `gorm:"uniqueIndex:idx_purchases_order_ticket,priority:1"`
+	Status  string  `gorm:"not null;default:completed;index"`
+
+	// Price fields are a snapshot of the ticket price at purchase time, in the minor unit of Currency
+	UnitPrice int64  `gorm:"not null;default:0"`
+	Total     int64  `gorm:"not null;default:0"`
+	Currency  string `gorm:"size:3;not null;default:USD"`
+
+	// Refund fields
+	RefundedQuantity int `gorm:"not null;default:0"`
+	CancelReason     string
+
 	// Relationships
 	Ticket Ticket `gorm:"foreignKey:TicketId;references:Id"`
+	User   User   `gorm:"foreignKey:UserId;references:Id"`
 
 	// Audit fields
 	CreatedBy string    `gorm:"not null"`
@@ -21,6 +44,10 @@ type Purchase struct {
 	CreatedAt time.Time `gorm:"autoCreateTime"`
 	UpdatedAt time.Time `gorm:"autoUpdateTime"`
 	IsActive  bool      `gorm:"default:true"`
+
+	// AllocationLeft is what is left of the ticket allocation once the purchase took its quantity. It is only set by
+	// CreateWithAllocation.
+	AllocationLeft *int `gorm:"-"`
 }
 
 // TableName specifies the table name for the Purchase model
@@ -28,8 +55,28 @@ func (Purchase) TableName() string {
 	return "public.purchases"
 }
 
-// BeforeCreate is a GORM hook that is triggered before creating a new record
+// BeforeCreate is a GORM hook that is triggered before creating a new record.
+// Purchases sold from the Redis inventory already have an id.
 func (p *Purchase) BeforeCreate(tx *gorm.DB) error {
-	p.Id = uuid.New().String()
+	if p.Id == "" {
+		p.Id = uuid.New().String()
+	}
 	return nil
 }
+
+// RemainingQuantity returns the quantity that has not been refunded yet
+func (p *Purchase) RemainingQuantity() int {
+	return p.Quantity - p.RefundedQuantity
+}
+
+// SetPrice records the unit price and the total of the purchase quantity
+func (p *Purchase) SetPrice(unitPrice money.Money) {
+	p.UnitPrice = unitPrice.Amount
+	p.Total = unitPrice.Multiply(p.Quantity).Amount
+	p.Currency = unitPrice.Currency
+}
+
+// Price returns the unit price the purchase was made at
+func (p *Purchase) Price() money.Money {
+	return money.New(p.UnitPrice, p.Currency)
+}
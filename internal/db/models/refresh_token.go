@@ -0,0 +1,34 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// RefreshToken is a long-lived token that is exchanged for a new access token.
+// Only the hash of the token is stored and a token can be used once.
+type RefreshToken struct {
+	Id        string    `gorm:"primaryKey"`
+	UserId    string    `gorm:"not null;index"`
+	TokenHash string    `gorm:"not null;uniqueIndex;size:64"`
+	ExpiresAt time.Time `gorm:"not null"`
+	RevokedAt *time.Time
+
+	// Relationships
+	User User `gorm:"foreignKey:UserId;references:Id;constraint:OnDelete:CASCADE"`
+
+	// Audit fields
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for the RefreshToken model
+func (RefreshToken) TableName() string {
+	return "public.refresh_tokens"
+}
+
+// BeforeCreate is a GORM hook that is triggered before creating a new record
+func (t *RefreshToken) BeforeCreate(tx *gorm.DB) error {
+	t.Id = uuid.New().String()
+	return nil
+}
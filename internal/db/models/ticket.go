@@ -3,6 +3,7 @@ package models
 import (
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"ticket-purchase/pkg/money"
 	"time"
 )
 
@@ -10,7 +11,29 @@ type Ticket struct {
 	Id          string `json:"id" gorm:"primaryKey"`
 	Name        string `json:"name" gorm:"not null"`
 	Description string `json:"description"`
-	Allocation  int    `json:"allocation" gorm:"not null"`
+	Allocation  int    `json:"allocation" gorm:"not null;check:allocation >= 0"`
+
+	// Price is in the minor unit of Currency, e.g. cents for USD
+	Price    int64  `json:"price" gorm:"not null;default:0;check:price >= 0"`
+	Currency string `json:"currency" gorm:"size:3;not null;default:USD"`
+
+	// OwnerId is the organizer who manages the ticket
+	OwnerId string `json:"owner_id" gorm:"index"`
+
+	// WaitingRoom queues buyers in front of purchases during a high-demand launch
+	WaitingRoom bool `json:"waiting_room" gorm:"not null;default:false"`
+
+	// Sale window, tickets can only be bought between the two. A missing bound leaves the window open on that side.
+	SaleStartsAt *time.Time `json:"sale_starts_at" gorm:"index"`
+	SaleEndsAt   *time.Time `json:"sale_ends_at" gorm:"index"`
+
+	// Purchase limits, 0 means unlimited. MaxPerUser counts the active purchases and holds of the user.
+	MaxPerOrder int `json:"max_per_order" gorm:"not null;default:0;check:max_per_order >= 0"`
+	MaxPerUser  int `json:"max_per_user" gorm:"not null;default:0;check:max_per_user >= 0"`
+
+	// Event date and refund policy
+	EventStartsAt     *time.Time `json:"event_starts_at"`
+	RefundCutoffHours int        `json:"refund_cutoff_hours" gorm:"not null;default:0"`
 
 	// Audit fields
 	CreatedBy string    `json:"created_by" gorm:"not null"`
@@ -18,8 +41,18 @@ type Ticket struct {
 	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 	IsActive  bool      `json:"is_active" gorm:"default:true"`
+
+	// AllocationChange is how much the allocation changed. It is only set by UpdateDetails.
+	AllocationChange int `json:"-" gorm:"-"`
 }
 
+// Sale statuses of a ticket
+const (
+	SaleStatusUpcoming = "upcoming"
+	SaleStatusOnSale   = "on_sale"
+	SaleStatusEnded    = "ended"
+)
+
 // TableName specifies the table name for the Ticket model
 func (Ticket) TableName() string {
 	return "public.tickets"
@@ -30,3 +63,31 @@ func (t *Ticket) BeforeCreate(tx *gorm.DB) error {
 	t.Id = uuid.New().String()
 	return nil
 }
+
+// UnitPrice returns the price of one ticket
+func (t *Ticket) UnitPrice() money.Money {
+	return money.New(t.Price, t.Currency)
+}
+
+// IsRefundable reports whether purchases of the ticket can still be refunded at the given time.
+// Refunds close RefundCutoffHours before the event starts.
+func (t *Ticket) IsRefundable(now time.Time) bool {
+	if t.EventStartsAt == nil {
+		return true
+	}
+
+	deadline := t.EventStartsAt.Add(-time.Duration(t.RefundCutoffHours) * time.Hour)
+	return now.Before(deadline)
+}
+
+// SaleStatus reports whether the ticket is on sale at the given time. Sales end at SaleEndsAt exactly.
+func (t *Ticket) SaleStatus(now time.Time) string {
+	if t.SaleStartsAt != nil && now.Before(*t.SaleStartsAt) {
+		return SaleStatusUpcoming
+	}
+
+	if t.SaleEndsAt != nil && !now.Before(*t.SaleEndsAt) {
+		return SaleStatusEnded
+	}
+	return SaleStatusOnSale
+}
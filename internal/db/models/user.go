@@ -0,0 +1,41 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	UserStatusActive   = "active"
+	UserStatusDisabled = "disabled"
+)
+
+type User struct {
+	Id           string `json:"id" gorm:"primaryKey"`
+	Email        string `json:"email" gorm:"not null;uniqueIndex;size:255"`
+	PasswordHash string `json:"-" gorm:"not null"`
+	Locale       string `json:"locale" gorm:"not null;default:en;size:8"`
+	Status       string `json:"status" gorm:"not null;default:active;size:16"`
+	Role         string `json:"role" gorm:"not null;default:customer;size:16"`
+
+	// Audit fields
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specifies the table name for the User model
+func (User) TableName() string {
+	return "public.users"
+}
+
+// BeforeCreate is a GORM hook that is triggered before creating a new record
+func (u *User) BeforeCreate(tx *gorm.DB) error {
+	u.Id = uuid.New().String()
+	return nil
+}
+
+// IsActive reports whether the user can sign in
+func (u *User) IsActive() bool {
+	return u.Status == UserStatusActive
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Webhook delivery statuses. Pending deliveries are retried until they are delivered or run out of attempts and
+// become dead letters, which are only sent again when they are redelivered by hand.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryDead      = "dead"
+)
+
+// WebhookDelivery is an event queued for a subscription
+type WebhookDelivery struct {
+	Id             string `gorm:"primaryKey"`
+	SubscriptionId string `gorm:"not null;uniqueIndex:idx_webhook_deliveries_event,priority:1"`
+	// EventId is the id of the domain event, a subscription gets one delivery per event
+	EventId   string `gorm:"not null;uniqueIndex:idx_webhook_deliveries_event,priority:2"`
+	EventType string `gorm:"not null"`
+	// Payload is the JSON body sent to the subscription
+	Payload string `gorm:"type:text;not null"`
+
+	Status        string    `gorm:"not null;default:pending;index:idx_webhook_deliveries_due,priority:1"`
+	Attempts      int       `gorm:"not null;default:0"`
+	NextAttemptAt time.Time `gorm:"not null;index:idx_webhook_deliveries_due,priority:2"`
+	// LastError and LastStatus describe the last failed attempt, the status is 0 when no response was received
+	LastError   string
+	LastStatus  int `gorm:"not null;default:0"`
+	DeliveredAt *time.Time
+
+	// Relationships
+	Subscription WebhookSubscription `gorm:"foreignKey:SubscriptionId;references:Id;constraint:OnDelete:CASCADE"`
+
+	// Audit fields
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// TableName specifies the table name for the WebhookDelivery model
+func (WebhookDelivery) TableName() string {
+	return "public.webhook_deliveries"
+}
+
+// BeforeCreate is a GORM hook that is triggered before creating a new record
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.Id == "" {
+		d.Id = uuid.New().String()
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// WebhookEvent records a provider callback that was processed, so deliveries of the same event are only applied once
+type WebhookEvent struct {
+	Id       string `gorm:"primaryKey;size:255"`
+	Provider string `gorm:"not null"`
+	Type     string `gorm:"not null"`
+
+	// Audit fields
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for the WebhookEvent model
+func (WebhookEvent) TableName() string {
+	return "public.webhook_events"
+}
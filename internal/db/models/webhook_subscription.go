@@ -0,0 +1,51 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// WebhookSubscription is an endpoint of a partner system that receives events
+type WebhookSubscription struct {
+	Id  string `gorm:"primaryKey"`
+	Url string `gorm:"not null"`
+	// Secret signs the deliveries, it is only shown when the subscription is created
+	Secret string `gorm:"not null"`
+	// Events is the comma separated list of event types the subscription receives
+	Events string `gorm:"not null"`
+
+	// Audit fields
+	CreatedBy string    `gorm:"not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// TableName specifies the table name for the WebhookSubscription model
+func (WebhookSubscription) TableName() string {
+	return "public.webhook_subscriptions"
+}
+
+// BeforeCreate is a GORM hook that is triggered before creating a new record
+func (s *WebhookSubscription) BeforeCreate(tx *gorm.DB) error {
+	if s.Id == "" {
+		s.Id = uuid.New().String()
+	}
+	return nil
+}
+
+// EventList returns the event types the subscription receives
+func (s *WebhookSubscription) EventList() []string {
+	return strings.Split(s.Events, ",")
+}
+
+// Subscribes reports whether the subscription receives the event type
+func (s *WebhookSubscription) Subscribes(eventType string) bool {
+	for _, event := range s.EventList() {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"ticket-purchase/internal/db/models"
+	"time"
+)
+
+var (
+	// ErrInsufficientAllocation is returned when a ticket does not have enough allocation left for a purchase
+	ErrInsufficientAllocation = errors.New("insufficient ticket allocation")
+	// ErrTicketInactive is returned when a deactivated ticket is purchased or held
+	ErrTicketInactive = errors.New("ticket is not active")
+	// ErrMaxPerUserExceeded is returned when a purchase or hold would take a user over the per user limit of a ticket
+	ErrMaxPerUserExceeded = errors.New("per user ticket limit exceeded")
+)
+
+// reserveAllocation takes quantity from the allocation of an active ticket inside the given transaction, writes the
+// AllocationChanged event and returns the allocation left. The decrement is conditional on the remaining allocation,
+// so concurrent reservations can never oversell.
+func reserveAllocation(tx *gorm.DB, ticketTable string, ticketId string, quantity int, updatedBy string, updatedAt time.Time) (int, error) {
+	result := tx.Table(ticketTable).
+		Where("id = ? AND is_active AND allocation >= ?", ticketId, quantity).
+		UpdateColumns(map[string]interface{}{
+			"allocation": gorm.Expr("allocation - ?", quantity),
+			"updated_by": updatedBy,
+			"updated_at": updatedAt,
+		})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	if result.RowsAffected == 1 {
+		// The update locked the ticket row, so no other reservation changed it in between
+		var allocationLeft int
+		if err := tx.Table(ticketTable).Select("allocation").Where("id = ?", ticketId).Scan(&allocationLeft).Error; err != nil {
+			return 0, err
+		}
+		return allocationLeft, writeAllocationChanged(tx, ticketId, allocationLeft, -quantity)
+	}
+
+	var ticket struct {
+		IsActive bool
+	}
+	lookup := tx.Table(ticketTable).Select("is_active").Where("id = ?", ticketId).Limit(1).Scan(&ticket)
+	if lookup.Error != nil {
+		return 0, lookup.Error
+	}
+	if lookup.RowsAffected == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+	if !ticket.IsActive {
+		return 0, ErrTicketInactive
+	}
+	return 0, ErrInsufficientAllocation
+}
+
+// checkUserLimit rejects a purchase or hold that takes the user over the max_per_user of the ticket. The user owns the
+// remaining quantity of their active purchases and the quantity of their active holds. It has to run after
+// reserveAllocation, whose update locks the ticket row, so that concurrent orders of the same ticket are counted one
+// after another.
+func checkUserLimit(tx *gorm.DB, ticketId string, userId string, quantity int) error {
+	var ticket struct {
+		MaxPerUser int
+	}
+	result := tx.Table(models.Ticket{}.TableName()).Select("max_per_user").Where("id = ?", ticketId).Limit(1).Scan(&ticket)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if ticket.MaxPerUser == 0 {
+		return nil
+	}
+
+	var purchased, held int
+	result = tx.Table(models.Purchase{}.TableName()).
+		Where("ticket_id = ? AND user_id = ? AND is_active", ticketId, userId).
+		Select("COALESCE(SUM(quantity - refunded_quantity), 0)").
+		Scan(&purchased)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	result = tx.Table(models.Hold{}.TableName()).
+		Where("ticket_id = ? AND user_id = ? AND status = ?", ticketId, userId, models.HoldStatusActive).
+		Select("COALESCE(SUM(quantity), 0)").
+		Scan(&held)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if purchased+held+quantity > ticket.MaxPerUser {
+		return ErrMaxPerUserExceeded
+	}
+	return nil
+}
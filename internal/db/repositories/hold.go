@@ -0,0 +1,202 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ticket-purchase/internal/db/models"
+	"time"
+)
+
+var (
+	// ErrHoldNotActive is returned when a hold was already confirmed or released
+	ErrHoldNotActive = errors.New("hold is not active")
+	// ErrHoldExpired is returned when a hold is confirmed after its expiry time
+	ErrHoldExpired = errors.New("hold is expired")
+)
+
+//go:generate mockgen -destination=../../mocks/repositories/hold_repository_mock.go -package=repositories ticket-purchase/internal/db/repositories HoldRepository
+type HoldRepository interface {
+	FindById(ctx context.Context, id string) (*models.Hold, error)
+	CreateWithAllocation(ctx context.Context, hold *models.Hold) error
+	Confirm(ctx context.Context, id string, purchase *models.Purchase) (*models.Hold, error)
+	ReleaseExpired(ctx context.Context, now time.Time, limit int) ([]models.Hold, error)
+	SumActiveQuantity(ctx context.Context, ticketId string) (int, error)
+	SumActiveQuantities(ctx context.Context, ticketIds []string) (map[string]int, error)
+}
+
+type holdRepository struct {
+	db            *gorm.DB
+	tableName     string
+	ticketTable   string
+	purchaseTable string
+}
+
+func NewHoldRepository(db *gorm.DB) HoldRepository {
+	var holdModel models.Hold
+	var ticketModel models.Ticket
+	var purchaseModel models.Purchase
+	return &holdRepository{
+		db:            db,
+		tableName:     holdModel.TableName(),
+		ticketTable:   ticketModel.TableName(),
+		purchaseTable: purchaseModel.TableName(),
+	}
+}
+
+func (r *holdRepository) FindById(ctx context.Context, id string) (*models.Hold, error) {
+	var hold models.Hold
+	result := r.db.Table(r.tableName).WithContext(ctx).Where("id = ?", id).First(&hold)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &hold, nil
+}
+
+// CreateWithAllocation reserves the hold quantity from the ticket allocation and inserts the hold in a single transaction
+func (r *holdRepository) CreateWithAllocation(ctx context.Context, hold *models.Hold) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		_, err := reserveAllocation(tx, r.ticketTable, hold.TicketId, hold.Quantity, hold.UpdatedBy, hold.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		if err := checkUserLimit(tx, hold.TicketId, hold.UserId, hold.Quantity); err != nil {
+			return err
+		}
+
+		return tx.Table(r.tableName).Create(hold).Error
+	})
+}
+
+// Confirm turns an active hold into a purchase. The reserved quantity was already taken from the
+// ticket allocation when the hold was created, so only the purchase row is inserted.
+func (r *holdRepository) Confirm(ctx context.Context, id string, purchase *models.Purchase) (*models.Hold, error) {
+	var hold models.Hold
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Table(r.tableName).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&hold)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if hold.Status != models.HoldStatusActive {
+			return ErrHoldNotActive
+		}
+
+		if !hold.ExpiresAt.After(purchase.CreatedAt) {
+			return ErrHoldExpired
+		}
+
+		// The ticket row is locked so that the event of the purchase is ordered with the other events of the ticket
+		var ticket models.Ticket
+		result = tx.Table(r.ticketTable).Clauses(clause.Locking{Strength: "UPDATE"}).Select("is_active").Where("id = ?", hold.TicketId).First(&ticket)
+		if err := result.Error; err != nil {
+			return err
+		}
+		if !ticket.IsActive {
+			return ErrTicketInactive
+		}
+
+		purchase.TicketId = hold.TicketId
+		purchase.UserId = hold.UserId
+		purchase.Quantity = hold.Quantity
+		purchase.SetPrice(hold.Price())
+		if purchase.CreatedBy == "" {
+			purchase.CreatedBy = hold.UserId
+			purchase.UpdatedBy = hold.UserId
+		}
+		if err := tx.Table(r.purchaseTable).Create(purchase).Error; err != nil {
+			return err
+		}
+
+		if err := writeTicketPurchased(tx, purchase); err != nil {
+			return err
+		}
+
+		hold.Status = models.HoldStatusConfirmed
+		hold.PurchaseId = &purchase.Id
+		hold.UpdatedBy = purchase.UpdatedBy
+		hold.UpdatedAt = purchase.UpdatedAt
+		return tx.Table(r.tableName).Where("id = ?", hold.Id).Updates(map[string]interface{}{
+			"status":      hold.Status,
+			"purchase_id": hold.PurchaseId,
+			"updated_by":  hold.UpdatedBy,
+			"updated_at":  hold.UpdatedAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// ReleaseExpired returns the quantity of up to limit expired holds back to their tickets and returns the released holds.
+// Rows locked by another instance are skipped, so several sweepers can run at the same time.
+func (r *holdRepository) ReleaseExpired(ctx context.Context, now time.Time, limit int) ([]models.Hold, error) {
+	var holds []models.Hold
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Table(r.tableName).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND expires_at <= ?", models.HoldStatusActive, now).
+			Order("expires_at").
+			Limit(limit).
+			Find(&holds)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		for _, hold := range holds {
+			err := returnAllocation(tx, r.ticketTable, hold.TicketId, hold.Quantity, map[string]interface{}{})
+			if err != nil {
+				return err
+			}
+
+			err = tx.Table(r.tableName).Where("id = ?", hold.Id).Updates(map[string]interface{}{
+				"status":     models.HoldStatusExpired,
+				"updated_by": "system",
+				"updated_at": now,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return holds, nil
+}
+
+func (r *holdRepository) SumActiveQuantity(ctx context.Context, ticketId string) (int, error) {
+	var held int
+	result := r.db.Table(r.tableName).WithContext(ctx).
+		Where("ticket_id = ? AND status = ?", ticketId, models.HoldStatusActive).
+		Select("COALESCE(SUM(quantity), 0)").
+		Scan(&held)
+	return held, result.Error
+}
+
+// SumActiveQuantities returns the held quantity of each given ticket. Tickets without active holds are left out.
+func (r *holdRepository) SumActiveQuantities(ctx context.Context, ticketIds []string) (map[string]int, error) {
+	var rows []struct {
+		TicketId string
+		Held     int
+	}
+	result := r.db.Table(r.tableName).WithContext(ctx).
+		Where("ticket_id IN ? AND status = ?", ticketIds, models.HoldStatusActive).
+		Select("ticket_id, SUM(quantity) AS held").
+		Group("ticket_id").
+		Scan(&rows)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	held := make(map[string]int, len(rows))
+	for _, row := range rows {
+		held[row.TicketId] = row.Held
+	}
+	return held, nil
+}
@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ticket-purchase/internal/db/models"
+	"time"
+)
+
+//go:generate mockgen -destination=../../mocks/repositories/idempotency_repository_mock.go -package=repositories ticket-purchase/internal/db/repositories IdempotencyRepository
+type IdempotencyRepository interface {
+	FindByKey(ctx context.Context, userId string, key string) (*models.IdempotencyKey, error)
+	// Acquire inserts the key and reports whether the request holds it. An expired key, or a key of the same request
+	// whose lease ended before now without a response, is taken over. It returns false while another request holds it.
+	Acquire(ctx context.Context, record *models.IdempotencyKey, now time.Time) (bool, error)
+	SaveResponse(ctx context.Context, userId string, key string, statusCode int, contentType string, body []byte) error
+	Delete(ctx context.Context, userId string, key string) error
+	// DeleteExpired removes the keys that expired before now and returns how many were removed
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+type idempotencyRepository struct {
+	db        *gorm.DB
+	tableName string
+}
+
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	var idempotencyModel models.IdempotencyKey
+	return &idempotencyRepository{db: db, tableName: idempotencyModel.TableName()}
+}
+
+func (r *idempotencyRepository) FindByKey(ctx context.Context, userId string, key string) (*models.IdempotencyKey, error) {
+	var record models.IdempotencyKey
+	result := r.db.Table(r.tableName).WithContext(ctx).Where("user_id = ? AND key = ?", userId, key).First(&record)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &record, nil
+}
+
+func (r *idempotencyRepository) Acquire(ctx context.Context, record *models.IdempotencyKey, now time.Time) (bool, error) {
+	result := r.db.Table(r.tableName).WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"fingerprint", "status_code", "content_type", "response_body", "created_at", "updated_at", "expires_at", "locked_until",
+		}),
+		Where: clause.Where{Exprs: []clause.Expression{gorm.Expr(
+			"idempotency_keys.expires_at < ? OR (idempotency_keys.status_code = 0 AND idempotency_keys.locked_until < ? AND idempotency_keys.fingerprint = excluded.fingerprint)",
+			now, now,
+		)}},
+	}).Create(record)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *idempotencyRepository) SaveResponse(ctx context.Context, userId string, key string, statusCode int, contentType string, body []byte) error {
+	result := r.db.Table(r.tableName).WithContext(ctx).Where("user_id = ? AND key = ?", userId, key).Updates(map[string]interface{}{
+		"status_code":   statusCode,
+		"content_type":  contentType,
+		"response_body": body,
+		"updated_at":    time.Now(),
+	})
+	return result.Error
+}
+
+func (r *idempotencyRepository) Delete(ctx context.Context, userId string, key string) error {
+	result := r.db.Table(r.tableName).WithContext(ctx).Where("user_id = ? AND key = ?", userId, key).Delete(&models.IdempotencyKey{})
+	return result.Error
+}
+
+func (r *idempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	result := r.db.Table(r.tableName).WithContext(ctx).Where("expires_at < ?", now).Delete(&models.IdempotencyKey{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return int(result.RowsAffected), nil
+}
@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"ticket-purchase/internal/db/models"
+	"time"
+)
+
+func TestIdempotencyRepository_Acquire(t *testing.T) {
+	db := setupPostgresTest(t)
+	repo := NewIdempotencyRepository(db)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	userId := uuid.New().String()
+	t.Cleanup(func() {
+		db.Table(models.IdempotencyKey{}.TableName()).Where("user_id = ?", userId).Delete(&models.IdempotencyKey{})
+	})
+
+	key := func(fingerprint string, lockedUntil time.Time) *models.IdempotencyKey {
+		return &models.IdempotencyKey{
+			UserId:      userId,
+			Key:         "key-1",
+			Fingerprint: fingerprint,
+			ExpiresAt:   now.Add(time.Hour),
+			LockedUntil: lockedUntil,
+		}
+	}
+
+	acquired, err := repo.Acquire(ctx, key("first", now.Add(time.Minute)), now)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+
+	// The first request is still running
+	acquired, err = repo.Acquire(ctx, key("first", now.Add(time.Minute)), now)
+	assert.NoError(t, err)
+	assert.False(t, acquired)
+
+	// Its lease is over, a retry of the same request takes the key over but another request does not
+	later := now.Add(2 * time.Minute)
+	acquired, err = repo.Acquire(ctx, key("second", later.Add(time.Minute)), later)
+	assert.NoError(t, err)
+	assert.False(t, acquired)
+
+	acquired, err = repo.Acquire(ctx, key("first", later.Add(time.Minute)), later)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+
+	// A stored response is never taken over while the key lives
+	assert.NoError(t, repo.SaveResponse(ctx, userId, "key-1", 200, "application/json", []byte(`{}`)))
+	evenLater := later.Add(10 * time.Minute)
+	acquired, err = repo.Acquire(ctx, key("first", evenLater.Add(time.Minute)), evenLater)
+	assert.NoError(t, err)
+	assert.False(t, acquired)
+
+	record, err := repo.FindByKey(ctx, userId, "key-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, record.StatusCode)
+
+	// Expired keys are removed by the sweeper
+	deleted, err := repo.DeleteExpired(ctx, now.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, deleted, 1)
+
+	_, err = repo.FindByKey(ctx, userId, "key-1")
+	assert.Error(t, err)
+}
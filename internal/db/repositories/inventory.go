@@ -0,0 +1,344 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInventoryNotLoaded is returned when the Redis counter of a ticket has not been loaded from the database yet
+var ErrInventoryNotLoaded = errors.New("ticket inventory is not loaded")
+
+// ErrInventoryBusy is returned when a counter cannot be loaded because sales of the ticket are still being persisted
+var ErrInventoryBusy = errors.New("ticket inventory has unpersisted sales")
+
+const (
+	inventoryKeyPrefix   = "inventory:"
+	inventoryTicketsKey  = "inventory:tickets"
+	inventorySalesStream = "inventory:sales"
+	inventorySalesGroup  = "inventory-persisters"
+)
+
+// InventorySale is a purchase taken from a Redis counter that is waiting to be written to the database
+type InventorySale struct {
+	// MessageId is the id of the sale in the Redis stream
+	MessageId  string
+	PurchaseId string
+	TicketId   string
+	UserId     string
+	Quantity   int
+	// UnitPrice is in the minor unit of Currency
+	UnitPrice int64
+	Currency  string
+	CreatedAt time.Time
+	// Available is what is left on the counter once the sale is reserved, it is only set by Reserve
+	Available int
+}
+
+// InventorySnapshot is the state of a ticket counter
+type InventorySnapshot struct {
+	Loaded    bool
+	Available int
+	// Pending is the quantity sold from the counter but not written to the database yet
+	Pending int
+}
+
+//go:generate mockgen -destination=../../mocks/repositories/inventory_repository_mock.go -package=repositories ticket-purchase/internal/db/repositories InventoryRepository
+type InventoryRepository interface {
+	// Load sets the counter of a ticket unless it is already loaded
+	Load(ctx context.Context, ticketId string, allocation int) error
+	// Reserve takes the quantity of the sale from the counter and queues the sale in a single atomic step
+	Reserve(ctx context.Context, sale *InventorySale) error
+	// ClaimSales returns queued sales for the consumer. Sales another consumer has not acknowledged for minIdle are
+	// handed over too, so that sales of a crashed instance are not lost.
+	ClaimSales(ctx context.Context, consumer string, count int, minIdle time.Duration, block time.Duration) ([]InventorySale, error)
+	// Persisted acknowledges a sale written to the database
+	Persisted(ctx context.Context, sale InventorySale) error
+	// Rejected acknowledges a sale the database refused and returns its quantity to the counter
+	Rejected(ctx context.Context, sale InventorySale) error
+	// Take takes quantity from the counter of a ticket for a change the caller writes to the database itself, e.g. a
+	// hold. It returns what is left on the counter.
+	Take(ctx context.Context, ticketId string, quantity int) (int, error)
+	// Adjust adds delta to the counter of a ticket, e.g. for a refund written to the database. Counters that are not
+	// loaded are left alone, they are loaded from the database when they are needed.
+	Adjust(ctx context.Context, ticketId string, delta int) error
+	// Snapshot returns the state of the counter of a ticket
+	Snapshot(ctx context.Context, ticketId string) (InventorySnapshot, error)
+	// TicketIds lists the tickets with a loaded counter
+	TicketIds(ctx context.Context) ([]string, error)
+}
+
+type inventoryRepository struct {
+	client redis.UniversalClient
+}
+
+func NewInventoryRepository(client redis.UniversalClient) InventoryRepository {
+	return &inventoryRepository{client: client}
+}
+
+func availableKey(ticketId string) string {
+	return inventoryKeyPrefix + ticketId + ":available"
+}
+
+func pendingKey(ticketId string) string {
+	return inventoryKeyPrefix + ticketId + ":pending"
+}
+
+// loadScript sets the counter unless it exists. A counter with pending sales is not loaded, because the allocation
+// read from the database does not include them yet.
+var loadScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return 1
+end
+if tonumber(redis.call('GET', KEYS[2]) or '0') > 0 then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1])
+redis.call('SADD', KEYS[3], ARGV[2])
+return 1
+`)
+
+// reserveScript decrements the counter, counts the quantity as pending and queues the sale. It returns what is left
+// on the counter.
+var reserveScript = redis.NewScript(`
+local available = redis.call('GET', KEYS[1])
+if not available then
+	return -2
+end
+local quantity = tonumber(ARGV[1])
+if tonumber(available) < quantity then
+	return -1
+end
+redis.call('DECRBY', KEYS[1], quantity)
+redis.call('INCRBY', KEYS[2], quantity)
+redis.call('XADD', KEYS[3], '*',
+	'purchase_id', ARGV[2], 'ticket_id', ARGV[3], 'user_id', ARGV[4], 'quantity', ARGV[1], 'created_at', ARGV[5],
+	'unit_price', ARGV[6], 'currency', ARGV[7])
+return tonumber(available) - quantity
+`)
+
+// settleScript acknowledges a sale once and removes it from the pending quantity.
+// Rejected sales (ARGV[4] = 1) are returned to the counter.
+var settleScript = redis.NewScript(`
+if redis.call('XACK', KEYS[1], ARGV[1], ARGV[2]) == 0 then
+	return 0
+end
+redis.call('XDEL', KEYS[1], ARGV[2])
+redis.call('DECRBY', KEYS[2], ARGV[3])
+if ARGV[4] == '1' and redis.call('EXISTS', KEYS[3]) == 1 then
+	redis.call('INCRBY', KEYS[3], ARGV[3])
+end
+return 1
+`)
+
+// takeScript decrements the counter without queueing a sale. It returns what is left on the counter.
+var takeScript = redis.NewScript(`
+local available = redis.call('GET', KEYS[1])
+if not available then
+	return -2
+end
+if tonumber(available) < tonumber(ARGV[1]) then
+	return -1
+end
+return redis.call('DECRBY', KEYS[1], ARGV[1])
+`)
+
+// adjustScript changes a counter that is loaded
+var adjustScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('INCRBY', KEYS[1], ARGV[1])
+return 1
+`)
+
+func (r *inventoryRepository) Load(ctx context.Context, ticketId string, allocation int) error {
+	keys := []string{availableKey(ticketId), pendingKey(ticketId), inventoryTicketsKey}
+	loaded, err := loadScript.Run(ctx, r.client, keys, allocation, ticketId).Int()
+	if err != nil {
+		return err
+	}
+
+	if loaded == 0 {
+		return ErrInventoryBusy
+	}
+	return nil
+}
+
+func (r *inventoryRepository) Reserve(ctx context.Context, sale *InventorySale) error {
+	keys := []string{availableKey(sale.TicketId), pendingKey(sale.TicketId), inventorySalesStream}
+	result, err := reserveScript.Run(ctx, r.client, keys,
+		sale.Quantity, sale.PurchaseId, sale.TicketId, sale.UserId, sale.CreatedAt.UTC().Format(time.RFC3339Nano),
+		sale.UnitPrice, sale.Currency,
+	).Int()
+	if err != nil {
+		return err
+	}
+
+	switch result {
+	case -2:
+		return ErrInventoryNotLoaded
+	case -1:
+		return ErrInsufficientAllocation
+	}
+
+	sale.Available = result
+	return nil
+}
+
+func (r *inventoryRepository) ClaimSales(
+	ctx context.Context,
+	consumer string,
+	count int,
+	minIdle time.Duration,
+	block time.Duration,
+) ([]InventorySale, error) {
+	err := r.client.XGroupCreateMkStream(ctx, inventorySalesStream, inventorySalesGroup, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil, err
+	}
+
+	// Sales abandoned by other consumers come first
+	claimed, _, err := r.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   inventorySalesStream,
+		Group:    inventorySalesGroup,
+		Consumer: consumer,
+		MinIdle:  minIdle,
+		Start:    "0",
+		Count:    int64(count),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(claimed) > 0 {
+		return parseSales(claimed)
+	}
+
+	streams, err := r.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    inventorySalesGroup,
+		Consumer: consumer,
+		Streams:  []string{inventorySalesStream, ">"},
+		Count:    int64(count),
+		Block:    block,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []redis.XMessage
+	for _, stream := range streams {
+		messages = append(messages, stream.Messages...)
+	}
+	return parseSales(messages)
+}
+
+func (r *inventoryRepository) Persisted(ctx context.Context, sale InventorySale) error {
+	return r.settle(ctx, sale, false)
+}
+
+func (r *inventoryRepository) Rejected(ctx context.Context, sale InventorySale) error {
+	return r.settle(ctx, sale, true)
+}
+
+func (r *inventoryRepository) settle(ctx context.Context, sale InventorySale, rejected bool) error {
+	keys := []string{inventorySalesStream, pendingKey(sale.TicketId), availableKey(sale.TicketId)}
+	flag := "0"
+	if rejected {
+		flag = "1"
+	}
+	return settleScript.Run(ctx, r.client, keys, inventorySalesGroup, sale.MessageId, sale.Quantity, flag).Err()
+}
+
+func (r *inventoryRepository) Snapshot(ctx context.Context, ticketId string) (InventorySnapshot, error) {
+	values, err := r.client.MGet(ctx, availableKey(ticketId), pendingKey(ticketId)).Result()
+	if err != nil {
+		return InventorySnapshot{}, err
+	}
+
+	var snapshot InventorySnapshot
+	if available, ok := values[0].(string); ok {
+		snapshot.Loaded = true
+		if snapshot.Available, err = strconv.Atoi(available); err != nil {
+			return InventorySnapshot{}, err
+		}
+	}
+
+	if pending, ok := values[1].(string); ok {
+		if snapshot.Pending, err = strconv.Atoi(pending); err != nil {
+			return InventorySnapshot{}, err
+		}
+	}
+	return snapshot, nil
+}
+
+func (r *inventoryRepository) Take(ctx context.Context, ticketId string, quantity int) (int, error) {
+	result, err := takeScript.Run(ctx, r.client, []string{availableKey(ticketId)}, quantity).Int()
+	if err != nil {
+		return 0, err
+	}
+
+	switch result {
+	case -2:
+		return 0, ErrInventoryNotLoaded
+	case -1:
+		return 0, ErrInsufficientAllocation
+	}
+	return result, nil
+}
+
+func (r *inventoryRepository) Adjust(ctx context.Context, ticketId string, delta int) error {
+	return adjustScript.Run(ctx, r.client, []string{availableKey(ticketId)}, delta).Err()
+}
+
+func (r *inventoryRepository) TicketIds(ctx context.Context) ([]string, error) {
+	return r.client.SMembers(ctx, inventoryTicketsKey).Result()
+}
+
+func parseSales(messages []redis.XMessage) ([]InventorySale, error) {
+	sales := make([]InventorySale, 0, len(messages))
+	for _, message := range messages {
+		quantity, err := strconv.Atoi(stringValue(message.Values["quantity"]))
+		if err != nil {
+			return nil, err
+		}
+
+		createdAt, err := time.Parse(time.RFC3339Nano, stringValue(message.Values["created_at"]))
+		if err != nil {
+			return nil, err
+		}
+
+		// Sales queued before prices were added have none
+		var unitPrice int64
+		if value := stringValue(message.Values["unit_price"]); value != "" {
+			unitPrice, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		sales = append(sales, InventorySale{
+			MessageId:  message.ID,
+			PurchaseId: stringValue(message.Values["purchase_id"]),
+			TicketId:   stringValue(message.Values["ticket_id"]),
+			UserId:     stringValue(message.Values["user_id"]),
+			Quantity:   quantity,
+			UnitPrice:  unitPrice,
+			Currency:   stringValue(message.Values["currency"]),
+			CreatedAt:  createdAt,
+		})
+	}
+	return sales, nil
+}
+
+func stringValue(value interface{}) string {
+	s, _ := value.(string)
+	return s
+}
@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// inventoryCounter keeps the Redis counter of a ticket in step with the changes of its allocation that are written
+// to the database directly, so that Redis never sells what a hold, an order or an edit already took.
+type inventoryCounter struct {
+	inventoryRepo InventoryRepository
+	ticketRepo    TicketRepository
+}
+
+// take takes quantity from the counter of the ticket, loading it from the database first when it is not loaded.
+// It returns what is left on the counter.
+func (c inventoryCounter) take(ctx context.Context, ticketId string, quantity int) (int, error) {
+	left, err := c.inventoryRepo.Take(ctx, ticketId, quantity)
+	if !errors.Is(err, ErrInventoryNotLoaded) {
+		return left, err
+	}
+
+	ticket, err := c.ticketRepo.FindById(ctx, ticketId)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := c.inventoryRepo.Load(ctx, ticketId, ticket.Allocation); err != nil {
+		return 0, err
+	}
+	return c.inventoryRepo.Take(ctx, ticketId, quantity)
+}
+
+// adjust adds delta to the counter of the ticket, e.g. to give back what take took. The database change is already
+// made, so a failure is only logged and left to the reconciler.
+func (c inventoryCounter) adjust(ctx context.Context, ticketId string, delta int) {
+	if delta == 0 {
+		return
+	}
+
+	if err := c.inventoryRepo.Adjust(ctx, ticketId, delta); err != nil {
+		log.Error("Error adjusting the inventory of ticket ", ticketId, " by ", delta, ": ", err)
+	}
+}
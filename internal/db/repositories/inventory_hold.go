@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"context"
+	"ticket-purchase/internal/db/models"
+	"time"
+)
+
+// inventoryHoldRepository takes holds from the Redis counters before writing them to the database, and gives the
+// quantity of released holds back, so that the counters never sell what is held. Everything else goes to the
+// wrapped repository.
+type inventoryHoldRepository struct {
+	HoldRepository
+	inventoryCounter
+}
+
+// NewInventoryHoldRepository wraps a hold repository so that holds are taken from the Redis counters as well.
+// Counters are loaded from the tickets read with ticketRepo, which should not be cached.
+func NewInventoryHoldRepository(next HoldRepository, inventoryRepo InventoryRepository, ticketRepo TicketRepository) HoldRepository {
+	return &inventoryHoldRepository{
+		HoldRepository:   next,
+		inventoryCounter: inventoryCounter{inventoryRepo: inventoryRepo, ticketRepo: ticketRepo},
+	}
+}
+
+func (r *inventoryHoldRepository) CreateWithAllocation(ctx context.Context, hold *models.Hold) error {
+	if _, err := r.take(ctx, hold.TicketId, hold.Quantity); err != nil {
+		return err
+	}
+
+	if err := r.HoldRepository.CreateWithAllocation(ctx, hold); err != nil {
+		r.adjust(ctx, hold.TicketId, hold.Quantity)
+		return err
+	}
+	return nil
+}
+
+func (r *inventoryHoldRepository) ReleaseExpired(ctx context.Context, now time.Time, limit int) ([]models.Hold, error) {
+	holds, err := r.HoldRepository.ReleaseExpired(ctx, now, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hold := range holds {
+		r.adjust(ctx, hold.TicketId, hold.Quantity)
+	}
+	return holds, nil
+}
@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"ticket-purchase/internal/db/models"
+)
+
+// inventoryOrderRepository takes the lines of a checkout from the Redis counters before the order is checked out in
+// the database, so that checkouts and Redis sales never sell the same tickets. Everything else goes to the wrapped
+// repository.
+type inventoryOrderRepository struct {
+	OrderRepository
+	inventoryCounter
+}
+
+// NewInventoryOrderRepository wraps an order repository so that checkouts are taken from the Redis counters as well.
+// Counters are loaded from the tickets read with ticketRepo, which should not be cached.
+func NewInventoryOrderRepository(next OrderRepository, inventoryRepo InventoryRepository, ticketRepo TicketRepository) OrderRepository {
+	return &inventoryOrderRepository{
+		OrderRepository:  next,
+		inventoryCounter: inventoryCounter{inventoryRepo: inventoryRepo, ticketRepo: ticketRepo},
+	}
+}
+
+func (r *inventoryOrderRepository) Checkout(ctx context.Context, order *models.Order) error {
+	// Lines are taken in the order of their tickets like in the database
+	sorted := make([]*models.Purchase, 0, len(order.Lines))
+	for i := range order.Lines {
+		sorted = append(sorted, &order.Lines[i])
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].TicketId < sorted[j].TicketId
+	})
+
+	var taken []*models.Purchase
+	giveBack := func() {
+		for _, line := range taken {
+			r.adjust(ctx, line.TicketId, line.Quantity)
+		}
+	}
+
+	// Every line is tried, so that all the lines that cannot be taken are reported at once
+	lineErrors := map[string]error{}
+	allocationsLeft := map[string]int{}
+	for _, line := range sorted {
+		allocationLeft, err := r.take(ctx, line.TicketId, line.Quantity)
+		if isLineError(err) {
+			lineErrors[line.Id] = err
+			continue
+		}
+		if err != nil {
+			giveBack()
+			return err
+		}
+		taken = append(taken, line)
+		allocationsLeft[line.Id] = allocationLeft
+	}
+
+	if len(lineErrors) > 0 {
+		giveBack()
+		return &CheckoutError{Lines: lineErrors}
+	}
+
+	if err := r.OrderRepository.Checkout(ctx, order); err != nil {
+		giveBack()
+		return err
+	}
+
+	// The counter also counts the sales that are not persisted yet
+	for _, line := range sorted {
+		allocationLeft := allocationsLeft[line.Id]
+		line.AllocationLeft = &allocationLeft
+	}
+	return nil
+}
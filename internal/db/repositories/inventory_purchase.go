@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"ticket-purchase/internal/db/models"
+)
+
+// inventoryPurchaseRepository sells tickets from Redis counters instead of locking the ticket row.
+// CreateWithAllocation only reserves the quantity in Redis and queues the purchase, which the inventory
+// persister writes to the database afterwards. Refunds give their quantity back to the counters. Everything else
+// goes to the wrapped repository.
+type inventoryPurchaseRepository struct {
+	PurchaseRepository
+	inventoryCounter
+}
+
+// NewInventoryPurchaseRepository wraps a purchase repository so that purchases are taken from the Redis counters.
+// Counters are loaded from the tickets read with ticketRepo, which should not be cached.
+func NewInventoryPurchaseRepository(next PurchaseRepository, inventoryRepo InventoryRepository, ticketRepo TicketRepository) PurchaseRepository {
+	return &inventoryPurchaseRepository{
+		PurchaseRepository: next,
+		inventoryCounter:   inventoryCounter{inventoryRepo: inventoryRepo, ticketRepo: ticketRepo},
+	}
+}
+
+func (r *inventoryPurchaseRepository) CreateWithAllocation(ctx context.Context, purchase *models.Purchase) error {
+	ticket, err := r.ticketRepo.FindById(ctx, purchase.TicketId)
+	if err != nil {
+		return err
+	}
+
+	if !ticket.IsActive {
+		return ErrTicketInactive
+	}
+
+	// The per user limit needs the purchases of the user, which only the database has. These tickets are sold from
+	// the ticket row once the counter gave the quantity.
+	if ticket.MaxPerUser > 0 {
+		if _, err := r.take(ctx, ticket.Id, purchase.Quantity); err != nil {
+			return err
+		}
+
+		if err := r.PurchaseRepository.CreateWithAllocation(ctx, purchase); err != nil {
+			r.adjust(ctx, ticket.Id, purchase.Quantity)
+			return err
+		}
+		return nil
+	}
+
+	// The id is given up front so that writing the purchase to the database can be retried safely
+	if purchase.Id == "" {
+		purchase.Id = uuid.New().String()
+	}
+
+	sale := InventorySale{
+		PurchaseId: purchase.Id,
+		TicketId:   purchase.TicketId,
+		UserId:     purchase.UserId,
+		Quantity:   purchase.Quantity,
+		UnitPrice:  purchase.UnitPrice,
+		Currency:   purchase.Currency,
+		CreatedAt:  purchase.CreatedAt,
+	}
+
+	err = r.inventoryRepo.Reserve(ctx, &sale)
+	if errors.Is(err, ErrInventoryNotLoaded) {
+		if err := r.inventoryRepo.Load(ctx, ticket.Id, ticket.Allocation); err != nil {
+			return err
+		}
+		err = r.inventoryRepo.Reserve(ctx, &sale)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	purchase.AllocationLeft = &sale.Available
+	return nil
+}
+
+func (r *inventoryPurchaseRepository) Refund(ctx context.Context, purchase *models.Purchase, quantity int) error {
+	if err := r.PurchaseRepository.Refund(ctx, purchase, quantity); err != nil {
+		return err
+	}
+
+	r.adjust(ctx, purchase.TicketId, quantity)
+	return nil
+}
+
+func (r *inventoryPurchaseRepository) CompleteRefund(ctx context.Context, purchase *models.Purchase, quantity int) error {
+	if err := r.PurchaseRepository.CompleteRefund(ctx, purchase, quantity); err != nil {
+		return err
+	}
+
+	r.adjust(ctx, purchase.TicketId, quantity)
+	return nil
+}
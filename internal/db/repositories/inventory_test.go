@@ -0,0 +1,466 @@
+package repositories_test
+
+import (
+	"context"
+	"errors"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"ticket-purchase/internal/db/models"
+	"ticket-purchase/internal/db/repositories"
+	mocks "ticket-purchase/internal/mocks/repositories"
+	"time"
+)
+
+const inventoryTicketId = "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b"
+
+var inventoryTestTime = time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+func setupInventoryTest(t *testing.T) (repositories.InventoryRepository, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	return repositories.NewInventoryRepository(client), server
+}
+
+func inventorySale(purchaseId string, quantity int) *repositories.InventorySale {
+	return &repositories.InventorySale{
+		PurchaseId: purchaseId,
+		TicketId:   inventoryTicketId,
+		UserId:     "user-1",
+		Quantity:   quantity,
+		UnitPrice:  1250,
+		Currency:   "TRY",
+		CreatedAt:  inventoryTestTime,
+	}
+}
+
+func TestInventoryRepository_Reserve(t *testing.T) {
+	repo, _ := setupInventoryTest(t)
+	ctx := context.Background()
+
+	assert.ErrorIs(t, repo.Reserve(ctx, inventorySale("purchase-1", 1)), repositories.ErrInventoryNotLoaded)
+
+	assert.NoError(t, repo.Load(ctx, inventoryTicketId, 5))
+	sale := inventorySale("purchase-1", 3)
+	assert.NoError(t, repo.Reserve(ctx, sale))
+	assert.Equal(t, 2, sale.Available)
+	assert.ErrorIs(t, repo.Reserve(ctx, inventorySale("purchase-2", 3)), repositories.ErrInsufficientAllocation)
+
+	snapshot, err := repo.Snapshot(ctx, inventoryTicketId)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	assert.Equal(t, repositories.InventorySnapshot{Loaded: true, Available: 2, Pending: 3}, snapshot)
+
+	// Loading again keeps the live counter
+	assert.NoError(t, repo.Load(ctx, inventoryTicketId, 5))
+	snapshot, _ = repo.Snapshot(ctx, inventoryTicketId)
+	assert.Equal(t, 2, snapshot.Available)
+
+	ticketIds, err := repo.TicketIds(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{inventoryTicketId}, ticketIds)
+}
+
+func TestInventoryRepository_Reserve_Concurrent(t *testing.T) {
+	repo, _ := setupInventoryTest(t)
+	ctx := context.Background()
+	assert.NoError(t, repo.Load(ctx, inventoryTicketId, 20))
+
+	var sold atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.Reserve(ctx, inventorySale("purchase", 1))
+			if err == nil {
+				sold.Add(1)
+				return
+			}
+			assert.ErrorIs(t, err, repositories.ErrInsufficientAllocation)
+		}()
+	}
+	wg.Wait()
+
+	snapshot, _ := repo.Snapshot(ctx, inventoryTicketId)
+	assert.Equal(t, int32(20), sold.Load())
+	assert.Equal(t, 0, snapshot.Available)
+	assert.Equal(t, 20, snapshot.Pending)
+}
+
+func TestInventoryRepository_Claim_And_Persist(t *testing.T) {
+	repo, _ := setupInventoryTest(t)
+	ctx := context.Background()
+	assert.NoError(t, repo.Load(ctx, inventoryTicketId, 5))
+	assert.NoError(t, repo.Reserve(ctx, inventorySale("purchase-1", 2)))
+
+	sales, err := repo.ClaimSales(ctx, "api-1", 10, time.Minute, time.Millisecond)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	assert.Len(t, sales, 1)
+	assert.Equal(t, "purchase-1", sales[0].PurchaseId)
+	assert.Equal(t, "user-1", sales[0].UserId)
+	assert.Equal(t, 2, sales[0].Quantity)
+	assert.Equal(t, int64(1250), sales[0].UnitPrice)
+	assert.Equal(t, "TRY", sales[0].Currency)
+	assert.True(t, inventoryTestTime.Equal(sales[0].CreatedAt))
+
+	// Acknowledging twice only settles the sale once
+	assert.NoError(t, repo.Persisted(ctx, sales[0]))
+	assert.NoError(t, repo.Persisted(ctx, sales[0]))
+
+	snapshot, _ := repo.Snapshot(ctx, inventoryTicketId)
+	assert.Equal(t, repositories.InventorySnapshot{Loaded: true, Available: 3, Pending: 0}, snapshot)
+
+	sales, err = repo.ClaimSales(ctx, "api-1", 10, time.Minute, time.Millisecond)
+	assert.NoError(t, err)
+	assert.Empty(t, sales)
+}
+
+func TestInventoryRepository_Claim_Abandoned_Sales(t *testing.T) {
+	repo, _ := setupInventoryTest(t)
+	ctx := context.Background()
+	assert.NoError(t, repo.Load(ctx, inventoryTicketId, 5))
+	assert.NoError(t, repo.Reserve(ctx, inventorySale("purchase-1", 1)))
+
+	sales, _ := repo.ClaimSales(ctx, "api-1", 10, time.Minute, time.Millisecond)
+	assert.Len(t, sales, 1)
+
+	// api-1 never acknowledges the sale, so api-2 takes it over
+	sales, err := repo.ClaimSales(ctx, "api-2", 10, 0, time.Millisecond)
+	assert.NoError(t, err)
+	assert.Len(t, sales, 1)
+	assert.Equal(t, "purchase-1", sales[0].PurchaseId)
+}
+
+func TestInventoryRepository_Rejected_Returns_Quantity(t *testing.T) {
+	repo, _ := setupInventoryTest(t)
+	ctx := context.Background()
+	assert.NoError(t, repo.Load(ctx, inventoryTicketId, 5))
+	assert.NoError(t, repo.Reserve(ctx, inventorySale("purchase-1", 2)))
+
+	sales, _ := repo.ClaimSales(ctx, "api-1", 10, time.Minute, time.Millisecond)
+	assert.NoError(t, repo.Rejected(ctx, sales[0]))
+
+	snapshot, _ := repo.Snapshot(ctx, inventoryTicketId)
+	assert.Equal(t, repositories.InventorySnapshot{Loaded: true, Available: 5, Pending: 0}, snapshot)
+}
+
+func TestInventoryRepository_Load_Busy(t *testing.T) {
+	repo, server := setupInventoryTest(t)
+	ctx := context.Background()
+	assert.NoError(t, repo.Load(ctx, inventoryTicketId, 5))
+	assert.NoError(t, repo.Reserve(ctx, inventorySale("purchase-1", 2)))
+
+	// The counter was evicted while a sale is still pending
+	server.Del("inventory:" + inventoryTicketId + ":available")
+
+	assert.ErrorIs(t, repo.Load(ctx, inventoryTicketId, 5), repositories.ErrInventoryBusy)
+}
+
+func TestInventoryRepository_Take_And_Adjust(t *testing.T) {
+	repo, _ := setupInventoryTest(t)
+	ctx := context.Background()
+
+	_, err := repo.Take(ctx, inventoryTicketId, 1)
+	assert.ErrorIs(t, err, repositories.ErrInventoryNotLoaded)
+
+	// Counters that are not loaded are left alone
+	assert.NoError(t, repo.Adjust(ctx, inventoryTicketId, 3))
+	snapshot, _ := repo.Snapshot(ctx, inventoryTicketId)
+	assert.False(t, snapshot.Loaded)
+
+	assert.NoError(t, repo.Load(ctx, inventoryTicketId, 5))
+	left, err := repo.Take(ctx, inventoryTicketId, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, left)
+
+	_, err = repo.Take(ctx, inventoryTicketId, 2)
+	assert.ErrorIs(t, err, repositories.ErrInsufficientAllocation)
+
+	assert.NoError(t, repo.Adjust(ctx, inventoryTicketId, 3))
+	snapshot, _ = repo.Snapshot(ctx, inventoryTicketId)
+	assert.Equal(t, repositories.InventorySnapshot{Loaded: true, Available: 4}, snapshot)
+}
+
+func TestInventoryPurchaseRepository_CreateWithAllocation(t *testing.T) {
+	inventoryRepo, _ := setupInventoryTest(t)
+	ct := gomock.NewController(t)
+	ticketRepo := mocks.NewMockTicketRepository(ct)
+	purchaseRepo := mocks.NewMockPurchaseRepository(ct)
+	repo := repositories.NewInventoryPurchaseRepository(purchaseRepo, inventoryRepo, ticketRepo)
+	ctx := context.Background()
+
+	ticket := models.Ticket{Id: inventoryTicketId, Allocation: 3, IsActive: true}
+	ticketRepo.EXPECT().FindById(ctx, inventoryTicketId).Return(&ticket, nil).Times(3)
+
+	// The counter is loaded from the ticket on the first purchase, the database is not written
+	first := models.Purchase{TicketId: inventoryTicketId, UserId: "user-1", Quantity: 2, CreatedAt: inventoryTestTime}
+	assert.NoError(t, repo.CreateWithAllocation(ctx, &first))
+	assert.NotEmpty(t, first.Id)
+
+	second := models.Purchase{TicketId: inventoryTicketId, UserId: "user-2", Quantity: 2, CreatedAt: inventoryTestTime}
+	assert.ErrorIs(t, repo.CreateWithAllocation(ctx, &second), repositories.ErrInsufficientAllocation)
+
+	ticket.IsActive = false
+	assert.ErrorIs(t, repo.CreateWithAllocation(ctx, &second), repositories.ErrTicketInactive)
+
+	snapshot, _ := inventoryRepo.Snapshot(ctx, inventoryTicketId)
+	assert.Equal(t, repositories.InventorySnapshot{Loaded: true, Available: 1, Pending: 2}, snapshot)
+}
+
+func TestInventoryPurchaseRepository_Ticket_Not_Found(t *testing.T) {
+	inventoryRepo, _ := setupInventoryTest(t)
+	ct := gomock.NewController(t)
+	ticketRepo := mocks.NewMockTicketRepository(ct)
+	repo := repositories.NewInventoryPurchaseRepository(mocks.NewMockPurchaseRepository(ct), inventoryRepo, ticketRepo)
+	notFound := errors.New("record not found")
+
+	ticketRepo.EXPECT().FindById(gomock.Any(), "missing").Return(nil, notFound)
+
+	err := repo.CreateWithAllocation(context.Background(), &models.Purchase{TicketId: "missing", Quantity: 1})
+	assert.ErrorIs(t, err, notFound)
+}
+
+func TestInventoryPurchaseRepository_Per_User_Limit_Uses_Database(t *testing.T) {
+	inventoryRepo, _ := setupInventoryTest(t)
+	ct := gomock.NewController(t)
+	ticketRepo := mocks.NewMockTicketRepository(ct)
+	purchaseRepo := mocks.NewMockPurchaseRepository(ct)
+	repo := repositories.NewInventoryPurchaseRepository(purchaseRepo, inventoryRepo, ticketRepo)
+	ctx := context.Background()
+
+	ticket := models.Ticket{Id: inventoryTicketId, Allocation: 3, MaxPerUser: 2, IsActive: true}
+	refused := models.Purchase{TicketId: inventoryTicketId, UserId: "user-1", Quantity: 3, CreatedAt: inventoryTestTime}
+	purchase := models.Purchase{TicketId: inventoryTicketId, UserId: "user-1", Quantity: 2, CreatedAt: inventoryTestTime}
+	ticketRepo.EXPECT().FindById(ctx, inventoryTicketId).Return(&ticket, nil).Times(3)
+	purchaseRepo.EXPECT().CreateWithAllocation(ctx, &refused).Return(repositories.ErrMaxPerUserExceeded)
+	purchaseRepo.EXPECT().CreateWithAllocation(ctx, &purchase).Return(nil)
+
+	// The counter gets back what the database refused, and gives what the database sold
+	assert.ErrorIs(t, repo.CreateWithAllocation(ctx, &refused), repositories.ErrMaxPerUserExceeded)
+	snapshot, _ := inventoryRepo.Snapshot(ctx, inventoryTicketId)
+	assert.Equal(t, repositories.InventorySnapshot{Loaded: true, Available: 3}, snapshot)
+
+	assert.NoError(t, repo.CreateWithAllocation(ctx, &purchase))
+	snapshot, _ = inventoryRepo.Snapshot(ctx, inventoryTicketId)
+	assert.Equal(t, repositories.InventorySnapshot{Loaded: true, Available: 1}, snapshot)
+}
+
+func TestInventoryPurchaseRepository_Refund_Returns_To_Counter(t *testing.T) {
+	inventoryRepo, _ := setupInventoryTest(t)
+	ct := gomock.NewController(t)
+	purchaseRepo := mocks.NewMockPurchaseRepository(ct)
+	repo := repositories.NewInventoryPurchaseRepository(purchaseRepo, inventoryRepo, mocks.NewMockTicketRepository(ct))
+	ctx := context.Background()
+	assert.NoError(t, inventoryRepo.Load(ctx, inventoryTicketId, 1))
+
+	purchase := models.Purchase{TicketId: inventoryTicketId, Quantity: 3}
+	purchaseRepo.EXPECT().Refund(ctx, &purchase, 1).Return(nil)
+	purchaseRepo.EXPECT().CompleteRefund(ctx, &purchase, 2).Return(nil)
+	purchaseRepo.EXPECT().Refund(ctx, &purchase, 3).Return(repositories.ErrRefundExceedsQuantity)
+
+	assert.NoError(t, repo.Refund(ctx, &purchase, 1))
+	assert.NoError(t, repo.CompleteRefund(ctx, &purchase, 2))
+	assert.ErrorIs(t, repo.Refund(ctx, &purchase, 3), repositories.ErrRefundExceedsQuantity)
+
+	snapshot, _ := inventoryRepo.Snapshot(ctx, inventoryTicketId)
+	assert.Equal(t, 4, snapshot.Available)
+}
+
+func TestInventoryHoldRepository_Holds_Alongside_Sales(t *testing.T) {
+	inventoryRepo, _ := setupInventoryTest(t)
+	ct := gomock.NewController(t)
+	ticketRepo := mocks.NewMockTicketRepository(ct)
+	holdRepo := mocks.NewMockHoldRepository(ct)
+	purchases := repositories.NewInventoryPurchaseRepository(mocks.NewMockPurchaseRepository(ct), inventoryRepo, ticketRepo)
+	holds := repositories.NewInventoryHoldRepository(holdRepo, inventoryRepo, ticketRepo)
+	ctx := context.Background()
+
+	ticket := models.Ticket{Id: inventoryTicketId, Allocation: 4, IsActive: true}
+	ticketRepo.EXPECT().FindById(ctx, inventoryTicketId).Return(&ticket, nil).AnyTimes()
+
+	// The hold loads the counter and takes from it before the database
+	held := models.Hold{TicketId: inventoryTicketId, UserId: "user-1", Quantity: 2}
+	holdRepo.EXPECT().CreateWithAllocation(ctx, &held).Return(nil)
+	assert.NoError(t, holds.CreateWithAllocation(ctx, &held))
+
+	sale := models.Purchase{TicketId: inventoryTicketId, UserId: "user-2", Quantity: 3, CreatedAt: inventoryTestTime}
+	assert.ErrorIs(t, purchases.CreateWithAllocation(ctx, &sale), repositories.ErrInsufficientAllocation)
+
+	sale.Quantity = 2
+	assert.NoError(t, purchases.CreateWithAllocation(ctx, &sale))
+
+	// Holds do not reach the database when Redis sold everything
+	assert.ErrorIs(t, holds.CreateWithAllocation(ctx, &models.Hold{TicketId: inventoryTicketId, Quantity: 1}),
+		repositories.ErrInsufficientAllocation)
+
+	// Expired holds are given back to the counter and can be sold again
+	holdRepo.EXPECT().ReleaseExpired(ctx, inventoryTestTime, 10).Return([]models.Hold{held}, nil)
+	released, err := holds.ReleaseExpired(ctx, inventoryTestTime, 10)
+	assert.NoError(t, err)
+	assert.Len(t, released, 1)
+
+	snapshot, _ := inventoryRepo.Snapshot(ctx, inventoryTicketId)
+	assert.Equal(t, repositories.InventorySnapshot{Loaded: true, Available: 2, Pending: 2}, snapshot)
+}
+
+func TestInventoryHoldRepository_Database_Failure_Gives_Back(t *testing.T) {
+	inventoryRepo, _ := setupInventoryTest(t)
+	ct := gomock.NewController(t)
+	ticketRepo := mocks.NewMockTicketRepository(ct)
+	holdRepo := mocks.NewMockHoldRepository(ct)
+	holds := repositories.NewInventoryHoldRepository(holdRepo, inventoryRepo, ticketRepo)
+	ctx := context.Background()
+
+	hold := models.Hold{TicketId: inventoryTicketId, UserId: "user-1", Quantity: 2}
+	ticketRepo.EXPECT().FindById(ctx, inventoryTicketId).Return(&models.Ticket{Id: inventoryTicketId, Allocation: 3}, nil)
+	holdRepo.EXPECT().CreateWithAllocation(ctx, &hold).Return(repositories.ErrMaxPerUserExceeded)
+
+	assert.ErrorIs(t, holds.CreateWithAllocation(ctx, &hold), repositories.ErrMaxPerUserExceeded)
+
+	snapshot, _ := inventoryRepo.Snapshot(ctx, inventoryTicketId)
+	assert.Equal(t, 3, snapshot.Available)
+}
+
+func TestInventoryTicketRepository_UpdateDetails_Adjusts_Counter(t *testing.T) {
+	inventoryRepo, _ := setupInventoryTest(t)
+	ticketRepo := mocks.NewMockTicketRepository(gomock.NewController(t))
+	repo := repositories.NewInventoryTicketRepository(ticketRepo, inventoryRepo)
+	ctx := context.Background()
+	assert.NoError(t, inventoryRepo.Load(ctx, inventoryTicketId, 5))
+
+	allocation := 8
+	ticket := models.Ticket{Id: inventoryTicketId}
+	ticketRepo.EXPECT().FindTotalAllocation(ctx, inventoryTicketId).Return(10, nil)
+	ticketRepo.EXPECT().UpdateDetails(ctx, &ticket, &allocation).DoAndReturn(
+		func(ctx context.Context, ticket *models.Ticket, totalAllocation *int) (*models.Ticket, error) {
+			ticket.Allocation = 3
+			ticket.AllocationChange = -2
+			return ticket, nil
+		})
+
+	_, err := repo.UpdateDetails(ctx, &ticket, &allocation)
+	assert.NoError(t, err)
+
+	snapshot, _ := inventoryRepo.Snapshot(ctx, inventoryTicketId)
+	assert.Equal(t, 3, snapshot.Available)
+}
+
+func TestInventoryTicketRepository_UpdateDetails_Pending_Sales(t *testing.T) {
+	inventoryRepo, _ := setupInventoryTest(t)
+	ct := gomock.NewController(t)
+	ticketRepo := mocks.NewMockTicketRepository(ct)
+	purchases := repositories.NewInventoryPurchaseRepository(mocks.NewMockPurchaseRepository(ct), inventoryRepo, ticketRepo)
+	repo := repositories.NewInventoryTicketRepository(ticketRepo, inventoryRepo)
+	ctx := context.Background()
+
+	// Redis sold 3 of 5, the persister has not written them yet, so the database still has an allocation of 5
+	ticketRepo.EXPECT().FindById(ctx, inventoryTicketId).
+		Return(&models.Ticket{Id: inventoryTicketId, Allocation: 5, IsActive: true}, nil)
+	sale := models.Purchase{TicketId: inventoryTicketId, UserId: "user-1", Quantity: 3, CreatedAt: inventoryTestTime}
+	assert.NoError(t, purchases.CreateWithAllocation(ctx, &sale))
+	ticketRepo.EXPECT().FindTotalAllocation(ctx, inventoryTicketId).Return(5, nil).Times(2)
+
+	// A total below the pending sales is refused without reaching the database
+	ticket := models.Ticket{Id: inventoryTicketId}
+	tooLow := 2
+	_, err := repo.UpdateDetails(ctx, &ticket, &tooLow)
+	assert.ErrorIs(t, err, repositories.ErrAllocationBelowSold)
+
+	snapshot, _ := inventoryRepo.Snapshot(ctx, inventoryTicketId)
+	assert.Equal(t, repositories.InventorySnapshot{Loaded: true, Available: 2, Pending: 3}, snapshot)
+
+	// A total above them is taken from the counter, the database change is not applied a second time
+	allocation := 4
+	ticketRepo.EXPECT().UpdateDetails(ctx, &ticket, &allocation).DoAndReturn(
+		func(ctx context.Context, ticket *models.Ticket, totalAllocation *int) (*models.Ticket, error) {
+			ticket.Allocation = 4
+			ticket.AllocationChange = -1
+			return ticket, nil
+		})
+	_, err = repo.UpdateDetails(ctx, &ticket, &allocation)
+	assert.NoError(t, err)
+
+	snapshot, _ = inventoryRepo.Snapshot(ctx, inventoryTicketId)
+	assert.Equal(t, repositories.InventorySnapshot{Loaded: true, Available: 1, Pending: 3}, snapshot)
+}
+
+func TestInventoryTicketRepository_UpdateDetails_Database_Failure_Gives_Back(t *testing.T) {
+	inventoryRepo, _ := setupInventoryTest(t)
+	ticketRepo := mocks.NewMockTicketRepository(gomock.NewController(t))
+	repo := repositories.NewInventoryTicketRepository(ticketRepo, inventoryRepo)
+	ctx := context.Background()
+	assert.NoError(t, inventoryRepo.Load(ctx, inventoryTicketId, 5))
+
+	allocation := 3
+	ticket := models.Ticket{Id: inventoryTicketId}
+	ticketRepo.EXPECT().FindTotalAllocation(ctx, inventoryTicketId).Return(5, nil)
+	ticketRepo.EXPECT().UpdateDetails(ctx, &ticket, &allocation).Return(nil, repositories.ErrAllocationBelowSold)
+
+	_, err := repo.UpdateDetails(ctx, &ticket, &allocation)
+	assert.ErrorIs(t, err, repositories.ErrAllocationBelowSold)
+
+	snapshot, _ := inventoryRepo.Snapshot(ctx, inventoryTicketId)
+	assert.Equal(t, 5, snapshot.Available)
+}
+
+func TestInventoryOrderRepository_Checkout_Alongside_Sales(t *testing.T) {
+	inventoryRepo, _ := setupInventoryTest(t)
+	ct := gomock.NewController(t)
+	ticketRepo := mocks.NewMockTicketRepository(ct)
+	orderRepo := mocks.NewMockOrderRepository(ct)
+	purchases := repositories.NewInventoryPurchaseRepository(mocks.NewMockPurchaseRepository(ct), inventoryRepo, ticketRepo)
+	orders := repositories.NewInventoryOrderRepository(orderRepo, inventoryRepo, ticketRepo)
+	ctx := context.Background()
+
+	const otherTicketId = "5a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b"
+	ticketRepo.EXPECT().FindById(ctx, inventoryTicketId).
+		Return(&models.Ticket{Id: inventoryTicketId, Allocation: 2, IsActive: true}, nil).AnyTimes()
+	ticketRepo.EXPECT().FindById(ctx, otherTicketId).
+		Return(&models.Ticket{Id: otherTicketId, Allocation: 5, IsActive: true}, nil).AnyTimes()
+
+	// Redis sold the whole allocation of the first ticket, the checkout is rejected without reaching the database
+	sale := models.Purchase{TicketId: inventoryTicketId, UserId: "user-2", Quantity: 2, CreatedAt: inventoryTestTime}
+	assert.NoError(t, purchases.CreateWithAllocation(ctx, &sale))
+
+	order := models.Order{Id: "order-1", Lines: []models.Purchase{
+		{Id: "line-1", TicketId: otherTicketId, Quantity: 2},
+		{Id: "line-2", TicketId: inventoryTicketId, Quantity: 1},
+	}}
+	err := orders.Checkout(ctx, &order)
+
+	var rejected *repositories.CheckoutError
+	if !errors.As(err, &rejected) {
+		t.Fatalf("Expected a checkout error, got %v", err)
+	}
+	assert.Equal(t, map[string]error{"line-2": repositories.ErrInsufficientAllocation}, rejected.Lines)
+
+	snapshot, _ := inventoryRepo.Snapshot(ctx, otherTicketId)
+	assert.Equal(t, 5, snapshot.Available)
+
+	// Lines the database refuses are given back
+	order.Lines = order.Lines[:1]
+	orderRepo.EXPECT().Checkout(ctx, &order).Return(repositories.ErrCartChanged)
+	assert.ErrorIs(t, orders.Checkout(ctx, &order), repositories.ErrCartChanged)
+
+	snapshot, _ = inventoryRepo.Snapshot(ctx, otherTicketId)
+	assert.Equal(t, 5, snapshot.Available)
+
+	orderRepo.EXPECT().Checkout(ctx, &order).Return(nil)
+	assert.NoError(t, orders.Checkout(ctx, &order))
+	assert.Equal(t, 3, *order.Lines[0].AllocationLeft)
+
+	snapshot, _ = inventoryRepo.Snapshot(ctx, otherTicketId)
+	assert.Equal(t, 3, snapshot.Available)
+}
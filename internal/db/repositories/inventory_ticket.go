@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"ticket-purchase/internal/db/models"
+)
+
+// inventoryTicketRepository applies allocation edits to the Redis counters. A lower allocation is taken from the
+// counter first, a higher one is added once it is written to the database.
+// Everything else goes to the wrapped repository.
+type inventoryTicketRepository struct {
+	TicketRepository
+	inventoryCounter
+}
+
+// NewInventoryTicketRepository wraps a ticket repository so that allocation edits reach the Redis counters. It
+// should wrap the database repository, below any cache.
+func NewInventoryTicketRepository(next TicketRepository, inventoryRepo InventoryRepository) TicketRepository {
+	return &inventoryTicketRepository{
+		TicketRepository: next,
+		inventoryCounter: inventoryCounter{inventoryRepo: inventoryRepo, ticketRepo: next},
+	}
+}
+
+// UpdateDetails takes a lower allocation from the counter before it writes it to the database. The counter has the
+// sales the persister has not written yet, a total below them would leave it negative and refund paid orders.
+func (r *inventoryTicketRepository) UpdateDetails(ctx context.Context, ticket *models.Ticket, totalAllocation *int) (*models.Ticket, error) {
+	taken := 0
+	if totalAllocation != nil {
+		total, err := r.TicketRepository.FindTotalAllocation(ctx, ticket.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		if decrease := total - *totalAllocation; decrease > 0 {
+			if _, err := r.take(ctx, ticket.Id, decrease); err != nil {
+				if errors.Is(err, ErrInsufficientAllocation) {
+					return nil, ErrAllocationBelowSold
+				}
+				return nil, err
+			}
+			taken = decrease
+		}
+	}
+
+	updated, err := r.TicketRepository.UpdateDetails(ctx, ticket, totalAllocation)
+	if err != nil {
+		r.adjust(ctx, ticket.Id, taken)
+		return nil, err
+	}
+
+	// The database computes the change under its row lock, it only differs from what was taken when another edit ran
+	// at the same time
+	r.adjust(ctx, updated.Id, updated.AllocationChange+taken)
+	return updated, nil
+}
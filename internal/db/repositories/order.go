@@ -0,0 +1,287 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sort"
+	"strings"
+	"ticket-purchase/internal/db/models"
+)
+
+var (
+	// ErrOrderNotCart is returned when an order is checked out after it left the cart
+	ErrOrderNotCart = errors.New("order is not a cart")
+	// ErrCartChanged is returned when the lines of a cart changed while it was checked out
+	ErrCartChanged = errors.New("cart changed during checkout")
+)
+
+// CheckoutError is returned when lines of an order could not be reserved. Nothing of the order was reserved.
+type CheckoutError struct {
+	// Lines are the errors by line id
+	Lines map[string]error
+}
+
+func (e *CheckoutError) Error() string {
+	reasons := make([]string, 0, len(e.Lines))
+	for id, err := range e.Lines {
+		reasons = append(reasons, id+": "+err.Error())
+	}
+	sort.Strings(reasons)
+	return "checkout rejected: " + strings.Join(reasons, ", ")
+}
+
+//go:generate mockgen -destination=../../mocks/repositories/order_repository_mock.go -package=repositories ticket-purchase/internal/db/repositories OrderRepository
+type OrderRepository interface {
+	// FindById finds an order with its lines and their tickets
+	FindById(ctx context.Context, id string) (*models.Order, error)
+	// FindCart finds the cart of the user with its lines and their tickets
+	FindCart(ctx context.Context, userId string) (*models.Order, error)
+	// SaveLine puts the line in the cart of its user, creating the cart when the user has none. The line replaces
+	// the quantity and price of the line of the same ticket.
+	SaveLine(ctx context.Context, line *models.Purchase) error
+	// RemoveLine removes a line from the cart of the user
+	RemoveLine(ctx context.Context, userId string, lineId string) error
+	// Checkout reserves the allocation of every line and completes the order in a single transaction. Lines that
+	// cannot be reserved are returned in a CheckoutError and nothing is reserved. The lines and the order are
+	// updated with the prices they were given.
+	Checkout(ctx context.Context, order *models.Order) error
+}
+
+type orderRepository struct {
+	db            *gorm.DB
+	tableName     string
+	purchaseTable string
+	ticketTable   string
+}
+
+func NewOrderRepository(db *gorm.DB) OrderRepository {
+	var orderModel models.Order
+	var purchaseModel models.Purchase
+	var ticketModel models.Ticket
+	return &orderRepository{
+		db:            db,
+		tableName:     orderModel.TableName(),
+		purchaseTable: purchaseModel.TableName(),
+		ticketTable:   ticketModel.TableName(),
+	}
+}
+
+// withLines preloads the lines of orders in the order they were added
+func withLines(db *gorm.DB) *gorm.DB {
+	return db.Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at, id")
+	}).Preload("Lines.Ticket")
+}
+
+func (r *orderRepository) FindById(ctx context.Context, id string) (*models.Order, error) {
+	var order models.Order
+	result := withLines(r.db.WithContext(ctx)).Where("id = ?", id).First(&order)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &order, nil
+}
+
+func (r *orderRepository) FindCart(ctx context.Context, userId string) (*models.Order, error) {
+	var order models.Order
+	result := withLines(r.db.WithContext(ctx)).Where("user_id = ? AND status = ?", userId, models.OrderStatusCart).First(&order)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &order, nil
+}
+
+// lockCart returns the cart of the user with its row locked, so that changes to a cart and its checkout run one after
+// another. The cart is created when the user has none.
+func (r *orderRepository) lockCart(tx *gorm.DB, line *models.Purchase) (*models.Order, error) {
+	cart := models.Order{
+		UserId:    line.UserId,
+		Status:    models.OrderStatusCart,
+		CreatedAt: line.UpdatedAt,
+		UpdatedAt: line.UpdatedAt,
+	}
+	err := tx.Table(r.tableName).Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "user_id"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "status = 'cart'"}}},
+		DoNothing:   true,
+	}).Create(&cart).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// The existing cart is read when the user already had one
+	var locked models.Order
+	err = tx.Table(r.tableName).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND status = ?", line.UserId, models.OrderStatusCart).
+		First(&locked).Error
+	if err != nil {
+		return nil, err
+	}
+	return &locked, nil
+}
+
+func (r *orderRepository) SaveLine(ctx context.Context, line *models.Purchase) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		cart, err := r.lockCart(tx, line)
+		if err != nil {
+			return err
+		}
+
+		line.OrderId = &cart.Id
+		line.Status = models.PurchaseStatusCart
+		line.IsActive = false
+
+		// The columns are selected, so that the zero IsActive is written instead of its default
+		err = tx.Table(r.purchaseTable).
+			Select("id", "order_id", "ticket_id", "user_id", "quantity", "unit_price", "total", "currency", "status",
+				"is_active", "created_by", "updated_by", "created_at", "updated_at").
+			Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "order_id"}, {Name: "ticket_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"quantity", "unit_price", "total", "currency", "updated_by", "updated_at"}),
+			}).
+			Create(line).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Table(r.tableName).Where("id = ?", cart.Id).UpdateColumn("updated_at", line.UpdatedAt).Error
+	})
+}
+
+func (r *orderRepository) RemoveLine(ctx context.Context, userId string, lineId string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var cart models.Order
+		err := tx.Table(r.tableName).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND status = ?", userId, models.OrderStatusCart).
+			First(&cart).Error
+		if err != nil {
+			return err
+		}
+
+		result := tx.Table(r.purchaseTable).
+			Where("id = ? AND order_id = ? AND status = ?", lineId, cart.Id, models.PurchaseStatusCart).
+			Delete(&models.Purchase{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func (r *orderRepository) Checkout(ctx context.Context, order *models.Order) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current models.Order
+		result := tx.Table(r.tableName).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", order.Id).First(&current)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if current.Status != models.OrderStatusCart {
+			return ErrOrderNotCart
+		}
+
+		// The cart is locked, its lines can only have changed before the checkout started
+		var lines []models.Purchase
+		if err := tx.Table(r.purchaseTable).Where("order_id = ?", order.Id).Find(&lines).Error; err != nil {
+			return err
+		}
+		if !sameLines(lines, order.Lines) {
+			return ErrCartChanged
+		}
+
+		// Ticket rows are locked in the order of their ids, so that checkouts sharing tickets cannot deadlock
+		sorted := make([]*models.Purchase, 0, len(order.Lines))
+		for i := range order.Lines {
+			sorted = append(sorted, &order.Lines[i])
+		}
+		sort.Slice(sorted, func(i, j int) bool {
+			return sorted[i].TicketId < sorted[j].TicketId
+		})
+
+		// Every line is tried, so that all the lines that cannot be reserved are reported at once
+		lineErrors := map[string]error{}
+		allocationsLeft := map[string]int{}
+		for _, line := range sorted {
+			allocationLeft, err := reserveAllocation(tx, r.ticketTable, line.TicketId, line.Quantity, line.UpdatedBy, line.UpdatedAt)
+			if err == nil {
+				err = checkUserLimit(tx, line.TicketId, line.UserId, line.Quantity)
+			}
+			if isLineError(err) {
+				lineErrors[line.Id] = err
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			allocationsLeft[line.Id] = allocationLeft
+		}
+
+		if len(lineErrors) > 0 {
+			return &CheckoutError{Lines: lineErrors}
+		}
+
+		for _, line := range sorted {
+			err := tx.Table(r.purchaseTable).Where("id = ?", line.Id).UpdateColumns(map[string]interface{}{
+				"status":     models.PurchaseStatusCompleted,
+				"is_active":  true,
+				"unit_price": line.UnitPrice,
+				"total":      line.Total,
+				"currency":   line.Currency,
+				"updated_by": line.UpdatedBy,
+				"updated_at": line.UpdatedAt,
+			}).Error
+			if err != nil {
+				return err
+			}
+
+			if err := writeTicketPurchased(tx, line); err != nil {
+				return err
+			}
+
+			allocationLeft := allocationsLeft[line.Id]
+			line.AllocationLeft = &allocationLeft
+			line.Status = models.PurchaseStatusCompleted
+			line.IsActive = true
+		}
+
+		order.Status = models.OrderStatusCompleted
+		return tx.Table(r.tableName).Where("id = ?", order.Id).UpdateColumns(map[string]interface{}{
+			"status":         order.Status,
+			"total":          order.Total,
+			"currency":       order.Currency,
+			"checked_out_at": order.CheckedOutAt,
+			"updated_at":     order.UpdatedAt,
+		}).Error
+	})
+}
+
+// sameLines reports whether the stored lines of a cart are the lines it is checked out with
+func sameLines(stored []models.Purchase, lines []models.Purchase) bool {
+	if len(stored) != len(lines) {
+		return false
+	}
+
+	quantities := make(map[string]int, len(stored))
+	for _, line := range stored {
+		quantities[line.Id] = line.Quantity
+	}
+	for _, line := range lines {
+		if quantity, ok := quantities[line.Id]; !ok || quantity != line.Quantity {
+			return false
+		}
+	}
+	return true
+}
+
+// isLineError reports whether the error rejects a single line of a checkout rather than failing the whole checkout
+func isLineError(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound) ||
+		errors.Is(err, ErrInsufficientAllocation) ||
+		errors.Is(err, ErrTicketInactive) ||
+		errors.Is(err, ErrMaxPerUserExceeded)
+}
@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"testing"
+	"ticket-purchase/internal/db/models"
+	"time"
+)
+
+// createOrderTicket inserts a ticket for a checkout and removes it with its lines, orders and events after the test
+func createOrderTicket(t *testing.T, db *gorm.DB, user *models.User, allocation int) *models.Ticket {
+	ticket, err := NewTicketRepository(db).Create(context.Background(), &models.Ticket{
+		Name:       "Order Ticket",
+		Allocation: allocation,
+		IsActive:   true,
+		CreatedBy:  user.Id,
+		UpdatedBy:  user.Id,
+	})
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	t.Cleanup(func() {
+		db.Table(models.OutboxEvent{}.TableName()).Where("ticket_id = ?", ticket.Id).Delete(&models.OutboxEvent{})
+		db.Table(models.Purchase{}.TableName()).Where("ticket_id = ?", ticket.Id).Delete(&models.Purchase{})
+		db.Table(models.Order{}.TableName()).Where("user_id = ?", user.Id).Delete(&models.Order{})
+		db.Table(models.Ticket{}.TableName()).Where("id = ?", ticket.Id).Delete(&models.Ticket{})
+	})
+	return ticket
+}
+
+func TestOrderRepository_Checkout_All_Or_Nothing(t *testing.T) {
+	db := setupPostgresTest(t)
+	ctx := context.Background()
+	user := createTestUser(t, db)
+	repo := NewOrderRepository(db)
+
+	concert := createOrderTicket(t, db, user, 5)
+	parking := createOrderTicket(t, db, user, 1)
+
+	saveLine := func(ticket *models.Ticket, quantity int) {
+		err := repo.SaveLine(ctx, &models.Purchase{
+			TicketId:  ticket.Id,
+			UserId:    user.Id,
+			Quantity:  quantity,
+			CreatedBy: user.Id,
+			UpdatedBy: user.Id,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("Expected error to be nil, got %v", err)
+		}
+	}
+	allocation := func(ticket *models.Ticket) int {
+		var current models.Ticket
+		if err := db.Table(models.Ticket{}.TableName()).Where("id = ?", ticket.Id).First(&current).Error; err != nil {
+			t.Fatalf("Expected error to be nil, got %v", err)
+		}
+		return current.Allocation
+	}
+
+	saveLine(concert, 2)
+	saveLine(parking, 2)
+
+	cart, err := repo.FindCart(ctx, user.Id)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	assert.Len(t, cart.Lines, 2)
+
+	// Parking has 1 left, so nothing is reserved
+	err = repo.Checkout(ctx, cart)
+	var rejected *CheckoutError
+	assert.True(t, errors.As(err, &rejected))
+	assert.Len(t, rejected.Lines, 1)
+	assert.ErrorIs(t, rejected.Lines[cart.LineOf(parking.Id).Id], ErrInsufficientAllocation)
+	assert.Equal(t, 5, allocation(concert))
+	assert.Equal(t, 1, allocation(parking))
+
+	// Saving the line of the same ticket replaces its quantity
+	saveLine(parking, 1)
+	cart, err = repo.FindCart(ctx, user.Id)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	assert.Len(t, cart.Lines, 2)
+
+	assert.NoError(t, repo.Checkout(ctx, cart))
+	assert.Equal(t, 3, allocation(concert))
+	assert.Equal(t, 0, allocation(parking))
+	assert.Equal(t, 0, *cart.LineOf(parking.Id).AllocationLeft)
+
+	order, err := repo.FindById(ctx, cart.Id)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	assert.Equal(t, models.OrderStatusCompleted, order.Status)
+	for _, line := range order.Lines {
+		assert.Equal(t, models.PurchaseStatusCompleted, line.Status)
+		assert.True(t, line.IsActive)
+	}
+
+	// A completed order cannot be checked out again and the user starts a new cart
+	assert.ErrorIs(t, repo.Checkout(ctx, order), ErrOrderNotCart)
+	_, err = repo.FindCart(ctx, user.Id)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
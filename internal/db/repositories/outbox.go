@@ -0,0 +1,149 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ticket-purchase/internal/db/models"
+	"ticket-purchase/internal/events"
+	"time"
+)
+
+//go:generate mockgen -destination=../../mocks/repositories/outbox_repository_mock.go -package=repositories ticket-purchase/internal/db/repositories OutboxRepository
+type OutboxRepository interface {
+	// Relay passes up to limit unpublished events to publish in the order they were written and marks them
+	// published at now. When an event fails the later events of its ticket are skipped in this pass, so the events
+	// of a ticket are never published out of order, while other tickets go on. The failure is counted and the event
+	// is parked after maxAttempts, so that the events after it are published. It returns how many events were
+	// published with the errors of the failed ones. Relays of several instances run one after another.
+	Relay(ctx context.Context, limit int, maxAttempts int, now time.Time, publish func(event models.OutboxEvent) error) (int, error)
+	// DeletePublished removes the events published before the given time and reports how many were removed
+	DeletePublished(ctx context.Context, before time.Time) (int64, error)
+}
+
+type outboxRepository struct {
+	db        *gorm.DB
+	tableName string
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	var outboxModel models.OutboxEvent
+	return &outboxRepository{db: db, tableName: outboxModel.TableName()}
+}
+
+// writeOutbox adds an event of the ticket to the outbox inside the given transaction. It has to run after the
+// transaction locked the ticket row, so that the events of a ticket get their positions in the order they commit.
+func writeOutbox(tx *gorm.DB, ticketId string, eventType string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return tx.Table(models.OutboxEvent{}.TableName()).Create(&models.OutboxEvent{
+		TicketId: ticketId,
+		Type:     eventType,
+		Payload:  string(body),
+	}).Error
+}
+
+// writeAllocationChanged adds the AllocationChanged event of the ticket, and SoldOut when nothing is left
+func writeAllocationChanged(tx *gorm.DB, ticketId string, allocation int, delta int) error {
+	err := writeOutbox(tx, ticketId, events.TypeAllocationChanged, events.AllocationChanged{
+		TicketId:   ticketId,
+		Allocation: allocation,
+		Delta:      delta,
+	})
+	if err != nil || allocation > 0 || delta >= 0 {
+		return err
+	}
+	return writeOutbox(tx, ticketId, events.TypeSoldOut, events.SoldOut{TicketId: ticketId})
+}
+
+// returnAllocation gives quantity back to the allocation of the ticket and writes the AllocationChanged event
+func returnAllocation(tx *gorm.DB, ticketTable string, ticketId string, quantity int, changes map[string]interface{}) error {
+	changes["allocation"] = gorm.Expr("allocation + ?", quantity)
+	if err := tx.Table(ticketTable).Where("id = ?", ticketId).UpdateColumns(changes).Error; err != nil {
+		return err
+	}
+
+	var allocation int
+	if err := tx.Table(ticketTable).Select("allocation").Where("id = ?", ticketId).Scan(&allocation).Error; err != nil {
+		return err
+	}
+	return writeAllocationChanged(tx, ticketId, allocation, quantity)
+}
+
+func (r *outboxRepository) Relay(ctx context.Context, limit int, maxAttempts int, now time.Time, publish func(event models.OutboxEvent) error) (int, error) {
+	var published int
+	var publishErrs []error
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The rows are not skipped when locked, another relay waits here until the events it would publish are
+		// marked, which keeps the order across instances
+		var outbox []models.OutboxEvent
+		result := tx.Table(r.tableName).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("published_at IS NULL AND parked_at IS NULL").
+			Order("position").
+			Limit(limit).
+			Find(&outbox)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		positions := make([]int64, 0, len(outbox))
+		failedTickets := map[string]bool{}
+		for _, event := range outbox {
+			if failedTickets[event.TicketId] {
+				continue
+			}
+
+			if err := publish(event); err != nil {
+				failedTickets[event.TicketId] = true
+				publishErrs = append(publishErrs, fmt.Errorf("event %s of ticket %s: %w", event.Id, event.TicketId, err))
+				if err := r.fail(tx, event, maxAttempts, now, err); err != nil {
+					return err
+				}
+				continue
+			}
+			positions = append(positions, event.Position)
+		}
+
+		if len(positions) == 0 {
+			return nil
+		}
+
+		err := tx.Table(r.tableName).Where("position IN ?", positions).UpdateColumn("published_at", now).Error
+		if err != nil {
+			return err
+		}
+
+		published = len(positions)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return published, errors.Join(publishErrs...)
+}
+
+// fail counts the failed publish of the event and parks it once it reached maxAttempts
+func (r *outboxRepository) fail(tx *gorm.DB, event models.OutboxEvent, maxAttempts int, now time.Time, publishErr error) error {
+	changes := map[string]interface{}{
+		"attempts":   event.Attempts + 1,
+		"last_error": publishErr.Error(),
+	}
+	if event.Attempts+1 >= maxAttempts {
+		changes["parked_at"] = now
+		log.Error("Parking outbox event ", event.Id, " of ticket ", event.TicketId, " after ", event.Attempts+1, " attempts: ", publishErr)
+	}
+	return tx.Table(r.tableName).Where("position = ?", event.Position).UpdateColumns(changes).Error
+}
+
+func (r *outboxRepository) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.Table(r.tableName).WithContext(ctx).Where("published_at < ?", before).Delete(&models.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"ticket-purchase/internal/db/models"
+	"ticket-purchase/internal/events"
+	"time"
+)
+
+func TestOutboxRepository_Relay(t *testing.T) {
+	db := setupPostgresTest(t)
+	ctx := context.Background()
+	user := createTestUser(t, db)
+	repo := NewOutboxRepository(db)
+
+	ticket, err := NewTicketRepository(db).Create(ctx, &models.Ticket{
+		Name:       "Outbox Ticket",
+		Allocation: 2,
+		CreatedBy:  user.Id,
+		UpdatedBy:  user.Id,
+	})
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	t.Cleanup(func() {
+		db.Table(models.OutboxEvent{}.TableName()).Where("ticket_id = ?", ticket.Id).Delete(&models.OutboxEvent{})
+		db.Table(models.Purchase{}.TableName()).Where("ticket_id = ?", ticket.Id).Delete(&models.Purchase{})
+		db.Table(models.Ticket{}.TableName()).Where("id = ?", ticket.Id).Delete(&models.Ticket{})
+	})
+
+	purchaseRepo := NewPurchaseRepository(db)
+	purchase := models.Purchase{
+		TicketId:  ticket.Id,
+		UserId:    user.Id,
+		Quantity:  2,
+		CreatedBy: user.Id,
+		UpdatedBy: user.Id,
+	}
+	if err := purchaseRepo.CreateWithAllocation(ctx, &purchase); err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	if err := purchaseRepo.Refund(ctx, &purchase, 1); err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	other, err := NewTicketRepository(db).Create(ctx, &models.Ticket{
+		Name:       "Other Outbox Ticket",
+		Allocation: 1,
+		CreatedBy:  user.Id,
+		UpdatedBy:  user.Id,
+	})
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	t.Cleanup(func() {
+		db.Table(models.OutboxEvent{}.TableName()).Where("ticket_id = ?", other.Id).Delete(&models.OutboxEvent{})
+		db.Table(models.Ticket{}.TableName()).Where("id = ?", other.Id).Delete(&models.Ticket{})
+	})
+
+	// A failing publish skips the later events of its ticket, the events of other tickets are published
+	var published []models.OutboxEvent
+	otherPublished := 0
+	failure := errors.New("bus is down")
+	failSoldOut := func(event models.OutboxEvent) error {
+		if event.TicketId == ticket.Id && event.Type == events.TypeSoldOut {
+			return failure
+		}
+		if event.TicketId == ticket.Id {
+			published = append(published, event)
+		}
+		if event.TicketId == other.Id {
+			otherPublished++
+		}
+		return nil
+	}
+	_, err = repo.Relay(ctx, 100, 2, time.Now(), failSoldOut)
+	assert.ErrorIs(t, err, failure)
+	assert.Len(t, published, 2)
+	assert.Equal(t, 1, otherPublished)
+
+	// The second failure parks the event, the events after it are published in the next pass
+	_, err = repo.Relay(ctx, 100, 2, time.Now(), failSoldOut)
+	assert.ErrorIs(t, err, failure)
+	assert.Len(t, published, 2)
+
+	_, err = repo.Relay(ctx, 100, 2, time.Now(), func(event models.OutboxEvent) error {
+		if event.TicketId == ticket.Id {
+			published = append(published, event)
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+
+	types := make([]string, 0, len(published))
+	for _, event := range published {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []string{
+		events.TypeTicketCreated,
+		events.TypeAllocationChanged,
+		events.TypeTicketPurchased,
+		events.TypeAllocationChanged,
+	}, types)
+
+	var returned events.AllocationChanged
+	assert.NoError(t, json.Unmarshal([]byte(published[3].Payload), &returned))
+	assert.Equal(t, events.AllocationChanged{TicketId: ticket.Id, Allocation: 1, Delta: 1}, returned)
+
+	var parked models.OutboxEvent
+	assert.NoError(t, db.Table(models.OutboxEvent{}.TableName()).Where("ticket_id = ? AND type = ?", ticket.Id, events.TypeSoldOut).First(&parked).Error)
+	assert.NotNil(t, parked.ParkedAt)
+	assert.Nil(t, parked.PublishedAt)
+	assert.Equal(t, 2, parked.Attempts)
+	assert.Contains(t, parked.LastError, failure.Error())
+
+	// Published and parked events are not relayed again
+	_, err = repo.Relay(ctx, 100, 2, time.Now(), func(event models.OutboxEvent) error {
+		assert.NotEqual(t, ticket.Id, event.TicketId)
+		return nil
+	})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"strconv"
+	"strings"
+	"ticket-purchase/pkg/pagination"
+	"time"
+)
+
+// Sort orders a listing by a whitelisted column
+type Sort struct {
+	Column     string
+	Descending bool
+}
+
+// applyKeyset orders the query by the sort column and id, and continues after the cursor when one is given
+func applyKeyset(query *gorm.DB, sort Sort, cursor *pagination.Cursor, limit int) (*gorm.DB, error) {
+	direction, operator := "ASC", ">"
+	if sort.Descending {
+		direction, operator = "DESC", "<"
+	}
+
+	if cursor != nil {
+		value, err := parseSortValue(sort.Column, cursor.Value)
+		if err != nil {
+			return nil, pagination.ErrInvalidCursor
+		}
+
+		condition := fmt.Sprintf("((%s %s ?) OR (%s = ? AND id %s ?))", sort.Column, operator, sort.Column, operator)
+		query = query.Where(condition, value, value, cursor.Id)
+	}
+
+	order := fmt.Sprintf("%s %s, id %s", sort.Column, direction, direction)
+	return query.Order(order).Limit(limit), nil
+}
+
+// parseSortValue converts a cursor value back to the type of its sort column
+func parseSortValue(column string, value string) (interface{}, error) {
+	switch column {
+	case "created_at", "updated_at":
+		return time.Parse(time.RFC3339Nano, value)
+	case "name":
+		return value, nil
+	default:
+		return strconv.Atoi(value)
+	}
+}
+
+func formatTime(value time.Time) string {
+	return value.UTC().Format(time.RFC3339Nano)
+}
+
+// escapeLike escapes the wildcard characters of a LIKE pattern
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
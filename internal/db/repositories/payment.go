@@ -0,0 +1,163 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ticket-purchase/internal/db/models"
+)
+
+// ErrPaymentStatusChanged is returned when a payment moved to another status since it was read
+var ErrPaymentStatusChanged = errors.New("payment status changed")
+
+//go:generate mockgen -destination=../../mocks/repositories/payment_repository_mock.go -package=repositories ticket-purchase/internal/db/repositories PaymentRepository
+type PaymentRepository interface {
+	Create(ctx context.Context, payment *models.Payment) error
+	FindById(ctx context.Context, id string) (*models.Payment, error)
+	// FindByPurchaseId returns the latest payment of a purchase
+	FindByPurchaseId(ctx context.Context, purchaseId string) (*models.Payment, error)
+	// Transition saves the status and the provider fields of the payment if it still has the from status
+	Transition(ctx context.Context, payment *models.Payment, from string) error
+	// AddRefund adds amount to the refunded amount of a captured payment. The payment becomes refunded once the whole
+	// amount is refunded. Concurrent refunds are added up atomically.
+	AddRefund(ctx context.Context, payment *models.Payment, amount int64) error
+	// ReleaseRefund takes back an amount added by AddRefund that the provider did not refund
+	ReleaseRefund(ctx context.Context, payment *models.Payment, amount int64) error
+	// SyncRefund raises the refunded amount of a captured payment to the total refunded the provider reported.
+	// Lower totals, e.g. of callbacks delivered out of order, are ignored.
+	SyncRefund(ctx context.Context, payment *models.Payment, refundedAmount int64) error
+}
+
+type paymentRepository struct {
+	db        *gorm.DB
+	tableName string
+}
+
+func NewPaymentRepository(db *gorm.DB) PaymentRepository {
+	var paymentModel models.Payment
+	return &paymentRepository{
+		db:        db,
+		tableName: paymentModel.TableName(),
+	}
+}
+
+func (r *paymentRepository) Create(ctx context.Context, payment *models.Payment) error {
+	return r.db.Table(r.tableName).WithContext(ctx).Create(payment).Error
+}
+
+func (r *paymentRepository) FindById(ctx context.Context, id string) (*models.Payment, error) {
+	var payment models.Payment
+	result := r.db.Table(r.tableName).WithContext(ctx).Where("id = ?", id).First(&payment)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &payment, nil
+}
+
+func (r *paymentRepository) FindByPurchaseId(ctx context.Context, purchaseId string) (*models.Payment, error) {
+	var payment models.Payment
+	result := r.db.Table(r.tableName).WithContext(ctx).
+		Where("purchase_id = ?", purchaseId).
+		Order("created_at DESC").
+		First(&payment)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &payment, nil
+}
+
+func (r *paymentRepository) Transition(ctx context.Context, payment *models.Payment, from string) error {
+	result := r.db.Table(r.tableName).WithContext(ctx).
+		Where("id = ? AND status = ?", payment.Id, from).
+		Updates(map[string]interface{}{
+			"status":           payment.Status,
+			"authorization_id": payment.AuthorizationId,
+			"refunded_amount":  payment.RefundedAmount,
+			"failure_reason":   payment.FailureReason,
+			"updated_at":       payment.UpdatedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrPaymentStatusChanged
+	}
+	return nil
+}
+
+func (r *paymentRepository) AddRefund(ctx context.Context, payment *models.Payment, amount int64) error {
+	var updated models.Payment
+	result := r.db.Table(r.tableName).WithContext(ctx).
+		Model(&updated).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND status = ? AND refunded_amount + ? <= amount", payment.Id, models.PaymentStatusCaptured, amount).
+		Updates(map[string]interface{}{
+			"refunded_amount": gorm.Expr("refunded_amount + ?", amount),
+			"status": gorm.Expr("CASE WHEN refunded_amount + ? = amount THEN ? ELSE status END",
+				amount, models.PaymentStatusRefunded),
+			"updated_at": payment.UpdatedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrPaymentStatusChanged
+	}
+
+	payment.RefundedAmount = updated.RefundedAmount
+	payment.Status = updated.Status
+	return nil
+}
+
+func (r *paymentRepository) ReleaseRefund(ctx context.Context, payment *models.Payment, amount int64) error {
+	var updated models.Payment
+	result := r.db.Table(r.tableName).WithContext(ctx).
+		Model(&updated).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND status IN ? AND refunded_amount >= ?", payment.Id,
+			[]string{models.PaymentStatusCaptured, models.PaymentStatusRefunded}, amount).
+		Updates(map[string]interface{}{
+			"refunded_amount": gorm.Expr("refunded_amount - ?", amount),
+			"status":          models.PaymentStatusCaptured,
+			"updated_at":      payment.UpdatedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrPaymentStatusChanged
+	}
+
+	payment.RefundedAmount = updated.RefundedAmount
+	payment.Status = updated.Status
+	return nil
+}
+
+func (r *paymentRepository) SyncRefund(ctx context.Context, payment *models.Payment, refundedAmount int64) error {
+	var updated models.Payment
+	result := r.db.Table(r.tableName).WithContext(ctx).
+		Model(&updated).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND status = ? AND refunded_amount < ? AND ? <= amount", payment.Id, models.PaymentStatusCaptured, refundedAmount, refundedAmount).
+		Updates(map[string]interface{}{
+			"refunded_amount": refundedAmount,
+			"status": gorm.Expr("CASE WHEN ? = amount THEN ? ELSE status END",
+				refundedAmount, models.PaymentStatusRefunded),
+			"updated_at": payment.UpdatedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrPaymentStatusChanged
+	}
+
+	payment.RefundedAmount = updated.RefundedAmount
+	payment.Status = updated.Status
+	return nil
+}
@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"ticket-purchase/internal/db/models"
+)
+
+func TestPaymentRepository_AddRefund_Concurrent(t *testing.T) {
+	db := setupPostgresTest(t)
+	repo := NewPaymentRepository(db)
+	ctx := context.Background()
+
+	payment := models.Payment{
+		PurchaseId: uuid.New().String(),
+		UserId:     uuid.New().String(),
+		Amount:     1000,
+		Currency:   "USD",
+		Status:     models.PaymentStatusCaptured,
+		Provider:   "fake",
+	}
+	if err := repo.Create(ctx, &payment); err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	t.Cleanup(func() { db.Delete(&models.Payment{}, "id = ?", payment.Id) })
+
+	// Ten refunds of 300 race for 1000, only three fit
+	var refunded atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			refund := payment
+			err := repo.AddRefund(ctx, &refund, 300)
+			if err == nil {
+				refunded.Add(1)
+				return
+			}
+			assert.ErrorIs(t, err, ErrPaymentStatusChanged)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(3), refunded.Load())
+
+	// The last 100 refunds the whole amount
+	assert.NoError(t, repo.AddRefund(ctx, &payment, 100))
+	assert.Equal(t, int64(1000), payment.RefundedAmount)
+	assert.Equal(t, models.PaymentStatusRefunded, payment.Status)
+
+	found, err := repo.FindByPurchaseId(ctx, payment.PurchaseId)
+	assert.NoError(t, err)
+	assert.Equal(t, models.PaymentStatusRefunded, found.Status)
+}
+
+func TestPaymentRepository_SyncRefund(t *testing.T) {
+	db := setupPostgresTest(t)
+	repo := NewPaymentRepository(db)
+	ctx := context.Background()
+
+	payment := models.Payment{
+		PurchaseId: uuid.New().String(),
+		UserId:     uuid.New().String(),
+		Amount:     1000,
+		Currency:   "USD",
+		Status:     models.PaymentStatusCaptured,
+		Provider:   "fake",
+	}
+	if err := repo.Create(ctx, &payment); err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	t.Cleanup(func() { db.Delete(&models.Payment{}, "id = ?", payment.Id) })
+
+	assert.NoError(t, repo.SyncRefund(ctx, &payment, 600))
+	assert.Equal(t, int64(600), payment.RefundedAmount)
+
+	// Totals delivered out of order never lower the refunded amount
+	stale := payment
+	assert.ErrorIs(t, repo.SyncRefund(ctx, &stale, 400), ErrPaymentStatusChanged)
+
+	assert.NoError(t, repo.SyncRefund(ctx, &payment, 1000))
+	assert.Equal(t, models.PaymentStatusRefunded, payment.Status)
+}
+
+func TestPaymentRepository_ReleaseRefund(t *testing.T) {
+	db := setupPostgresTest(t)
+	repo := NewPaymentRepository(db)
+	ctx := context.Background()
+
+	payment := models.Payment{
+		PurchaseId: uuid.New().String(),
+		UserId:     uuid.New().String(),
+		Amount:     1000,
+		Currency:   "USD",
+		Status:     models.PaymentStatusCaptured,
+		Provider:   "fake",
+	}
+	if err := repo.Create(ctx, &payment); err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	t.Cleanup(func() { db.Delete(&models.Payment{}, "id = ?", payment.Id) })
+
+	// A full refund the provider refused leaves the payment captured again
+	assert.NoError(t, repo.AddRefund(ctx, &payment, 1000))
+	assert.Equal(t, models.PaymentStatusRefunded, payment.Status)
+
+	assert.NoError(t, repo.ReleaseRefund(ctx, &payment, 1000))
+	assert.Equal(t, int64(0), payment.RefundedAmount)
+	assert.Equal(t, models.PaymentStatusCaptured, payment.Status)
+
+	assert.ErrorIs(t, repo.ReleaseRefund(ctx, &payment, 100), ErrPaymentStatusChanged)
+}
+
+func TestWebhookEventRepository_CreateIfNotExists(t *testing.T) {
+	db := setupPostgresTest(t)
+	repo := NewWebhookEventRepository(db)
+	ctx := context.Background()
+
+	id := "evt_" + uuid.New().String()
+	t.Cleanup(func() { _ = repo.Delete(ctx, id) })
+
+	created, err := repo.CreateIfNotExists(ctx, &models.WebhookEvent{Id: id, Provider: "fake", Type: "payment.captured"})
+	assert.NoError(t, err)
+	assert.True(t, created)
+
+	created, err = repo.CreateIfNotExists(ctx, &models.WebhookEvent{Id: id, Provider: "fake", Type: "payment.captured"})
+	assert.NoError(t, err)
+	assert.False(t, created)
+}
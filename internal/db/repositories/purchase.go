@@ -2,38 +2,245 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"gorm.io/gorm"
-	"sync"
+	"gorm.io/gorm/clause"
+	"strconv"
 	"ticket-purchase/internal/db/models"
+	"ticket-purchase/internal/events"
+	"ticket-purchase/pkg/pagination"
+	"time"
 )
 
+// ErrRefundExceedsQuantity is returned when a refund is larger than the quantity left on an active purchase
+var ErrRefundExceedsQuantity = errors.New("refund exceeds remaining purchase quantity")
+
+// PurchaseSortColumns are the columns purchases can be sorted by
+var PurchaseSortColumns = []string{"created_at", "quantity"}
+
+// PurchaseFilter narrows a purchase listing. Empty fields are ignored.
+type PurchaseFilter struct {
+	UserId      string
+	TicketId    string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Sort        Sort
+	Cursor      *pagination.Cursor
+	Limit       int
+}
+
 //go:generate mockgen -destination=../../mocks/repositories/purchase_repository_mock.go -package=repositories ticket-purchase/internal/db/repositories PurchaseRepository
 type PurchaseRepository interface {
+	FindById(ctx context.Context, id string) (*models.Purchase, error)
+	FindAll(ctx context.Context, filter PurchaseFilter) ([]models.Purchase, error)
 	Create(ctx context.Context, purchase *models.Purchase) error
+	CreateWithAllocation(ctx context.Context, purchase *models.Purchase) error
+	Refund(ctx context.Context, purchase *models.Purchase, quantity int) error
+	// ReserveRefund takes quantity off an active purchase without returning it to the ticket allocation yet.
+	// Concurrent reservations are checked atomically, ErrRefundExceedsQuantity is returned for the ones that do not fit.
+	// The refunded quantity and the active flag of the purchase are set to the ones written.
+	ReserveRefund(ctx context.Context, purchase *models.Purchase, quantity int) error
+	// CancelRefund puts back a reserved quantity whose money could not be returned
+	CancelRefund(ctx context.Context, purchase *models.Purchase, quantity int) error
+	// CompleteRefund returns a reserved quantity to the ticket allocation once its money was returned. The purchase is
+	// cancelled when the reservation deactivated it.
+	CompleteRefund(ctx context.Context, purchase *models.Purchase, quantity int) error
 }
 
 type purchaseRepository struct {
-	db        *gorm.DB
-	dbMutex   sync.Mutex
-	tableName string
+	db          *gorm.DB
+	tableName   string
+	ticketTable string
 }
 
 func NewPurchaseRepository(db *gorm.DB) PurchaseRepository {
 	var purchaseModel models.Purchase
+	var ticketModel models.Ticket
 	return &purchaseRepository{
-		db:        db,
-		tableName: purchaseModel.TableName(),
+		db:          db,
+		tableName:   purchaseModel.TableName(),
+		ticketTable: ticketModel.TableName(),
+	}
+}
+
+func (r *purchaseRepository) FindById(ctx context.Context, id string) (*models.Purchase, error) {
+	var purchase models.Purchase
+	result := r.db.WithContext(ctx).Preload("Ticket").Where("id = ? AND status <> ?", id, models.PurchaseStatusCart).First(&purchase)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &purchase, nil
+}
+
+func (r *purchaseRepository) FindAll(ctx context.Context, filter PurchaseFilter) ([]models.Purchase, error) {
+	// Lines of carts are not purchased yet
+	query := r.db.WithContext(ctx).Preload("Ticket").Where("status <> ?", models.PurchaseStatusCart)
+	if filter.UserId != "" {
+		query = query.Where("user_id = ?", filter.UserId)
+	}
+	if filter.TicketId != "" {
+		query = query.Where("ticket_id = ?", filter.TicketId)
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedFrom)
 	}
+	if filter.CreatedTo != nil {
+		query = query.Where("created_at < ?", *filter.CreatedTo)
+	}
+
+	query, err := applyKeyset(query, filter.Sort, filter.Cursor, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var purchases []models.Purchase
+	result := query.Find(&purchases)
+	return purchases, result.Error
+}
+
+// PurchaseCursor returns the cursor pointing at the given purchase for the sort column
+func PurchaseCursor(purchase *models.Purchase, sort Sort) pagination.Cursor {
+	var value string
+	switch sort.Column {
+	case "quantity":
+		value = strconv.Itoa(purchase.Quantity)
+	default:
+		value = formatTime(purchase.CreatedAt)
+	}
+	return pagination.Cursor{Value: value, Id: purchase.Id}
 }
 
 func (r *purchaseRepository) Create(ctx context.Context, purchase *models.Purchase) error {
-	tx := r.db.Begin()
-	defer tx.Commit()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Table(r.tableName).Create(purchase).Error
+	})
+}
+
+// CreateWithAllocation decrements the ticket allocation and inserts the purchase in a single transaction.
+// Purchases over the per user limit of the ticket are rolled back with ErrMaxPerUserExceeded.
+func (r *purchaseRepository) CreateWithAllocation(ctx context.Context, purchase *models.Purchase) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		allocationLeft, err := reserveAllocation(tx, r.ticketTable, purchase.TicketId, purchase.Quantity, purchase.UpdatedBy, purchase.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		if err := checkUserLimit(tx, purchase.TicketId, purchase.UserId, purchase.Quantity); err != nil {
+			return err
+		}
 
-	result := tx.Table(r.tableName).WithContext(ctx).Create(purchase)
+		if err := tx.Table(r.tableName).Create(purchase).Error; err != nil {
+			return err
+		}
+
+		if err := writeTicketPurchased(tx, purchase); err != nil {
+			return err
+		}
+
+		purchase.AllocationLeft = &allocationLeft
+		return nil
+	})
+}
+
+// Refund returns quantity of an active purchase back to the ticket allocation in a single transaction.
+// The purchase is deactivated once its whole quantity is refunded. UpdatedBy, UpdatedAt and CancelReason
+// are taken from the given purchase.
+func (r *purchaseRepository) Refund(ctx context.Context, purchase *models.Purchase, quantity int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := r.reserveRefund(tx, purchase, quantity); err != nil {
+			return err
+		}
+		return r.completeRefund(tx, purchase, quantity)
+	})
+}
+
+func (r *purchaseRepository) ReserveRefund(ctx context.Context, purchase *models.Purchase, quantity int) error {
+	return r.reserveRefund(r.db.WithContext(ctx), purchase, quantity)
+}
+
+func (r *purchaseRepository) CancelRefund(ctx context.Context, purchase *models.Purchase, quantity int) error {
+	result := r.db.Table(r.tableName).WithContext(ctx).
+		Where("id = ? AND refunded_quantity >= ?", purchase.Id, quantity).
+		UpdateColumns(map[string]interface{}{
+			"refunded_quantity": gorm.Expr("refunded_quantity - ?", quantity),
+			"is_active":         true,
+			"updated_at":        purchase.UpdatedAt,
+		})
 	if result.Error != nil {
-		tx.Rollback()
 		return result.Error
 	}
+
+	if result.RowsAffected == 0 {
+		return ErrRefundExceedsQuantity
+	}
+
+	purchase.RefundedQuantity -= quantity
+	purchase.IsActive = true
 	return nil
 }
+
+func (r *purchaseRepository) CompleteRefund(ctx context.Context, purchase *models.Purchase, quantity int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return r.completeRefund(tx, purchase, quantity)
+	})
+}
+
+// reserveRefund takes quantity off the purchase if it is still active and has that much left. The purchase is
+// deactivated once its whole quantity is refunded. The refunded quantity and the active flag of the purchase are set
+// to the ones written.
+func (r *purchaseRepository) reserveRefund(tx *gorm.DB, purchase *models.Purchase, quantity int) error {
+	var updated models.Purchase
+	result := tx.Table(r.tableName).
+		Model(&updated).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND is_active AND quantity - refunded_quantity >= ?", purchase.Id, quantity).
+		UpdateColumns(map[string]interface{}{
+			"refunded_quantity": gorm.Expr("refunded_quantity + ?", quantity),
+			"is_active":         gorm.Expr("quantity - refunded_quantity > ?", quantity),
+			"cancel_reason":     purchase.CancelReason,
+			"updated_by":        purchase.UpdatedBy,
+			"updated_at":        purchase.UpdatedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrRefundExceedsQuantity
+	}
+
+	purchase.RefundedQuantity = updated.RefundedQuantity
+	purchase.IsActive = updated.IsActive
+	return nil
+}
+
+// completeRefund returns the quantity to the allocation. Only the refund that deactivated the purchase cancels it, so
+// the purchase is cancelled once.
+func (r *purchaseRepository) completeRefund(tx *gorm.DB, purchase *models.Purchase, quantity int) error {
+	err := returnAllocation(tx, r.ticketTable, purchase.TicketId, quantity, map[string]interface{}{
+		"updated_by": purchase.UpdatedBy,
+		"updated_at": purchase.UpdatedAt,
+	})
+	if err != nil || purchase.IsActive {
+		return err
+	}
+
+	return writeOutbox(tx, purchase.TicketId, events.TypePurchaseCancelled, events.PurchaseCancelled{
+		TicketId:   purchase.TicketId,
+		PurchaseId: purchase.Id,
+		UserId:     purchase.UserId,
+		Reason:     purchase.CancelReason,
+	})
+}
+
+// writeTicketPurchased adds the TicketPurchased event of a purchase inserted in the transaction
+func writeTicketPurchased(tx *gorm.DB, purchase *models.Purchase) error {
+	return writeOutbox(tx, purchase.TicketId, events.TypeTicketPurchased, events.TicketPurchased{
+		TicketId:   purchase.TicketId,
+		PurchaseId: purchase.Id,
+		UserId:     purchase.UserId,
+		Quantity:   purchase.Quantity,
+		UnitPrice:  purchase.UnitPrice,
+		Currency:   purchase.Currency,
+	})
+}
@@ -0,0 +1,277 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"ticket-purchase/internal/db/models"
+	"ticket-purchase/internal/events"
+	"time"
+)
+
+// setupPostgresTest connects to the database given in TEST_DATABASE_DSN and skips the test when it is not set
+func setupPostgresTest(t *testing.T) *gorm.DB {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set, skipping database test")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	sqlDB.SetMaxOpenConns(20)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	if err := db.AutoMigrate(models.User{}, models.Ticket{}, models.Purchase{}, models.Order{}, models.Hold{}, models.Payment{}, models.WebhookEvent{}, models.WebhookSubscription{}, models.WebhookDelivery{}, models.OutboxEvent{}, models.IdempotencyKey{}); err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	return db
+}
+
+// createTestUser inserts a user for the foreign key of purchases and removes it after the test
+func createTestUser(t *testing.T, db *gorm.DB) *models.User {
+	user := models.User{
+		Email:        uuid.New().String() + "@example.com",
+		PasswordHash: "hash",
+	}
+	if err := NewUserRepository(db).Create(context.Background(), &user); err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	t.Cleanup(func() {
+		db.Table(models.User{}.TableName()).Where("id = ?", user.Id).Delete(&models.User{})
+	})
+	return &user
+}
+
+func TestPurchaseRepository_CreateWithAllocation_Concurrent(t *testing.T) {
+	db := setupPostgresTest(t)
+	ctx := context.Background()
+
+	const allocation = 500
+	const buyers = 3000
+
+	user := createTestUser(t, db)
+
+	ticket, err := NewTicketRepository(db).Create(ctx, &models.Ticket{
+		Name:       "Concurrency Ticket",
+		Allocation: allocation,
+	})
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	t.Cleanup(func() {
+		db.Table(models.OutboxEvent{}.TableName()).Where("ticket_id = ?", ticket.Id).Delete(&models.OutboxEvent{})
+		db.Table(models.Purchase{}.TableName()).Where("ticket_id = ?", ticket.Id).Delete(&models.Purchase{})
+		db.Table(models.Ticket{}.TableName()).Where("id = ?", ticket.Id).Delete(&models.Ticket{})
+	})
+
+	repo := NewPurchaseRepository(db)
+
+	var succeeded, rejected, failed int64
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			err := repo.CreateWithAllocation(ctx, &models.Purchase{
+				TicketId:  ticket.Id,
+				UserId:    user.Id,
+				Quantity:  1,
+				CreatedBy: user.Id,
+				UpdatedBy: user.Id,
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			})
+			switch {
+			case err == nil:
+				atomic.AddInt64(&succeeded, 1)
+			case errors.Is(err, ErrInsufficientAllocation):
+				atomic.AddInt64(&rejected, 1)
+			default:
+				atomic.AddInt64(&failed, 1)
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	assert.Equal(t, int64(0), failed)
+	assert.Equal(t, int64(allocation), succeeded)
+	assert.Equal(t, int64(buyers-allocation), rejected)
+
+	var remaining models.Ticket
+	if err := db.Table(models.Ticket{}.TableName()).Where("id = ?", ticket.Id).First(&remaining).Error; err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	assert.Equal(t, 0, remaining.Allocation)
+
+	var purchases int64
+	db.Table(models.Purchase{}.TableName()).Where("ticket_id = ?", ticket.Id).Count(&purchases)
+	assert.Equal(t, int64(allocation), purchases)
+}
+
+func TestPurchaseRepository_CreateWithAllocation_Not_Found(t *testing.T) {
+	db := setupPostgresTest(t)
+	user := createTestUser(t, db)
+
+	err := NewPurchaseRepository(db).CreateWithAllocation(context.Background(), &models.Purchase{
+		TicketId:  "00000000-0000-0000-0000-000000000000",
+		UserId:    user.Id,
+		Quantity:  1,
+		CreatedBy: user.Id,
+		UpdatedBy: user.Id,
+	})
+
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestPurchaseRepository_CreateWithAllocation_Max_Per_User(t *testing.T) {
+	db := setupPostgresTest(t)
+	ctx := context.Background()
+
+	const maxPerUser = 3
+	const orders = 50
+
+	user := createTestUser(t, db)
+
+	ticket, err := NewTicketRepository(db).Create(ctx, &models.Ticket{
+		Name:       "Limited Ticket",
+		Allocation: 100,
+		MaxPerUser: maxPerUser,
+	})
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	t.Cleanup(func() {
+		db.Table(models.OutboxEvent{}.TableName()).Where("ticket_id = ?", ticket.Id).Delete(&models.OutboxEvent{})
+		db.Table(models.Purchase{}.TableName()).Where("ticket_id = ?", ticket.Id).Delete(&models.Purchase{})
+		db.Table(models.Ticket{}.TableName()).Where("id = ?", ticket.Id).Delete(&models.Ticket{})
+	})
+
+	repo := NewPurchaseRepository(db)
+
+	var succeeded, rejected int64
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < orders; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			err := repo.CreateWithAllocation(ctx, &models.Purchase{
+				TicketId:  ticket.Id,
+				UserId:    user.Id,
+				Quantity:  1,
+				CreatedBy: user.Id,
+				UpdatedBy: user.Id,
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			})
+			switch {
+			case err == nil:
+				atomic.AddInt64(&succeeded, 1)
+			case errors.Is(err, ErrMaxPerUserExceeded):
+				atomic.AddInt64(&rejected, 1)
+			default:
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	assert.Equal(t, int64(maxPerUser), succeeded)
+	assert.Equal(t, int64(orders-maxPerUser), rejected)
+
+	// Rejected purchases give their allocation back
+	var remaining models.Ticket
+	if err := db.Table(models.Ticket{}.TableName()).Where("id = ?", ticket.Id).First(&remaining).Error; err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	assert.Equal(t, 100-maxPerUser, remaining.Allocation)
+}
+
+func TestPurchaseRepository_ReserveRefund_Concurrent(t *testing.T) {
+	db := setupPostgresTest(t)
+	ctx := context.Background()
+
+	user := createTestUser(t, db)
+	ticket, err := NewTicketRepository(db).Create(ctx, &models.Ticket{Name: "Refund Ticket", Allocation: 10})
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	t.Cleanup(func() {
+		db.Table(models.OutboxEvent{}.TableName()).Where("ticket_id = ?", ticket.Id).Delete(&models.OutboxEvent{})
+		db.Table(models.Purchase{}.TableName()).Where("ticket_id = ?", ticket.Id).Delete(&models.Purchase{})
+		db.Table(models.Ticket{}.TableName()).Where("id = ?", ticket.Id).Delete(&models.Ticket{})
+	})
+
+	repo := NewPurchaseRepository(db)
+	purchase := models.Purchase{TicketId: ticket.Id, UserId: user.Id, Quantity: 4, CreatedBy: user.Id, UpdatedBy: user.Id}
+	if err := repo.CreateWithAllocation(ctx, &purchase); err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	// Ten cancellations of the whole purchase race, only one of them may reach the provider
+	var reserved atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			attempt := purchase
+			err := repo.ReserveRefund(ctx, &attempt, 4)
+			if err == nil {
+				reserved.Add(1)
+				return
+			}
+			assert.ErrorIs(t, err, ErrRefundExceedsQuantity)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), reserved.Load())
+
+	// The allocation is only returned once the refund completes
+	found, err := repo.FindById(ctx, purchase.Id)
+	assert.NoError(t, err)
+	assert.False(t, found.IsActive)
+	assert.Equal(t, 6, found.Ticket.Allocation)
+
+	assert.NoError(t, repo.CancelRefund(ctx, &purchase, 4))
+	found, _ = repo.FindById(ctx, purchase.Id)
+	assert.True(t, found.IsActive)
+	assert.Equal(t, 0, found.RefundedQuantity)
+
+	assert.NoError(t, repo.ReserveRefund(ctx, &purchase, 4))
+	assert.False(t, purchase.IsActive)
+	assert.NoError(t, repo.CompleteRefund(ctx, &purchase, 4))
+	found, _ = repo.FindById(ctx, purchase.Id)
+	assert.Equal(t, 10, found.Ticket.Allocation)
+
+	// Only the completed refund cancelled the purchase
+	var cancelled int64
+	db.Table(models.OutboxEvent{}.TableName()).
+		Where("ticket_id = ? AND type = ?", ticket.Id, events.TypePurchaseCancelled).
+		Count(&cancelled)
+	assert.Equal(t, int64(1), cancelled)
+}
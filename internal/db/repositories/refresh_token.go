@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ticket-purchase/internal/db/models"
+)
+
+// ErrRefreshTokenInvalid is returned when a refresh token is unknown, expired or already used
+var ErrRefreshTokenInvalid = errors.New("refresh token is invalid")
+
+//go:generate mockgen -destination=../../mocks/repositories/refresh_token_repository_mock.go -package=repositories ticket-purchase/internal/db/repositories RefreshTokenRepository
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *models.RefreshToken) error
+	Rotate(ctx context.Context, tokenHash string, next *models.RefreshToken) (*models.RefreshToken, error)
+}
+
+type refreshTokenRepository struct {
+	db        *gorm.DB
+	tableName string
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	var refreshTokenModel models.RefreshToken
+	return &refreshTokenRepository{db: db, tableName: refreshTokenModel.TableName()}
+}
+
+func (r *refreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	return r.db.Table(r.tableName).WithContext(ctx).Create(token).Error
+}
+
+// Rotate revokes the refresh token with the given hash and inserts the next token of the same user in a single
+// transaction. The row is locked, so a token that is used twice at the same time is only accepted once.
+func (r *refreshTokenRepository) Rotate(ctx context.Context, tokenHash string, next *models.RefreshToken) (*models.RefreshToken, error) {
+	var current models.RefreshToken
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Table(r.tableName).Clauses(clause.Locking{Strength: "UPDATE"}).Where("token_hash = ?", tokenHash).First(&current)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return ErrRefreshTokenInvalid
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if current.RevokedAt != nil || !current.ExpiresAt.After(next.CreatedAt) {
+			return ErrRefreshTokenInvalid
+		}
+
+		revokedAt := next.CreatedAt
+		if err := tx.Table(r.tableName).Where("id = ?", current.Id).Update("revoked_at", revokedAt).Error; err != nil {
+			return err
+		}
+		current.RevokedAt = &revokedAt
+
+		next.UserId = current.UserId
+		return tx.Table(r.tableName).Create(next).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &current, nil
+}
@@ -2,36 +2,126 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strconv"
 	"sync"
 	"ticket-purchase/internal/db/models"
+	"ticket-purchase/internal/events"
+	"ticket-purchase/pkg/pagination"
+	"time"
 )
 
+// ErrAllocationBelowSold is returned when a ticket allocation would be lowered below the quantity already sold or held
+var ErrAllocationBelowSold = errors.New("allocation is below the sold quantity")
+
+// TicketSortColumns are the columns tickets can be sorted by
+var TicketSortColumns = []string{"created_at", "name", "allocation"}
+
+// TicketFilter narrows a ticket listing. Empty fields are ignored.
+type TicketFilter struct {
+	Name          string
+	MinAllocation *int
+	MaxAllocation *int
+	IsActive      *bool
+	CreatedFrom   *time.Time
+	CreatedTo     *time.Time
+	// SaleStatus lists the tickets that are upcoming, on sale or ended at Now
+	SaleStatus string
+	Now        time.Time
+	Sort       Sort
+	Cursor     *pagination.Cursor
+	Limit      int
+}
+
 //go:generate mockgen -destination=../../mocks/repositories/ticket_repository_mock.go -package=repositories ticket-purchase/internal/db/repositories TicketRepository
 type TicketRepository interface {
-	FindAll(ctx context.Context) ([]models.Ticket, error)
+	FindAll(ctx context.Context, filter TicketFilter) ([]models.Ticket, error)
 	FindById(ctx context.Context, id string) (*models.Ticket, error)
+	FindAllocation(ctx context.Context, id string) (int, error)
+	// FindTotalAllocation returns the allocation a ticket was given: what is left plus what is sold and held
+	FindTotalAllocation(ctx context.Context, id string) (int, error)
 	Create(ctx context.Context, ticket *models.Ticket) (*models.Ticket, error)
 	Update(ctx context.Context, ticket *models.Ticket) (*models.Ticket, error)
+	UpdateDetails(ctx context.Context, ticket *models.Ticket, totalAllocation *int) (*models.Ticket, error)
+	SetActive(ctx context.Context, ticket *models.Ticket) error
 }
 
 type ticketRepository struct {
-	db        *gorm.DB
-	dbMutex   sync.Mutex
-	tableName string
+	db            *gorm.DB
+	dbMutex       sync.Mutex
+	tableName     string
+	purchaseTable string
+	holdTable     string
 }
 
 func NewTicketRepository(db *gorm.DB) TicketRepository {
 	var ticketModel models.Ticket
-	return &ticketRepository{db: db, tableName: ticketModel.TableName()}
+	var purchaseModel models.Purchase
+	var holdModel models.Hold
+	return &ticketRepository{
+		db:            db,
+		tableName:     ticketModel.TableName(),
+		purchaseTable: purchaseModel.TableName(),
+		holdTable:     holdModel.TableName(),
+	}
 }
 
-func (r *ticketRepository) FindAll(ctx context.Context) ([]models.Ticket, error) {
+func (r *ticketRepository) FindAll(ctx context.Context, filter TicketFilter) ([]models.Ticket, error) {
+	query := r.db.Table(r.tableName).WithContext(ctx)
+	if filter.Name != "" {
+		query = query.Where("name ILIKE ?", "%"+escapeLike(filter.Name)+"%")
+	}
+	if filter.MinAllocation != nil {
+		query = query.Where("allocation >= ?", *filter.MinAllocation)
+	}
+	if filter.MaxAllocation != nil {
+		query = query.Where("allocation <= ?", *filter.MaxAllocation)
+	}
+	if filter.IsActive != nil {
+		query = query.Where("is_active = ?", *filter.IsActive)
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("created_at < ?", *filter.CreatedTo)
+	}
+
+	switch filter.SaleStatus {
+	case models.SaleStatusUpcoming:
+		query = query.Where("sale_starts_at > ?", filter.Now)
+	case models.SaleStatusOnSale:
+		query = query.Where("(sale_starts_at IS NULL OR sale_starts_at <= ?) AND (sale_ends_at IS NULL OR sale_ends_at > ?)", filter.Now, filter.Now)
+	case models.SaleStatusEnded:
+		query = query.Where("sale_ends_at <= ?", filter.Now)
+	}
+
+	query, err := applyKeyset(query, filter.Sort, filter.Cursor, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+
 	var tickets []models.Ticket
-	result := r.db.Table(r.tableName).WithContext(ctx).Find(&tickets)
+	result := query.Find(&tickets)
 	return tickets, result.Error
 }
 
+// TicketCursor returns the cursor pointing at the given ticket for the sort column
+func TicketCursor(ticket *models.Ticket, sort Sort) pagination.Cursor {
+	var value string
+	switch sort.Column {
+	case "name":
+		value = ticket.Name
+	case "allocation":
+		value = strconv.Itoa(ticket.Allocation)
+	default:
+		value = formatTime(ticket.CreatedAt)
+	}
+	return pagination.Cursor{Value: value, Id: ticket.Id}
+}
+
 func (r *ticketRepository) FindById(ctx context.Context, id string) (*models.Ticket, error) {
 	var ticket models.Ticket
 	result := r.db.Table(r.tableName).WithContext(ctx).Where("id = ?", id).First(&ticket)
@@ -41,14 +131,85 @@ func (r *ticketRepository) FindById(ctx context.Context, id string) (*models.Tic
 	return &ticket, nil
 }
 
+// FindAllocation returns the allocation left of a ticket without loading the rest of it
+func (r *ticketRepository) FindAllocation(ctx context.Context, id string) (int, error) {
+	var allocations []int
+	result := r.db.Table(r.tableName).WithContext(ctx).Where("id = ?", id).Pluck("allocation", &allocations)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if len(allocations) == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+	return allocations[0], nil
+}
+
+// FindTotalAllocation reads the allocation under a share lock of the ticket row, purchases and holds change it in the
+// same transaction as their own rows, so the sums below match it
+func (r *ticketRepository) FindTotalAllocation(ctx context.Context, id string) (int, error) {
+	var total int
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var allocations []int
+		result := tx.Table(r.tableName).Clauses(clause.Locking{Strength: "SHARE"}).Where("id = ?", id).Pluck("allocation", &allocations)
+		if result.Error != nil {
+			return result.Error
+		}
+		if len(allocations) == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		sold, held, err := r.soldAndHeld(tx, id)
+		if err != nil {
+			return err
+		}
+		total = allocations[0] + sold + held
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// soldAndHeld sums the quantity of the active purchases and holds of a ticket
+func (r *ticketRepository) soldAndHeld(tx *gorm.DB, id string) (int, int, error) {
+	var sold, held int
+	err := tx.Table(r.purchaseTable).
+		Where("ticket_id = ? AND is_active", id).
+		Select("COALESCE(SUM(quantity - refunded_quantity), 0)").
+		Scan(&sold).Error
+	if err != nil {
+		return 0, 0, err
+	}
+
+	err = tx.Table(r.holdTable).
+		Where("ticket_id = ? AND status = ?", id, models.HoldStatusActive).
+		Select("COALESCE(SUM(quantity), 0)").
+		Scan(&held).Error
+	if err != nil {
+		return 0, 0, err
+	}
+	return sold, held, nil
+}
+
+// Create inserts the ticket and writes its TicketCreated event in a single transaction
 func (r *ticketRepository) Create(ctx context.Context, ticket *models.Ticket) (*models.Ticket, error) {
-	tx := r.db.Begin()
-	defer tx.Commit()
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(r.tableName).Create(ticket).Scan(&ticket).Error; err != nil {
+			return err
+		}
 
-	result := r.db.Table(r.tableName).WithContext(ctx).Create(ticket).Scan(&ticket)
-	if result.Error != nil {
-		tx.Rollback()
-		return nil, result.Error
+		return writeOutbox(tx, ticket.Id, events.TypeTicketCreated, events.TicketCreated{
+			TicketId:   ticket.Id,
+			Name:       ticket.Name,
+			OwnerId:    ticket.OwnerId,
+			Allocation: ticket.Allocation,
+			Price:      ticket.Price,
+			Currency:   ticket.Currency,
+		})
+	})
+	if err != nil {
+		return nil, err
 	}
 	return ticket, nil
 }
@@ -68,3 +229,87 @@ func (r *ticketRepository) Update(ctx context.Context, ticket *models.Ticket) (*
 	}
 	return ticket, nil
 }
+
+// UpdateDetails updates the name and description of a ticket. When totalAllocation is given, the remaining
+// allocation is recalculated from it under a row lock, so it can never drop below what is already sold or held.
+func (r *ticketRepository) UpdateDetails(ctx context.Context, ticket *models.Ticket, totalAllocation *int) (*models.Ticket, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current models.Ticket
+		result := tx.Table(r.tableName).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", ticket.Id).First(&current)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		changes := map[string]interface{}{
+			"name":            ticket.Name,
+			"description":     ticket.Description,
+			"waiting_room":    ticket.WaitingRoom,
+			"event_starts_at": ticket.EventStartsAt,
+			"sale_starts_at":  ticket.SaleStartsAt,
+			"sale_ends_at":    ticket.SaleEndsAt,
+			"max_per_order":   ticket.MaxPerOrder,
+			"max_per_user":    ticket.MaxPerUser,
+			"price":           ticket.Price,
+			"currency":        ticket.Currency,
+			"updated_by":      ticket.UpdatedBy,
+			"updated_at":      ticket.UpdatedAt,
+		}
+
+		ticket.Allocation = current.Allocation
+		if totalAllocation != nil {
+			sold, held, err := r.soldAndHeld(tx, ticket.Id)
+			if err != nil {
+				return err
+			}
+
+			if *totalAllocation < sold+held {
+				return ErrAllocationBelowSold
+			}
+
+			ticket.Allocation = *totalAllocation - sold - held
+			ticket.AllocationChange = ticket.Allocation - current.Allocation
+			changes["allocation"] = ticket.Allocation
+		}
+
+		if err := tx.Table(r.tableName).Where("id = ?", ticket.Id).UpdateColumns(changes).Error; err != nil {
+			return err
+		}
+
+		if ticket.AllocationChange != 0 {
+			if err := writeAllocationChanged(tx, ticket.Id, ticket.Allocation, ticket.AllocationChange); err != nil {
+				return err
+			}
+		}
+		return writeTicketUpdated(tx, ticket)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ticket, nil
+}
+
+// SetActive soft deletes or restores a ticket using its IsActive flag
+func (r *ticketRepository) SetActive(ctx context.Context, ticket *models.Ticket) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Table(r.tableName).Where("id = ?", ticket.Id).UpdateColumns(map[string]interface{}{
+			"is_active":  ticket.IsActive,
+			"updated_by": ticket.UpdatedBy,
+			"updated_at": ticket.UpdatedAt,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return writeTicketUpdated(tx, ticket)
+	})
+}
+
+// writeTicketUpdated adds the TicketUpdated event of a ticket changed in the transaction
+func writeTicketUpdated(tx *gorm.DB, ticket *models.Ticket) error {
+	return writeOutbox(tx, ticket.Id, events.TypeTicketUpdated, events.TicketUpdated{
+		TicketId: ticket.Id,
+		IsActive: ticket.IsActive,
+	})
+}
@@ -0,0 +1,143 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+	"ticket-purchase/internal/db/models"
+	"time"
+)
+
+const ticketCacheKeyPrefix = "ticket:"
+
+// cachedTicketRepository is a read-through Redis cache for FindById. Writes go to the wrapped repository and
+// invalidate the cached ticket. Redis errors are logged and the wrapped repository is used instead.
+// The allocation is changed by purchases, holds, refunds and checkouts that never go through this repository. Their
+// repositories invalidate the ticket as well, see NewTicketCachePurchaseRepository, and so do the domain events of
+// every change, see NewTicketCacheInvalidator.
+type cachedTicketRepository struct {
+	TicketRepository
+	client redis.UniversalClient
+	ttl    time.Duration
+	group  singleflight.Group
+}
+
+// NewCachedTicketRepository wraps a ticket repository with a Redis cache that keeps tickets for ttl
+func NewCachedTicketRepository(next TicketRepository, client redis.UniversalClient, ttl time.Duration) TicketRepository {
+	return &cachedTicketRepository{
+		TicketRepository: next,
+		client:           client,
+		ttl:              ttl,
+	}
+}
+
+func ticketCacheKey(id string) string {
+	return ticketCacheKeyPrefix + id
+}
+
+func (r *cachedTicketRepository) FindById(ctx context.Context, id string) (*models.Ticket, error) {
+	if ticket, ok := r.get(ctx, id); ok {
+		return ticket, nil
+	}
+
+	// Concurrent misses of the same ticket share one database query. It is not cancelled with the first caller,
+	// whose result is shared with the others.
+	value, err, _ := r.group.Do(id, func() (interface{}, error) {
+		loadCtx := context.WithoutCancel(ctx)
+		ticket, err := r.TicketRepository.FindById(loadCtx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		r.set(loadCtx, ticket)
+		return ticket, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Every caller gets its own copy because the services change the tickets they load
+	ticket := *value.(*models.Ticket)
+	return &ticket, nil
+}
+
+func (r *cachedTicketRepository) Create(ctx context.Context, ticket *models.Ticket) (*models.Ticket, error) {
+	created, err := r.TicketRepository.Create(ctx, ticket)
+	if err != nil {
+		return nil, err
+	}
+
+	invalidateTicket(ctx, r.client, created.Id)
+	return created, nil
+}
+
+func (r *cachedTicketRepository) Update(ctx context.Context, ticket *models.Ticket) (*models.Ticket, error) {
+	updated, err := r.TicketRepository.Update(ctx, ticket)
+	if err != nil {
+		return nil, err
+	}
+
+	invalidateTicket(ctx, r.client, ticket.Id)
+	return updated, nil
+}
+
+func (r *cachedTicketRepository) UpdateDetails(ctx context.Context, ticket *models.Ticket, totalAllocation *int) (*models.Ticket, error) {
+	updated, err := r.TicketRepository.UpdateDetails(ctx, ticket, totalAllocation)
+	if err != nil {
+		return nil, err
+	}
+
+	invalidateTicket(ctx, r.client, ticket.Id)
+	return updated, nil
+}
+
+func (r *cachedTicketRepository) SetActive(ctx context.Context, ticket *models.Ticket) error {
+	if err := r.TicketRepository.SetActive(ctx, ticket); err != nil {
+		return err
+	}
+
+	invalidateTicket(ctx, r.client, ticket.Id)
+	return nil
+}
+
+// get returns the cached ticket. Misses, Redis errors and unreadable entries are all reported as a miss.
+func (r *cachedTicketRepository) get(ctx context.Context, id string) (*models.Ticket, bool) {
+	data, err := r.client.Get(ctx, ticketCacheKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false
+	}
+
+	if err != nil {
+		log.Warn("Ticket cache is unavailable, reading from the database: ", err)
+		return nil, false
+	}
+
+	var ticket models.Ticket
+	if err := json.Unmarshal(data, &ticket); err != nil {
+		log.Warn("Ignoring unreadable cached ticket ", id, ": ", err)
+		return nil, false
+	}
+	return &ticket, true
+}
+
+func (r *cachedTicketRepository) set(ctx context.Context, ticket *models.Ticket) {
+	data, err := json.Marshal(ticket)
+	if err != nil {
+		log.Warn("Error encoding ticket ", ticket.Id, " for the cache: ", err)
+		return
+	}
+
+	if err := r.client.Set(ctx, ticketCacheKey(ticket.Id), data, r.ttl).Err(); err != nil {
+		log.Warn("Error caching ticket ", ticket.Id, ": ", err)
+	}
+}
+
+// invalidateTicket removes a changed ticket from the cache. If Redis is down the entry expires with its TTL.
+func invalidateTicket(ctx context.Context, client redis.UniversalClient, id string) {
+	if err := client.Del(ctx, ticketCacheKey(id)).Err(); err != nil {
+		log.Warn("Error invalidating cached ticket ", id, ": ", err)
+	}
+}
@@ -0,0 +1,131 @@
+package repositories
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"ticket-purchase/internal/db/models"
+	"ticket-purchase/internal/events"
+	"time"
+)
+
+// ticketCachePurchaseRepository drops the cached ticket of every purchase and refund that changes its allocation.
+// Everything else goes to the wrapped repository.
+type ticketCachePurchaseRepository struct {
+	PurchaseRepository
+	client redis.UniversalClient
+}
+
+// NewTicketCachePurchaseRepository wraps a purchase repository so that the tickets cached by NewCachedTicketRepository
+// are invalidated when purchases change their allocation
+func NewTicketCachePurchaseRepository(next PurchaseRepository, client redis.UniversalClient) PurchaseRepository {
+	return &ticketCachePurchaseRepository{PurchaseRepository: next, client: client}
+}
+
+func (r *ticketCachePurchaseRepository) Create(ctx context.Context, purchase *models.Purchase) error {
+	if err := r.PurchaseRepository.Create(ctx, purchase); err != nil {
+		return err
+	}
+
+	invalidateTicket(ctx, r.client, purchase.TicketId)
+	return nil
+}
+
+func (r *ticketCachePurchaseRepository) CreateWithAllocation(ctx context.Context, purchase *models.Purchase) error {
+	if err := r.PurchaseRepository.CreateWithAllocation(ctx, purchase); err != nil {
+		return err
+	}
+
+	invalidateTicket(ctx, r.client, purchase.TicketId)
+	return nil
+}
+
+func (r *ticketCachePurchaseRepository) Refund(ctx context.Context, purchase *models.Purchase, quantity int) error {
+	if err := r.PurchaseRepository.Refund(ctx, purchase, quantity); err != nil {
+		return err
+	}
+
+	invalidateTicket(ctx, r.client, purchase.TicketId)
+	return nil
+}
+
+func (r *ticketCachePurchaseRepository) CompleteRefund(ctx context.Context, purchase *models.Purchase, quantity int) error {
+	if err := r.PurchaseRepository.CompleteRefund(ctx, purchase, quantity); err != nil {
+		return err
+	}
+
+	invalidateTicket(ctx, r.client, purchase.TicketId)
+	return nil
+}
+
+// ticketCacheHoldRepository drops the cached ticket of every hold that is taken or released
+type ticketCacheHoldRepository struct {
+	HoldRepository
+	client redis.UniversalClient
+}
+
+// NewTicketCacheHoldRepository wraps a hold repository so that the tickets cached by NewCachedTicketRepository are
+// invalidated when holds change their allocation
+func NewTicketCacheHoldRepository(next HoldRepository, client redis.UniversalClient) HoldRepository {
+	return &ticketCacheHoldRepository{HoldRepository: next, client: client}
+}
+
+func (r *ticketCacheHoldRepository) CreateWithAllocation(ctx context.Context, hold *models.Hold) error {
+	if err := r.HoldRepository.CreateWithAllocation(ctx, hold); err != nil {
+		return err
+	}
+
+	invalidateTicket(ctx, r.client, hold.TicketId)
+	return nil
+}
+
+func (r *ticketCacheHoldRepository) ReleaseExpired(ctx context.Context, now time.Time, limit int) ([]models.Hold, error) {
+	released, err := r.HoldRepository.ReleaseExpired(ctx, now, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	invalidated := map[string]bool{}
+	for _, hold := range released {
+		if !invalidated[hold.TicketId] {
+			invalidated[hold.TicketId] = true
+			invalidateTicket(ctx, r.client, hold.TicketId)
+		}
+	}
+	return released, nil
+}
+
+// ticketCacheOrderRepository drops the cached tickets of the lines of every checkout
+type ticketCacheOrderRepository struct {
+	OrderRepository
+	client redis.UniversalClient
+}
+
+// NewTicketCacheOrderRepository wraps an order repository so that the tickets cached by NewCachedTicketRepository
+// are invalidated when checkouts change their allocation
+func NewTicketCacheOrderRepository(next OrderRepository, client redis.UniversalClient) OrderRepository {
+	return &ticketCacheOrderRepository{OrderRepository: next, client: client}
+}
+
+func (r *ticketCacheOrderRepository) Checkout(ctx context.Context, order *models.Order) error {
+	if err := r.OrderRepository.Checkout(ctx, order); err != nil {
+		return err
+	}
+
+	for _, line := range order.Lines {
+		invalidateTicket(ctx, r.client, line.TicketId)
+	}
+	return nil
+}
+
+// NewTicketCacheInvalidator returns a consumer of the outbox that drops the cached ticket of every event that changes
+// it. The repositories above invalidate right after their change, the event catches a ticket that a concurrent read
+// cached again before the change was committed.
+func NewTicketCacheInvalidator(client redis.UniversalClient) events.Handler {
+	return func(ctx context.Context, event events.Event) error {
+		switch event.Type {
+		case events.TypeAllocationChanged, events.TypeSoldOut, events.TypeTicketUpdated:
+			invalidateTicket(ctx, client, event.TicketId)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,252 @@
+package repositories_test
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+	"sync"
+	"testing"
+	"ticket-purchase/internal/db/models"
+	"ticket-purchase/internal/db/repositories"
+	"ticket-purchase/internal/events"
+	mocks "ticket-purchase/internal/mocks/repositories"
+	"time"
+)
+
+const cacheTestTTL = time.Minute
+
+var cachedTicket = models.Ticket{Id: "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b", Name: "Ticket 1", Allocation: 100, IsActive: true}
+
+func setupTicketCacheTest(t *testing.T) (repositories.TicketRepository, *mocks.MockTicketRepository, *miniredis.Miniredis) {
+	ct := gomock.NewController(t)
+	next := mocks.NewMockTicketRepository(ct)
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	return repositories.NewCachedTicketRepository(next, client, cacheTestTTL), next, server
+}
+
+func TestCachedTicketRepository_FindById_Reads_Through(t *testing.T) {
+	repo, next, server := setupTicketCacheTest(t)
+	ticket := cachedTicket
+
+	next.EXPECT().FindById(gomock.Any(), ticket.Id).Return(&ticket, nil).Times(1)
+
+	for i := 0; i < 3; i++ {
+		found, err := repo.FindById(context.Background(), ticket.Id)
+		if err != nil {
+			t.Fatalf("Expected error to be nil, got %v", err)
+		}
+		assert.Equal(t, ticket.Name, found.Name)
+		assert.Equal(t, ticket.Allocation, found.Allocation)
+	}
+
+	assert.True(t, server.Exists("ticket:"+ticket.Id))
+	assert.Equal(t, cacheTestTTL, server.TTL("ticket:"+ticket.Id))
+}
+
+func TestCachedTicketRepository_FindById_Hit_Skips_Database(t *testing.T) {
+	repo, next, _ := setupTicketCacheTest(t)
+	ticket := cachedTicket
+
+	// Only the first read goes to the database, hits read neither the ticket nor its allocation
+	next.EXPECT().FindById(gomock.Any(), ticket.Id).Return(&ticket, nil).Times(1)
+	next.EXPECT().FindAllocation(gomock.Any(), gomock.Any()).Times(0)
+
+	_, _ = repo.FindById(context.Background(), ticket.Id)
+	found, err := repo.FindById(context.Background(), ticket.Id)
+
+	assert.NoError(t, err)
+	assert.Equal(t, ticket.Allocation, found.Allocation)
+}
+
+func TestCachedTicketRepository_FindById_Expires(t *testing.T) {
+	repo, next, server := setupTicketCacheTest(t)
+	ticket := cachedTicket
+
+	next.EXPECT().FindById(gomock.Any(), ticket.Id).Return(&ticket, nil).Times(2)
+
+	_, _ = repo.FindById(context.Background(), ticket.Id)
+	server.FastForward(cacheTestTTL)
+	_, err := repo.FindById(context.Background(), ticket.Id)
+
+	assert.NoError(t, err)
+}
+
+func TestCachedTicketRepository_FindById_Not_Found_Is_Not_Cached(t *testing.T) {
+	repo, next, server := setupTicketCacheTest(t)
+
+	next.EXPECT().FindById(gomock.Any(), "missing").Return(nil, gorm.ErrRecordNotFound)
+
+	found, err := repo.FindById(context.Background(), "missing")
+
+	assert.Nil(t, found)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.False(t, server.Exists("ticket:missing"))
+}
+
+func TestCachedTicketRepository_Writes_Invalidate(t *testing.T) {
+	repo, next, _ := setupTicketCacheTest(t)
+	ticket := cachedTicket
+	allocation := 50
+
+	// Every write is followed by a fresh read from the database
+	next.EXPECT().FindById(gomock.Any(), ticket.Id).Return(&ticket, nil).Times(4)
+	next.EXPECT().Update(gomock.Any(), gomock.Any()).Return(&ticket, nil)
+	next.EXPECT().UpdateDetails(gomock.Any(), gomock.Any(), &allocation).Return(&ticket, nil)
+	next.EXPECT().SetActive(gomock.Any(), gomock.Any()).Return(nil)
+
+	_, _ = repo.FindById(context.Background(), ticket.Id)
+
+	_, err := repo.Update(context.Background(), &ticket)
+	assert.NoError(t, err)
+	_, _ = repo.FindById(context.Background(), ticket.Id)
+
+	_, err = repo.UpdateDetails(context.Background(), &ticket, &allocation)
+	assert.NoError(t, err)
+	_, _ = repo.FindById(context.Background(), ticket.Id)
+
+	assert.NoError(t, repo.SetActive(context.Background(), &ticket))
+	_, _ = repo.FindById(context.Background(), ticket.Id)
+}
+
+func TestCachedTicketRepository_Create_Invalidates(t *testing.T) {
+	repo, next, server := setupTicketCacheTest(t)
+	ticket := cachedTicket
+
+	_ = server.Set("ticket:"+ticket.Id, `{"id":"stale"}`)
+	next.EXPECT().Create(gomock.Any(), &ticket).Return(&ticket, nil)
+
+	_, err := repo.Create(context.Background(), &ticket)
+
+	assert.NoError(t, err)
+	assert.False(t, server.Exists("ticket:"+ticket.Id))
+}
+
+func TestCachedTicketRepository_FindById_Single_Flight(t *testing.T) {
+	repo, next, _ := setupTicketCacheTest(t)
+	ticket := cachedTicket
+	release := make(chan struct{})
+
+	next.EXPECT().FindById(gomock.Any(), ticket.Id).DoAndReturn(func(_ context.Context, _ string) (*models.Ticket, error) {
+		<-release
+		return &ticket, nil
+	}).Times(1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			found, err := repo.FindById(context.Background(), ticket.Id)
+			assert.NoError(t, err)
+			assert.Equal(t, ticket.Name, found.Name)
+		}()
+	}
+
+	// Give every reader time to miss the cache and join the load
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+}
+
+func TestCachedTicketRepository_Redis_Down(t *testing.T) {
+	repo, next, server := setupTicketCacheTest(t)
+	ticket := cachedTicket
+	server.Close()
+
+	next.EXPECT().FindById(gomock.Any(), ticket.Id).Return(&ticket, nil).Times(2)
+	next.EXPECT().Update(gomock.Any(), gomock.Any()).Return(&ticket, nil)
+
+	for i := 0; i < 2; i++ {
+		found, err := repo.FindById(context.Background(), ticket.Id)
+		if err != nil {
+			t.Fatalf("Expected error to be nil, got %v", err)
+		}
+		assert.Equal(t, ticket.Id, found.Id)
+	}
+
+	_, err := repo.Update(context.Background(), &ticket)
+	assert.NoError(t, err)
+}
+
+// cacheTicket puts a ticket in the cache and returns a client of the server
+func cacheTicket(t *testing.T, server *miniredis.Miniredis, ticketId string) redis.UniversalClient {
+	_ = server.Set("ticket:"+ticketId, `{"id":"`+ticketId+`"}`)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+	return client
+}
+
+func TestTicketCachePurchaseRepository_Invalidates(t *testing.T) {
+	server := miniredis.RunT(t)
+	next := mocks.NewMockPurchaseRepository(gomock.NewController(t))
+	repo := repositories.NewTicketCachePurchaseRepository(next, cacheTicket(t, server, cachedTicket.Id))
+	purchase := models.Purchase{TicketId: cachedTicket.Id, Quantity: 2}
+
+	next.EXPECT().CreateWithAllocation(gomock.Any(), &purchase).Return(nil)
+	assert.NoError(t, repo.CreateWithAllocation(context.Background(), &purchase))
+	assert.False(t, server.Exists("ticket:"+cachedTicket.Id))
+
+	cacheTicket(t, server, cachedTicket.Id)
+	next.EXPECT().CompleteRefund(gomock.Any(), &purchase, 1).Return(nil)
+	assert.NoError(t, repo.CompleteRefund(context.Background(), &purchase, 1))
+	assert.False(t, server.Exists("ticket:"+cachedTicket.Id))
+
+	// A failed purchase changed nothing
+	cacheTicket(t, server, cachedTicket.Id)
+	next.EXPECT().Refund(gomock.Any(), &purchase, 1).Return(gorm.ErrRecordNotFound)
+	assert.Error(t, repo.Refund(context.Background(), &purchase, 1))
+	assert.True(t, server.Exists("ticket:"+cachedTicket.Id))
+}
+
+func TestTicketCacheHoldRepository_ReleaseExpired_Invalidates(t *testing.T) {
+	server := miniredis.RunT(t)
+	next := mocks.NewMockHoldRepository(gomock.NewController(t))
+	repo := repositories.NewTicketCacheHoldRepository(next, cacheTicket(t, server, cachedTicket.Id))
+	now := time.Now()
+
+	cacheTicket(t, server, "other")
+	next.EXPECT().ReleaseExpired(gomock.Any(), now, 10).Return([]models.Hold{{TicketId: cachedTicket.Id}, {TicketId: cachedTicket.Id}}, nil)
+
+	released, err := repo.ReleaseExpired(context.Background(), now, 10)
+
+	assert.NoError(t, err)
+	assert.Len(t, released, 2)
+	assert.False(t, server.Exists("ticket:"+cachedTicket.Id))
+	assert.True(t, server.Exists("ticket:other"))
+}
+
+func TestTicketCacheOrderRepository_Checkout_Invalidates(t *testing.T) {
+	server := miniredis.RunT(t)
+	next := mocks.NewMockOrderRepository(gomock.NewController(t))
+	repo := repositories.NewTicketCacheOrderRepository(next, cacheTicket(t, server, cachedTicket.Id))
+	cacheTicket(t, server, "other")
+	order := models.Order{Lines: []models.Purchase{{TicketId: cachedTicket.Id}, {TicketId: "other"}}}
+
+	next.EXPECT().Checkout(gomock.Any(), &order).Return(nil)
+
+	assert.NoError(t, repo.Checkout(context.Background(), &order))
+	assert.False(t, server.Exists("ticket:"+cachedTicket.Id))
+	assert.False(t, server.Exists("ticket:other"))
+}
+
+func TestTicketCacheInvalidator(t *testing.T) {
+	server := miniredis.RunT(t)
+	handle := repositories.NewTicketCacheInvalidator(cacheTicket(t, server, cachedTicket.Id))
+
+	assert.NoError(t, handle(context.Background(), events.Event{Type: events.TypeTicketPurchased, TicketId: cachedTicket.Id}))
+	assert.True(t, server.Exists("ticket:"+cachedTicket.Id))
+
+	assert.NoError(t, handle(context.Background(), events.Event{Type: events.TypeAllocationChanged, TicketId: cachedTicket.Id}))
+	assert.False(t, server.Exists("ticket:"+cachedTicket.Id))
+}
@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ticket-purchase/internal/db/models"
+)
+
+// ErrEmailTaken is returned when a user with the same email already exists
+var ErrEmailTaken = errors.New("email is already registered")
+
+//go:generate mockgen -destination=../../mocks/repositories/user_repository_mock.go -package=repositories ticket-purchase/internal/db/repositories UserRepository
+type UserRepository interface {
+	FindById(ctx context.Context, id string) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	Create(ctx context.Context, user *models.User) error
+	UpdateRole(ctx context.Context, user *models.User) error
+}
+
+type userRepository struct {
+	db        *gorm.DB
+	tableName string
+}
+
+func NewUserRepository(db *gorm.DB) UserRepository {
+	var userModel models.User
+	return &userRepository{db: db, tableName: userModel.TableName()}
+}
+
+func (r *userRepository) FindById(ctx context.Context, id string) (*models.User, error) {
+	var user models.User
+	result := r.db.Table(r.tableName).WithContext(ctx).Where("id = ?", id).First(&user)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &user, nil
+}
+
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	result := r.db.Table(r.tableName).WithContext(ctx).Where("email = ?", email).First(&user)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &user, nil
+}
+
+// Create inserts the user. It returns ErrEmailTaken when the email is already registered.
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	result := r.db.Table(r.tableName).WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "email"}}, DoNothing: true}).
+		Create(user)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrEmailTaken
+	}
+	return nil
+}
+
+// UpdateRole stores the role of the user
+func (r *userRepository) UpdateRole(ctx context.Context, user *models.User) error {
+	result := r.db.Table(r.tableName).WithContext(ctx).
+		Where("id = ?", user.Id).
+		Updates(map[string]interface{}{"role": user.Role, "updated_at": user.UpdatedAt})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
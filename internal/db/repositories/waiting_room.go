@@ -0,0 +1,238 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+// ErrQueueTokenNotFound is returned for tokens that were never issued for the ticket or were already cleaned up
+var ErrQueueTokenNotFound = errors.New("queue token not found")
+
+// ErrQueueTokenNotUsable is returned when a token to be used is not admitted, its admission expired or it was already used
+var ErrQueueTokenNotUsable = errors.New("queue token cannot be used")
+
+const (
+	waitingRoomKeyPrefix  = "waitingroom:"
+	waitingRoomTicketsKey = "waitingroom:tickets"
+
+	// waitingRoomRetention is how long the queue of a ticket is kept after the last user joined
+	waitingRoomRetention = 24 * time.Hour
+)
+
+// Statuses of a queue entry
+const (
+	QueueStatusWaiting  = "waiting"
+	QueueStatusAdmitted = "admitted"
+	QueueStatusExpired  = "expired"
+	QueueStatusUsed     = "used"
+)
+
+// QueueEntry is the place of a user in the waiting room of a ticket
+type QueueEntry struct {
+	Token    string
+	TicketId string
+	UserId   string
+	Status   string
+	// Position is 1 for the next user to be admitted, it is 0 once the user left the queue
+	Position int
+	// AdmittedUntil is when an admitted user can no longer buy
+	AdmittedUntil *time.Time
+}
+
+//go:generate mockgen -destination=../../mocks/repositories/waiting_room_repository_mock.go -package=repositories ticket-purchase/internal/db/repositories WaitingRoomRepository
+type WaitingRoomRepository interface {
+	// Join queues the user with the token. A user who is still waiting or admitted keeps their place and token.
+	Join(ctx context.Context, ticketId string, userId string, token string, now time.Time) (*QueueEntry, error)
+	// Find returns the entry of a token
+	Find(ctx context.Context, ticketId string, token string, now time.Time) (*QueueEntry, error)
+	// Admit lets the next users of the queue buy for the window. Instances share the rate, so that no more than
+	// perSecond users are admitted per second in total. It returns how many users were admitted.
+	Admit(ctx context.Context, ticketId string, now time.Time, perSecond int, window time.Duration) (int, error)
+	// TicketIds lists the tickets with users waiting
+	TicketIds(ctx context.Context) ([]string, error)
+	// Use marks an admitted token as used, so that one admission allows a single purchase. It returns
+	// ErrQueueTokenNotUsable when the token is not admitted, its admission expired or it was already used.
+	Use(ctx context.Context, ticketId string, token string, now time.Time) error
+	// Release makes a used token usable again for the rest of its admission
+	Release(ctx context.Context, ticketId string, token string) error
+}
+
+type waitingRoomRepository struct {
+	client redis.UniversalClient
+}
+
+func NewWaitingRoomRepository(client redis.UniversalClient) WaitingRoomRepository {
+	return &waitingRoomRepository{client: client}
+}
+
+// waitingRoomKeys are the keys of the waiting room of a ticket: the queue of tokens by arrival, the arrival counter,
+// the token of every user, the user of every token, the admitted tokens by the end of their window, the time of
+// the last admission, the tickets with users waiting and the admitted tokens already used for a purchase
+func waitingRoomKeys(ticketId string) []string {
+	prefix := waitingRoomKeyPrefix + ticketId
+	return []string{
+		prefix + ":queue",
+		prefix + ":sequence",
+		prefix + ":users",
+		prefix + ":tokens",
+		prefix + ":admitted",
+		prefix + ":admitted_at",
+		waitingRoomTicketsKey,
+		prefix + ":used",
+	}
+}
+
+// joinScript returns the current token of the user, or queues the new token when the user has none, their
+// admission expired or they already used it
+var joinScript = redis.NewScript(`
+local now = tonumber(ARGV[3])
+local existing = redis.call('HGET', KEYS[3], ARGV[1])
+if existing then
+	local admitted_until = tonumber(redis.call('ZSCORE', KEYS[5], existing))
+	local usable = admitted_until and admitted_until > now and redis.call('SISMEMBER', KEYS[8], existing) == 0
+	if redis.call('ZSCORE', KEYS[1], existing) or usable then
+		return existing
+	end
+	redis.call('HDEL', KEYS[4], existing)
+	redis.call('ZREM', KEYS[5], existing)
+	redis.call('SREM', KEYS[8], existing)
+end
+
+local sequence = redis.call('INCR', KEYS[2])
+redis.call('ZADD', KEYS[1], sequence, ARGV[2])
+redis.call('HSET', KEYS[3], ARGV[1], ARGV[2])
+redis.call('HSET', KEYS[4], ARGV[2], ARGV[1])
+redis.call('SADD', KEYS[7], ARGV[5])
+for i = 1, 6 do
+	redis.call('PEXPIRE', KEYS[i], ARGV[4])
+end
+return ARGV[2]
+`)
+
+// useScript marks an admitted token whose admission has not expired as used. It returns 0 when the token cannot be used.
+var useScript = redis.NewScript(`
+local admitted_until = tonumber(redis.call('ZSCORE', KEYS[5], ARGV[1]))
+if not admitted_until or admitted_until <= tonumber(ARGV[2]) then
+	return 0
+end
+if redis.call('SADD', KEYS[8], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('PEXPIRE', KEYS[8], ARGV[3])
+return 1
+`)
+
+// admitScript admits as many users as the rate allows since the last admission. Unused admissions are kept for up to
+// a second, so that instances calling it at different times still admit the full rate, but an idle queue does not
+// admit a burst.
+var admitScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local base = math.max(tonumber(redis.call('GET', KEYS[6]) or '0'), now - 1000)
+local count = math.floor((now - base) * rate / 1000)
+if count < 1 then
+	return 0
+end
+
+local popped = redis.call('ZPOPMIN', KEYS[1], count)
+local admitted = #popped / 2
+for i = 1, #popped, 2 do
+	redis.call('ZADD', KEYS[5], now + tonumber(ARGV[3]), popped[i])
+end
+
+if admitted < count then
+	redis.call('SET', KEYS[6], now)
+else
+	redis.call('SET', KEYS[6], tostring(base + count * 1000 / rate))
+end
+redis.call('PEXPIRE', KEYS[6], ARGV[4])
+
+if redis.call('ZCARD', KEYS[1]) == 0 then
+	redis.call('SREM', KEYS[7], ARGV[5])
+end
+return admitted
+`)
+
+func (r *waitingRoomRepository) Join(ctx context.Context, ticketId string, userId string, token string, now time.Time) (*QueueEntry, error) {
+	token, err := joinScript.Run(ctx, r.client, waitingRoomKeys(ticketId),
+		userId, token, now.UnixMilli(), waitingRoomRetention.Milliseconds(), ticketId,
+	).Text()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Find(ctx, ticketId, token, now)
+}
+
+func (r *waitingRoomRepository) Find(ctx context.Context, ticketId string, token string, now time.Time) (*QueueEntry, error) {
+	keys := waitingRoomKeys(ticketId)
+
+	var userId *redis.StringCmd
+	var admittedUntil *redis.FloatCmd
+	var rank *redis.IntCmd
+	var used *redis.BoolCmd
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		userId = pipe.HGet(ctx, keys[3], token)
+		admittedUntil = pipe.ZScore(ctx, keys[4], token)
+		rank = pipe.ZRank(ctx, keys[0], token)
+		used = pipe.SIsMember(ctx, keys[7], token)
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	if errors.Is(userId.Err(), redis.Nil) {
+		return nil, ErrQueueTokenNotFound
+	}
+
+	entry := QueueEntry{Token: token, TicketId: ticketId, UserId: userId.Val()}
+	switch {
+	case admittedUntil.Err() == nil:
+		until := time.UnixMilli(int64(admittedUntil.Val())).UTC()
+		entry.AdmittedUntil = &until
+		entry.Status = QueueStatusAdmitted
+		if used.Val() {
+			entry.Status = QueueStatusUsed
+		} else if !until.After(now) {
+			entry.Status = QueueStatusExpired
+		}
+	case rank.Err() == nil:
+		entry.Status = QueueStatusWaiting
+		entry.Position = int(rank.Val()) + 1
+	default:
+		return nil, ErrQueueTokenNotFound
+	}
+	return &entry, nil
+}
+
+func (r *waitingRoomRepository) Admit(ctx context.Context, ticketId string, now time.Time, perSecond int, window time.Duration) (int, error) {
+	return admitScript.Run(ctx, r.client, waitingRoomKeys(ticketId),
+		now.UnixMilli(), strconv.Itoa(perSecond), window.Milliseconds(), waitingRoomRetention.Milliseconds(), ticketId,
+	).Int()
+}
+
+func (r *waitingRoomRepository) Use(ctx context.Context, ticketId string, token string, now time.Time) error {
+	used, err := useScript.Run(ctx, r.client, waitingRoomKeys(ticketId),
+		token, now.UnixMilli(), waitingRoomRetention.Milliseconds(),
+	).Int()
+	if err != nil {
+		return err
+	}
+
+	if used == 0 {
+		return ErrQueueTokenNotUsable
+	}
+	return nil
+}
+
+func (r *waitingRoomRepository) Release(ctx context.Context, ticketId string, token string) error {
+	return r.client.SRem(ctx, waitingRoomKeys(ticketId)[7], token).Err()
+}
+
+func (r *waitingRoomRepository) TicketIds(ctx context.Context) ([]string, error) {
+	return r.client.SMembers(ctx, waitingRoomTicketsKey).Result()
+}
@@ -0,0 +1,166 @@
+package repositories_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"ticket-purchase/internal/db/repositories"
+	"time"
+)
+
+const waitingRoomTicketId = "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b"
+
+var waitingRoomTestTime = time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+func setupWaitingRoomTest(t *testing.T) (repositories.WaitingRoomRepository, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	return repositories.NewWaitingRoomRepository(client), server
+}
+
+// joinUsers queues user-1 to user-n with the tokens token-1 to token-n
+func joinUsers(t *testing.T, repo repositories.WaitingRoomRepository, n int) {
+	for i := 1; i <= n; i++ {
+		_, err := repo.Join(context.Background(), waitingRoomTicketId, fmt.Sprint("user-", i), fmt.Sprint("token-", i), waitingRoomTestTime)
+		if err != nil {
+			t.Fatalf("Expected error to be nil, got %v", err)
+		}
+	}
+}
+
+func TestWaitingRoomRepository_Join(t *testing.T) {
+	repo, _ := setupWaitingRoomTest(t)
+	ctx := context.Background()
+	joinUsers(t, repo, 2)
+
+	entry, err := repo.Find(ctx, waitingRoomTicketId, "token-2", waitingRoomTestTime)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	assert.Equal(t, repositories.QueueEntry{
+		Token:    "token-2",
+		TicketId: waitingRoomTicketId,
+		UserId:   "user-2",
+		Status:   repositories.QueueStatusWaiting,
+		Position: 2,
+	}, *entry)
+
+	// Joining again keeps the place in the queue
+	entry, err = repo.Join(ctx, waitingRoomTicketId, "user-1", "token-new", waitingRoomTestTime)
+	assert.NoError(t, err)
+	assert.Equal(t, "token-1", entry.Token)
+	assert.Equal(t, 1, entry.Position)
+
+	ticketIds, err := repo.TicketIds(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{waitingRoomTicketId}, ticketIds)
+}
+
+func TestWaitingRoomRepository_Find_Unknown_Token(t *testing.T) {
+	repo, _ := setupWaitingRoomTest(t)
+	joinUsers(t, repo, 1)
+
+	_, err := repo.Find(context.Background(), waitingRoomTicketId, "unknown", waitingRoomTestTime)
+	assert.ErrorIs(t, err, repositories.ErrQueueTokenNotFound)
+
+	_, err = repo.Find(context.Background(), "other-ticket", "token-1", waitingRoomTestTime)
+	assert.ErrorIs(t, err, repositories.ErrQueueTokenNotFound)
+}
+
+func TestWaitingRoomRepository_Admit(t *testing.T) {
+	repo, _ := setupWaitingRoomTest(t)
+	ctx := context.Background()
+	joinUsers(t, repo, 5)
+
+	admitted, err := repo.Admit(ctx, waitingRoomTicketId, waitingRoomTestTime, 2, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, admitted)
+
+	entry, _ := repo.Find(ctx, waitingRoomTicketId, "token-2", waitingRoomTestTime)
+	assert.Equal(t, repositories.QueueStatusAdmitted, entry.Status)
+	assert.Equal(t, waitingRoomTestTime.Add(time.Minute), *entry.AdmittedUntil)
+
+	entry, _ = repo.Find(ctx, waitingRoomTicketId, "token-5", waitingRoomTestTime)
+	assert.Equal(t, 3, entry.Position)
+
+	// Another instance admitting in the same second does not exceed the rate
+	admitted, _ = repo.Admit(ctx, waitingRoomTicketId, waitingRoomTestTime.Add(200*time.Millisecond), 2, time.Minute)
+	assert.Equal(t, 0, admitted)
+
+	admitted, _ = repo.Admit(ctx, waitingRoomTicketId, waitingRoomTestTime.Add(500*time.Millisecond), 2, time.Minute)
+	assert.Equal(t, 1, admitted)
+
+	// Admissions left unused while nobody asked are not piled up
+	admitted, _ = repo.Admit(ctx, waitingRoomTicketId, waitingRoomTestTime.Add(time.Hour), 2, time.Minute)
+	assert.Equal(t, 2, admitted)
+
+	ticketIds, _ := repo.TicketIds(ctx)
+	assert.Empty(t, ticketIds)
+}
+
+func TestWaitingRoomRepository_Admission_Expires(t *testing.T) {
+	repo, _ := setupWaitingRoomTest(t)
+	ctx := context.Background()
+	joinUsers(t, repo, 1)
+
+	_, _ = repo.Admit(ctx, waitingRoomTicketId, waitingRoomTestTime, 1, time.Minute)
+	later := waitingRoomTestTime.Add(time.Minute)
+
+	entry, _ := repo.Find(ctx, waitingRoomTicketId, "token-1", later)
+	assert.Equal(t, repositories.QueueStatusExpired, entry.Status)
+
+	// An expired user queues again at the end with a new token
+	entry, err := repo.Join(ctx, waitingRoomTicketId, "user-1", "token-again", later)
+	assert.NoError(t, err)
+	assert.Equal(t, "token-again", entry.Token)
+	assert.Equal(t, repositories.QueueStatusWaiting, entry.Status)
+
+	_, err = repo.Find(ctx, waitingRoomTicketId, "token-1", later)
+	assert.ErrorIs(t, err, repositories.ErrQueueTokenNotFound)
+}
+
+func TestWaitingRoomRepository_Use(t *testing.T) {
+	repo, _ := setupWaitingRoomTest(t)
+	ctx := context.Background()
+	joinUsers(t, repo, 2)
+
+	// Waiting tokens cannot be used
+	assert.ErrorIs(t, repo.Use(ctx, waitingRoomTicketId, "token-1", waitingRoomTestTime), repositories.ErrQueueTokenNotUsable)
+
+	_, _ = repo.Admit(ctx, waitingRoomTicketId, waitingRoomTestTime, 1, time.Minute)
+	assert.NoError(t, repo.Use(ctx, waitingRoomTicketId, "token-1", waitingRoomTestTime))
+	assert.ErrorIs(t, repo.Use(ctx, waitingRoomTicketId, "token-1", waitingRoomTestTime), repositories.ErrQueueTokenNotUsable)
+
+	entry, _ := repo.Find(ctx, waitingRoomTicketId, "token-1", waitingRoomTestTime)
+	assert.Equal(t, repositories.QueueStatusUsed, entry.Status)
+
+	// A released token can be used again until its admission expires
+	assert.NoError(t, repo.Release(ctx, waitingRoomTicketId, "token-1"))
+	assert.NoError(t, repo.Use(ctx, waitingRoomTicketId, "token-1", waitingRoomTestTime))
+	_ = repo.Release(ctx, waitingRoomTicketId, "token-1")
+	assert.ErrorIs(t, repo.Use(ctx, waitingRoomTicketId, "token-1", waitingRoomTestTime.Add(time.Minute)), repositories.ErrQueueTokenNotUsable)
+
+	// A user who used their admission queues again with a new token
+	_ = repo.Use(ctx, waitingRoomTicketId, "token-1", waitingRoomTestTime)
+	entry, err := repo.Join(ctx, waitingRoomTicketId, "user-1", "token-again", waitingRoomTestTime)
+	assert.NoError(t, err)
+	assert.Equal(t, "token-again", entry.Token)
+	assert.Equal(t, repositories.QueueStatusWaiting, entry.Status)
+}
+
+func TestWaitingRoomRepository_Expires_Idle_Queues(t *testing.T) {
+	repo, server := setupWaitingRoomTest(t)
+	joinUsers(t, repo, 1)
+
+	server.FastForward(25 * time.Hour)
+
+	_, err := repo.Find(context.Background(), waitingRoomTicketId, "token-1", waitingRoomTestTime)
+	assert.ErrorIs(t, err, repositories.ErrQueueTokenNotFound)
+}
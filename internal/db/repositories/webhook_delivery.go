@@ -0,0 +1,141 @@
+package repositories
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ticket-purchase/internal/db/models"
+	"ticket-purchase/pkg/pagination"
+	"time"
+)
+
+// webhookDeliverySort lists deliveries newest first
+var webhookDeliverySort = Sort{Column: "created_at", Descending: true}
+
+type WebhookDeliveryFilter struct {
+	SubscriptionId string
+	Status         string
+	Cursor         *pagination.Cursor
+	Limit          int
+}
+
+//go:generate mockgen -destination=../../mocks/repositories/webhook_delivery_repository_mock.go -package=repositories ticket-purchase/internal/db/repositories WebhookDeliveryRepository
+type WebhookDeliveryRepository interface {
+	// CreateBatch queues deliveries, skipping the ones already queued for the same subscription and event
+	CreateBatch(ctx context.Context, deliveries []models.WebhookDelivery) error
+	FindById(ctx context.Context, id string) (*models.WebhookDelivery, error)
+	// FindAll lists deliveries newest first
+	FindAll(ctx context.Context, filter WebhookDeliveryFilter) ([]models.WebhookDelivery, error)
+	// ClaimDue returns up to limit pending deliveries that are due at now with their subscriptions, and moves their
+	// next attempt lease into the future, so other instances skip them while they are sent. Deliveries of an instance
+	// that stops while sending are retried once the lease is over.
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error)
+	// Save stores the outcome of an attempt or a redelivery
+	Save(ctx context.Context, delivery *models.WebhookDelivery) error
+}
+
+type webhookDeliveryRepository struct {
+	db        *gorm.DB
+	tableName string
+}
+
+func NewWebhookDeliveryRepository(db *gorm.DB) WebhookDeliveryRepository {
+	var deliveryModel models.WebhookDelivery
+	return &webhookDeliveryRepository{db: db, tableName: deliveryModel.TableName()}
+}
+
+// WebhookDeliveryCursor returns the cursor that continues a listing after the delivery
+func WebhookDeliveryCursor(delivery *models.WebhookDelivery) pagination.Cursor {
+	return pagination.Cursor{Value: formatTime(delivery.CreatedAt), Id: delivery.Id}
+}
+
+func (r *webhookDeliveryRepository) CreateBatch(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.Table(r.tableName).WithContext(ctx).
+		Omit("Subscription").
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "subscription_id"}, {Name: "event_id"}},
+			DoNothing: true,
+		}).
+		Create(&deliveries).Error
+}
+
+func (r *webhookDeliveryRepository) FindById(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	result := r.db.Table(r.tableName).WithContext(ctx).Where("id = ?", id).First(&delivery)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &delivery, nil
+}
+
+func (r *webhookDeliveryRepository) FindAll(ctx context.Context, filter WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+	query := r.db.Table(r.tableName).WithContext(ctx)
+	if filter.SubscriptionId != "" {
+		query = query.Where("subscription_id = ?", filter.SubscriptionId)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	query, err := applyKeyset(query, webhookDeliverySort, filter.Cursor, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveries []models.WebhookDelivery
+	result := query.Find(&deliveries)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return deliveries, nil
+}
+
+func (r *webhookDeliveryRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Table(r.tableName).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&deliveries)
+		if result.Error != nil || len(deliveries) == 0 {
+			return result.Error
+		}
+
+		ids := make([]string, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			ids = append(ids, delivery.Id)
+		}
+
+		err := tx.Table(r.tableName).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"next_attempt_at": now.Add(lease),
+			"updated_at":      now,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		// The subscriptions are read in the same transaction, so a deleted subscription is never sent to
+		return tx.Preload("Subscription").Where("id IN ?", ids).Order("next_attempt_at").Find(&deliveries).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *webhookDeliveryRepository) Save(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return r.db.Table(r.tableName).WithContext(ctx).Where("id = ?", delivery.Id).Updates(map[string]interface{}{
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"last_error":      delivery.LastError,
+		"last_status":     delivery.LastStatus,
+		"delivered_at":    delivery.DeliveredAt,
+		"updated_at":      delivery.UpdatedAt,
+	}).Error
+}
@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"sync"
+	"testing"
+	"ticket-purchase/internal/db/models"
+	"time"
+)
+
+func TestWebhookDeliveryRepository_ClaimDue(t *testing.T) {
+	db := setupPostgresTest(t)
+	subscriptionRepo := NewWebhookSubscriptionRepository(db)
+	repo := NewWebhookDeliveryRepository(db)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	subscription := models.WebhookSubscription{
+		Url:       "https://partner.example.com/hooks",
+		Secret:    "whsec_test",
+		Events:    "ticket.created",
+		CreatedBy: "admin",
+	}
+	if err := subscriptionRepo.Create(ctx, &subscription); err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	t.Cleanup(func() { _ = subscriptionRepo.Delete(ctx, subscription.Id) })
+
+	deliveries := make([]models.WebhookDelivery, 0, 5)
+	for i := 0; i < 5; i++ {
+		deliveries = append(deliveries, models.WebhookDelivery{
+			SubscriptionId: subscription.Id,
+			EventId:        "event-" + strconv.Itoa(i),
+			EventType:      "ticket.created",
+			Payload:        "{}",
+			Status:         models.WebhookDeliveryPending,
+			NextAttemptAt:  now.Add(-time.Minute),
+		})
+	}
+	// Not due yet
+	deliveries[4].NextAttemptAt = now.Add(time.Hour)
+	if err := repo.CreateBatch(ctx, deliveries); err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	// An event relayed again is not queued twice
+	again := deliveries[0]
+	again.Id = ""
+	assert.NoError(t, repo.CreateBatch(ctx, []models.WebhookDelivery{again}))
+	queued, err := repo.FindAll(ctx, WebhookDeliveryFilter{SubscriptionId: subscription.Id, Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, queued, 5)
+
+	// Two instances claim at the same time, every due delivery is claimed once
+	var mu sync.Mutex
+	claimed := map[string]int{}
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			due, err := repo.ClaimDue(ctx, now, time.Minute, 10)
+			assert.NoError(t, err)
+			mu.Lock()
+			defer mu.Unlock()
+			for _, delivery := range due {
+				assert.Equal(t, subscription.Url, delivery.Subscription.Url)
+				claimed[delivery.Id]++
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, claimed, 4)
+	for _, count := range claimed {
+		assert.Equal(t, 1, count)
+	}
+
+	// Leased deliveries are not due again until the lease is over
+	due, err := repo.ClaimDue(ctx, now.Add(30*time.Second), time.Minute, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, due)
+
+	// Deleting the subscription removes its deliveries
+	assert.NoError(t, subscriptionRepo.Delete(ctx, subscription.Id))
+	remaining, err := repo.FindAll(ctx, WebhookDeliveryFilter{SubscriptionId: subscription.Id, Limit: 10})
+	assert.NoError(t, err)
+	assert.Empty(t, remaining)
+}
@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ticket-purchase/internal/db/models"
+)
+
+//go:generate mockgen -destination=../../mocks/repositories/webhook_event_repository_mock.go -package=repositories ticket-purchase/internal/db/repositories WebhookEventRepository
+type WebhookEventRepository interface {
+	// CreateIfNotExists records the event and reports whether it was created.
+	// It returns false when the event was already received.
+	CreateIfNotExists(ctx context.Context, event *models.WebhookEvent) (bool, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type webhookEventRepository struct {
+	db        *gorm.DB
+	tableName string
+}
+
+func NewWebhookEventRepository(db *gorm.DB) WebhookEventRepository {
+	var webhookEventModel models.WebhookEvent
+	return &webhookEventRepository{db: db, tableName: webhookEventModel.TableName()}
+}
+
+func (r *webhookEventRepository) CreateIfNotExists(ctx context.Context, event *models.WebhookEvent) (bool, error) {
+	result := r.db.Table(r.tableName).WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(event)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *webhookEventRepository) Delete(ctx context.Context, id string) error {
+	return r.db.Table(r.tableName).WithContext(ctx).Where("id = ?", id).Delete(&models.WebhookEvent{}).Error
+}
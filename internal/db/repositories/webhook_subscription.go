@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"ticket-purchase/internal/db/models"
+)
+
+//go:generate mockgen -destination=../../mocks/repositories/webhook_subscription_repository_mock.go -package=repositories ticket-purchase/internal/db/repositories WebhookSubscriptionRepository
+type WebhookSubscriptionRepository interface {
+	Create(ctx context.Context, subscription *models.WebhookSubscription) error
+	FindById(ctx context.Context, id string) (*models.WebhookSubscription, error)
+	// FindAll returns the subscriptions oldest first
+	FindAll(ctx context.Context) ([]models.WebhookSubscription, error)
+	// Delete removes the subscription and its deliveries
+	Delete(ctx context.Context, id string) error
+}
+
+type webhookSubscriptionRepository struct {
+	db        *gorm.DB
+	tableName string
+}
+
+func NewWebhookSubscriptionRepository(db *gorm.DB) WebhookSubscriptionRepository {
+	var subscriptionModel models.WebhookSubscription
+	return &webhookSubscriptionRepository{db: db, tableName: subscriptionModel.TableName()}
+}
+
+func (r *webhookSubscriptionRepository) Create(ctx context.Context, subscription *models.WebhookSubscription) error {
+	return r.db.Table(r.tableName).WithContext(ctx).Create(subscription).Error
+}
+
+func (r *webhookSubscriptionRepository) FindById(ctx context.Context, id string) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	result := r.db.Table(r.tableName).WithContext(ctx).Where("id = ?", id).First(&subscription)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &subscription, nil
+}
+
+func (r *webhookSubscriptionRepository) FindAll(ctx context.Context) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	result := r.db.Table(r.tableName).WithContext(ctx).Order("created_at, id").Find(&subscriptions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return subscriptions, nil
+}
+
+func (r *webhookSubscriptionRepository) Delete(ctx context.Context, id string) error {
+	result := r.db.Table(r.tableName).WithContext(ctx).Where("id = ?", id).Delete(&models.WebhookSubscription{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package dto
+
+import "time"
+
+type RegisterRequest struct {
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+	Locale   string `json:"locale" validate:"omitempty,oneof=en tr"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type UserRoleRequest struct {
+	UserId string `json:"-"`
+	Role   string `json:"role" validate:"required,oneof=admin organizer support customer"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type UserResponse struct {
+	Id        string    `json:"id"`
+	Email     string    `json:"email"`
+	Locale    string    `json:"locale"`
+	Status    string    `json:"status"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TokenResponse struct {
+	AccessToken           string       `json:"access_token"`
+	TokenType             string       `json:"token_type"`
+	ExpiresAt             time.Time    `json:"expires_at"`
+	RefreshToken          string       `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time    `json:"refresh_token_expires_at"`
+	User                  UserResponse `json:"user"`
+}
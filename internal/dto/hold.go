@@ -0,0 +1,22 @@
+package dto
+
+import "time"
+
+type HoldCreateRequest struct {
+	TicketId string `json:"-"`
+	UserId   string `json:"-"`
+	Quantity int    `json:"quantity" validate:"gt=0"`
+	Minutes  int    `json:"minutes" validate:"gte=0"`
+}
+
+type HoldResponse struct {
+	Id         string    `json:"id"`
+	TicketId   string    `json:"ticket_id"`
+	UserId     string    `json:"user_id"`
+	Quantity   int       `json:"quantity"`
+	UnitPrice  int64     `json:"unit_price"`
+	Currency   string    `json:"currency"`
+	Status     string    `json:"status"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	PurchaseId string    `json:"purchase_id,omitempty"`
+}
@@ -0,0 +1,41 @@
+package dto
+
+import "time"
+
+type CartLineCreateRequest struct {
+	TicketId string `json:"ticket_id" validate:"required"`
+	Quantity int    `json:"quantity" validate:"gt=0"`
+	UserId   string `json:"-"`
+}
+
+type CartLineUpdateRequest struct {
+	Quantity int    `json:"quantity" validate:"gt=0"`
+	UserId   string `json:"-"`
+}
+
+type OrderLineResponse struct {
+	Id        string `json:"id"`
+	TicketId  string `json:"ticket_id"`
+	Quantity  int    `json:"quantity"`
+	UnitPrice int64  `json:"unit_price"`
+	Total     int64  `json:"total"`
+	Currency  string `json:"currency"`
+	// Status is "cart" until checkout, "completed" once the line is purchased and "rejected" for lines that failed
+	// a checkout, Error tells why
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+
+	Ticket *TicketSummary `json:"ticket,omitempty"`
+}
+
+type OrderResponse struct {
+	Id           string              `json:"id,omitempty"`
+	UserId       string              `json:"user_id"`
+	Status       string              `json:"status"`
+	Total        int64               `json:"total"`
+	Currency     string              `json:"currency,omitempty"`
+	Lines        []OrderLineResponse `json:"lines"`
+	CheckedOutAt *time.Time          `json:"checked_out_at,omitempty"`
+	CreatedAt    time.Time           `json:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+}
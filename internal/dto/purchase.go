@@ -0,0 +1,53 @@
+package dto
+
+import "time"
+
+type PurchaseCancelRequest struct {
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+type PurchaseRefundRequest struct {
+	Quantity int    `json:"quantity" validate:"gt=0"`
+	Reason   string `json:"reason" validate:"max=500"`
+}
+
+type PurchaseListRequest struct {
+	UserId   string `query:"-"`
+	TicketId string `query:"-"`
+	Cursor   string `query:"cursor"`
+	Limit    int    `query:"limit" validate:"gte=0,lte=100"`
+	Sort     string `query:"sort" validate:"omitempty,oneof=created_at quantity"`
+	Order    string `query:"order" validate:"omitempty,oneof=asc desc"`
+	From     string `query:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	To       string `query:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+}
+
+type TicketSummary struct {
+	Id            string     `json:"id"`
+	Name          string     `json:"name"`
+	Description   string     `json:"desc"`
+	EventStartsAt *time.Time `json:"event_starts_at"`
+}
+
+type PurchaseResponse struct {
+	Id               string    `json:"id"`
+	TicketId         string    `json:"ticket_id"`
+	UserId           string    `json:"user_id"`
+	OrderId          string    `json:"order_id,omitempty"`
+	Quantity         int       `json:"quantity"`
+	RefundedQuantity int       `json:"refunded_quantity"`
+	UnitPrice        int64     `json:"unit_price"`
+	Total            int64     `json:"total"`
+	Currency         string    `json:"currency"`
+	CancelReason     string    `json:"cancel_reason,omitempty"`
+	IsActive         bool      `json:"is_active"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+
+	Ticket *TicketSummary `json:"ticket,omitempty"`
+}
+
+type PurchaseListResponse struct {
+	Items      []PurchaseResponse `json:"items"`
+	Pagination PageInfo           `json:"pagination"`
+}
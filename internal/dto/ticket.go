@@ -1,9 +1,38 @@
 package dto
 
+import "time"
+
 type TicketCreateRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"desc"`
-	Allocation  int    `json:"allocation"`
+	UserId      string `json:"-"`
+	Name        string `json:"name" validate:"required,max=255"`
+	Description string `json:"desc" validate:"max=2000"`
+	Allocation  int    `json:"allocation" validate:"gte=0"`
+	// Price is in the minor unit of the currency, e.g. cents. The currency defaults to USD.
+	Price             int64      `json:"price" validate:"gte=0"`
+	Currency          string     `json:"currency" validate:"omitempty,iso4217"`
+	EventStartsAt     *time.Time `json:"event_starts_at"`
+	RefundCutoffHours int        `json:"refund_cutoff_hours" validate:"gte=0"`
+	SaleStartsAt      *time.Time `json:"sale_starts_at"`
+	SaleEndsAt        *time.Time `json:"sale_ends_at"`
+	MaxPerOrder       int        `json:"max_per_order" validate:"gte=0"`
+	MaxPerUser        int        `json:"max_per_user" validate:"gte=0"`
+	WaitingRoom       bool       `json:"waiting_room"`
+}
+
+// TicketUpdateRequest changes only the fields that are present.
+// Allocation is the new total allocation, including tickets that are already sold or held.
+type TicketUpdateRequest struct {
+	Name          *string    `json:"name" validate:"omitnil,min=1,max=255"`
+	Description   *string    `json:"desc" validate:"omitnil,max=2000"`
+	Allocation    *int       `json:"allocation" validate:"omitnil,gte=0"`
+	Price         *int64     `json:"price" validate:"omitnil,gte=0"`
+	Currency      *string    `json:"currency" validate:"omitnil,iso4217"`
+	EventStartsAt *time.Time `json:"event_starts_at"`
+	SaleStartsAt  *time.Time `json:"sale_starts_at"`
+	SaleEndsAt    *time.Time `json:"sale_ends_at"`
+	MaxPerOrder   *int       `json:"max_per_order" validate:"omitnil,gte=0"`
+	MaxPerUser    *int       `json:"max_per_user" validate:"omitnil,gte=0"`
+	WaitingRoom   *bool      `json:"waiting_room"`
 }
 
 type TicketResponse struct {
@@ -11,10 +40,53 @@ type TicketResponse struct {
 	Name        string `json:"name"`
 	Description string `json:"desc"`
 	Allocation  int    `json:"allocation"`
+	Held        int    `json:"held"`
+	Available   int    `json:"available"`
+	OwnerId     string `json:"owner_id"`
+
+	// Price is in the minor unit of the currency, FormattedPrice is the price in the language of the request
+	Price          int64  `json:"price"`
+	Currency       string `json:"currency"`
+	FormattedPrice string `json:"formatted_price"`
+
+	EventStartsAt     *time.Time `json:"event_starts_at"`
+	RefundCutoffHours int        `json:"refund_cutoff_hours"`
+	SaleStartsAt      *time.Time `json:"sale_starts_at"`
+	SaleEndsAt        *time.Time `json:"sale_ends_at"`
+	// SaleStatus is upcoming, on_sale or ended
+	SaleStatus string `json:"sale_status"`
+	// MaxPerOrder and MaxPerUser are 0 when purchases are not limited
+	MaxPerOrder int  `json:"max_per_order"`
+	MaxPerUser  int  `json:"max_per_user"`
+	WaitingRoom bool `json:"waiting_room"`
+	IsActive    bool `json:"is_active"`
+}
+
+type TicketListRequest struct {
+	Name          string `query:"name" validate:"max=255"`
+	MinAllocation *int   `query:"min_allocation" validate:"omitnil,gte=0"`
+	MaxAllocation *int   `query:"max_allocation" validate:"omitnil,gte=0"`
+	Active        *bool  `query:"active"`
+	From          string `query:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	To            string `query:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Cursor        string `query:"cursor"`
+	Limit         int    `query:"limit" validate:"gte=0,lte=100"`
+	Sort          string `query:"sort" validate:"omitempty,oneof=created_at name allocation"`
+	Order         string `query:"order" validate:"omitempty,oneof=asc desc"`
+	// Sale lists the tickets that are upcoming, on sale or ended now
+	Sale string `query:"sale" validate:"omitempty,oneof=upcoming on_sale ended"`
+
+	// IncludeInactive lists soft deleted tickets too
+	IncludeInactive bool `query:"include_inactive"`
+}
+
+type TicketListResponse struct {
+	Items      []TicketResponse `json:"items"`
+	Pagination PageInfo         `json:"pagination"`
 }
 
 type TicketPurchaseRequest struct {
 	TicketId string `json:"-"`
-	UserId   string `json:"user_id"`
-	Quantity int    `json:"quantity"`
+	UserId   string `json:"-"`
+	Quantity int    `json:"quantity" validate:"gt=0"`
 }
@@ -0,0 +1,14 @@
+package dto
+
+import "time"
+
+type QueueTokenResponse struct {
+	Token    string `json:"token"`
+	TicketId string `json:"ticket_id"`
+	// Status is waiting, admitted, expired or used
+	Status   string `json:"status"`
+	Position int    `json:"position"`
+	// EstimatedWaitSeconds is how long until a waiting user is admitted at the current rate
+	EstimatedWaitSeconds int        `json:"estimated_wait_seconds"`
+	AdmittedUntil        *time.Time `json:"admitted_until"`
+}
@@ -0,0 +1,49 @@
+package dto
+
+import "time"
+
+type WebhookSubscriptionCreateRequest struct {
+	Url    string   `json:"url" validate:"required,url,max=2048"`
+	Events []string `json:"events" validate:"required,min=1,dive,required"`
+	UserId string   `json:"-"`
+}
+
+type WebhookSubscriptionResponse struct {
+	Id     string   `json:"id"`
+	Url    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret signs the deliveries, it is only returned when the subscription is created
+	Secret    string    `json:"secret,omitempty"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookSubscriptionListResponse struct {
+	Items []WebhookSubscriptionResponse `json:"items"`
+}
+
+type WebhookDeliveryListRequest struct {
+	SubscriptionId string `query:"subscription_id"`
+	Status         string `query:"status" validate:"omitempty,oneof=pending delivered dead"`
+	Cursor         string `query:"cursor"`
+	Limit          int    `query:"limit" validate:"gte=0,lte=100"`
+}
+
+type WebhookDeliveryResponse struct {
+	Id             string     `json:"id"`
+	SubscriptionId string     `json:"subscription_id"`
+	EventId        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastError      string     `json:"last_error,omitempty"`
+	LastStatus     int        `json:"last_status,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type WebhookDeliveryListResponse struct {
+	Items      []WebhookDeliveryResponse `json:"items"`
+	Pagination PageInfo                  `json:"pagination"`
+}
@@ -0,0 +1,82 @@
+// Package events carries the domain events of tickets from the outbox to the services that react to them
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Event types
+const (
+	TypeTicketCreated     = "TicketCreated"
+	TypeTicketUpdated     = "TicketUpdated"
+	TypeTicketPurchased   = "TicketPurchased"
+	TypePurchaseCancelled = "PurchaseCancelled"
+	TypeAllocationChanged = "AllocationChanged"
+	TypeSoldOut           = "SoldOut"
+)
+
+// Event is a domain event of a ticket. Events of the same ticket are published in the order they happened.
+type Event struct {
+	// Id is unique per event. Events can be published more than once, consumers drop the ids they have seen.
+	Id         string          `json:"id"`
+	Type       string          `json:"type"`
+	TicketId   string          `json:"ticket_id"`
+	Payload    json.RawMessage `json:"payload"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
+
+// TicketCreated is the payload of a new ticket
+type TicketCreated struct {
+	TicketId   string `json:"ticket_id"`
+	Name       string `json:"name"`
+	OwnerId    string `json:"owner_id"`
+	Allocation int    `json:"allocation"`
+	Price      int64  `json:"price"`
+	Currency   string `json:"currency"`
+}
+
+// TicketUpdated is the payload of a ticket whose details were edited, or that was deleted or restored
+type TicketUpdated struct {
+	TicketId string `json:"ticket_id"`
+	IsActive bool   `json:"is_active"`
+}
+
+// TicketPurchased is the payload of a purchase written to the database
+type TicketPurchased struct {
+	TicketId   string `json:"ticket_id"`
+	PurchaseId string `json:"purchase_id"`
+	UserId     string `json:"user_id"`
+	Quantity   int    `json:"quantity"`
+	UnitPrice  int64  `json:"unit_price"`
+	Currency   string `json:"currency"`
+}
+
+// PurchaseCancelled is the payload of a purchase whose last remaining quantity was refunded
+type PurchaseCancelled struct {
+	TicketId   string `json:"ticket_id"`
+	PurchaseId string `json:"purchase_id"`
+	UserId     string `json:"user_id"`
+	Reason     string `json:"reason"`
+}
+
+// AllocationChanged is the payload of a change of the allocation left for sale. Delta is negative when allocation
+// was taken by a purchase or a hold.
+type AllocationChanged struct {
+	TicketId   string `json:"ticket_id"`
+	Allocation int    `json:"allocation"`
+	Delta      int    `json:"delta"`
+}
+
+// SoldOut is the payload of a ticket whose allocation was taken to zero
+type SoldOut struct {
+	TicketId string `json:"ticket_id"`
+}
+
+// Bus delivers events to their consumers
+type Bus interface {
+	// Publish delivers the event. Events are published one at a time in order, an event is only published after the
+	// previous one succeeded.
+	Publish(ctx context.Context, event Event) error
+}
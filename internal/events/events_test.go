@@ -0,0 +1,100 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var testEvent = Event{
+	Id:         "event-1",
+	Type:       TypeSoldOut,
+	TicketId:   "ticket-1",
+	Payload:    json.RawMessage(`{"ticket_id":"ticket-1"}`),
+	OccurredAt: time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC),
+}
+
+func TestMemoryBus_Publish(t *testing.T) {
+	bus := NewMemoryBus()
+
+	var handled []string
+	bus.Subscribe(func(ctx context.Context, event Event) error {
+		handled = append(handled, event.Id)
+		return nil
+	})
+
+	assert.NoError(t, bus.Publish(context.Background(), testEvent))
+	assert.Equal(t, []string{"event-1"}, handled)
+}
+
+func TestMemoryBus_Publish_Handler_Fails(t *testing.T) {
+	bus := NewMemoryBus()
+	failure := errors.New("handler failed")
+	called := false
+	bus.Subscribe(func(ctx context.Context, event Event) error {
+		return failure
+	})
+	bus.Subscribe(func(ctx context.Context, event Event) error {
+		called = true
+		return nil
+	})
+
+	assert.ErrorIs(t, bus.Publish(context.Background(), testEvent), failure)
+	assert.False(t, called)
+}
+
+func TestMultiBus_Publish(t *testing.T) {
+	first := NewMemoryBus()
+	second := NewMemoryBus()
+	failure := errors.New("bus is down")
+
+	var handled []string
+	first.Subscribe(func(ctx context.Context, event Event) error {
+		if event.Id == "event-2" {
+			return failure
+		}
+		return nil
+	})
+	second.Subscribe(func(ctx context.Context, event Event) error {
+		handled = append(handled, event.Id)
+		return nil
+	})
+
+	bus := NewMultiBus(first, second)
+	failing := testEvent
+	failing.Id = "event-2"
+	assert.NoError(t, bus.Publish(context.Background(), testEvent))
+	assert.ErrorIs(t, bus.Publish(context.Background(), failing), failure)
+	assert.Equal(t, []string{"event-1"}, handled)
+}
+
+func TestRedisBus_Publish(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	bus := NewRedisBus(client, DefaultStream, 1000)
+	second := testEvent
+	second.Id = "event-2"
+	assert.NoError(t, bus.Publish(context.Background(), testEvent))
+	assert.NoError(t, bus.Publish(context.Background(), second))
+
+	entries, err := client.XRange(context.Background(), DefaultStream, "-", "+").Result()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, map[string]interface{}{
+		"id":          "event-1",
+		"type":        TypeSoldOut,
+		"ticket_id":   "ticket-1",
+		"payload":     `{"ticket_id":"ticket-1"}`,
+		"occurred_at": "2020-01-01T12:00:00Z",
+	}, entries[0].Values)
+	assert.Equal(t, "event-2", entries[1].Values["id"])
+}
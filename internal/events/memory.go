@@ -0,0 +1,57 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// Handler consumes events of a MemoryBus
+type Handler func(ctx context.Context, event Event) error
+
+// MemoryBus is an in-process bus that hands every event to its handlers. It keeps no events of its own.
+type MemoryBus struct {
+	mu       sync.Mutex
+	handlers []Handler
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{}
+}
+
+// Subscribe adds a handler that is called with every event published afterwards
+func (b *MemoryBus) Subscribe(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Publish calls the handlers in the order they subscribed. A failing handler fails the event, which is published
+// to every handler again when it is retried.
+func (b *MemoryBus) Publish(ctx context.Context, event Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, handler := range b.handlers {
+		if err := handler(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type multiBus []Bus
+
+// NewMultiBus returns a bus that publishes every event to the given buses in order. An event fails at the first bus
+// that fails it and is published to every bus again when it is retried.
+func NewMultiBus(buses ...Bus) Bus {
+	return multiBus(buses)
+}
+
+func (b multiBus) Publish(ctx context.Context, event Event) error {
+	for _, bus := range b {
+		if err := bus.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
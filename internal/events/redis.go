@@ -0,0 +1,41 @@
+package events
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// DefaultStream is the Redis stream ticket events are added to
+const DefaultStream = "events:tickets"
+
+type redisBus struct {
+	client redis.UniversalClient
+	stream string
+	maxLen int64
+}
+
+// NewRedisBus returns a bus that adds events to a Redis stream. All tickets share the stream, so consumers read the
+// events of a ticket in order. The stream is trimmed to about maxLen entries, zero keeps every entry.
+func NewRedisBus(client redis.UniversalClient, stream string, maxLen int64) Bus {
+	return &redisBus{
+		client: client,
+		stream: stream,
+		maxLen: maxLen,
+	}
+}
+
+func (b *redisBus) Publish(ctx context.Context, event Event) error {
+	return b.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: b.stream,
+		MaxLen: b.maxLen,
+		Approx: b.maxLen > 0,
+		Values: map[string]interface{}{
+			"id":          event.Id,
+			"type":        event.Type,
+			"ticket_id":   event.TicketId,
+			"payload":     string(event.Payload),
+			"occurred_at": event.OccurredAt.UTC().Format(time.RFC3339Nano),
+		},
+	}).Err()
+}
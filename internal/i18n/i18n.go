@@ -3,7 +3,7 @@ package i18n
 import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
-	"ticket-purchase/cmd/config"
+	"ticket-purchase/pkg/enum"
 )
 
 type localize struct {
@@ -53,7 +53,7 @@ func (b *localizeBuilder) BuildWithContext(c *fiber.Ctx) string {
 		pluralCount = b.localize.pluralCount
 	}
 
-	lang := config.GetLanguage(c)
+	lang := GetLanguage(c)
 	loc := i18n.NewLocalizer(bundle, lang)
 
 	message := loc.MustLocalize(&i18n.LocalizeConfig{
@@ -98,7 +98,7 @@ func (b *localizeBuilder) BuildWithLanguage(lang string) string {
 // CreateMsg is a helper function for creating message with context
 func CreateMsg(ctx *fiber.Ctx, messageId string, templateData ...map[string]string) string {
 
-	loc := i18n.NewLocalizer(bundle, config.GetLanguage(ctx))
+	loc := i18n.NewLocalizer(bundle, GetLanguage(ctx))
 	msg := loc.MustLocalize(&i18n.LocalizeConfig{
 		MessageID: messageId,
 	})
@@ -112,3 +112,8 @@ func CreateMsg(ctx *fiber.Ctx, messageId string, templateData ...map[string]stri
 
 	return msg
 }
+
+// GetLanguage returns the language the client asked for with the Accept-Language header
+func GetLanguage(ctx *fiber.Ctx) string {
+	return ctx.Get("Accept-Language", enum.DefaultLanguage)
+}
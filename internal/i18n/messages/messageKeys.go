@@ -1,12 +1,69 @@
 package messages
 
 var (
-	Success                = "success"
-	UnexpectedError        = "unexpected_error"
-	BadRequest             = "bad_request"
-	NotFound               = "not_found"
-	ErrorTicketCreate      = "error_ticket_create"
-	ErrorTicketUpdate      = "error_ticket_update"
-	ErrorPurchase          = "error_purchase"
-	ErrorTicketAllocations = "error_ticket_allocations"
+	Success                  = "success"
+	UnexpectedError          = "unexpected_error"
+	BadRequest               = "bad_request"
+	NotFound                 = "not_found"
+	ErrorTicketCreate        = "error_ticket_create"
+	ErrorTicketUpdate        = "error_ticket_update"
+	ErrorPurchase            = "error_purchase"
+	ErrorTicketAllocations   = "error_ticket_allocations"
+	InvalidIdempotencyKey    = "invalid_idempotency_key"
+	IdempotencyKeyReused     = "idempotency_key_reused"
+	IdempotencyKeyInProgress = "idempotency_key_in_progress"
+	ErrorHoldCreate          = "error_hold_create"
+	HoldNotActive            = "hold_not_active"
+	HoldExpired              = "hold_expired"
+	PurchaseNotActive        = "purchase_not_active"
+	RefundExceedsQuantity    = "refund_exceeds_quantity"
+	RefundWindowClosed       = "refund_window_closed"
+	ErrorPurchaseRefund      = "error_purchase_refund"
+	InvalidCursor            = "invalid_cursor"
+	TicketInactive           = "ticket_inactive"
+	AllocationBelowSold      = "allocation_below_sold"
+	ValidationFailed         = "validation_failed"
+	ValidationInvalid        = "validation_invalid"
+	ValidationRequired       = "validation_required"
+	ValidationGt             = "validation_gt"
+	ValidationGte            = "validation_gte"
+	ValidationLt             = "validation_lt"
+	ValidationLte            = "validation_lte"
+	ValidationMin            = "validation_min"
+	ValidationMax            = "validation_max"
+	ValidationOneOf          = "validation_oneof"
+	ValidationDatetime       = "validation_datetime"
+	ValidationAtLeastOne     = "validation_at_least_one"
+	Unauthorized             = "unauthorized"
+	Forbidden                = "forbidden"
+	EmailTaken               = "email_taken"
+	InvalidCredentials       = "invalid_credentials"
+	UserDisabled             = "user_disabled"
+	RefreshTokenInvalid      = "refresh_token_invalid"
+	ValidationEmail          = "validation_email"
+	TooManyRequests          = "too_many_requests"
+	WaitingRoomDisabled      = "waiting_room_disabled"
+	QueueTokenRequired       = "queue_token_required"
+	QueueNotAdmitted         = "queue_not_admitted"
+	SaleNotStarted           = "sale_not_started"
+	SaleEnded                = "sale_ended"
+	SaleWindowInvalid        = "sale_window_invalid"
+	MaxPerOrderExceeded      = "max_per_order_exceeded"
+	MaxPerUserExceeded       = "max_per_user_exceeded"
+	PaymentDeclined          = "payment_declined"
+	PaymentUnavailable       = "payment_unavailable"
+	ErrorPaymentRefund       = "error_payment_refund"
+	InvalidWebhookSignature  = "invalid_webhook_signature"
+	StaleWebhook             = "stale_webhook"
+	WebhookEventUnknown      = "webhook_event_unknown"
+	WebhookUrlInvalid        = "webhook_url_invalid"
+	WebhookDeliveryPending   = "webhook_delivery_pending"
+	CartEmpty                = "cart_empty"
+	CartChanged              = "cart_changed"
+	CheckoutRejected         = "checkout_rejected"
+	ErrorOrderCheckout       = "error_order_checkout"
+	OrderCurrencyMismatch    = "order_currency_mismatch"
+	TicketWaitingRoom        = "ticket_waiting_room"
+	QueueTokenUsed           = "queue_token_used"
+	WebhookUrlNotPublic      = "webhook_url_not_public"
 )
@@ -0,0 +1,25 @@
+package i18n
+
+import (
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+	"ticket-purchase/pkg/money"
+)
+
+var moneyMatcher = language.NewMatcher([]language.Tag{language.English, language.Turkish})
+
+// FormatMoney formats the amount with the currency symbol and the separators of the language, e.g. "$ 1,234.50" in
+// English and "₺ 1.234,50" in Turkish. lang can be an Accept-Language header.
+func FormatMoney(lang string, amount money.Money) string {
+	tags, _, _ := language.ParseAcceptLanguage(lang)
+	tag, _, _ := moneyMatcher.Match(tags...)
+	printer := message.NewPrinter(tag)
+
+	unit, err := currency.ParseISO(amount.Currency)
+	if err != nil {
+		return printer.Sprintf("%v %s", number.Decimal(amount.Major(), number.Scale(amount.Digits())), amount.Currency)
+	}
+	return printer.Sprint(currency.NarrowSymbol(unit.Amount(amount.Major())))
+}
@@ -0,0 +1,28 @@
+package i18n
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"ticket-purchase/pkg/money"
+)
+
+func TestFormatMoney(t *testing.T) {
+	tests := []struct {
+		lang   string
+		amount money.Money
+		want   string
+	}{
+		{lang: "en", amount: money.New(123450, "USD"), want: "$ 1,234.50"},
+		{lang: "tr-TR,tr;q=0.9", amount: money.New(123450, "TRY"), want: "₺ 1.234,50"},
+		{lang: "tr", amount: money.New(1500, "JPY"), want: "¥ 1.500"},
+		{lang: "de", amount: money.New(999, "EUR"), want: "€ 9.99"},
+		{lang: "", amount: money.New(0, "USD"), want: "$ 0.00"},
+		{lang: "en", amount: money.New(1000, "ZZZ"), want: "10.00 ZZZ"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.want, func(t *testing.T) {
+			assert.Equal(t, test.want, FormatMoney(test.lang, test.amount))
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package mail
+
+import (
+	"github.com/gofiber/fiber/v2/log"
+	"ticket-purchase/cmd/config"
+)
+
+// Message is an email with an HTML and a plain text body
+type Message struct {
+	To      []string
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Sender delivers an email synchronously
+type Sender interface {
+	Send(message Message) error
+}
+
+// NewSender returns an SMTP sender, or a sender that only logs the emails when no SMTP server is configured
+func NewSender(conf config.MailConfig) Sender {
+	if conf.Host == "" {
+		return logSender{}
+	}
+	return NewSMTPSender(conf)
+}
+
+// logSender writes the text body of the emails to the log, it is used in development
+type logSender struct{}
+
+func (logSender) Send(message Message) error {
+	log.Infof("Email to %v: %s\n%s", message.To, message.Subject, message.Text)
+	return nil
+}
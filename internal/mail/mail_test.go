@@ -0,0 +1,110 @@
+package mail
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"mime"
+	"mime/multipart"
+	netmail "net/mail"
+	"strings"
+	"testing"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/mail/mailtest"
+)
+
+func TestRender_Falls_Back_To_Default_Language(t *testing.T) {
+	message, err := Render(PurchaseConfirmationTemplate, "de", "user@example.com", PurchaseConfirmation{
+		PurchaseId: "purchase-1",
+		TicketName: "Concert",
+		Quantity:   2,
+	})
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	assert.Equal(t, []string{"user@example.com"}, message.To)
+	assert.Equal(t, "Your tickets for Concert", message.Subject)
+	assert.Contains(t, message.Text, "Quantity: 2")
+	assert.Contains(t, message.HTML, "purchase-1")
+}
+
+func TestRender_Escapes_HTML(t *testing.T) {
+	message, err := Render(PurchaseConfirmationTemplate, "en", "user@example.com", PurchaseConfirmation{
+		TicketName: "<script>alert(1)</script>",
+	})
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	assert.NotContains(t, message.HTML, "<script>")
+	assert.Contains(t, message.Text, "<script>")
+}
+
+func TestSMTPSender_Send(t *testing.T) {
+	server := mailtest.NewServer(t)
+	sender := NewSMTPSender(config.MailConfig{
+		Host:     server.Host(),
+		Port:     server.Port(),
+		Username: "tickets@example.com",
+		Password: "secret",
+	})
+
+	message, err := Render(PurchaseConfirmationTemplate, "tr", "user@example.com", PurchaseConfirmation{
+		PurchaseId: "purchase-1",
+		TicketName: "Gösteri",
+		Quantity:   2,
+	})
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	if err := sender.Send(message); err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	messages := server.Messages()
+	assert.Len(t, messages, 1)
+	assert.Contains(t, messages[0], "To: user@example.com")
+	assert.Contains(t, messages[0], "Subject: =?utf-8?q?G=C3=B6steri_biletleriniz?=")
+
+	// Both bodies are sent unchanged as alternatives, the text first
+	parsed, err := netmail.ReadMessage(strings.NewReader(messages[0]))
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	var contentTypes, bodies []string
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Expected error to be nil, got %v", err)
+		}
+		body, _ := io.ReadAll(part)
+		contentTypes = append(contentTypes, part.Header.Get("Content-Type"))
+		bodies = append(bodies, strings.ReplaceAll(string(body), "\r\n", "\n"))
+	}
+
+	assert.Equal(t, []string{"text/plain; charset=utf-8", "text/html; charset=utf-8"}, contentTypes)
+	assert.Equal(t, []string{message.Text, message.HTML}, bodies)
+	assert.Contains(t, bodies[1], "<strong>Gösteri</strong>")
+}
+
+func TestSMTPSender_Send_Transient_Failure(t *testing.T) {
+	server := mailtest.NewServer(t)
+	server.FailNext(1)
+	sender := NewSMTPSender(config.MailConfig{Host: server.Host(), Port: server.Port(), Username: "tickets@example.com"})
+
+	err := sender.Send(Message{To: []string{"user@example.com"}, Subject: "Hello", HTML: "<p>Hello</p>"})
+
+	assert.Error(t, err)
+	assert.Empty(t, server.Messages())
+}
@@ -0,0 +1,134 @@
+// Package mailtest provides a local SMTP server for tests
+package mailtest
+
+import (
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Server is a minimal SMTP server that accepts PLAIN authentication and keeps the received messages in memory
+type Server struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	messages []string
+	failures int
+	received chan struct{}
+}
+
+// NewServer starts a server on a random local port. It is closed when the test finishes.
+func NewServer(t *testing.T) *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	server := &Server{listener: listener, received: make(chan struct{}, 100)}
+	go server.serve()
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	return server
+}
+
+// Host returns the host the server listens on
+func (s *Server) Host() string {
+	host, _, _ := net.SplitHostPort(s.listener.Addr().String())
+	return host
+}
+
+// Port returns the port the server listens on
+func (s *Server) Port() string {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return port
+}
+
+// FailNext rejects the next n messages with a transient error
+func (s *Server) FailNext(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = n
+}
+
+// Messages returns the raw data of the accepted messages
+func (s *Server) Messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.messages...)
+}
+
+// Received is signalled every time a message is accepted
+func (s *Server) Received() <-chan struct{} {
+	return s.received
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	text := textproto.NewConn(conn)
+	_ = text.PrintfLine("220 localhost ESMTP")
+
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch command {
+		case "EHLO":
+			_ = text.PrintfLine("250-localhost")
+			_ = text.PrintfLine("250 AUTH PLAIN")
+		case "HELO":
+			_ = text.PrintfLine("250 localhost")
+		case "AUTH":
+			_ = text.PrintfLine("235 Authentication successful")
+		case "MAIL", "RCPT", "RSET", "NOOP":
+			_ = text.PrintfLine("250 OK")
+		case "DATA":
+			_ = text.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := io.ReadAll(text.DotReader())
+			if err != nil {
+				return
+			}
+			_ = text.PrintfLine(s.accept(string(data)))
+		case "QUIT":
+			_ = text.PrintfLine("221 Bye")
+			return
+		default:
+			_ = text.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+// accept stores the message unless a failure was requested and returns the reply
+func (s *Server) accept(data string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failures > 0 {
+		s.failures--
+		return "451 Try again later"
+	}
+
+	s.messages = append(s.messages, data)
+	select {
+	case s.received <- struct{}{}:
+	default:
+	}
+	return "250 OK"
+}
@@ -0,0 +1,81 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"ticket-purchase/cmd/config"
+	"time"
+)
+
+type smtpSender struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPSender returns a sender that delivers emails with PLAIN authentication through the configured SMTP server
+func NewSMTPSender(conf config.MailConfig) Sender {
+	return &smtpSender{
+		addr: net.JoinHostPort(conf.Host, conf.Port),
+		auth: smtp.PlainAuth("", conf.Username, conf.Password, conf.Host),
+		from: conf.Username,
+	}
+}
+
+func (s *smtpSender) Send(message Message) error {
+	body, err := buildMessage(s.from, message, time.Now())
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(s.addr, s.auth, s.from, message.To, body)
+}
+
+// buildMessage writes the email as multipart/alternative with the text body before the HTML body, so that clients
+// show the HTML when they can and the text otherwise. Both bodies are sent unchanged in quoted-printable.
+func buildMessage(from string, message Message, date time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(message.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", writer.Boundary())
+
+	for _, part := range []struct {
+		contentType string
+		body        string
+	}{
+		{contentType: "text/plain; charset=utf-8", body: message.Text},
+		{contentType: "text/html; charset=utf-8", body: message.HTML},
+	} {
+		partWriter, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		encoder := quotedprintable.NewWriter(partWriter)
+		if _, err := encoder.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
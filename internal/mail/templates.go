@@ -0,0 +1,60 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"io/fs"
+	texttemplate "text/template"
+	"ticket-purchase/pkg/enum"
+)
+
+// PurchaseConfirmationTemplate is sent after a ticket purchase
+const PurchaseConfirmationTemplate = "purchase_confirmation"
+
+// PurchaseConfirmation is the data of the purchase confirmation email
+type PurchaseConfirmation struct {
+	PurchaseId string
+	TicketName string
+	Quantity   int
+}
+
+//go:embed templates
+var templateFS embed.FS
+
+// Render builds an email from the "<name>.<locale>.html" and "<name>.<locale>.txt" templates.
+// The subject is the "subject" block of the text template. Unknown locales fall back to the default language.
+func Render(name string, locale string, to string, data any) (Message, error) {
+	base := "templates/" + name + "." + locale
+	if _, err := fs.Stat(templateFS, base+".txt"); err != nil {
+		base = "templates/" + name + "." + enum.DefaultLanguage
+	}
+
+	text, err := texttemplate.ParseFS(templateFS, base+".txt")
+	if err != nil {
+		return Message{}, err
+	}
+
+	html, err := htmltemplate.ParseFS(templateFS, base+".html")
+	if err != nil {
+		return Message{}, err
+	}
+
+	var subject, textBody, htmlBody bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := text.Execute(&textBody, data); err != nil {
+		return Message{}, err
+	}
+	if err := html.Execute(&htmlBody, data); err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		To:      []string{to},
+		Subject: subject.String(),
+		HTML:    htmlBody.String(),
+		Text:    textBody.String(),
+	}, nil
+}
@@ -0,0 +1,131 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ticket-purchase/internal/db/repositories (interfaces: HoldRepository)
+//
+// Generated by this command:
+//
+//	mockgen -destination=../../mocks/repositories/hold_repository_mock.go -package=repositories ticket-purchase/internal/db/repositories HoldRepository
+//
+
+// Package repositories is a generated GoMock package.
+package repositories
+
+import (
+	context "context"
+	reflect "reflect"
+	models "ticket-purchase/internal/db/models"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockHoldRepository is a mock of HoldRepository interface.
+type MockHoldRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockHoldRepositoryMockRecorder
+}
+
+// MockHoldRepositoryMockRecorder is the mock recorder for MockHoldRepository.
+type MockHoldRepositoryMockRecorder struct {
+	mock *MockHoldRepository
+}
+
+// NewMockHoldRepository creates a new mock instance.
+func NewMockHoldRepository(ctrl *gomock.Controller) *MockHoldRepository {
+	mock := &MockHoldRepository{ctrl: ctrl}
+	mock.recorder = &MockHoldRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHoldRepository) EXPECT() *MockHoldRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Confirm mocks base method.
+func (m *MockHoldRepository) Confirm(arg0 context.Context, arg1 string, arg2 *models.Purchase) (*models.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Confirm", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Confirm indicates an expected call of Confirm.
+func (mr *MockHoldRepositoryMockRecorder) Confirm(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Confirm", reflect.TypeOf((*MockHoldRepository)(nil).Confirm), arg0, arg1, arg2)
+}
+
+// CreateWithAllocation mocks base method.
+func (m *MockHoldRepository) CreateWithAllocation(arg0 context.Context, arg1 *models.Hold) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWithAllocation", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateWithAllocation indicates an expected call of CreateWithAllocation.
+func (mr *MockHoldRepositoryMockRecorder) CreateWithAllocation(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWithAllocation", reflect.TypeOf((*MockHoldRepository)(nil).CreateWithAllocation), arg0, arg1)
+}
+
+// FindById mocks base method.
+func (m *MockHoldRepository) FindById(arg0 context.Context, arg1 string) (*models.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindById", arg0, arg1)
+	ret0, _ := ret[0].(*models.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindById indicates an expected call of FindById.
+func (mr *MockHoldRepositoryMockRecorder) FindById(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindById", reflect.TypeOf((*MockHoldRepository)(nil).FindById), arg0, arg1)
+}
+
+// ReleaseExpired mocks base method.
+func (m *MockHoldRepository) ReleaseExpired(arg0 context.Context, arg1 time.Time, arg2 int) ([]models.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseExpired", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]models.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReleaseExpired indicates an expected call of ReleaseExpired.
+func (mr *MockHoldRepositoryMockRecorder) ReleaseExpired(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseExpired", reflect.TypeOf((*MockHoldRepository)(nil).ReleaseExpired), arg0, arg1, arg2)
+}
+
+// SumActiveQuantities mocks base method.
+func (m *MockHoldRepository) SumActiveQuantities(arg0 context.Context, arg1 []string) (map[string]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SumActiveQuantities", arg0, arg1)
+	ret0, _ := ret[0].(map[string]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SumActiveQuantities indicates an expected call of SumActiveQuantities.
+func (mr *MockHoldRepositoryMockRecorder) SumActiveQuantities(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SumActiveQuantities", reflect.TypeOf((*MockHoldRepository)(nil).SumActiveQuantities), arg0, arg1)
+}
+
+// SumActiveQuantity mocks base method.
+func (m *MockHoldRepository) SumActiveQuantity(arg0 context.Context, arg1 string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SumActiveQuantity", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SumActiveQuantity indicates an expected call of SumActiveQuantity.
+func (mr *MockHoldRepositoryMockRecorder) SumActiveQuantity(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SumActiveQuantity", reflect.TypeOf((*MockHoldRepository)(nil).SumActiveQuantity), arg0, arg1)
+}
@@ -0,0 +1,172 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ticket-purchase/internal/db/repositories (interfaces: InventoryRepository)
+//
+// Generated by this command:
+//
+//	mockgen -destination=../../mocks/repositories/inventory_repository_mock.go -package=repositories ticket-purchase/internal/db/repositories InventoryRepository
+//
+
+// Package repositories is a generated GoMock package.
+package repositories
+
+import (
+	context "context"
+	reflect "reflect"
+	repositories "ticket-purchase/internal/db/repositories"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockInventoryRepository is a mock of InventoryRepository interface.
+type MockInventoryRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockInventoryRepositoryMockRecorder
+}
+
+// MockInventoryRepositoryMockRecorder is the mock recorder for MockInventoryRepository.
+type MockInventoryRepositoryMockRecorder struct {
+	mock *MockInventoryRepository
+}
+
+// NewMockInventoryRepository creates a new mock instance.
+func NewMockInventoryRepository(ctrl *gomock.Controller) *MockInventoryRepository {
+	mock := &MockInventoryRepository{ctrl: ctrl}
+	mock.recorder = &MockInventoryRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInventoryRepository) EXPECT() *MockInventoryRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Adjust mocks base method.
+func (m *MockInventoryRepository) Adjust(arg0 context.Context, arg1 string, arg2 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Adjust", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Adjust indicates an expected call of Adjust.
+func (mr *MockInventoryRepositoryMockRecorder) Adjust(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Adjust", reflect.TypeOf((*MockInventoryRepository)(nil).Adjust), arg0, arg1, arg2)
+}
+
+// ClaimSales mocks base method.
+func (m *MockInventoryRepository) ClaimSales(arg0 context.Context, arg1 string, arg2 int, arg3, arg4 time.Duration) ([]repositories.InventorySale, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimSales", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].([]repositories.InventorySale)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimSales indicates an expected call of ClaimSales.
+func (mr *MockInventoryRepositoryMockRecorder) ClaimSales(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimSales", reflect.TypeOf((*MockInventoryRepository)(nil).ClaimSales), arg0, arg1, arg2, arg3, arg4)
+}
+
+// Load mocks base method.
+func (m *MockInventoryRepository) Load(arg0 context.Context, arg1 string, arg2 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Load", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Load indicates an expected call of Load.
+func (mr *MockInventoryRepositoryMockRecorder) Load(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Load", reflect.TypeOf((*MockInventoryRepository)(nil).Load), arg0, arg1, arg2)
+}
+
+// Persisted mocks base method.
+func (m *MockInventoryRepository) Persisted(arg0 context.Context, arg1 repositories.InventorySale) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Persisted", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Persisted indicates an expected call of Persisted.
+func (mr *MockInventoryRepositoryMockRecorder) Persisted(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Persisted", reflect.TypeOf((*MockInventoryRepository)(nil).Persisted), arg0, arg1)
+}
+
+// Rejected mocks base method.
+func (m *MockInventoryRepository) Rejected(arg0 context.Context, arg1 repositories.InventorySale) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rejected", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Rejected indicates an expected call of Rejected.
+func (mr *MockInventoryRepositoryMockRecorder) Rejected(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rejected", reflect.TypeOf((*MockInventoryRepository)(nil).Rejected), arg0, arg1)
+}
+
+// Reserve mocks base method.
+func (m *MockInventoryRepository) Reserve(arg0 context.Context, arg1 *repositories.InventorySale) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reserve", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Reserve indicates an expected call of Reserve.
+func (mr *MockInventoryRepositoryMockRecorder) Reserve(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reserve", reflect.TypeOf((*MockInventoryRepository)(nil).Reserve), arg0, arg1)
+}
+
+// Snapshot mocks base method.
+func (m *MockInventoryRepository) Snapshot(arg0 context.Context, arg1 string) (repositories.InventorySnapshot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Snapshot", arg0, arg1)
+	ret0, _ := ret[0].(repositories.InventorySnapshot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Snapshot indicates an expected call of Snapshot.
+func (mr *MockInventoryRepositoryMockRecorder) Snapshot(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Snapshot", reflect.TypeOf((*MockInventoryRepository)(nil).Snapshot), arg0, arg1)
+}
+
+// Take mocks base method.
+func (m *MockInventoryRepository) Take(arg0 context.Context, arg1 string, arg2 int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Take", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Take indicates an expected call of Take.
+func (mr *MockInventoryRepositoryMockRecorder) Take(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Take", reflect.TypeOf((*MockInventoryRepository)(nil).Take), arg0, arg1, arg2)
+}
+
+// TicketIds mocks base method.
+func (m *MockInventoryRepository) TicketIds(arg0 context.Context) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TicketIds", arg0)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TicketIds indicates an expected call of TicketIds.
+func (mr *MockInventoryRepositoryMockRecorder) TicketIds(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TicketIds", reflect.TypeOf((*MockInventoryRepository)(nil).TicketIds), arg0)
+}
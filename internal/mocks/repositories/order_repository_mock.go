@@ -0,0 +1,113 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ticket-purchase/internal/db/repositories (interfaces: OrderRepository)
+//
+// Generated by this command:
+//
+//	mockgen -destination=../../mocks/repositories/order_repository_mock.go -package=repositories ticket-purchase/internal/db/repositories OrderRepository
+//
+
+// Package repositories is a generated GoMock package.
+package repositories
+
+import (
+	context "context"
+	reflect "reflect"
+	models "ticket-purchase/internal/db/models"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockOrderRepository is a mock of OrderRepository interface.
+type MockOrderRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockOrderRepositoryMockRecorder
+}
+
+// MockOrderRepositoryMockRecorder is the mock recorder for MockOrderRepository.
+type MockOrderRepositoryMockRecorder struct {
+	mock *MockOrderRepository
+}
+
+// NewMockOrderRepository creates a new mock instance.
+func NewMockOrderRepository(ctrl *gomock.Controller) *MockOrderRepository {
+	mock := &MockOrderRepository{ctrl: ctrl}
+	mock.recorder = &MockOrderRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOrderRepository) EXPECT() *MockOrderRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Checkout mocks base method.
+func (m *MockOrderRepository) Checkout(arg0 context.Context, arg1 *models.Order) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Checkout", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Checkout indicates an expected call of Checkout.
+func (mr *MockOrderRepositoryMockRecorder) Checkout(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Checkout", reflect.TypeOf((*MockOrderRepository)(nil).Checkout), arg0, arg1)
+}
+
+// FindById mocks base method.
+func (m *MockOrderRepository) FindById(arg0 context.Context, arg1 string) (*models.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindById", arg0, arg1)
+	ret0, _ := ret[0].(*models.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindById indicates an expected call of FindById.
+func (mr *MockOrderRepositoryMockRecorder) FindById(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindById", reflect.TypeOf((*MockOrderRepository)(nil).FindById), arg0, arg1)
+}
+
+// FindCart mocks base method.
+func (m *MockOrderRepository) FindCart(arg0 context.Context, arg1 string) (*models.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindCart", arg0, arg1)
+	ret0, _ := ret[0].(*models.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindCart indicates an expected call of FindCart.
+func (mr *MockOrderRepositoryMockRecorder) FindCart(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindCart", reflect.TypeOf((*MockOrderRepository)(nil).FindCart), arg0, arg1)
+}
+
+// RemoveLine mocks base method.
+func (m *MockOrderRepository) RemoveLine(arg0 context.Context, arg1, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveLine", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveLine indicates an expected call of RemoveLine.
+func (mr *MockOrderRepositoryMockRecorder) RemoveLine(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveLine", reflect.TypeOf((*MockOrderRepository)(nil).RemoveLine), arg0, arg1, arg2)
+}
+
+// SaveLine mocks base method.
+func (m *MockOrderRepository) SaveLine(arg0 context.Context, arg1 *models.Purchase) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveLine", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveLine indicates an expected call of SaveLine.
+func (mr *MockOrderRepositoryMockRecorder) SaveLine(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveLine", reflect.TypeOf((*MockOrderRepository)(nil).SaveLine), arg0, arg1)
+}
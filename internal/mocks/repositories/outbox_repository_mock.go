@@ -0,0 +1,72 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ticket-purchase/internal/db/repositories (interfaces: OutboxRepository)
+//
+// Generated by this command:
+//
+//	mockgen -destination=../../mocks/repositories/outbox_repository_mock.go -package=repositories ticket-purchase/internal/db/repositories OutboxRepository
+//
+
+// Package repositories is a generated GoMock package.
+package repositories
+
+import (
+	context "context"
+	reflect "reflect"
+	models "ticket-purchase/internal/db/models"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockOutboxRepository is a mock of OutboxRepository interface.
+type MockOutboxRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockOutboxRepositoryMockRecorder
+}
+
+// MockOutboxRepositoryMockRecorder is the mock recorder for MockOutboxRepository.
+type MockOutboxRepositoryMockRecorder struct {
+	mock *MockOutboxRepository
+}
+
+// NewMockOutboxRepository creates a new mock instance.
+func NewMockOutboxRepository(ctrl *gomock.Controller) *MockOutboxRepository {
+	mock := &MockOutboxRepository{ctrl: ctrl}
+	mock.recorder = &MockOutboxRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOutboxRepository) EXPECT() *MockOutboxRepositoryMockRecorder {
+	return m.recorder
+}
+
+// DeletePublished mocks base method.
+func (m *MockOutboxRepository) DeletePublished(arg0 context.Context, arg1 time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePublished", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeletePublished indicates an expected call of DeletePublished.
+func (mr *MockOutboxRepositoryMockRecorder) DeletePublished(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePublished", reflect.TypeOf((*MockOutboxRepository)(nil).DeletePublished), arg0, arg1)
+}
+
+// Relay mocks base method.
+func (m *MockOutboxRepository) Relay(arg0 context.Context, arg1, arg2 int, arg3 time.Time, arg4 func(models.OutboxEvent) error) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Relay", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Relay indicates an expected call of Relay.
+func (mr *MockOutboxRepositoryMockRecorder) Relay(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Relay", reflect.TypeOf((*MockOutboxRepository)(nil).Relay), arg0, arg1, arg2, arg3, arg4)
+}
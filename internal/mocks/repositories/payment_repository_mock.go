@@ -0,0 +1,141 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ticket-purchase/internal/db/repositories (interfaces: PaymentRepository)
+//
+// Generated by this command:
+//
+//	mockgen -destination=../../mocks/repositories/payment_repository_mock.go -package=repositories ticket-purchase/internal/db/repositories PaymentRepository
+//
+
+// Package repositories is a generated GoMock package.
+package repositories
+
+import (
+	context "context"
+	reflect "reflect"
+	models "ticket-purchase/internal/db/models"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockPaymentRepository is a mock of PaymentRepository interface.
+type MockPaymentRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockPaymentRepositoryMockRecorder
+}
+
+// MockPaymentRepositoryMockRecorder is the mock recorder for MockPaymentRepository.
+type MockPaymentRepositoryMockRecorder struct {
+	mock *MockPaymentRepository
+}
+
+// NewMockPaymentRepository creates a new mock instance.
+func NewMockPaymentRepository(ctrl *gomock.Controller) *MockPaymentRepository {
+	mock := &MockPaymentRepository{ctrl: ctrl}
+	mock.recorder = &MockPaymentRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPaymentRepository) EXPECT() *MockPaymentRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AddRefund mocks base method.
+func (m *MockPaymentRepository) AddRefund(arg0 context.Context, arg1 *models.Payment, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddRefund", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddRefund indicates an expected call of AddRefund.
+func (mr *MockPaymentRepositoryMockRecorder) AddRefund(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddRefund", reflect.TypeOf((*MockPaymentRepository)(nil).AddRefund), arg0, arg1, arg2)
+}
+
+// Create mocks base method.
+func (m *MockPaymentRepository) Create(arg0 context.Context, arg1 *models.Payment) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockPaymentRepositoryMockRecorder) Create(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockPaymentRepository)(nil).Create), arg0, arg1)
+}
+
+// FindById mocks base method.
+func (m *MockPaymentRepository) FindById(arg0 context.Context, arg1 string) (*models.Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindById", arg0, arg1)
+	ret0, _ := ret[0].(*models.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindById indicates an expected call of FindById.
+func (mr *MockPaymentRepositoryMockRecorder) FindById(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindById", reflect.TypeOf((*MockPaymentRepository)(nil).FindById), arg0, arg1)
+}
+
+// FindByPurchaseId mocks base method.
+func (m *MockPaymentRepository) FindByPurchaseId(arg0 context.Context, arg1 string) (*models.Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByPurchaseId", arg0, arg1)
+	ret0, _ := ret[0].(*models.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByPurchaseId indicates an expected call of FindByPurchaseId.
+func (mr *MockPaymentRepositoryMockRecorder) FindByPurchaseId(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByPurchaseId", reflect.TypeOf((*MockPaymentRepository)(nil).FindByPurchaseId), arg0, arg1)
+}
+
+// ReleaseRefund mocks base method.
+func (m *MockPaymentRepository) ReleaseRefund(arg0 context.Context, arg1 *models.Payment, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseRefund", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseRefund indicates an expected call of ReleaseRefund.
+func (mr *MockPaymentRepositoryMockRecorder) ReleaseRefund(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseRefund", reflect.TypeOf((*MockPaymentRepository)(nil).ReleaseRefund), arg0, arg1, arg2)
+}
+
+// SyncRefund mocks base method.
+func (m *MockPaymentRepository) SyncRefund(arg0 context.Context, arg1 *models.Payment, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SyncRefund", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SyncRefund indicates an expected call of SyncRefund.
+func (mr *MockPaymentRepositoryMockRecorder) SyncRefund(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SyncRefund", reflect.TypeOf((*MockPaymentRepository)(nil).SyncRefund), arg0, arg1, arg2)
+}
+
+// Transition mocks base method.
+func (m *MockPaymentRepository) Transition(arg0 context.Context, arg1 *models.Payment, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transition", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Transition indicates an expected call of Transition.
+func (mr *MockPaymentRepositoryMockRecorder) Transition(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transition", reflect.TypeOf((*MockPaymentRepository)(nil).Transition), arg0, arg1, arg2)
+}
@@ -13,6 +13,7 @@ import (
 	context "context"
 	reflect "reflect"
 	models "ticket-purchase/internal/db/models"
+	repositories "ticket-purchase/internal/db/repositories"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -40,6 +41,34 @@ func (m *MockPurchaseRepository) EXPECT() *MockPurchaseRepositoryMockRecorder {
 	return m.recorder
 }
 
+// CancelRefund mocks base method.
+func (m *MockPurchaseRepository) CancelRefund(arg0 context.Context, arg1 *models.Purchase, arg2 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelRefund", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelRefund indicates an expected call of CancelRefund.
+func (mr *MockPurchaseRepositoryMockRecorder) CancelRefund(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelRefund", reflect.TypeOf((*MockPurchaseRepository)(nil).CancelRefund), arg0, arg1, arg2)
+}
+
+// CompleteRefund mocks base method.
+func (m *MockPurchaseRepository) CompleteRefund(arg0 context.Context, arg1 *models.Purchase, arg2 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteRefund", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CompleteRefund indicates an expected call of CompleteRefund.
+func (mr *MockPurchaseRepositoryMockRecorder) CompleteRefund(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteRefund", reflect.TypeOf((*MockPurchaseRepository)(nil).CompleteRefund), arg0, arg1, arg2)
+}
+
 // Create mocks base method.
 func (m *MockPurchaseRepository) Create(arg0 context.Context, arg1 *models.Purchase) error {
 	m.ctrl.T.Helper()
@@ -53,3 +82,75 @@ func (mr *MockPurchaseRepositoryMockRecorder) Create(arg0, arg1 any) *gomock.Cal
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockPurchaseRepository)(nil).Create), arg0, arg1)
 }
+
+// CreateWithAllocation mocks base method.
+func (m *MockPurchaseRepository) CreateWithAllocation(arg0 context.Context, arg1 *models.Purchase) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWithAllocation", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateWithAllocation indicates an expected call of CreateWithAllocation.
+func (mr *MockPurchaseRepositoryMockRecorder) CreateWithAllocation(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWithAllocation", reflect.TypeOf((*MockPurchaseRepository)(nil).CreateWithAllocation), arg0, arg1)
+}
+
+// FindAll mocks base method.
+func (m *MockPurchaseRepository) FindAll(arg0 context.Context, arg1 repositories.PurchaseFilter) ([]models.Purchase, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAll", arg0, arg1)
+	ret0, _ := ret[0].([]models.Purchase)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAll indicates an expected call of FindAll.
+func (mr *MockPurchaseRepositoryMockRecorder) FindAll(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAll", reflect.TypeOf((*MockPurchaseRepository)(nil).FindAll), arg0, arg1)
+}
+
+// FindById mocks base method.
+func (m *MockPurchaseRepository) FindById(arg0 context.Context, arg1 string) (*models.Purchase, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindById", arg0, arg1)
+	ret0, _ := ret[0].(*models.Purchase)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindById indicates an expected call of FindById.
+func (mr *MockPurchaseRepositoryMockRecorder) FindById(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindById", reflect.TypeOf((*MockPurchaseRepository)(nil).FindById), arg0, arg1)
+}
+
+// Refund mocks base method.
+func (m *MockPurchaseRepository) Refund(arg0 context.Context, arg1 *models.Purchase, arg2 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Refund", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Refund indicates an expected call of Refund.
+func (mr *MockPurchaseRepositoryMockRecorder) Refund(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refund", reflect.TypeOf((*MockPurchaseRepository)(nil).Refund), arg0, arg1, arg2)
+}
+
+// ReserveRefund mocks base method.
+func (m *MockPurchaseRepository) ReserveRefund(arg0 context.Context, arg1 *models.Purchase, arg2 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReserveRefund", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReserveRefund indicates an expected call of ReserveRefund.
+func (mr *MockPurchaseRepositoryMockRecorder) ReserveRefund(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReserveRefund", reflect.TypeOf((*MockPurchaseRepository)(nil).ReserveRefund), arg0, arg1, arg2)
+}
@@ -13,6 +13,7 @@ import (
 	context "context"
 	reflect "reflect"
 	models "ticket-purchase/internal/db/models"
+	repositories "ticket-purchase/internal/db/repositories"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -56,18 +57,33 @@ func (mr *MockTicketRepositoryMockRecorder) Create(arg0, arg1 any) *gomock.Call
 }
 
 // FindAll mocks base method.
-func (m *MockTicketRepository) FindAll(arg0 context.Context) ([]models.Ticket, error) {
+func (m *MockTicketRepository) FindAll(arg0 context.Context, arg1 repositories.TicketFilter) ([]models.Ticket, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FindAll", arg0)
+	ret := m.ctrl.Call(m, "FindAll", arg0, arg1)
 	ret0, _ := ret[0].([]models.Ticket)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // FindAll indicates an expected call of FindAll.
-func (mr *MockTicketRepositoryMockRecorder) FindAll(arg0 any) *gomock.Call {
+func (mr *MockTicketRepositoryMockRecorder) FindAll(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAll", reflect.TypeOf((*MockTicketRepository)(nil).FindAll), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAll", reflect.TypeOf((*MockTicketRepository)(nil).FindAll), arg0, arg1)
+}
+
+// FindAllocation mocks base method.
+func (m *MockTicketRepository) FindAllocation(arg0 context.Context, arg1 string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAllocation", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAllocation indicates an expected call of FindAllocation.
+func (mr *MockTicketRepositoryMockRecorder) FindAllocation(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAllocation", reflect.TypeOf((*MockTicketRepository)(nil).FindAllocation), arg0, arg1)
 }
 
 // FindById mocks base method.
@@ -85,6 +101,35 @@ func (mr *MockTicketRepositoryMockRecorder) FindById(arg0, arg1 any) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindById", reflect.TypeOf((*MockTicketRepository)(nil).FindById), arg0, arg1)
 }
 
+// FindTotalAllocation mocks base method.
+func (m *MockTicketRepository) FindTotalAllocation(arg0 context.Context, arg1 string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindTotalAllocation", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindTotalAllocation indicates an expected call of FindTotalAllocation.
+func (mr *MockTicketRepositoryMockRecorder) FindTotalAllocation(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindTotalAllocation", reflect.TypeOf((*MockTicketRepository)(nil).FindTotalAllocation), arg0, arg1)
+}
+
+// SetActive mocks base method.
+func (m *MockTicketRepository) SetActive(arg0 context.Context, arg1 *models.Ticket) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetActive", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetActive indicates an expected call of SetActive.
+func (mr *MockTicketRepositoryMockRecorder) SetActive(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetActive", reflect.TypeOf((*MockTicketRepository)(nil).SetActive), arg0, arg1)
+}
+
 // Update mocks base method.
 func (m *MockTicketRepository) Update(arg0 context.Context, arg1 *models.Ticket) (*models.Ticket, error) {
 	m.ctrl.T.Helper()
@@ -99,3 +144,18 @@ func (mr *MockTicketRepositoryMockRecorder) Update(arg0, arg1 any) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockTicketRepository)(nil).Update), arg0, arg1)
 }
+
+// UpdateDetails mocks base method.
+func (m *MockTicketRepository) UpdateDetails(arg0 context.Context, arg1 *models.Ticket, arg2 *int) (*models.Ticket, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDetails", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.Ticket)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateDetails indicates an expected call of UpdateDetails.
+func (mr *MockTicketRepositoryMockRecorder) UpdateDetails(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDetails", reflect.TypeOf((*MockTicketRepository)(nil).UpdateDetails), arg0, arg1, arg2)
+}
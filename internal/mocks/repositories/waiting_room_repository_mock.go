@@ -0,0 +1,130 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ticket-purchase/internal/db/repositories (interfaces: WaitingRoomRepository)
+//
+// Generated by this command:
+//
+//	mockgen -destination=../../mocks/repositories/waiting_room_repository_mock.go -package=repositories ticket-purchase/internal/db/repositories WaitingRoomRepository
+//
+
+// Package repositories is a generated GoMock package.
+package repositories
+
+import (
+	context "context"
+	reflect "reflect"
+	repositories "ticket-purchase/internal/db/repositories"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockWaitingRoomRepository is a mock of WaitingRoomRepository interface.
+type MockWaitingRoomRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockWaitingRoomRepositoryMockRecorder
+}
+
+// MockWaitingRoomRepositoryMockRecorder is the mock recorder for MockWaitingRoomRepository.
+type MockWaitingRoomRepositoryMockRecorder struct {
+	mock *MockWaitingRoomRepository
+}
+
+// NewMockWaitingRoomRepository creates a new mock instance.
+func NewMockWaitingRoomRepository(ctrl *gomock.Controller) *MockWaitingRoomRepository {
+	mock := &MockWaitingRoomRepository{ctrl: ctrl}
+	mock.recorder = &MockWaitingRoomRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWaitingRoomRepository) EXPECT() *MockWaitingRoomRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Admit mocks base method.
+func (m *MockWaitingRoomRepository) Admit(arg0 context.Context, arg1 string, arg2 time.Time, arg3 int, arg4 time.Duration) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Admit", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Admit indicates an expected call of Admit.
+func (mr *MockWaitingRoomRepositoryMockRecorder) Admit(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Admit", reflect.TypeOf((*MockWaitingRoomRepository)(nil).Admit), arg0, arg1, arg2, arg3, arg4)
+}
+
+// Find mocks base method.
+func (m *MockWaitingRoomRepository) Find(arg0 context.Context, arg1, arg2 string, arg3 time.Time) (*repositories.QueueEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Find", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*repositories.QueueEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Find indicates an expected call of Find.
+func (mr *MockWaitingRoomRepositoryMockRecorder) Find(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockWaitingRoomRepository)(nil).Find), arg0, arg1, arg2, arg3)
+}
+
+// Join mocks base method.
+func (m *MockWaitingRoomRepository) Join(arg0 context.Context, arg1, arg2, arg3 string, arg4 time.Time) (*repositories.QueueEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Join", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(*repositories.QueueEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Join indicates an expected call of Join.
+func (mr *MockWaitingRoomRepositoryMockRecorder) Join(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Join", reflect.TypeOf((*MockWaitingRoomRepository)(nil).Join), arg0, arg1, arg2, arg3, arg4)
+}
+
+// Release mocks base method.
+func (m *MockWaitingRoomRepository) Release(arg0 context.Context, arg1, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockWaitingRoomRepositoryMockRecorder) Release(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockWaitingRoomRepository)(nil).Release), arg0, arg1, arg2)
+}
+
+// TicketIds mocks base method.
+func (m *MockWaitingRoomRepository) TicketIds(arg0 context.Context) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TicketIds", arg0)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TicketIds indicates an expected call of TicketIds.
+func (mr *MockWaitingRoomRepositoryMockRecorder) TicketIds(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TicketIds", reflect.TypeOf((*MockWaitingRoomRepository)(nil).TicketIds), arg0)
+}
+
+// Use mocks base method.
+func (m *MockWaitingRoomRepository) Use(arg0 context.Context, arg1, arg2 string, arg3 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Use", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Use indicates an expected call of Use.
+func (mr *MockWaitingRoomRepositoryMockRecorder) Use(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Use", reflect.TypeOf((*MockWaitingRoomRepository)(nil).Use), arg0, arg1, arg2, arg3)
+}
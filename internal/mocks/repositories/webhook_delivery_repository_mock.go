@@ -0,0 +1,116 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ticket-purchase/internal/db/repositories (interfaces: WebhookDeliveryRepository)
+//
+// Generated by this command:
+//
+//	mockgen -destination=../../mocks/repositories/webhook_delivery_repository_mock.go -package=repositories ticket-purchase/internal/db/repositories WebhookDeliveryRepository
+//
+
+// Package repositories is a generated GoMock package.
+package repositories
+
+import (
+	context "context"
+	reflect "reflect"
+	models "ticket-purchase/internal/db/models"
+	repositories "ticket-purchase/internal/db/repositories"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockWebhookDeliveryRepository is a mock of WebhookDeliveryRepository interface.
+type MockWebhookDeliveryRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockWebhookDeliveryRepositoryMockRecorder
+}
+
+// MockWebhookDeliveryRepositoryMockRecorder is the mock recorder for MockWebhookDeliveryRepository.
+type MockWebhookDeliveryRepositoryMockRecorder struct {
+	mock *MockWebhookDeliveryRepository
+}
+
+// NewMockWebhookDeliveryRepository creates a new mock instance.
+func NewMockWebhookDeliveryRepository(ctrl *gomock.Controller) *MockWebhookDeliveryRepository {
+	mock := &MockWebhookDeliveryRepository{ctrl: ctrl}
+	mock.recorder = &MockWebhookDeliveryRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWebhookDeliveryRepository) EXPECT() *MockWebhookDeliveryRepositoryMockRecorder {
+	return m.recorder
+}
+
+// ClaimDue mocks base method.
+func (m *MockWebhookDeliveryRepository) ClaimDue(arg0 context.Context, arg1 time.Time, arg2 time.Duration, arg3 int) ([]models.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimDue", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]models.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimDue indicates an expected call of ClaimDue.
+func (mr *MockWebhookDeliveryRepositoryMockRecorder) ClaimDue(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimDue", reflect.TypeOf((*MockWebhookDeliveryRepository)(nil).ClaimDue), arg0, arg1, arg2, arg3)
+}
+
+// CreateBatch mocks base method.
+func (m *MockWebhookDeliveryRepository) CreateBatch(arg0 context.Context, arg1 []models.WebhookDelivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateBatch", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateBatch indicates an expected call of CreateBatch.
+func (mr *MockWebhookDeliveryRepositoryMockRecorder) CreateBatch(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBatch", reflect.TypeOf((*MockWebhookDeliveryRepository)(nil).CreateBatch), arg0, arg1)
+}
+
+// FindAll mocks base method.
+func (m *MockWebhookDeliveryRepository) FindAll(arg0 context.Context, arg1 repositories.WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAll", arg0, arg1)
+	ret0, _ := ret[0].([]models.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAll indicates an expected call of FindAll.
+func (mr *MockWebhookDeliveryRepositoryMockRecorder) FindAll(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAll", reflect.TypeOf((*MockWebhookDeliveryRepository)(nil).FindAll), arg0, arg1)
+}
+
+// FindById mocks base method.
+func (m *MockWebhookDeliveryRepository) FindById(arg0 context.Context, arg1 string) (*models.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindById", arg0, arg1)
+	ret0, _ := ret[0].(*models.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindById indicates an expected call of FindById.
+func (mr *MockWebhookDeliveryRepositoryMockRecorder) FindById(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindById", reflect.TypeOf((*MockWebhookDeliveryRepository)(nil).FindById), arg0, arg1)
+}
+
+// Save mocks base method.
+func (m *MockWebhookDeliveryRepository) Save(arg0 context.Context, arg1 *models.WebhookDelivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockWebhookDeliveryRepositoryMockRecorder) Save(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockWebhookDeliveryRepository)(nil).Save), arg0, arg1)
+}
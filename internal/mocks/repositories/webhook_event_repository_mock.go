@@ -0,0 +1,70 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ticket-purchase/internal/db/repositories (interfaces: WebhookEventRepository)
+//
+// Generated by this command:
+//
+//	mockgen -destination=../../mocks/repositories/webhook_event_repository_mock.go -package=repositories ticket-purchase/internal/db/repositories WebhookEventRepository
+//
+
+// Package repositories is a generated GoMock package.
+package repositories
+
+import (
+	context "context"
+	reflect "reflect"
+	models "ticket-purchase/internal/db/models"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockWebhookEventRepository is a mock of WebhookEventRepository interface.
+type MockWebhookEventRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockWebhookEventRepositoryMockRecorder
+}
+
+// MockWebhookEventRepositoryMockRecorder is the mock recorder for MockWebhookEventRepository.
+type MockWebhookEventRepositoryMockRecorder struct {
+	mock *MockWebhookEventRepository
+}
+
+// NewMockWebhookEventRepository creates a new mock instance.
+func NewMockWebhookEventRepository(ctrl *gomock.Controller) *MockWebhookEventRepository {
+	mock := &MockWebhookEventRepository{ctrl: ctrl}
+	mock.recorder = &MockWebhookEventRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWebhookEventRepository) EXPECT() *MockWebhookEventRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateIfNotExists mocks base method.
+func (m *MockWebhookEventRepository) CreateIfNotExists(arg0 context.Context, arg1 *models.WebhookEvent) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateIfNotExists", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateIfNotExists indicates an expected call of CreateIfNotExists.
+func (mr *MockWebhookEventRepositoryMockRecorder) CreateIfNotExists(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIfNotExists", reflect.TypeOf((*MockWebhookEventRepository)(nil).CreateIfNotExists), arg0, arg1)
+}
+
+// Delete mocks base method.
+func (m *MockWebhookEventRepository) Delete(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockWebhookEventRepositoryMockRecorder) Delete(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockWebhookEventRepository)(nil).Delete), arg0, arg1)
+}
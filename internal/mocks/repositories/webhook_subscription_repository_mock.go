@@ -0,0 +1,99 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ticket-purchase/internal/db/repositories (interfaces: WebhookSubscriptionRepository)
+//
+// Generated by this command:
+//
+//	mockgen -destination=../../mocks/repositories/webhook_subscription_repository_mock.go -package=repositories ticket-purchase/internal/db/repositories WebhookSubscriptionRepository
+//
+
+// Package repositories is a generated GoMock package.
+package repositories
+
+import (
+	context "context"
+	reflect "reflect"
+	models "ticket-purchase/internal/db/models"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockWebhookSubscriptionRepository is a mock of WebhookSubscriptionRepository interface.
+type MockWebhookSubscriptionRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockWebhookSubscriptionRepositoryMockRecorder
+}
+
+// MockWebhookSubscriptionRepositoryMockRecorder is the mock recorder for MockWebhookSubscriptionRepository.
+type MockWebhookSubscriptionRepositoryMockRecorder struct {
+	mock *MockWebhookSubscriptionRepository
+}
+
+// NewMockWebhookSubscriptionRepository creates a new mock instance.
+func NewMockWebhookSubscriptionRepository(ctrl *gomock.Controller) *MockWebhookSubscriptionRepository {
+	mock := &MockWebhookSubscriptionRepository{ctrl: ctrl}
+	mock.recorder = &MockWebhookSubscriptionRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWebhookSubscriptionRepository) EXPECT() *MockWebhookSubscriptionRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockWebhookSubscriptionRepository) Create(arg0 context.Context, arg1 *models.WebhookSubscription) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockWebhookSubscriptionRepositoryMockRecorder) Create(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockWebhookSubscriptionRepository)(nil).Create), arg0, arg1)
+}
+
+// Delete mocks base method.
+func (m *MockWebhookSubscriptionRepository) Delete(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockWebhookSubscriptionRepositoryMockRecorder) Delete(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockWebhookSubscriptionRepository)(nil).Delete), arg0, arg1)
+}
+
+// FindAll mocks base method.
+func (m *MockWebhookSubscriptionRepository) FindAll(arg0 context.Context) ([]models.WebhookSubscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAll", arg0)
+	ret0, _ := ret[0].([]models.WebhookSubscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAll indicates an expected call of FindAll.
+func (mr *MockWebhookSubscriptionRepositoryMockRecorder) FindAll(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAll", reflect.TypeOf((*MockWebhookSubscriptionRepository)(nil).FindAll), arg0)
+}
+
+// FindById mocks base method.
+func (m *MockWebhookSubscriptionRepository) FindById(arg0 context.Context, arg1 string) (*models.WebhookSubscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindById", arg0, arg1)
+	ret0, _ := ret[0].(*models.WebhookSubscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindById indicates an expected call of FindById.
+func (mr *MockWebhookSubscriptionRepositoryMockRecorder) FindById(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindById", reflect.TypeOf((*MockWebhookSubscriptionRepository)(nil).FindById), arg0, arg1)
+}
@@ -0,0 +1,176 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"sync"
+	"ticket-purchase/pkg/money"
+)
+
+// Outcomes of authorizations with the fake provider
+const (
+	FakeSucceed = "succeed"
+	FakeDecline = "decline"
+	FakeTimeout = "timeout"
+)
+
+// FakeProvider is a provider for development and tests. Every authorization has the configured outcome,
+// and authorization ids are derived from the payment id, so results are the same on every run.
+// With FakeTimeout calls block until their context is done.
+type FakeProvider struct {
+	outcome        string
+	authorizations fakeStore
+}
+
+type fakeAuthorization struct {
+	Amount   money.Money `json:"amount"`
+	Captured int64       `json:"captured"`
+	Refunded int64       `json:"refunded"`
+	Voided   bool        `json:"voided"`
+}
+
+// fakeStore keeps the authorizations of the fake provider
+type fakeStore interface {
+	// create stores the authorization unless there is one with the id already
+	create(ctx context.Context, id string, authorization fakeAuthorization) error
+	// update stores the authorization changed by change, unless change fails. It returns ErrUnknownAuthorization when
+	// there is no authorization with the id.
+	update(ctx context.Context, id string, change func(authorization *fakeAuthorization) error) error
+}
+
+// NewFakeProvider returns a fake provider that keeps its authorizations in memory. A payment and its refund must
+// reach the same instance, so it only suits a single instance.
+func NewFakeProvider(outcome string) (*FakeProvider, error) {
+	return newFakeProvider(outcome, &memoryFakeStore{authorizations: make(map[string]*fakeAuthorization)})
+}
+
+// NewRedisFakeProvider returns a fake provider that keeps its authorizations in Redis, so that every instance of the
+// API sees them
+func NewRedisFakeProvider(outcome string, client redis.UniversalClient) (*FakeProvider, error) {
+	return newFakeProvider(outcome, &redisFakeStore{client: client})
+}
+
+func newFakeProvider(outcome string, authorizations fakeStore) (*FakeProvider, error) {
+	switch outcome {
+	case "":
+		outcome = FakeSucceed
+	case FakeSucceed, FakeDecline, FakeTimeout:
+	default:
+		return nil, fmt.Errorf("unknown fake payment outcome %q", outcome)
+	}
+
+	return &FakeProvider{outcome: outcome, authorizations: authorizations}, nil
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) Authorize(ctx context.Context, request AuthorizeRequest) (*Authorization, error) {
+	if err := p.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	if p.outcome == FakeDecline {
+		return nil, ErrDeclined
+	}
+
+	id := "fake_auth_" + request.PaymentId
+	if err := p.authorizations.create(ctx, id, fakeAuthorization{Amount: request.Amount}); err != nil {
+		return nil, err
+	}
+	return &Authorization{Id: id}, nil
+}
+
+func (p *FakeProvider) Capture(ctx context.Context, authorizationId string, amount money.Money) error {
+	if err := p.wait(ctx); err != nil {
+		return err
+	}
+
+	return p.authorizations.update(ctx, authorizationId, func(authorization *fakeAuthorization) error {
+		if authorization.Voided {
+			return ErrUnknownAuthorization
+		}
+
+		if amount.Currency != authorization.Amount.Currency || authorization.Captured+amount.Amount > authorization.Amount.Amount {
+			return ErrInvalidAmount
+		}
+
+		authorization.Captured += amount.Amount
+		return nil
+	})
+}
+
+func (p *FakeProvider) Void(ctx context.Context, authorizationId string) error {
+	if err := p.wait(ctx); err != nil {
+		return err
+	}
+
+	return p.authorizations.update(ctx, authorizationId, func(authorization *fakeAuthorization) error {
+		if authorization.Captured > 0 {
+			return ErrInvalidAmount
+		}
+
+		authorization.Voided = true
+		return nil
+	})
+}
+
+func (p *FakeProvider) Refund(ctx context.Context, authorizationId string, amount money.Money) error {
+	if err := p.wait(ctx); err != nil {
+		return err
+	}
+
+	return p.authorizations.update(ctx, authorizationId, func(authorization *fakeAuthorization) error {
+		if amount.Currency != authorization.Amount.Currency || authorization.Refunded+amount.Amount > authorization.Captured {
+			return ErrInvalidAmount
+		}
+
+		authorization.Refunded += amount.Amount
+		return nil
+	})
+}
+
+// wait blocks until the context is done when the provider times out
+func (p *FakeProvider) wait(ctx context.Context) error {
+	if p.outcome != FakeTimeout {
+		return nil
+	}
+
+	<-ctx.Done()
+	return ErrTimeout
+}
+
+// memoryFakeStore keeps the authorizations in the memory of the process
+type memoryFakeStore struct {
+	mu             sync.Mutex
+	authorizations map[string]*fakeAuthorization
+}
+
+func (s *memoryFakeStore) create(_ context.Context, id string, authorization fakeAuthorization) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.authorizations[id]; !ok {
+		s.authorizations[id] = &authorization
+	}
+	return nil
+}
+
+func (s *memoryFakeStore) update(_ context.Context, id string, change func(authorization *fakeAuthorization) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	authorization, ok := s.authorizations[id]
+	if !ok {
+		return ErrUnknownAuthorization
+	}
+
+	changed := *authorization
+	if err := change(&changed); err != nil {
+		return err
+	}
+	*authorization = changed
+	return nil
+}
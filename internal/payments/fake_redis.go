@@ -0,0 +1,70 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+const (
+	fakeAuthorizationPrefix = "payments:fake:"
+	// fakeAuthorizationTTL keeps authorizations long enough for their refunds
+	fakeAuthorizationTTL = 30 * 24 * time.Hour
+	// fakeUpdateAttempts is how often an update is tried again when another instance changed the authorization
+	fakeUpdateAttempts = 10
+)
+
+var errFakeUpdateConflict = errors.New("fake authorization changed concurrently")
+
+// redisFakeStore keeps every authorization as JSON under its own key. Updates watch the key, so concurrent changes
+// from other instances are not lost.
+type redisFakeStore struct {
+	client redis.UniversalClient
+}
+
+func (s *redisFakeStore) create(ctx context.Context, id string, authorization fakeAuthorization) error {
+	data, err := json.Marshal(authorization)
+	if err != nil {
+		return err
+	}
+	return s.client.SetNX(ctx, fakeAuthorizationPrefix+id, data, fakeAuthorizationTTL).Err()
+}
+
+func (s *redisFakeStore) update(ctx context.Context, id string, change func(authorization *fakeAuthorization) error) error {
+	key := fakeAuthorizationPrefix + id
+	for attempt := 0; attempt < fakeUpdateAttempts; attempt++ {
+		err := s.client.Watch(ctx, func(tx *redis.Tx) error {
+			data, err := tx.Get(ctx, key).Bytes()
+			if errors.Is(err, redis.Nil) {
+				return ErrUnknownAuthorization
+			}
+			if err != nil {
+				return err
+			}
+
+			var authorization fakeAuthorization
+			if err := json.Unmarshal(data, &authorization); err != nil {
+				return err
+			}
+			if err := change(&authorization); err != nil {
+				return err
+			}
+
+			data, err = json.Marshal(authorization)
+			if err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Set(ctx, key, data, redis.KeepTTL)
+				return nil
+			})
+			return err
+		}, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return errFakeUpdateConflict
+}
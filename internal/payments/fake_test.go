@@ -0,0 +1,103 @@
+package payments
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/pkg/money"
+	"time"
+)
+
+func TestFakeProvider_Succeed(t *testing.T) {
+	provider, _ := NewFakeProvider(FakeSucceed)
+	ctx := context.Background()
+	amount := money.New(2500, "EUR")
+
+	authorization, err := provider.Authorize(ctx, AuthorizeRequest{PaymentId: "payment-1", Amount: amount})
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	assert.Equal(t, "fake_auth_payment-1", authorization.Id)
+
+	// Authorizing the same payment again returns the same authorization
+	again, _ := provider.Authorize(ctx, AuthorizeRequest{PaymentId: "payment-1", Amount: amount})
+	assert.Equal(t, authorization.Id, again.Id)
+
+	assert.ErrorIs(t, provider.Capture(ctx, authorization.Id, money.New(3000, "EUR")), ErrInvalidAmount)
+	assert.NoError(t, provider.Capture(ctx, authorization.Id, amount))
+	assert.ErrorIs(t, provider.Void(ctx, authorization.Id), ErrInvalidAmount)
+
+	assert.NoError(t, provider.Refund(ctx, authorization.Id, money.New(1000, "EUR")))
+	assert.ErrorIs(t, provider.Refund(ctx, authorization.Id, money.New(2000, "EUR")), ErrInvalidAmount)
+	assert.ErrorIs(t, provider.Refund(ctx, "unknown", amount), ErrUnknownAuthorization)
+}
+
+func TestFakeProvider_Void(t *testing.T) {
+	provider, _ := NewFakeProvider(FakeSucceed)
+	ctx := context.Background()
+	amount := money.New(2500, "EUR")
+
+	authorization, _ := provider.Authorize(ctx, AuthorizeRequest{PaymentId: "payment-1", Amount: amount})
+
+	assert.NoError(t, provider.Void(ctx, authorization.Id))
+	assert.ErrorIs(t, provider.Capture(ctx, authorization.Id, amount), ErrUnknownAuthorization)
+}
+
+func TestFakeProvider_Decline(t *testing.T) {
+	provider, _ := NewFakeProvider(FakeDecline)
+
+	_, err := provider.Authorize(context.Background(), AuthorizeRequest{PaymentId: "payment-1", Amount: money.New(100, "USD")})
+
+	assert.ErrorIs(t, err, ErrDeclined)
+}
+
+func TestFakeProvider_Timeout(t *testing.T) {
+	provider, _ := NewFakeProvider(FakeTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := provider.Authorize(ctx, AuthorizeRequest{PaymentId: "payment-1", Amount: money.New(100, "USD")})
+
+	assert.ErrorIs(t, err, ErrTimeout)
+}
+
+func TestNewProvider(t *testing.T) {
+	provider, err := NewProvider(config.PaymentConfig{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "fake", provider.Name())
+
+	_, err = NewProvider(config.PaymentConfig{Provider: "unknown"}, nil)
+	assert.Error(t, err)
+
+	_, err = NewProvider(config.PaymentConfig{FakeOutcome: "maybe"}, nil)
+	assert.Error(t, err)
+}
+
+func TestRedisFakeProvider_Shared_Between_Instances(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	provider, _ := NewProvider(config.PaymentConfig{}, client)
+	other, _ := NewProvider(config.PaymentConfig{}, client)
+	ctx := context.Background()
+	amount := money.New(2500, "EUR")
+
+	// The payment is taken on one instance and refunded on the other
+	authorization, err := provider.Authorize(ctx, AuthorizeRequest{PaymentId: "payment-1", Amount: amount})
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	assert.NoError(t, provider.Capture(ctx, authorization.Id, amount))
+
+	assert.NoError(t, other.Refund(ctx, authorization.Id, money.New(1000, "EUR")))
+	assert.ErrorIs(t, provider.Refund(ctx, authorization.Id, money.New(2000, "EUR")), ErrInvalidAmount)
+	assert.ErrorIs(t, other.Void(ctx, authorization.Id), ErrInvalidAmount)
+	assert.ErrorIs(t, other.Refund(ctx, "unknown", amount), ErrUnknownAuthorization)
+
+	// Authorizing the payment again keeps what was captured
+	_, _ = other.Authorize(ctx, AuthorizeRequest{PaymentId: "payment-1", Amount: amount})
+	assert.ErrorIs(t, other.Capture(ctx, authorization.Id, money.New(1, "EUR")), ErrInvalidAmount)
+	assert.True(t, server.TTL("payments:fake:"+authorization.Id) > 0)
+}
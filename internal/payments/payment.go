@@ -0,0 +1,59 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/pkg/money"
+)
+
+var (
+	// ErrDeclined is returned when the provider refuses an authorization, e.g. for insufficient funds
+	ErrDeclined = errors.New("payment declined")
+	// ErrTimeout is returned when the provider did not answer in time. The outcome of the call is unknown.
+	ErrTimeout = errors.New("payment provider timed out")
+	// ErrUnknownAuthorization is returned for authorizations the provider never issued
+	ErrUnknownAuthorization = errors.New("unknown authorization")
+	// ErrInvalidAmount is returned when a capture or refund does not fit the authorized amount
+	ErrInvalidAmount = errors.New("invalid payment amount")
+)
+
+// AuthorizeRequest asks the provider to reserve an amount on the payment method of the user
+type AuthorizeRequest struct {
+	// PaymentId identifies the payment at the provider, authorizing it again returns the same authorization
+	PaymentId string
+	UserId    string
+	Amount    money.Money
+}
+
+// Authorization is an amount reserved by the provider that can be captured or voided
+type Authorization struct {
+	Id string
+}
+
+// Provider is a payment gateway. Money is first authorized, then captured once the order is committed, or voided
+// when it is not. Captured money can be refunded in parts.
+type Provider interface {
+	// Name identifies the provider on stored payments
+	Name() string
+	Authorize(ctx context.Context, request AuthorizeRequest) (*Authorization, error)
+	Capture(ctx context.Context, authorizationId string, amount money.Money) error
+	Void(ctx context.Context, authorizationId string) error
+	Refund(ctx context.Context, authorizationId string, amount money.Money) error
+}
+
+// NewProvider returns the provider of the configuration. The fake provider keeps its authorizations in Redis when a
+// client is given, otherwise in memory, which only works with a single instance.
+func NewProvider(conf config.PaymentConfig, redisClient *redis.Client) (Provider, error) {
+	switch conf.Provider {
+	case "", config.PaymentProviderFake:
+		if redisClient != nil {
+			return NewRedisFakeProvider(conf.FakeOutcome, redisClient)
+		}
+		return NewFakeProvider(conf.FakeOutcome)
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", conf.Provider)
+	}
+}
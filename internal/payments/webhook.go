@@ -0,0 +1,49 @@
+package payments
+
+import (
+	"ticket-purchase/pkg/signature"
+	"time"
+)
+
+// SignatureHeader carries the signature of provider callbacks in the format of the signature package
+const SignatureHeader = "X-Payment-Signature"
+
+var (
+	// ErrInvalidSignature is returned when no signature of a callback matches any of the secrets
+	ErrInvalidSignature = signature.ErrInvalid
+	// ErrStaleWebhook is returned when the timestamp of a callback is outside the tolerance
+	ErrStaleWebhook = signature.ErrStale
+)
+
+// Event types sent by providers
+const (
+	EventAuthorized = "payment.authorized"
+	EventCaptured   = "payment.captured"
+	EventRefunded   = "payment.refunded"
+	EventFailed     = "payment.failed"
+)
+
+// Event is a payment callback of a provider
+type Event struct {
+	// Id is unique per event, deliveries of the same event share it
+	Id   string `json:"id"`
+	Type string `json:"type"`
+	// PaymentId is the id the payment was authorized with
+	PaymentId       string `json:"payment_id"`
+	AuthorizationId string `json:"authorization_id"`
+	// RefundedAmount is the total refunded so far in minor units, it is only set for refunds
+	RefundedAmount int64 `json:"refunded_amount"`
+	// Reason explains failures
+	Reason string `json:"reason"`
+}
+
+// SignWebhook returns the signature header of a callback body sent at timestamp
+func SignWebhook(secret string, timestamp time.Time, body []byte) string {
+	return signature.Sign(secret, timestamp, body)
+}
+
+// VerifyWebhook checks that the signature header was made with one of the secrets and that its timestamp is within
+// tolerance of now
+func VerifyWebhook(header string, body []byte, secrets []string, tolerance time.Duration, now time.Time) error {
+	return signature.Verify(header, body, secrets, tolerance, now)
+}
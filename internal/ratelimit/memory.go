@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"ticket-purchase/cmd/config"
+	"time"
+)
+
+// memorySweepInterval is how often full buckets are dropped from memory
+const memorySweepInterval = time.Minute
+
+// memoryLimiter keeps the buckets in process. Limits are not shared between instances, it is used when Redis is
+// not configured.
+type memoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]memoryBucket
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	bucket
+	// fullAt is when the bucket is full again and no longer needs to be kept
+	fullAt time.Time
+}
+
+func NewMemoryLimiter() Limiter {
+	return &memoryLimiter{
+		buckets:   make(map[string]memoryBucket),
+		lastSweep: timeNow(),
+	}
+}
+
+func (l *memoryLimiter) Allow(_ context.Context, key string, policy config.RateLimitPolicy) (Result, error) {
+	now := timeNow()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var state *bucket
+	if stored, ok := l.buckets[key]; ok {
+		state = &stored.bucket
+	}
+
+	next, result := take(state, now, policy)
+	l.buckets[key] = memoryBucket{bucket: next, fullAt: now.Add(result.Reset)}
+
+	if now.Sub(l.lastSweep) >= memorySweepInterval {
+		l.sweep(now)
+	}
+	return result, nil
+}
+
+func (l *memoryLimiter) sweep(now time.Time) {
+	for key, stored := range l.buckets {
+		if !stored.fullAt.After(now) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"ticket-purchase/cmd/config"
+	"time"
+)
+
+var timeNow = time.Now
+
+// Result is the outcome of taking a request from a bucket
+type Result struct {
+	Allowed bool
+	// Limit is the size of the bucket and Remaining how many requests are left in it
+	Limit     int
+	Remaining int
+	// RetryAfter is how long a rejected client has to wait for the next request
+	RetryAfter time.Duration
+	// Reset is how long it takes until the bucket is full again
+	Reset time.Duration
+}
+
+// Limiter is a token bucket per key. A bucket holds policy.Burst requests and refills at policy.Limit per policy.Period.
+type Limiter interface {
+	Allow(ctx context.Context, key string, policy config.RateLimitPolicy) (Result, error)
+}
+
+// bucket is the state of a token bucket at a point in time
+type bucket struct {
+	tokens float64
+	at     time.Time
+}
+
+// refillRate is how many tokens are added per millisecond
+func refillRate(policy config.RateLimitPolicy) float64 {
+	return float64(policy.Limit) / float64(policy.Period.Milliseconds())
+}
+
+// take refills the bucket up to now and takes one token from it. A missing bucket starts full.
+func take(b *bucket, now time.Time, policy config.RateLimitPolicy) (bucket, Result) {
+	rate := refillRate(policy)
+	burst := float64(policy.Burst)
+
+	tokens := burst
+	if b != nil {
+		elapsed := float64(now.Sub(b.at).Milliseconds())
+		tokens = math.Min(burst, b.tokens+math.Max(0, elapsed)*rate)
+	}
+
+	result := Result{Limit: policy.Burst}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = milliseconds((1 - tokens) / rate)
+	}
+
+	result.Remaining = int(tokens)
+	result.Reset = milliseconds((burst - tokens) / rate)
+	return bucket{tokens: tokens, at: now}, result
+}
+
+func milliseconds(value float64) time.Duration {
+	return time.Duration(math.Ceil(value)) * time.Millisecond
+}
@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"ticket-purchase/cmd/config"
+	"time"
+)
+
+var rateLimitMockTime = time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+// testPolicy refills one request every 10 seconds and allows 3 at once
+var testPolicy = config.RateLimitPolicy{Limit: 6, Period: time.Minute, Burst: 3}
+
+// setupClock freezes the time of the limiters and returns a function that moves it forward
+func setupClock(t *testing.T) func(time.Duration) {
+	now := rateLimitMockTime
+	timeNow = func() time.Time {
+		return now
+	}
+	t.Cleanup(func() {
+		timeNow = time.Now
+	})
+
+	return func(duration time.Duration) {
+		now = now.Add(duration)
+	}
+}
+
+func limiters(t *testing.T) map[string]Limiter {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	return map[string]Limiter{
+		"memory": NewMemoryLimiter(),
+		"redis":  NewRedisLimiter(client),
+	}
+}
+
+func TestLimiter_Burst_Then_Reject(t *testing.T) {
+	for name, limiter := range limiters(t) {
+		t.Run(name, func(t *testing.T) {
+			setupClock(t)
+
+			for remaining := 2; remaining >= 0; remaining-- {
+				result, err := limiter.Allow(context.Background(), "client", testPolicy)
+				if err != nil {
+					t.Fatalf("Expected error to be nil, got %v", err)
+				}
+				assert.True(t, result.Allowed)
+				assert.Equal(t, 3, result.Limit)
+				assert.Equal(t, remaining, result.Remaining)
+			}
+
+			result, err := limiter.Allow(context.Background(), "client", testPolicy)
+			assert.NoError(t, err)
+			assert.False(t, result.Allowed)
+			assert.Equal(t, 0, result.Remaining)
+			assert.Equal(t, 10*time.Second, result.RetryAfter)
+			assert.Equal(t, 30*time.Second, result.Reset)
+
+			// Other clients have their own bucket
+			result, _ = limiter.Allow(context.Background(), "other", testPolicy)
+			assert.True(t, result.Allowed)
+		})
+	}
+}
+
+func TestLimiter_Refills(t *testing.T) {
+	for name, limiter := range limiters(t) {
+		t.Run(name, func(t *testing.T) {
+			advance := setupClock(t)
+
+			for i := 0; i < 3; i++ {
+				_, _ = limiter.Allow(context.Background(), "client", testPolicy)
+			}
+
+			advance(4 * time.Second)
+			result, _ := limiter.Allow(context.Background(), "client", testPolicy)
+			assert.False(t, result.Allowed)
+			assert.Equal(t, 6*time.Second, result.RetryAfter)
+
+			advance(6 * time.Second)
+			result, _ = limiter.Allow(context.Background(), "client", testPolicy)
+			assert.True(t, result.Allowed)
+
+			// The bucket never holds more than the burst
+			advance(time.Hour)
+			result, _ = limiter.Allow(context.Background(), "client", testPolicy)
+			assert.True(t, result.Allowed)
+			assert.Equal(t, 2, result.Remaining)
+		})
+	}
+}
+
+func TestRedisLimiter_Buckets_Expire(t *testing.T) {
+	setupClock(t)
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	_, err := NewRedisLimiter(client).Allow(context.Background(), "client", testPolicy)
+
+	assert.NoError(t, err)
+	assert.True(t, server.Exists("ratelimit:client"))
+	assert.Equal(t, 11*time.Second, server.TTL("ratelimit:client"))
+}
+
+func TestMemoryLimiter_Sweeps_Full_Buckets(t *testing.T) {
+	advance := setupClock(t)
+	limiter := NewMemoryLimiter().(*memoryLimiter)
+
+	_, _ = limiter.Allow(context.Background(), "client", testPolicy)
+	advance(memorySweepInterval)
+	_, _ = limiter.Allow(context.Background(), "other", testPolicy)
+
+	assert.NotContains(t, limiter.buckets, "client")
+	assert.Contains(t, limiter.buckets, "other")
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"ticket-purchase/cmd/config"
+	"time"
+)
+
+const redisKeyPrefix = "ratelimit:"
+
+// takeScript is take on a bucket stored in a Redis hash, so that instances share the limits. The time comes from the
+// instance, clock skew between instances only adds or removes the tokens refilled in that time. Buckets expire once
+// they are full again.
+var takeScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local burst = tonumber(ARGV[3])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'at')
+local tokens = burst
+if state[1] then
+	local elapsed = math.max(0, now - tonumber(state[2]))
+	tokens = math.min(burst, tonumber(state[1]) + elapsed * rate)
+end
+
+local allowed = 0
+local retry_after = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry_after = math.ceil((1 - tokens) / rate)
+end
+
+local reset = math.ceil((burst - tokens) / rate)
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'at', ARGV[1])
+redis.call('PEXPIRE', KEYS[1], reset + 1000)
+return {allowed, math.floor(tokens), retry_after, reset}
+`)
+
+type redisLimiter struct {
+	client redis.UniversalClient
+}
+
+func NewRedisLimiter(client redis.UniversalClient) Limiter {
+	return &redisLimiter{client: client}
+}
+
+func (l *redisLimiter) Allow(ctx context.Context, key string, policy config.RateLimitPolicy) (Result, error) {
+	rate := strconv.FormatFloat(refillRate(policy), 'g', -1, 64)
+	values, err := takeScript.Run(ctx, l.client, []string{redisKeyPrefix + key},
+		timeNow().UnixMilli(), rate, policy.Burst,
+	).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		Allowed:    values[0] == 1,
+		Limit:      policy.Burst,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		Reset:      time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
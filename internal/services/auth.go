@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"strings"
+	"sync"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/auth"
+	"ticket-purchase/internal/db/models"
+	"ticket-purchase/internal/db/repositories"
+	"ticket-purchase/internal/dto"
+	"ticket-purchase/pkg/enum"
+)
+
+// bcryptCost is lowered in tests
+var bcryptCost = bcrypt.DefaultCost
+
+// dummyPasswordHash is compared when the email is unknown, so that login takes the same time either way
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy-password"), bcryptCost)
+	return hash
+})
+
+type AuthService interface {
+	// Register creates a user and signs it in
+	Register(ctx context.Context, request *dto.RegisterRequest) (*dto.TokenResponse, error)
+	// Login checks the credentials of a user and issues new tokens
+	Login(ctx context.Context, request *dto.LoginRequest) (*dto.TokenResponse, error)
+	// Refresh exchanges a refresh token for new tokens. A refresh token can be used once.
+	Refresh(ctx context.Context, request *dto.RefreshRequest) (*dto.TokenResponse, error)
+	// UpdateRole changes the role of a user. It takes effect when the user gets a new access token.
+	UpdateRole(ctx context.Context, request *dto.UserRoleRequest) (*dto.UserResponse, error)
+}
+
+type authService struct {
+	userRepo         repositories.UserRepository
+	refreshTokenRepo repositories.RefreshTokenRepository
+	signer           auth.Signer
+	conf             config.AuthConfig
+}
+
+func NewAuthService(
+	userRepo repositories.UserRepository,
+	refreshTokenRepo repositories.RefreshTokenRepository,
+	signer auth.Signer,
+	conf config.AuthConfig,
+) AuthService {
+	return &authService{
+		userRepo:         userRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		signer:           signer,
+		conf:             conf,
+	}
+}
+
+func (s *authService) Register(ctx context.Context, request *dto.RegisterRequest) (*dto.TokenResponse, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcryptCost)
+	if err != nil {
+		return nil, apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	locale := request.Locale
+	if locale == "" {
+		locale = enum.DefaultLanguage
+	}
+
+	user := models.User{
+		Email:        normalizeEmail(request.Email),
+		PasswordHash: string(hash),
+		Locale:       locale,
+		Status:       models.UserStatusActive,
+		Role:         auth.RoleCustomer,
+		CreatedAt:    timeNow(),
+		UpdatedAt:    timeNow(),
+	}
+
+	err = s.userRepo.Create(ctx, &user)
+	if errors.Is(err, repositories.ErrEmailTaken) {
+		return nil, apperrors.ErrEmailTaken.Wrap(err)
+	}
+
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	refreshToken, err := s.newRefreshToken(&user)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.refreshTokenRepo.Create(ctx, refreshToken.record); err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	return s.tokenResponse(&user, refreshToken)
+}
+
+func (s *authService) Login(ctx context.Context, request *dto.LoginRequest) (*dto.TokenResponse, error) {
+	user, err := s.userRepo.FindByEmail(ctx, normalizeEmail(request.Email))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(request.Password))
+		return nil, apperrors.ErrInvalidCredentials.Wrap(err)
+	}
+
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(request.Password)); err != nil {
+		return nil, apperrors.ErrInvalidCredentials.Wrap(err)
+	}
+
+	if !user.IsActive() {
+		return nil, apperrors.ErrUserDisabled
+	}
+
+	refreshToken, err := s.newRefreshToken(user)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.refreshTokenRepo.Create(ctx, refreshToken.record); err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	return s.tokenResponse(user, refreshToken)
+}
+
+func (s *authService) Refresh(ctx context.Context, request *dto.RefreshRequest) (*dto.TokenResponse, error) {
+	// The user of the next token is taken from the current token while it is rotated
+	next, err := s.newRefreshToken(&models.User{})
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.refreshTokenRepo.Rotate(ctx, hashToken(request.RefreshToken), next.record)
+	if errors.Is(err, repositories.ErrRefreshTokenInvalid) {
+		return nil, apperrors.ErrRefreshTokenInvalid.Wrap(err)
+	}
+
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	user, err := s.userRepo.FindById(ctx, current.UserId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.ErrRefreshTokenInvalid.Wrap(err)
+	}
+
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	if !user.IsActive() {
+		return nil, apperrors.ErrUserDisabled
+	}
+
+	return s.tokenResponse(user, next)
+}
+
+func (s *authService) UpdateRole(ctx context.Context, request *dto.UserRoleRequest) (*dto.UserResponse, error) {
+	user, err := s.userRepo.FindById(ctx, request.UserId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.ErrNotFound.Wrap(err)
+	}
+
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	user.Role = request.Role
+	user.UpdatedAt = timeNow()
+
+	err = s.userRepo.UpdateRole(ctx, user)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.ErrNotFound.Wrap(err)
+	}
+
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	return userResponse(user), nil
+}
+
+// issuedRefreshToken is a refresh token with its raw value, which is returned to the client but never stored
+type issuedRefreshToken struct {
+	value  string
+	record *models.RefreshToken
+}
+
+func (s *authService) newRefreshToken(user *models.User) (*issuedRefreshToken, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	value := base64.RawURLEncoding.EncodeToString(buf)
+	now := timeNow()
+	return &issuedRefreshToken{
+		value: value,
+		record: &models.RefreshToken{
+			UserId:    user.Id,
+			TokenHash: hashToken(value),
+			ExpiresAt: now.Add(s.conf.RefreshTokenTTL),
+			CreatedAt: now,
+		},
+	}, nil
+}
+
+func (s *authService) tokenResponse(user *models.User, refreshToken *issuedRefreshToken) (*dto.TokenResponse, error) {
+	accessToken, expiresAt, err := s.signer.Sign(user.Id, user.Role)
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	return &dto.TokenResponse{
+		AccessToken:           accessToken,
+		TokenType:             "Bearer",
+		ExpiresAt:             expiresAt,
+		RefreshToken:          refreshToken.value,
+		RefreshTokenExpiresAt: refreshToken.record.ExpiresAt,
+		User:                  *userResponse(user),
+	}, nil
+}
+
+func userResponse(user *models.User) *dto.UserResponse {
+	return &dto.UserResponse{
+		Id:        user.Id,
+		Email:     user.Email,
+		Locale:    user.Locale,
+		Status:    user.Status,
+		Role:      user.Role,
+		CreatedAt: user.CreatedAt,
+	}
+}
+
+// hashToken returns the stored form of a refresh token
+func hashToken(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"testing"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/auth"
+	"ticket-purchase/internal/db/models"
+	dbRepositories "ticket-purchase/internal/db/repositories"
+	"ticket-purchase/internal/dto"
+	"ticket-purchase/internal/mocks/repositories"
+	"time"
+)
+
+var as AuthService
+var userRepo *repositories.MockUserRepository
+var refreshTokenRepo *repositories.MockRefreshTokenRepository
+var verifier auth.Verifier
+var authMockTime = time.Now().Truncate(time.Second)
+
+func setupAuthTest(t *testing.T) func() {
+	teardown := setupTicketTest(t)
+	ct := gomock.NewController(t)
+
+	timeNow = func() time.Time {
+		return authMockTime
+	}
+	bcryptCost = bcrypt.MinCost
+
+	conf := config.AuthConfig{
+		Algorithm:       auth.AlgorithmHS256,
+		Secret:          "secret",
+		AccessTokenTTL:  15 * time.Minute,
+		RefreshTokenTTL: 24 * time.Hour,
+	}
+
+	signer, err := auth.NewSigner(conf)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	verifier, err = auth.NewVerifier(conf)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	userRepo = repositories.NewMockUserRepository(ct)
+	refreshTokenRepo = repositories.NewMockRefreshTokenRepository(ct)
+	as = NewAuthService(userRepo, refreshTokenRepo, signer, conf)
+	return func() {
+		as = nil
+		timeNow = time.Now
+		bcryptCost = bcrypt.DefaultCost
+		teardown()
+	}
+}
+
+func mockUser(t *testing.T, password string) *models.User {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	return &models.User{
+		Id:           "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		Email:        "user@example.com",
+		PasswordHash: string(hash),
+		Locale:       "tr",
+		Status:       models.UserStatusActive,
+		Role:         auth.RoleCustomer,
+	}
+}
+
+func TestAuthService_Register_Success(t *testing.T) {
+	teardown := setupAuthTest(t)
+	defer teardown()
+
+	request := dto.RegisterRequest{Email: " User@Example.com ", Password: "correct-horse"}
+
+	userRepo.EXPECT().Create(fiberCtx.Context(), gomock.Any()).DoAndReturn(func(_ any, user *models.User) error {
+		assert.Equal(t, "user@example.com", user.Email)
+		assert.Equal(t, "en", user.Locale)
+		assert.Equal(t, models.UserStatusActive, user.Status)
+		assert.Equal(t, auth.RoleCustomer, user.Role)
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("correct-horse")))
+		user.Id = "new-user"
+		return nil
+	})
+	refreshTokenRepo.EXPECT().Create(fiberCtx.Context(), gomock.Any()).DoAndReturn(func(_ any, token *models.RefreshToken) error {
+		assert.Equal(t, "new-user", token.UserId)
+		assert.Equal(t, authMockTime.Add(24*time.Hour), token.ExpiresAt)
+		return nil
+	})
+
+	response, err := as.Register(fiberCtx.Context(), &request)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	assert.Equal(t, "new-user", response.User.Id)
+	assert.Equal(t, "Bearer", response.TokenType)
+	assert.NotEmpty(t, response.RefreshToken)
+
+	claims, err := verifier.Verify(response.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	assert.Equal(t, "new-user", claims.UserId())
+	assert.Equal(t, auth.RoleCustomer, claims.Role)
+}
+
+func TestAuthService_Register_Email_Taken(t *testing.T) {
+	teardown := setupAuthTest(t)
+	defer teardown()
+
+	userRepo.EXPECT().Create(fiberCtx.Context(), gomock.Any()).Return(dbRepositories.ErrEmailTaken)
+
+	response, err := as.Register(fiberCtx.Context(), &dto.RegisterRequest{Email: "user@example.com", Password: "correct-horse"})
+
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, apperrors.ErrEmailTaken)
+}
+
+func TestAuthService_Login_Success(t *testing.T) {
+	teardown := setupAuthTest(t)
+	defer teardown()
+
+	user := mockUser(t, "correct-horse")
+
+	userRepo.EXPECT().FindByEmail(fiberCtx.Context(), "user@example.com").Return(user, nil)
+	refreshTokenRepo.EXPECT().Create(fiberCtx.Context(), gomock.Any()).Return(nil)
+
+	response, err := as.Login(fiberCtx.Context(), &dto.LoginRequest{Email: "USER@example.com", Password: "correct-horse"})
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	assert.Equal(t, user.Id, response.User.Id)
+	assert.Equal(t, "tr", response.User.Locale)
+}
+
+func TestAuthService_Login_Invalid_Credentials(t *testing.T) {
+	teardown := setupAuthTest(t)
+	defer teardown()
+
+	userRepo.EXPECT().FindByEmail(fiberCtx.Context(), "user@example.com").Return(mockUser(t, "correct-horse"), nil)
+	userRepo.EXPECT().FindByEmail(fiberCtx.Context(), "unknown@example.com").Return(nil, gorm.ErrRecordNotFound)
+
+	_, err := as.Login(fiberCtx.Context(), &dto.LoginRequest{Email: "user@example.com", Password: "wrong-password"})
+	assert.ErrorIs(t, err, apperrors.ErrInvalidCredentials)
+
+	// Unknown emails get the same error as wrong passwords
+	_, err = as.Login(fiberCtx.Context(), &dto.LoginRequest{Email: "unknown@example.com", Password: "wrong-password"})
+	assert.ErrorIs(t, err, apperrors.ErrInvalidCredentials)
+}
+
+func TestAuthService_Login_Disabled_User(t *testing.T) {
+	teardown := setupAuthTest(t)
+	defer teardown()
+
+	user := mockUser(t, "correct-horse")
+	user.Status = models.UserStatusDisabled
+
+	userRepo.EXPECT().FindByEmail(fiberCtx.Context(), "user@example.com").Return(user, nil)
+
+	_, err := as.Login(fiberCtx.Context(), &dto.LoginRequest{Email: "user@example.com", Password: "correct-horse"})
+	assert.ErrorIs(t, err, apperrors.ErrUserDisabled)
+}
+
+func TestAuthService_Refresh_Rotates_Token(t *testing.T) {
+	teardown := setupAuthTest(t)
+	defer teardown()
+
+	user := mockUser(t, "correct-horse")
+
+	refreshTokenRepo.EXPECT().Rotate(fiberCtx.Context(), hashToken("current-token"), gomock.Any()).DoAndReturn(
+		func(_ any, _ string, next *models.RefreshToken) (*models.RefreshToken, error) {
+			assert.NotEqual(t, hashToken("current-token"), next.TokenHash)
+			next.UserId = user.Id
+			return &models.RefreshToken{UserId: user.Id}, nil
+		})
+	userRepo.EXPECT().FindById(fiberCtx.Context(), user.Id).Return(user, nil)
+
+	response, err := as.Refresh(fiberCtx.Context(), &dto.RefreshRequest{RefreshToken: "current-token"})
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	assert.NotEqual(t, "current-token", response.RefreshToken)
+	assert.Equal(t, user.Id, response.User.Id)
+}
+
+func TestAuthService_Refresh_Invalid_Token(t *testing.T) {
+	teardown := setupAuthTest(t)
+	defer teardown()
+
+	refreshTokenRepo.EXPECT().Rotate(fiberCtx.Context(), hashToken("used-token"), gomock.Any()).Return(nil, dbRepositories.ErrRefreshTokenInvalid)
+
+	response, err := as.Refresh(fiberCtx.Context(), &dto.RefreshRequest{RefreshToken: "used-token"})
+
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, apperrors.ErrRefreshTokenInvalid)
+}
+
+func TestAuthService_UpdateRole_Success(t *testing.T) {
+	teardown := setupAuthTest(t)
+	defer teardown()
+
+	user := mockUser(t, "correct-horse")
+
+	userRepo.EXPECT().FindById(fiberCtx.Context(), user.Id).Return(user, nil)
+	userRepo.EXPECT().UpdateRole(fiberCtx.Context(), user).DoAndReturn(func(_ any, updated *models.User) error {
+		assert.Equal(t, auth.RoleOrganizer, updated.Role)
+		assert.Equal(t, authMockTime, updated.UpdatedAt)
+		return nil
+	})
+
+	response, err := as.UpdateRole(fiberCtx.Context(), &dto.UserRoleRequest{UserId: user.Id, Role: auth.RoleOrganizer})
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	assert.Equal(t, auth.RoleOrganizer, response.Role)
+}
+
+func TestAuthService_UpdateRole_Not_Found(t *testing.T) {
+	teardown := setupAuthTest(t)
+	defer teardown()
+
+	userRepo.EXPECT().FindById(fiberCtx.Context(), "missing").Return(nil, gorm.ErrRecordNotFound)
+
+	response, err := as.UpdateRole(fiberCtx.Context(), &dto.UserRoleRequest{UserId: "missing", Role: auth.RoleAdmin})
+
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/db/models"
+	"ticket-purchase/internal/db/repositories"
+	"ticket-purchase/internal/dto"
+	"time"
+)
+
+// releaseBatchSize limits how many expired holds are released in one transaction
+const releaseBatchSize = 100
+
+type HoldService interface {
+	// Create reserves ticket allocation for a limited time
+	Create(ctx context.Context, request *dto.HoldCreateRequest) (*dto.HoldResponse, error)
+	// Confirm turns an active hold of the user into a purchase
+	Confirm(ctx context.Context, id string, userId string) (*dto.HoldResponse, error)
+	// ReleaseExpired returns the allocation of expired holds back to their tickets
+	ReleaseExpired(ctx context.Context) (int, error)
+}
+
+type holdService struct {
+	holdRepo   repositories.HoldRepository
+	ticketRepo repositories.TicketRepository
+	payments   PaymentService
+	conf       config.HoldConfig
+}
+
+func NewHoldService(
+	holdRepo repositories.HoldRepository,
+	ticketRepo repositories.TicketRepository,
+	payments PaymentService,
+	conf config.HoldConfig,
+) HoldService {
+	return &holdService{
+		holdRepo:   holdRepo,
+		ticketRepo: ticketRepo,
+		payments:   payments,
+		conf:       conf,
+	}
+}
+
+func (s *holdService) Create(ctx context.Context, request *dto.HoldCreateRequest) (*dto.HoldResponse, error) {
+	if request.Quantity <= 0 || request.Minutes < 0 {
+		return nil, apperrors.ErrBadRequest
+	}
+
+	// Holds made on sale can still be confirmed after the window closes
+	ticket, err := checkOrder(ctx, s.ticketRepo, request.TicketId, request.Quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	duration := s.conf.DefaultDuration
+	if request.Minutes > 0 {
+		duration = time.Duration(request.Minutes) * time.Minute
+	}
+	if duration > s.conf.MaxDuration {
+		duration = s.conf.MaxDuration
+	}
+
+	now := timeNow()
+	hold := models.Hold{
+		TicketId:  request.TicketId,
+		UserId:    request.UserId,
+		Quantity:  request.Quantity,
+		UnitPrice: ticket.Price,
+		Currency:  ticket.Currency,
+		Status:    models.HoldStatusActive,
+		ExpiresAt: now.Add(duration),
+		CreatedBy: request.UserId,
+		UpdatedBy: request.UserId,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	err = s.holdRepo.CreateWithAllocation(ctx, &hold)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.ErrNotFound.Wrap(err)
+	}
+
+	if errors.Is(err, repositories.ErrInsufficientAllocation) {
+		return nil, apperrors.ErrTicketAllocations.Wrap(err)
+	}
+
+	if errors.Is(err, repositories.ErrTicketInactive) {
+		return nil, apperrors.ErrTicketInactive.Wrap(err)
+	}
+
+	if errors.Is(err, repositories.ErrMaxPerUserExceeded) {
+		return nil, apperrors.ErrMaxPerUserExceeded.Wrap(err)
+	}
+
+	if err != nil {
+		return nil, apperrors.ErrHoldCreate.Wrap(err)
+	}
+
+	return holdResponse(&hold), nil
+}
+
+func (s *holdService) Confirm(ctx context.Context, id string, userId string) (*dto.HoldResponse, error) {
+	hold, err := s.holdRepo.FindById(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.ErrNotFound.Wrap(err)
+	}
+
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	// Only the user who created the hold can buy it
+	if hold.UserId != userId {
+		return nil, apperrors.ErrForbidden
+	}
+
+	// Holds that can no longer be bought are refused before the payment is authorized. Confirm checks again
+	// under the row lock.
+	now := timeNow()
+	if hold.Status != models.HoldStatusActive {
+		return nil, apperrors.ErrHoldNotActive
+	}
+
+	if !hold.ExpiresAt.After(now) {
+		return nil, apperrors.ErrHoldExpired
+	}
+
+	purchase := models.Purchase{
+		Id:        uuid.New().String(),
+		TicketId:  hold.TicketId,
+		UserId:    hold.UserId,
+		Quantity:  hold.Quantity,
+		CreatedBy: userId,
+		UpdatedBy: userId,
+		CreatedAt: now,
+		UpdatedAt: now,
+		IsActive:  true,
+	}
+	purchase.SetPrice(hold.Price())
+
+	payment, err := s.payments.Authorize(ctx, &purchase)
+	if err != nil {
+		return nil, err
+	}
+
+	hold, err = s.holdRepo.Confirm(ctx, id, &purchase)
+	if err != nil {
+		if voidErr := s.payments.Void(ctx, payment); voidErr != nil {
+			log.Error("Error voiding the payment of purchase ", purchase.Id, ": ", voidErr)
+		}
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.ErrNotFound.Wrap(err)
+	}
+
+	if errors.Is(err, repositories.ErrHoldNotActive) {
+		return nil, apperrors.ErrHoldNotActive.Wrap(err)
+	}
+
+	if errors.Is(err, repositories.ErrHoldExpired) {
+		return nil, apperrors.ErrHoldExpired.Wrap(err)
+	}
+
+	if errors.Is(err, repositories.ErrTicketInactive) {
+		return nil, apperrors.ErrTicketInactive.Wrap(err)
+	}
+
+	if err != nil {
+		return nil, apperrors.ErrPurchase.Wrap(err)
+	}
+
+	if err := s.payments.Capture(ctx, payment); err != nil {
+		log.Error("Error capturing the payment of purchase ", purchase.Id, ": ", err)
+	}
+
+	return holdResponse(hold), nil
+}
+
+func (s *holdService) ReleaseExpired(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		released, err := s.holdRepo.ReleaseExpired(ctx, timeNow(), releaseBatchSize)
+		total += len(released)
+		if err != nil || len(released) < releaseBatchSize {
+			return total, err
+		}
+	}
+}
+
+func holdResponse(hold *models.Hold) *dto.HoldResponse {
+	response := dto.HoldResponse{
+		Id:        hold.Id,
+		TicketId:  hold.TicketId,
+		UserId:    hold.UserId,
+		Quantity:  hold.Quantity,
+		UnitPrice: hold.UnitPrice,
+		Currency:  hold.Currency,
+		Status:    hold.Status,
+		ExpiresAt: hold.ExpiresAt,
+	}
+
+	if hold.PurchaseId != nil {
+		response.PurchaseId = *hold.PurchaseId
+	}
+
+	return &response
+}
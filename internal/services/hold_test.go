@@ -0,0 +1,282 @@
+package services
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/db/models"
+	dbRepositories "ticket-purchase/internal/db/repositories"
+	"ticket-purchase/internal/dto"
+	"ticket-purchase/internal/payments"
+	"time"
+)
+
+var hs HoldService
+var holdMockTime = time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+func setupHoldTest(t *testing.T) func() {
+	teardown := setupTicketTest(t)
+
+	timeNow = func() time.Time {
+		return holdMockTime
+	}
+
+	hs = NewHoldService(holdRepo, ticketRepo, newTestPaymentService(), config.HoldConfig{
+		DefaultDuration: 10 * time.Minute,
+		MaxDuration:     30 * time.Minute,
+	})
+	return func() {
+		hs = nil
+		timeNow = time.Now
+		teardown()
+	}
+}
+
+func TestHoldService_Create_Success(t *testing.T) {
+	teardown := setupHoldTest(t)
+	defer teardown()
+
+	request := dto.HoldCreateRequest{
+		TicketId: "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		UserId:   "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		Quantity: 2,
+	}
+
+	hold := models.Hold{
+		TicketId:  request.TicketId,
+		UserId:    request.UserId,
+		Quantity:  request.Quantity,
+		UnitPrice: 1250,
+		Currency:  "TRY",
+		Status:    models.HoldStatusActive,
+		ExpiresAt: holdMockTime.Add(10 * time.Minute),
+		CreatedBy: request.UserId,
+		UpdatedBy: request.UserId,
+		CreatedAt: holdMockTime,
+		UpdatedAt: holdMockTime,
+	}
+
+	ticket := mockTicketData[0]
+	ticket.Price = 1250
+	ticket.Currency = "TRY"
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), request.TicketId).Return(&ticket, nil)
+	holdRepo.EXPECT().CreateWithAllocation(fiberCtx.Context(), &hold).Return(nil)
+
+	response, err := hs.Create(fiberCtx.Context(), &request)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	assert.Equal(t, models.HoldStatusActive, response.Status)
+	assert.Equal(t, holdMockTime.Add(10*time.Minute), response.ExpiresAt)
+	assert.Equal(t, int64(1250), response.UnitPrice)
+}
+
+func TestHoldService_Create_Caps_Duration(t *testing.T) {
+	teardown := setupHoldTest(t)
+	defer teardown()
+
+	request := dto.HoldCreateRequest{
+		TicketId: "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		UserId:   "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		Quantity: 1,
+		Minutes:  120,
+	}
+
+	ticket := mockTicketData[0]
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), request.TicketId).Return(&ticket, nil)
+	holdRepo.EXPECT().CreateWithAllocation(fiberCtx.Context(), gomock.Any()).Return(nil)
+
+	response, err := hs.Create(fiberCtx.Context(), &request)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	assert.Equal(t, holdMockTime.Add(30*time.Minute), response.ExpiresAt)
+}
+
+func TestHoldService_Create_Insufficient_Allocation(t *testing.T) {
+	teardown := setupHoldTest(t)
+	defer teardown()
+
+	request := dto.HoldCreateRequest{
+		TicketId: "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		UserId:   "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		Quantity: 1000,
+	}
+
+	ticket := mockTicketData[0]
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), request.TicketId).Return(&ticket, nil)
+	holdRepo.EXPECT().CreateWithAllocation(fiberCtx.Context(), gomock.Any()).Return(dbRepositories.ErrInsufficientAllocation)
+
+	response, err := hs.Create(fiberCtx.Context(), &request)
+	if err == nil {
+		t.Fatalf("Expected error to be not nil, got nil")
+	}
+
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, apperrors.ErrTicketAllocations)
+}
+
+func TestHoldService_Confirm_Success(t *testing.T) {
+	teardown := setupHoldTest(t)
+	defer teardown()
+
+	id := "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4e"
+	purchaseId := "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4f"
+
+	userId := "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4c"
+
+	holdRepo.EXPECT().FindById(fiberCtx.Context(), id).Return(&models.Hold{
+		Id:        id,
+		TicketId:  "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		UserId:    userId,
+		Quantity:  2,
+		Status:    models.HoldStatusActive,
+		ExpiresAt: holdMockTime.Add(time.Minute),
+	}, nil)
+	holdRepo.EXPECT().Confirm(fiberCtx.Context(), id, gomock.Any()).DoAndReturn(func(_ any, _ string, purchase *models.Purchase) (*models.Hold, error) {
+		assert.NotEmpty(t, purchase.Id)
+		assert.Equal(t, userId, purchase.UserId)
+		assert.Equal(t, 2, purchase.Quantity)
+		assert.Equal(t, holdMockTime, purchase.CreatedAt)
+		return &models.Hold{
+			Id:         id,
+			TicketId:   "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+			Quantity:   2,
+			Status:     models.HoldStatusConfirmed,
+			PurchaseId: &purchaseId,
+		}, nil
+	})
+
+	response, err := hs.Confirm(fiberCtx.Context(), id, userId)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	assert.Equal(t, models.HoldStatusConfirmed, response.Status)
+	assert.Equal(t, purchaseId, response.PurchaseId)
+}
+
+func TestHoldService_Confirm_Expired(t *testing.T) {
+	teardown := setupHoldTest(t)
+	defer teardown()
+
+	holdRepo.EXPECT().FindById(fiberCtx.Context(), "expired").Return(&models.Hold{
+		Id:        "expired",
+		UserId:    "user",
+		Status:    models.HoldStatusActive,
+		ExpiresAt: holdMockTime.Add(-time.Minute),
+	}, nil)
+	holdRepo.EXPECT().Confirm(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	response, err := hs.Confirm(fiberCtx.Context(), "expired", "user")
+	if err == nil {
+		t.Fatalf("Expected error to be not nil, got nil")
+	}
+
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, apperrors.ErrHoldExpired)
+}
+
+func TestHoldService_Confirm_Other_User(t *testing.T) {
+	teardown := setupHoldTest(t)
+	defer teardown()
+
+	holdRepo.EXPECT().FindById(fiberCtx.Context(), "hold").Return(&models.Hold{Id: "hold", UserId: "owner"}, nil)
+
+	response, err := hs.Confirm(fiberCtx.Context(), "hold", "someone-else")
+	if err == nil {
+		t.Fatalf("Expected error to be not nil, got nil")
+	}
+
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, apperrors.ErrForbidden)
+}
+
+func TestHoldService_ReleaseExpired_Batches(t *testing.T) {
+	teardown := setupHoldTest(t)
+	defer teardown()
+
+	gomock.InOrder(
+		holdRepo.EXPECT().ReleaseExpired(fiberCtx.Context(), holdMockTime, releaseBatchSize).Return(make([]models.Hold, releaseBatchSize), nil),
+		holdRepo.EXPECT().ReleaseExpired(fiberCtx.Context(), holdMockTime, releaseBatchSize).Return(make([]models.Hold, 7), nil),
+	)
+
+	released, err := hs.ReleaseExpired(fiberCtx.Context())
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	assert.Equal(t, releaseBatchSize+7, released)
+}
+
+func TestHoldService_Create_Sale_Not_Started(t *testing.T) {
+	teardown := setupHoldTest(t)
+	defer teardown()
+
+	request := dto.HoldCreateRequest{
+		TicketId: "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		UserId:   "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		Quantity: 1,
+	}
+
+	startsAt := holdMockTime.Add(time.Minute)
+	ticket := mockTicketData[0]
+	ticket.SaleStartsAt = &startsAt
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), request.TicketId).Return(&ticket, nil)
+	holdRepo.EXPECT().CreateWithAllocation(gomock.Any(), gomock.Any()).Times(0)
+
+	response, err := hs.Create(fiberCtx.Context(), &request)
+
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, apperrors.ErrSaleNotStarted)
+}
+
+func TestHoldService_Create_Max_Per_User(t *testing.T) {
+	teardown := setupHoldTest(t)
+	defer teardown()
+
+	request := dto.HoldCreateRequest{
+		TicketId: "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		UserId:   "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		Quantity: 2,
+	}
+
+	ticket := mockTicketData[0]
+	ticket.MaxPerUser = 1
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), request.TicketId).Return(&ticket, nil)
+	holdRepo.EXPECT().CreateWithAllocation(fiberCtx.Context(), gomock.Any()).Return(dbRepositories.ErrMaxPerUserExceeded)
+
+	response, err := hs.Create(fiberCtx.Context(), &request)
+
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, apperrors.ErrMaxPerUserExceeded)
+}
+
+func TestHoldService_Confirm_Payment_Declined(t *testing.T) {
+	teardown := setupHoldTest(t)
+	defer teardown()
+
+	paymentProvider, _ = payments.NewFakeProvider(payments.FakeDecline)
+	hs = NewHoldService(holdRepo, ticketRepo, newTestPaymentService(), config.HoldConfig{})
+
+	holdRepo.EXPECT().FindById(fiberCtx.Context(), "hold").Return(&models.Hold{
+		Id:        "hold",
+		UserId:    "user",
+		Quantity:  2,
+		UnitPrice: 1250,
+		Currency:  "TRY",
+		Status:    models.HoldStatusActive,
+		ExpiresAt: holdMockTime.Add(time.Minute),
+	}, nil)
+	expectPaymentStatuses(models.PaymentStatusDeclined)
+	holdRepo.EXPECT().Confirm(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	response, err := hs.Confirm(fiberCtx.Context(), "hold", "user")
+
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, apperrors.ErrPaymentDeclined)
+}
@@ -0,0 +1,23 @@
+package services
+
+import (
+	"context"
+	"ticket-purchase/internal/db/repositories"
+)
+
+type IdempotencyService interface {
+	// DeleteExpired removes the idempotency keys past their TTL and returns how many were removed
+	DeleteExpired(ctx context.Context) (int, error)
+}
+
+type idempotencyService struct {
+	idempotencyRepo repositories.IdempotencyRepository
+}
+
+func NewIdempotencyService(idempotencyRepo repositories.IdempotencyRepository) IdempotencyService {
+	return &idempotencyService{idempotencyRepo: idempotencyRepo}
+}
+
+func (s *idempotencyService) DeleteExpired(ctx context.Context) (int, error) {
+	return s.idempotencyRepo.DeleteExpired(ctx, timeNow())
+}
@@ -0,0 +1,27 @@
+package services
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"ticket-purchase/internal/mocks/repositories"
+	"time"
+)
+
+func TestIdempotencyService_DeleteExpired(t *testing.T) {
+	timeNow = func() time.Time {
+		return webhookMockTime
+	}
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	idempotencyRepo := repositories.NewMockIdempotencyRepository(gomock.NewController(t))
+	idempotencyRepo.EXPECT().DeleteExpired(gomock.Any(), webhookMockTime).Return(3, nil)
+
+	deleted, err := NewIdempotencyService(idempotencyRepo).DeleteExpired(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, deleted)
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v2/log"
+	"gorm.io/gorm"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/db/models"
+	"ticket-purchase/internal/db/repositories"
+	"ticket-purchase/pkg/money"
+	"time"
+)
+
+// persistBlock is how long the persister waits for new sales before it checks for abandoned ones again
+const persistBlock = 2 * time.Second
+
+type InventoryService interface {
+	// PersistSales writes a batch of sales taken from the Redis counters to the database and returns how many
+	// were written. Sales the database refuses are given back to the counters.
+	PersistSales(ctx context.Context, consumer string) (int, error)
+	// Reconcile compares the Redis counters with the ticket allocations, repairs the ones that drifted the same
+	// way two rounds in a row and returns how many were repaired
+	Reconcile(ctx context.Context) (int, error)
+}
+
+type inventoryService struct {
+	inventoryRepo repositories.InventoryRepository
+	ticketRepo    repositories.TicketRepository
+	purchaseRepo  repositories.PurchaseRepository
+	payments      PaymentService
+	conf          config.InventoryConfig
+
+	// drifts are the drifts of the counters seen in the last round of Reconcile
+	drifts map[string]int
+}
+
+// NewInventoryService needs the database repositories, not the Redis backed or cached ones
+func NewInventoryService(
+	inventoryRepo repositories.InventoryRepository,
+	ticketRepo repositories.TicketRepository,
+	purchaseRepo repositories.PurchaseRepository,
+	payments PaymentService,
+	conf config.InventoryConfig,
+) InventoryService {
+	return &inventoryService{
+		inventoryRepo: inventoryRepo,
+		ticketRepo:    ticketRepo,
+		purchaseRepo:  purchaseRepo,
+		payments:      payments,
+		conf:          conf,
+		drifts:        map[string]int{},
+	}
+}
+
+func (s *inventoryService) PersistSales(ctx context.Context, consumer string) (int, error) {
+	sales, err := s.inventoryRepo.ClaimSales(ctx, consumer, s.conf.BatchSize, s.conf.ClaimIdle, persistBlock)
+	if err != nil {
+		return 0, err
+	}
+
+	persisted := 0
+	for _, sale := range sales {
+		ok, err := s.persist(ctx, sale)
+		if err != nil {
+			// The sale stays unacknowledged and is retried once it has been idle for ClaimIdle
+			log.Error("Error persisting purchase ", sale.PurchaseId, ": ", err)
+			continue
+		}
+
+		if ok {
+			persisted++
+		}
+	}
+	return persisted, nil
+}
+
+// persist writes a sale to the database. It reports false when the database refused the sale.
+func (s *inventoryService) persist(ctx context.Context, sale repositories.InventorySale) (bool, error) {
+	// A sale claimed again after a crash may already be written
+	_, err := s.purchaseRepo.FindById(ctx, sale.PurchaseId)
+	if err == nil {
+		return true, s.inventoryRepo.Persisted(ctx, sale)
+	}
+
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+
+	purchase := models.Purchase{
+		Id:        sale.PurchaseId,
+		TicketId:  sale.TicketId,
+		UserId:    sale.UserId,
+		Quantity:  sale.Quantity,
+		CreatedBy: sale.UserId,
+		UpdatedBy: sale.UserId,
+		CreatedAt: sale.CreatedAt,
+		UpdatedAt: sale.CreatedAt,
+	}
+	if sale.Currency != "" {
+		purchase.SetPrice(money.New(sale.UnitPrice, sale.Currency))
+	}
+
+	err = s.purchaseRepo.CreateWithAllocation(ctx, &purchase)
+	if errors.Is(err, repositories.ErrInsufficientAllocation) ||
+		errors.Is(err, repositories.ErrTicketInactive) ||
+		errors.Is(err, repositories.ErrMaxPerUserExceeded) ||
+		errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Purchase ", sale.PurchaseId, " of ticket ", sale.TicketId, " was refused by the database: ", err)
+
+		// The buyer was already charged when the counters sold the tickets. Nobody was told about the purchase yet,
+		// its events are only written with it.
+		if err := s.payments.Cancel(ctx, sale.PurchaseId); err != nil {
+			log.Error("Error cancelling the payment of purchase ", sale.PurchaseId, ": ", err)
+		}
+		return false, s.inventoryRepo.Rejected(ctx, sale)
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, s.inventoryRepo.Persisted(ctx, sale)
+}
+
+func (s *inventoryService) Reconcile(ctx context.Context) (int, error) {
+	ticketIds, err := s.inventoryRepo.TicketIds(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	drifts := make(map[string]int, len(s.drifts))
+	defer func() {
+		s.drifts = drifts
+	}()
+
+	repaired := 0
+	for _, ticketId := range ticketIds {
+		snapshot, err := s.inventoryRepo.Snapshot(ctx, ticketId)
+		if err != nil {
+			return repaired, err
+		}
+
+		if !snapshot.Loaded {
+			continue
+		}
+
+		allocation, err := s.ticketRepo.FindAllocation(ctx, ticketId)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return repaired, err
+		}
+
+		// Pending sales are taken from the counter but not yet from the database
+		drift := allocation - snapshot.Pending - snapshot.Available
+		if drift == 0 {
+			continue
+		}
+
+		// The counter and the database are not read at the same time, so a drift is only repaired once it was seen
+		// again unchanged. Changes in between move both by the same amount and leave the drift as it is.
+		if previous, ok := s.drifts[ticketId]; !ok || previous != drift {
+			drifts[ticketId] = drift
+			continue
+		}
+
+		if err := s.inventoryRepo.Adjust(ctx, ticketId, drift); err != nil {
+			return repaired, err
+		}
+
+		log.Warnf("Repaired inventory of ticket %s by %d to %d", ticketId, drift, snapshot.Available+drift)
+		repaired++
+	}
+	return repaired, nil
+}
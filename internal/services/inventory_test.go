@@ -0,0 +1,183 @@
+package services
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+	"testing"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/db/models"
+	dbRepositories "ticket-purchase/internal/db/repositories"
+	"ticket-purchase/internal/mocks/repositories"
+	"time"
+)
+
+var is InventoryService
+var inventoryRepo *repositories.MockInventoryRepository
+var inventoryMockTime = time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+var inventoryTestConf = config.InventoryConfig{
+	Backend:   config.InventoryBackendRedis,
+	BatchSize: 10,
+	ClaimIdle: 30 * time.Second,
+}
+
+func setupInventoryTest(t *testing.T) func() {
+	teardown := setupTicketTest(t)
+
+	inventoryRepo = repositories.NewMockInventoryRepository(gomock.NewController(t))
+	is = NewInventoryService(inventoryRepo, ticketRepo, purchaseRepo, newTestPaymentService(), inventoryTestConf)
+	return func() {
+		is = nil
+		teardown()
+	}
+}
+
+func mockInventorySale(purchaseId string) dbRepositories.InventorySale {
+	return dbRepositories.InventorySale{
+		MessageId:  "1-" + purchaseId,
+		PurchaseId: purchaseId,
+		TicketId:   mockTicketData[0].Id,
+		UserId:     "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		Quantity:   2,
+		UnitPrice:  1250,
+		Currency:   "TRY",
+		CreatedAt:  inventoryMockTime,
+	}
+}
+
+func expectClaim(sales ...dbRepositories.InventorySale) {
+	inventoryRepo.EXPECT().
+		ClaimSales(fiberCtx.Context(), "api-1", inventoryTestConf.BatchSize, inventoryTestConf.ClaimIdle, persistBlock).
+		Return(sales, nil)
+}
+
+func TestInventoryService_PersistSales_Success(t *testing.T) {
+	teardown := setupInventoryTest(t)
+	defer teardown()
+
+	sale := mockInventorySale("purchase-1")
+	purchase := models.Purchase{
+		Id:        sale.PurchaseId,
+		TicketId:  sale.TicketId,
+		UserId:    sale.UserId,
+		Quantity:  sale.Quantity,
+		UnitPrice: 1250,
+		Total:     2500,
+		Currency:  "TRY",
+		CreatedBy: sale.UserId,
+		UpdatedBy: sale.UserId,
+		CreatedAt: inventoryMockTime,
+		UpdatedAt: inventoryMockTime,
+	}
+
+	expectClaim(sale)
+	purchaseRepo.EXPECT().FindById(fiberCtx.Context(), sale.PurchaseId).Return(nil, gorm.ErrRecordNotFound)
+	purchaseRepo.EXPECT().CreateWithAllocation(fiberCtx.Context(), &purchase).Return(nil)
+	inventoryRepo.EXPECT().Persisted(fiberCtx.Context(), sale).Return(nil)
+
+	persisted, err := is.PersistSales(fiberCtx.Context(), "api-1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, persisted)
+}
+
+func TestInventoryService_PersistSales_Already_Persisted(t *testing.T) {
+	teardown := setupInventoryTest(t)
+	defer teardown()
+
+	sale := mockInventorySale("purchase-1")
+
+	expectClaim(sale)
+	purchaseRepo.EXPECT().FindById(fiberCtx.Context(), sale.PurchaseId).Return(&mockPurchaseData[0], nil)
+	purchaseRepo.EXPECT().CreateWithAllocation(gomock.Any(), gomock.Any()).Times(0)
+	inventoryRepo.EXPECT().Persisted(fiberCtx.Context(), sale).Return(nil)
+
+	persisted, err := is.PersistSales(fiberCtx.Context(), "api-1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, persisted)
+}
+
+func TestInventoryService_PersistSales_Rejected(t *testing.T) {
+	teardown := setupInventoryTest(t)
+	defer teardown()
+
+	sale := mockInventorySale("purchase-1")
+
+	expectClaim(sale)
+	purchaseRepo.EXPECT().FindById(fiberCtx.Context(), sale.PurchaseId).Return(nil, gorm.ErrRecordNotFound)
+	purchaseRepo.EXPECT().CreateWithAllocation(fiberCtx.Context(), gomock.Any()).Return(dbRepositories.ErrTicketInactive)
+	inventoryRepo.EXPECT().Rejected(fiberCtx.Context(), sale).Return(nil)
+
+	// The buyer is refunded in full
+	payment := capturedPayment(t, &models.Purchase{Id: sale.PurchaseId, UserId: sale.UserId, Total: 2500, Currency: "TRY"})
+	paymentRepo.EXPECT().FindByPurchaseId(fiberCtx.Context(), sale.PurchaseId).Return(payment, nil)
+	paymentRepo.EXPECT().AddRefund(fiberCtx.Context(), payment, int64(2500)).Return(nil)
+
+	persisted, err := is.PersistSales(fiberCtx.Context(), "api-1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, persisted)
+}
+
+func TestInventoryService_PersistSales_Database_Error_Keeps_Sale(t *testing.T) {
+	teardown := setupInventoryTest(t)
+	defer teardown()
+
+	failing := mockInventorySale("purchase-1")
+	sale := mockInventorySale("purchase-2")
+
+	expectClaim(failing, sale)
+	purchaseRepo.EXPECT().FindById(fiberCtx.Context(), failing.PurchaseId).Return(nil, errors.New("connection reset"))
+	purchaseRepo.EXPECT().FindById(fiberCtx.Context(), sale.PurchaseId).Return(nil, gorm.ErrRecordNotFound)
+	purchaseRepo.EXPECT().CreateWithAllocation(fiberCtx.Context(), gomock.Any()).Return(nil)
+	inventoryRepo.EXPECT().Persisted(fiberCtx.Context(), sale).Return(nil)
+
+	persisted, err := is.PersistSales(fiberCtx.Context(), "api-1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, persisted)
+}
+
+func TestInventoryService_Reconcile(t *testing.T) {
+	teardown := setupInventoryTest(t)
+	defer teardown()
+
+	drifted := mockTicketData[0]
+	inSync := mockTicketData[1]
+	ticketIds := []string{drifted.Id, inSync.Id, "pending", "moving", "missing"}
+
+	// The counter of drifted is 5 short in both rounds, the one of moving is behind by a different amount each time
+	for round, moved := range []int{1, 2} {
+		inventoryRepo.EXPECT().TicketIds(fiberCtx.Context()).Return(ticketIds, nil)
+		inventoryRepo.EXPECT().Snapshot(fiberCtx.Context(), drifted.Id).
+			Return(dbRepositories.InventorySnapshot{Loaded: true, Available: drifted.Allocation - 5}, nil)
+		inventoryRepo.EXPECT().Snapshot(fiberCtx.Context(), inSync.Id).
+			Return(dbRepositories.InventorySnapshot{Loaded: true, Available: inSync.Allocation}, nil)
+		inventoryRepo.EXPECT().Snapshot(fiberCtx.Context(), "pending").
+			Return(dbRepositories.InventorySnapshot{Loaded: true, Available: 1, Pending: 2}, nil)
+		inventoryRepo.EXPECT().Snapshot(fiberCtx.Context(), "moving").
+			Return(dbRepositories.InventorySnapshot{Loaded: true, Available: 10 - moved}, nil)
+		inventoryRepo.EXPECT().Snapshot(fiberCtx.Context(), "missing").
+			Return(dbRepositories.InventorySnapshot{Loaded: true, Available: 1}, nil)
+
+		ticketRepo.EXPECT().FindAllocation(fiberCtx.Context(), drifted.Id).Return(drifted.Allocation, nil)
+		ticketRepo.EXPECT().FindAllocation(fiberCtx.Context(), inSync.Id).Return(inSync.Allocation, nil)
+		ticketRepo.EXPECT().FindAllocation(fiberCtx.Context(), "pending").Return(3, nil)
+		ticketRepo.EXPECT().FindAllocation(fiberCtx.Context(), "moving").Return(10, nil)
+		ticketRepo.EXPECT().FindAllocation(fiberCtx.Context(), "missing").Return(0, gorm.ErrRecordNotFound)
+
+		want := 0
+		if round == 1 {
+			inventoryRepo.EXPECT().Adjust(fiberCtx.Context(), drifted.Id, 5).Return(nil)
+			want = 1
+		}
+
+		repaired, err := is.Reconcile(fiberCtx.Context())
+
+		assert.NoError(t, err)
+		assert.Equal(t, want, repaired)
+	}
+}
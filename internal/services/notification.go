@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2/log"
+	"ticket-purchase/internal/db/repositories"
+	"ticket-purchase/internal/events"
+	"ticket-purchase/internal/mail"
+)
+
+// MailQueue delivers emails in the background
+type MailQueue interface {
+	// Enqueue queues an email without blocking. It returns false when the email is dropped.
+	Enqueue(message mail.Message) bool
+}
+
+type NotificationService interface {
+	// HandleEvent queues the confirmation email of every purchase written to the database. It consumes the domain
+	// events of the outbox. Failures are logged and never fail the event.
+	HandleEvent(ctx context.Context, event events.Event) error
+}
+
+type notificationService struct {
+	ticketRepo repositories.TicketRepository
+	userRepo   repositories.UserRepository
+	mailQueue  MailQueue
+}
+
+func NewNotificationService(
+	ticketRepo repositories.TicketRepository,
+	userRepo repositories.UserRepository,
+	mailQueue MailQueue,
+) NotificationService {
+	return &notificationService{
+		ticketRepo: ticketRepo,
+		userRepo:   userRepo,
+		mailQueue:  mailQueue,
+	}
+}
+
+func (s *notificationService) HandleEvent(ctx context.Context, event events.Event) error {
+	if event.Type != events.TypeTicketPurchased {
+		return nil
+	}
+
+	var purchase events.TicketPurchased
+	if err := json.Unmarshal(event.Payload, &purchase); err != nil {
+		log.Error("Error decoding event ", event.Id, " of type ", event.Type, ": ", err)
+		return nil
+	}
+
+	s.purchaseConfirmed(ctx, purchase)
+	return nil
+}
+
+// purchaseConfirmed queues the confirmation email of a purchase
+func (s *notificationService) purchaseConfirmed(ctx context.Context, purchase events.TicketPurchased) {
+	user, err := s.userRepo.FindById(ctx, purchase.UserId)
+	if err != nil {
+		log.Error("Error loading the user of purchase confirmation ", purchase.PurchaseId, ": ", err)
+		return
+	}
+
+	ticket, err := s.ticketRepo.FindById(ctx, purchase.TicketId)
+	if err != nil {
+		log.Error("Error loading the ticket of purchase confirmation ", purchase.PurchaseId, ": ", err)
+		return
+	}
+
+	message, err := mail.Render(mail.PurchaseConfirmationTemplate, user.Locale, user.Email, mail.PurchaseConfirmation{
+		PurchaseId: purchase.PurchaseId,
+		TicketName: ticket.Name,
+		Quantity:   purchase.Quantity,
+	})
+	if err != nil {
+		log.Error("Error rendering purchase confirmation ", purchase.PurchaseId, ": ", err)
+		return
+	}
+
+	s.mailQueue.Enqueue(message)
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"ticket-purchase/internal/events"
+	"ticket-purchase/internal/mail"
+)
+
+var ns NotificationService
+var mailQueue *recordingMailQueue
+
+// recordingMailQueue keeps the queued emails instead of sending them
+type recordingMailQueue struct {
+	messages []mail.Message
+}
+
+func (q *recordingMailQueue) Enqueue(message mail.Message) bool {
+	q.messages = append(q.messages, message)
+	return true
+}
+
+func setupNotificationTest(t *testing.T) func() {
+	teardown := setupAuthTest(t)
+
+	mailQueue = &recordingMailQueue{}
+	ns = NewNotificationService(ticketRepo, userRepo, mailQueue)
+	return func() {
+		ns = nil
+		teardown()
+	}
+}
+
+// purchasedEvent is the TicketPurchased event of a purchase as the outbox relays it
+func purchasedEvent(t *testing.T, purchase events.TicketPurchased) events.Event {
+	payload, err := json.Marshal(purchase)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	return events.Event{
+		Id:       "event-1",
+		Type:     events.TypeTicketPurchased,
+		TicketId: purchase.TicketId,
+		Payload:  payload,
+	}
+}
+
+func TestNotificationService_HandleEvent_Localized(t *testing.T) {
+	teardown := setupNotificationTest(t)
+	defer teardown()
+
+	user := mockUser(t, "correct-horse")
+	ticket := mockTicketData[0]
+	event := purchasedEvent(t, events.TicketPurchased{PurchaseId: "purchase-1", TicketId: ticket.Id, UserId: user.Id, Quantity: 3})
+
+	userRepo.EXPECT().FindById(fiberCtx.Context(), user.Id).Return(user, nil)
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), ticket.Id).Return(&ticket, nil)
+
+	assert.NoError(t, ns.HandleEvent(fiberCtx.Context(), event))
+
+	assert.Len(t, mailQueue.messages, 1)
+	message := mailQueue.messages[0]
+	assert.Equal(t, []string{user.Email}, message.To)
+	assert.Equal(t, "Ticket 1 biletleriniz", message.Subject)
+	assert.Contains(t, message.Text, "Adet: 3")
+	assert.Contains(t, message.Text, "Satın alma numarası: purchase-1")
+	assert.Contains(t, message.HTML, "<strong>Ticket 1</strong>")
+}
+
+func TestNotificationService_HandleEvent_User_Lookup_Fails(t *testing.T) {
+	teardown := setupNotificationTest(t)
+	defer teardown()
+
+	event := purchasedEvent(t, events.TicketPurchased{PurchaseId: "purchase-1", TicketId: mockTicketData[0].Id, UserId: "missing"})
+
+	userRepo.EXPECT().FindById(fiberCtx.Context(), "missing").Return(nil, errors.New("connection refused"))
+
+	// The email is lost, the event is not retried for it
+	assert.NoError(t, ns.HandleEvent(fiberCtx.Context(), event))
+	assert.Empty(t, mailQueue.messages)
+}
+
+func TestNotificationService_HandleEvent_Ignores_Other_Events(t *testing.T) {
+	teardown := setupNotificationTest(t)
+	defer teardown()
+
+	event := purchasedEvent(t, events.TicketPurchased{PurchaseId: "purchase-1", TicketId: mockTicketData[0].Id})
+	event.Type = events.TypeAllocationChanged
+
+	assert.NoError(t, ns.HandleEvent(fiberCtx.Context(), event))
+	assert.Empty(t, mailQueue.messages)
+}
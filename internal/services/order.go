@@ -0,0 +1,313 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v2/log"
+	"gorm.io/gorm"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/auth"
+	"ticket-purchase/internal/db/models"
+	"ticket-purchase/internal/db/repositories"
+	"ticket-purchase/internal/dto"
+)
+
+// orderLineRejected is the status of a line that failed a checkout
+const orderLineRejected = "rejected"
+
+type OrderService interface {
+	// FindById finds an order visible to the actor
+	FindById(ctx context.Context, id string, actor auth.Actor) (*dto.OrderResponse, error)
+	// Cart returns the cart of the user, which is empty until a line is added
+	Cart(ctx context.Context, userId string) (*dto.OrderResponse, error)
+	// AddLine adds tickets to the cart of the user. Adding a ticket that is already in the cart adds to its quantity.
+	AddLine(ctx context.Context, request *dto.CartLineCreateRequest) (*dto.OrderResponse, error)
+	// UpdateLine changes the quantity of a line of the cart
+	UpdateLine(ctx context.Context, lineId string, request *dto.CartLineUpdateRequest) (*dto.OrderResponse, error)
+	// RemoveLine removes a line from the cart
+	RemoveLine(ctx context.Context, userId string, lineId string) (*dto.OrderResponse, error)
+	// Checkout purchases every line of the cart or none of them. A rejected checkout returns ErrCheckoutRejected
+	// with the order, whose lines tell which of them could not be purchased.
+	Checkout(ctx context.Context, userId string) (*dto.OrderResponse, error)
+}
+
+type orderService struct {
+	orderRepo  repositories.OrderRepository
+	ticketRepo repositories.TicketRepository
+	payments   PaymentService
+}
+
+func NewOrderService(
+	orderRepo repositories.OrderRepository,
+	ticketRepo repositories.TicketRepository,
+	payments PaymentService,
+) OrderService {
+	return &orderService{
+		orderRepo:  orderRepo,
+		ticketRepo: ticketRepo,
+		payments:   payments,
+	}
+}
+
+func (s *orderService) FindById(ctx context.Context, id string, actor auth.Actor) (*dto.OrderResponse, error) {
+	order, err := s.orderRepo.FindById(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.ErrNotFound.Wrap(err)
+	}
+
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	if !actor.Owns(order.UserId) && !actor.Can(auth.PermPurchaseRead) {
+		return nil, apperrors.ErrForbidden
+	}
+
+	return orderResponse(order, nil), nil
+}
+
+func (s *orderService) Cart(ctx context.Context, userId string) (*dto.OrderResponse, error) {
+	cart, err := s.findCart(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return orderResponse(cart, nil), nil
+}
+
+func (s *orderService) AddLine(ctx context.Context, request *dto.CartLineCreateRequest) (*dto.OrderResponse, error) {
+	cart, err := s.findCart(ctx, request.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	quantity := request.Quantity
+	if line := cart.LineOf(request.TicketId); line != nil {
+		quantity += line.Quantity
+	}
+
+	return s.saveLine(ctx, cart, request.TicketId, request.UserId, quantity)
+}
+
+func (s *orderService) UpdateLine(ctx context.Context, lineId string, request *dto.CartLineUpdateRequest) (*dto.OrderResponse, error) {
+	cart, err := s.findCart(ctx, request.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	line := cart.Line(lineId)
+	if line == nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	return s.saveLine(ctx, cart, line.TicketId, request.UserId, request.Quantity)
+}
+
+func (s *orderService) RemoveLine(ctx context.Context, userId string, lineId string) (*dto.OrderResponse, error) {
+	err := s.orderRepo.RemoveLine(ctx, userId, lineId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.ErrNotFound.Wrap(err)
+	}
+
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	return s.Cart(ctx, userId)
+}
+
+func (s *orderService) Checkout(ctx context.Context, userId string) (*dto.OrderResponse, error) {
+	order, err := s.findCart(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(order.Lines) == 0 {
+		return nil, apperrors.ErrCartEmpty
+	}
+
+	// Lines are priced again, the prices may have changed since they were added
+	now := timeNow()
+	lineErrors := map[string]error{}
+	order.Total = 0
+	order.Currency = ""
+	for i := range order.Lines {
+		line := &order.Lines[i]
+		ticket, err := s.checkLine(ctx, order, line.TicketId, line.Quantity)
+		if err != nil {
+			lineErrors[line.Id] = err
+			continue
+		}
+
+		line.SetPrice(ticket.UnitPrice())
+		line.UpdatedBy = userId
+		line.UpdatedAt = now
+		order.Total += line.Total
+		order.Currency = line.Currency
+	}
+
+	if len(lineErrors) > 0 {
+		return nil, apperrors.ErrCheckoutRejected.WithData(orderResponse(order, lineErrors))
+	}
+
+	// Every line is paid on its own, so that it can be refunded like any purchase. The payments are authorized before
+	// the allocation is taken, so a declined card never holds tickets.
+	linePayments := make([]*models.Payment, 0, len(order.Lines))
+	for i := range order.Lines {
+		payment, err := s.payments.Authorize(ctx, &order.Lines[i])
+		if err != nil {
+			s.voidPayments(ctx, linePayments)
+			lineErrors[order.Lines[i].Id] = err
+			return nil, apperrors.ErrCheckoutRejected.WithData(orderResponse(order, lineErrors)).Wrap(err)
+		}
+		linePayments = append(linePayments, payment)
+	}
+
+	order.UpdatedAt = now
+	order.CheckedOutAt = &now
+	err = s.orderRepo.Checkout(ctx, order)
+	if err != nil {
+		s.voidPayments(ctx, linePayments)
+		return nil, checkoutError(err, order)
+	}
+
+	// The tickets are sold at this point. An authorization that could not be captured stays authorized and is
+	// settled by hand.
+	for i, payment := range linePayments {
+		if err := s.payments.Capture(ctx, payment); err != nil {
+			log.Error("Error capturing the payment of purchase ", order.Lines[i].Id, ": ", err)
+		}
+	}
+
+	return orderResponse(order, nil), nil
+}
+
+// findCart returns the cart of the user, or an empty one when the user has none
+func (s *orderService) findCart(ctx context.Context, userId string) (*models.Order, error) {
+	cart, err := s.orderRepo.FindCart(ctx, userId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.Order{UserId: userId, Status: models.OrderStatusCart}, nil
+	}
+
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	return cart, nil
+}
+
+// checkLine returns the ticket of a line of the order. Besides the checks of a purchase, tickets sold through a
+// waiting room and tickets priced in another currency than the rest of the order are refused.
+func (s *orderService) checkLine(ctx context.Context, order *models.Order, ticketId string, quantity int) (*models.Ticket, error) {
+	ticket, err := checkOrder(ctx, s.ticketRepo, ticketId, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	if ticket.WaitingRoom {
+		return nil, apperrors.ErrTicketWaitingRoom
+	}
+
+	for _, line := range order.Lines {
+		if line.TicketId != ticketId && line.Currency != ticket.Currency {
+			return nil, apperrors.ErrOrderCurrencyMismatch
+		}
+	}
+	return ticket, nil
+}
+
+func (s *orderService) saveLine(ctx context.Context, cart *models.Order, ticketId string, userId string, quantity int) (*dto.OrderResponse, error) {
+	ticket, err := s.checkLine(ctx, cart, ticketId, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	now := timeNow()
+	line := models.Purchase{
+		TicketId:  ticketId,
+		UserId:    userId,
+		Quantity:  quantity,
+		CreatedBy: userId,
+		UpdatedBy: userId,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	line.SetPrice(ticket.UnitPrice())
+
+	if err := s.orderRepo.SaveLine(ctx, &line); err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	return s.Cart(ctx, userId)
+}
+
+func (s *orderService) voidPayments(ctx context.Context, linePayments []*models.Payment) {
+	for _, payment := range linePayments {
+		if err := s.payments.Void(ctx, payment); err != nil {
+			log.Error("Error voiding the payment of purchase ", payment.PurchaseId, ": ", err)
+		}
+	}
+}
+
+// checkoutError maps the errors of reserving the lines of an order to domain errors
+func checkoutError(err error, order *models.Order) error {
+	var rejected *repositories.CheckoutError
+	if errors.As(err, &rejected) {
+		lineErrors := make(map[string]error, len(rejected.Lines))
+		for id, lineErr := range rejected.Lines {
+			lineErrors[id] = purchaseError(lineErr)
+		}
+		return apperrors.ErrCheckoutRejected.WithData(orderResponse(order, lineErrors)).Wrap(err)
+	}
+
+	if errors.Is(err, repositories.ErrOrderNotCart) || errors.Is(err, repositories.ErrCartChanged) {
+		return apperrors.ErrCartChanged.Wrap(err)
+	}
+
+	return apperrors.ErrOrderCheckout.Wrap(err)
+}
+
+// orderResponse builds the order response. Lines with an error are reported as rejected.
+func orderResponse(order *models.Order, lineErrors map[string]error) *dto.OrderResponse {
+	response := dto.OrderResponse{
+		Id:           order.Id,
+		UserId:       order.UserId,
+		Status:       order.Status,
+		Total:        order.Total,
+		Currency:     order.Currency,
+		Lines:        make([]dto.OrderLineResponse, 0, len(order.Lines)),
+		CheckedOutAt: order.CheckedOutAt,
+		CreatedAt:    order.CreatedAt,
+		UpdatedAt:    order.UpdatedAt,
+	}
+
+	// The total of a cart follows its lines, it is only stored at checkout
+	if order.Status == models.OrderStatusCart {
+		response.Total = 0
+		for _, line := range order.Lines {
+			response.Total += line.Total
+			response.Currency = line.Currency
+		}
+	}
+
+	for i := range order.Lines {
+		line := &order.Lines[i]
+		lineResponse := dto.OrderLineResponse{
+			Id:        line.Id,
+			TicketId:  line.TicketId,
+			Quantity:  line.Quantity,
+			UnitPrice: line.UnitPrice,
+			Total:     line.Total,
+			Currency:  line.Currency,
+			Status:    line.Status,
+			Ticket:    ticketSummary(&line.Ticket),
+		}
+		if err, ok := lineErrors[line.Id]; ok {
+			lineResponse.Status = orderLineRejected
+			lineResponse.Error = apperrors.From(err).Code
+		}
+		response.Lines = append(response.Lines, lineResponse)
+	}
+
+	return &response
+}
@@ -0,0 +1,300 @@
+package services
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+	"testing"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/auth"
+	"ticket-purchase/internal/db/models"
+	dbRepositories "ticket-purchase/internal/db/repositories"
+	"ticket-purchase/internal/dto"
+	"ticket-purchase/internal/mocks/repositories"
+	"ticket-purchase/internal/payments"
+	"time"
+)
+
+const orderUserId = "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b"
+
+var ords OrderService
+var orderRepo *repositories.MockOrderRepository
+
+var orderMockTime = time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+func setupOrderTest(t *testing.T) func() {
+	teardown := setupTicketTest(t)
+
+	timeNow = func() time.Time {
+		return orderMockTime
+	}
+
+	ct := gomock.NewController(t)
+	orderRepo = repositories.NewMockOrderRepository(ct)
+	ords = NewOrderService(orderRepo, ticketRepo, newTestPaymentService())
+	return func() {
+		ords = nil
+		timeNow = time.Now
+		teardown()
+	}
+}
+
+// cartLine returns a line of the cart for the ticket
+func cartLine(id string, ticket models.Ticket, quantity int) models.Purchase {
+	orderId := "order-1"
+	line := models.Purchase{
+		Id:        id,
+		OrderId:   &orderId,
+		TicketId:  ticket.Id,
+		UserId:    orderUserId,
+		Quantity:  quantity,
+		Status:    models.PurchaseStatusCart,
+		CreatedBy: orderUserId,
+		UpdatedBy: orderUserId,
+		Ticket:    ticket,
+	}
+	line.SetPrice(ticket.UnitPrice())
+	return line
+}
+
+// cart returns the cart of the user with the lines
+func cart(lines ...models.Purchase) *models.Order {
+	return &models.Order{
+		Id:     "order-1",
+		UserId: orderUserId,
+		Status: models.OrderStatusCart,
+		Lines:  lines,
+	}
+}
+
+func TestOrderService_AddLine_Creates_Cart(t *testing.T) {
+	teardown := setupOrderTest(t)
+	defer teardown()
+
+	ticket := mockTicketData[0]
+	ticket.Price = 1250
+	ticket.Currency = "TRY"
+	request := dto.CartLineCreateRequest{TicketId: ticket.Id, Quantity: 2, UserId: orderUserId}
+
+	gomock.InOrder(
+		orderRepo.EXPECT().FindCart(fiberCtx.Context(), orderUserId).Return(nil, gorm.ErrRecordNotFound),
+		ticketRepo.EXPECT().FindById(fiberCtx.Context(), ticket.Id).Return(&ticket, nil),
+		orderRepo.EXPECT().SaveLine(fiberCtx.Context(), gomock.Any()).DoAndReturn(func(_ any, line *models.Purchase) error {
+			assert.Equal(t, &models.Purchase{
+				TicketId:  ticket.Id,
+				UserId:    orderUserId,
+				Quantity:  2,
+				UnitPrice: 1250,
+				Total:     2500,
+				Currency:  "TRY",
+				CreatedBy: orderUserId,
+				UpdatedBy: orderUserId,
+				CreatedAt: orderMockTime,
+				UpdatedAt: orderMockTime,
+			}, line)
+			return nil
+		}),
+		orderRepo.EXPECT().FindCart(fiberCtx.Context(), orderUserId).Return(cart(cartLine("line-1", ticket, 2)), nil),
+	)
+
+	response, err := ords.AddLine(fiberCtx.Context(), &request)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.OrderStatusCart, response.Status)
+	assert.Equal(t, int64(2500), response.Total)
+	assert.Equal(t, "TRY", response.Currency)
+	assert.Len(t, response.Lines, 1)
+	assert.Equal(t, models.PurchaseStatusCart, response.Lines[0].Status)
+	assert.Equal(t, ticket.Name, response.Lines[0].Ticket.Name)
+}
+
+func TestOrderService_AddLine_Adds_To_Quantity(t *testing.T) {
+	teardown := setupOrderTest(t)
+	defer teardown()
+
+	ticket := mockTicketData[0]
+	ticket.MaxPerOrder = 4
+	request := dto.CartLineCreateRequest{TicketId: ticket.Id, Quantity: 2, UserId: orderUserId}
+
+	// The line already has 3, 5 is above the per order limit
+	orderRepo.EXPECT().FindCart(fiberCtx.Context(), orderUserId).Return(cart(cartLine("line-1", ticket, 3)), nil)
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), ticket.Id).Return(&ticket, nil)
+	orderRepo.EXPECT().SaveLine(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := ords.AddLine(fiberCtx.Context(), &request)
+
+	assert.ErrorIs(t, err, apperrors.ErrMaxPerOrderExceeded)
+}
+
+func TestOrderService_AddLine_Waiting_Room(t *testing.T) {
+	teardown := setupOrderTest(t)
+	defer teardown()
+
+	ticket := mockTicketData[0]
+	ticket.WaitingRoom = true
+
+	orderRepo.EXPECT().FindCart(fiberCtx.Context(), orderUserId).Return(nil, gorm.ErrRecordNotFound)
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), ticket.Id).Return(&ticket, nil)
+	orderRepo.EXPECT().SaveLine(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := ords.AddLine(fiberCtx.Context(), &dto.CartLineCreateRequest{TicketId: ticket.Id, Quantity: 1, UserId: orderUserId})
+
+	assert.ErrorIs(t, err, apperrors.ErrTicketWaitingRoom)
+}
+
+func TestOrderService_AddLine_Currency_Mismatch(t *testing.T) {
+	teardown := setupOrderTest(t)
+	defer teardown()
+
+	first := mockTicketData[0]
+	first.Currency = "EUR"
+	second := mockTicketData[1]
+	second.Currency = "USD"
+
+	orderRepo.EXPECT().FindCart(fiberCtx.Context(), orderUserId).Return(cart(cartLine("line-1", first, 1)), nil)
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), second.Id).Return(&second, nil)
+	orderRepo.EXPECT().SaveLine(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := ords.AddLine(fiberCtx.Context(), &dto.CartLineCreateRequest{TicketId: second.Id, Quantity: 1, UserId: orderUserId})
+
+	assert.ErrorIs(t, err, apperrors.ErrOrderCurrencyMismatch)
+}
+
+func TestOrderService_UpdateLine_Not_Found(t *testing.T) {
+	teardown := setupOrderTest(t)
+	defer teardown()
+
+	orderRepo.EXPECT().FindCart(fiberCtx.Context(), orderUserId).Return(cart(cartLine("line-1", mockTicketData[0], 1)), nil)
+
+	_, err := ords.UpdateLine(fiberCtx.Context(), "line-2", &dto.CartLineUpdateRequest{Quantity: 2, UserId: orderUserId})
+
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+}
+
+func TestOrderService_RemoveLine(t *testing.T) {
+	teardown := setupOrderTest(t)
+	defer teardown()
+
+	orderRepo.EXPECT().RemoveLine(fiberCtx.Context(), orderUserId, "line-1").Return(nil)
+	orderRepo.EXPECT().FindCart(fiberCtx.Context(), orderUserId).Return(cart(), nil)
+
+	response, err := ords.RemoveLine(fiberCtx.Context(), orderUserId, "line-1")
+
+	assert.NoError(t, err)
+	assert.Empty(t, response.Lines)
+}
+
+func TestOrderService_Checkout_Success(t *testing.T) {
+	teardown := setupOrderTest(t)
+	defer teardown()
+
+	first := mockTicketData[0]
+	first.Currency = "USD"
+	second := mockTicketData[1]
+	second.Currency = "USD"
+	// The price changed since the line was added
+	added := cartLine("line-2", second, 2)
+	second.Price = 500
+
+	orderRepo.EXPECT().FindCart(fiberCtx.Context(), orderUserId).Return(cart(cartLine("line-1", first, 1), added), nil)
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), first.Id).Return(&first, nil)
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), second.Id).Return(&second, nil)
+	expectPaymentStatuses(models.PaymentStatusAuthorized, models.PaymentStatusCaptured)
+	orderRepo.EXPECT().Checkout(fiberCtx.Context(), gomock.Any()).DoAndReturn(func(_ any, order *models.Order) error {
+		assert.Equal(t, int64(1000), order.Total)
+		assert.Equal(t, "USD", order.Currency)
+		assert.Equal(t, orderMockTime, *order.CheckedOutAt)
+		assert.Equal(t, int64(1000), order.Lines[1].Total)
+
+		for i := range order.Lines {
+			order.Lines[i].Status = models.PurchaseStatusCompleted
+			order.Lines[i].IsActive = true
+		}
+		order.Status = models.OrderStatusCompleted
+		return nil
+	})
+
+	response, err := ords.Checkout(fiberCtx.Context(), orderUserId)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.OrderStatusCompleted, response.Status)
+	assert.Equal(t, int64(1000), response.Total)
+	for _, line := range response.Lines {
+		assert.Equal(t, models.PurchaseStatusCompleted, line.Status)
+	}
+}
+
+func TestOrderService_Checkout_Rejected(t *testing.T) {
+	teardown := setupOrderTest(t)
+	defer teardown()
+
+	first := mockTicketData[0]
+	second := mockTicketData[1]
+
+	orderRepo.EXPECT().FindCart(fiberCtx.Context(), orderUserId).Return(cart(cartLine("line-1", first, 1), cartLine("line-2", second, 300)), nil)
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), first.Id).Return(&first, nil)
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), second.Id).Return(&second, nil)
+	orderRepo.EXPECT().Checkout(fiberCtx.Context(), gomock.Any()).Return(&dbRepositories.CheckoutError{
+		Lines: map[string]error{"line-2": dbRepositories.ErrInsufficientAllocation},
+	})
+
+	response, err := ords.Checkout(fiberCtx.Context(), orderUserId)
+
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, apperrors.ErrCheckoutRejected)
+
+	var appErr *apperrors.Error
+	assert.True(t, errors.As(err, &appErr))
+	order := appErr.Data.(*dto.OrderResponse)
+	assert.Equal(t, models.OrderStatusCart, order.Status)
+	assert.Equal(t, models.PurchaseStatusCart, order.Lines[0].Status)
+	assert.Empty(t, order.Lines[0].Error)
+	assert.Equal(t, "rejected", order.Lines[1].Status)
+	assert.Equal(t, apperrors.ErrTicketAllocations.Code, order.Lines[1].Error)
+}
+
+func TestOrderService_Checkout_Payment_Declined(t *testing.T) {
+	teardown := setupOrderTest(t)
+	defer teardown()
+
+	paymentProvider, _ = payments.NewFakeProvider(payments.FakeDecline)
+	ords = NewOrderService(orderRepo, ticketRepo, newTestPaymentService())
+
+	ticket := mockTicketData[0]
+	ticket.Price = 1250
+
+	orderRepo.EXPECT().FindCart(fiberCtx.Context(), orderUserId).Return(cart(cartLine("line-1", ticket, 1)), nil)
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), ticket.Id).Return(&ticket, nil)
+	expectPaymentStatuses(models.PaymentStatusDeclined)
+	orderRepo.EXPECT().Checkout(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := ords.Checkout(fiberCtx.Context(), orderUserId)
+
+	assert.ErrorIs(t, err, apperrors.ErrCheckoutRejected)
+	var appErr *apperrors.Error
+	assert.True(t, errors.As(err, &appErr))
+	assert.Equal(t, apperrors.ErrPaymentDeclined.Code, appErr.Data.(*dto.OrderResponse).Lines[0].Error)
+}
+
+func TestOrderService_Checkout_Empty_Cart(t *testing.T) {
+	teardown := setupOrderTest(t)
+	defer teardown()
+
+	orderRepo.EXPECT().FindCart(fiberCtx.Context(), orderUserId).Return(nil, gorm.ErrRecordNotFound)
+
+	_, err := ords.Checkout(fiberCtx.Context(), orderUserId)
+
+	assert.ErrorIs(t, err, apperrors.ErrCartEmpty)
+}
+
+func TestOrderService_FindById_Forbidden(t *testing.T) {
+	teardown := setupOrderTest(t)
+	defer teardown()
+
+	orderRepo.EXPECT().FindById(fiberCtx.Context(), "order-1").Return(cart(), nil)
+
+	_, err := ords.FindById(fiberCtx.Context(), "order-1", auth.Actor{UserId: "someone-else", Role: auth.RoleCustomer})
+
+	assert.ErrorIs(t, err, apperrors.ErrForbidden)
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/db/models"
+	"ticket-purchase/internal/db/repositories"
+	"ticket-purchase/internal/events"
+)
+
+type OutboxService interface {
+	// Relay publishes the unpublished events of the outbox to the event bus in order and reports how many were
+	// published. Events are published at least once, an event whose publishing is not recorded is published again.
+	// An event that fails holds back the later events of its ticket until it goes through or is parked.
+	Relay(ctx context.Context) (int, error)
+	// Cleanup removes the events published longer than the retention ago
+	Cleanup(ctx context.Context) (int64, error)
+}
+
+type outboxService struct {
+	outboxRepo repositories.OutboxRepository
+	bus        events.Bus
+	conf       config.OutboxConfig
+}
+
+func NewOutboxService(outboxRepo repositories.OutboxRepository, bus events.Bus, conf config.OutboxConfig) OutboxService {
+	return &outboxService{
+		outboxRepo: outboxRepo,
+		bus:        bus,
+		conf:       conf,
+	}
+}
+
+func (s *outboxService) Relay(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		published, err := s.outboxRepo.Relay(ctx, s.conf.BatchSize, s.conf.MaxAttempts, timeNow(), func(event models.OutboxEvent) error {
+			return s.bus.Publish(ctx, outboxEvent(event))
+		})
+		total += published
+		if err != nil || published < s.conf.BatchSize {
+			return total, err
+		}
+	}
+}
+
+func (s *outboxService) Cleanup(ctx context.Context) (int64, error) {
+	return s.outboxRepo.DeletePublished(ctx, timeNow().Add(-s.conf.Retention))
+}
+
+func outboxEvent(event models.OutboxEvent) events.Event {
+	return events.Event{
+		Id:         event.Id,
+		Type:       event.Type,
+		TicketId:   event.TicketId,
+		Payload:    json.RawMessage(event.Payload),
+		OccurredAt: event.CreatedAt,
+	}
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"strconv"
+	"testing"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/db/models"
+	"ticket-purchase/internal/events"
+	"ticket-purchase/internal/mocks/repositories"
+	"time"
+)
+
+var obs OutboxService
+var outboxRepo *repositories.MockOutboxRepository
+var bus *events.MemoryBus
+
+// relayed are the events the bus handed to its handlers
+var relayed []events.Event
+
+var outboxTestConf = config.OutboxConfig{
+	BatchSize:   2,
+	MaxAttempts: 3,
+	Retention:   24 * time.Hour,
+}
+
+func setupOutboxTest(t *testing.T) func() {
+	timeNow = func() time.Time {
+		return webhookMockTime
+	}
+
+	ct := gomock.NewController(t)
+	outboxRepo = repositories.NewMockOutboxRepository(ct)
+	bus = events.NewMemoryBus()
+	relayed = nil
+	bus.Subscribe(func(_ context.Context, event events.Event) error {
+		relayed = append(relayed, event)
+		return nil
+	})
+	obs = NewOutboxService(outboxRepo, bus, outboxTestConf)
+	return func() {
+		obs = nil
+		timeNow = time.Now
+		ct.Finish()
+	}
+}
+
+func outboxRow(position int64, eventType string) models.OutboxEvent {
+	return models.OutboxEvent{
+		Position:  position,
+		Id:        "event-" + strconv.FormatInt(position, 10),
+		TicketId:  "ticket-1",
+		Type:      eventType,
+		Payload:   `{"ticket_id":"ticket-1"}`,
+		CreatedAt: webhookMockTime,
+	}
+}
+
+// relayRows publishes the rows like the repository does, skipping the later rows of a ticket that failed
+func relayRows(rows ...models.OutboxEvent) func(context.Context, int, int, time.Time, func(models.OutboxEvent) error) (int, error) {
+	return func(_ context.Context, _ int, _ int, _ time.Time, publish func(models.OutboxEvent) error) (int, error) {
+		published := 0
+		var errs []error
+		failedTickets := map[string]bool{}
+		for _, row := range rows {
+			if failedTickets[row.TicketId] {
+				continue
+			}
+			if err := publish(row); err != nil {
+				failedTickets[row.TicketId] = true
+				errs = append(errs, err)
+				continue
+			}
+			published++
+		}
+		return published, errors.Join(errs...)
+	}
+}
+
+func TestOutboxService_Relay(t *testing.T) {
+	teardown := setupOutboxTest(t)
+	defer teardown()
+
+	// A full batch is followed by another round until a batch is not full
+	gomock.InOrder(
+		outboxRepo.EXPECT().Relay(gomock.Any(), 2, 3, webhookMockTime, gomock.Any()).
+			DoAndReturn(relayRows(outboxRow(1, events.TypeTicketCreated), outboxRow(2, events.TypeAllocationChanged))),
+		outboxRepo.EXPECT().Relay(gomock.Any(), 2, 3, webhookMockTime, gomock.Any()).
+			DoAndReturn(relayRows(outboxRow(3, events.TypeSoldOut))),
+	)
+
+	published, err := obs.Relay(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, published)
+	assert.Equal(t, []events.Event{
+		{Id: "event-1", Type: events.TypeTicketCreated, TicketId: "ticket-1", Payload: json.RawMessage(`{"ticket_id":"ticket-1"}`), OccurredAt: webhookMockTime},
+		{Id: "event-2", Type: events.TypeAllocationChanged, TicketId: "ticket-1", Payload: json.RawMessage(`{"ticket_id":"ticket-1"}`), OccurredAt: webhookMockTime},
+		{Id: "event-3", Type: events.TypeSoldOut, TicketId: "ticket-1", Payload: json.RawMessage(`{"ticket_id":"ticket-1"}`), OccurredAt: webhookMockTime},
+	}, relayed)
+}
+
+func TestOutboxService_Relay_Bus_Fails(t *testing.T) {
+	teardown := setupOutboxTest(t)
+	defer teardown()
+
+	failure := errors.New("bus is down")
+	bus.Subscribe(func(_ context.Context, event events.Event) error {
+		if event.Type == events.TypeSoldOut {
+			return failure
+		}
+		return nil
+	})
+
+	// The failure of the first ticket does not hold back the other one
+	other := outboxRow(4, events.TypeTicketCreated)
+	other.TicketId = "ticket-2"
+	outboxRepo.EXPECT().Relay(gomock.Any(), 2, 3, webhookMockTime, gomock.Any()).
+		DoAndReturn(relayRows(outboxRow(1, events.TypeSoldOut), outboxRow(2, events.TypeAllocationChanged), other))
+
+	published, err := obs.Relay(context.Background())
+
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, 1, published)
+	assert.Len(t, relayed, 2)
+	assert.Equal(t, "event-4", relayed[1].Id)
+}
+
+func TestOutboxService_Cleanup(t *testing.T) {
+	teardown := setupOutboxTest(t)
+	defer teardown()
+
+	outboxRepo.EXPECT().DeletePublished(gomock.Any(), webhookMockTime.Add(-24*time.Hour)).Return(int64(5), nil)
+
+	removed, err := obs.Cleanup(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), removed)
+}
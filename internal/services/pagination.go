@@ -0,0 +1,54 @@
+package services
+
+import (
+	"slices"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/db/repositories"
+	"time"
+)
+
+// parseSort validates the requested sort column and order. Listings are newest first by default.
+func parseSort(column string, order string, allowed []string) (repositories.Sort, error) {
+	if column == "" {
+		column = "created_at"
+	}
+
+	if !slices.Contains(allowed, column) {
+		return repositories.Sort{}, apperrors.ErrBadRequest
+	}
+
+	switch order {
+	case "", "desc":
+		return repositories.Sort{Column: column, Descending: true}, nil
+	case "asc":
+		return repositories.Sort{Column: column}, nil
+	default:
+		return repositories.Sort{}, apperrors.ErrBadRequest
+	}
+}
+
+// parseDateRange parses optional RFC 3339 bounds of a date range filter
+func parseDateRange(from string, to string) (*time.Time, *time.Time, error) {
+	var fromTime, toTime *time.Time
+	if from != "" {
+		parsed, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return nil, nil, apperrors.ErrBadRequest.Wrap(err)
+		}
+		fromTime = &parsed
+	}
+
+	if to != "" {
+		parsed, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return nil, nil, apperrors.ErrBadRequest.Wrap(err)
+		}
+		toTime = &parsed
+	}
+
+	if fromTime != nil && toTime != nil && !fromTime.Before(*toTime) {
+		return nil, nil, apperrors.ErrBadRequest
+	}
+
+	return fromTime, toTime, nil
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2/log"
+	"gorm.io/gorm"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/db/models"
+	"ticket-purchase/internal/db/repositories"
+	"ticket-purchase/internal/payments"
+	"ticket-purchase/pkg/money"
+)
+
+type PaymentService interface {
+	// Authorize records a payment of the purchase total and authorizes it with the provider. The purchase needs its
+	// id already. Free purchases have no payment, nil is returned for them.
+	Authorize(ctx context.Context, purchase *models.Purchase) (*models.Payment, error)
+	// Capture takes the authorized amount once the purchase is committed
+	Capture(ctx context.Context, payment *models.Payment) error
+	// Void releases the authorization of a purchase that was not committed
+	Void(ctx context.Context, payment *models.Payment) error
+	// Refund returns part of the captured amount of a purchase. Free purchases and purchases without a payment are
+	// ignored.
+	Refund(ctx context.Context, purchaseId string, amount money.Money) error
+	// Cancel voids or refunds the whole payment of a purchase that was sold but could not be written
+	Cancel(ctx context.Context, purchaseId string) error
+}
+
+type paymentService struct {
+	paymentRepo repositories.PaymentRepository
+	provider    payments.Provider
+	conf        config.PaymentConfig
+}
+
+func NewPaymentService(paymentRepo repositories.PaymentRepository, provider payments.Provider, conf config.PaymentConfig) PaymentService {
+	return &paymentService{
+		paymentRepo: paymentRepo,
+		provider:    provider,
+		conf:        conf,
+	}
+}
+
+func (s *paymentService) Authorize(ctx context.Context, purchase *models.Purchase) (*models.Payment, error) {
+	if purchase.Total == 0 {
+		return nil, nil
+	}
+
+	now := timeNow()
+	payment := models.Payment{
+		PurchaseId: purchase.Id,
+		UserId:     purchase.UserId,
+		Amount:     purchase.Total,
+		Currency:   purchase.Currency,
+		Status:     models.PaymentStatusPending,
+		Provider:   s.provider.Name(),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := s.paymentRepo.Create(ctx, &payment); err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, s.conf.Timeout)
+	defer cancel()
+
+	authorization, err := s.provider.Authorize(callCtx, payments.AuthorizeRequest{
+		PaymentId: payment.Id,
+		UserId:    payment.UserId,
+		Amount:    payment.Total(),
+	})
+	if errors.Is(err, payments.ErrDeclined) {
+		return nil, s.fail(ctx, &payment, models.PaymentStatusDeclined, err, apperrors.ErrPaymentDeclined)
+	}
+
+	// The outcome of a timed out authorization is unknown. The provider releases authorizations that are never
+	// captured, so the payment is only marked as failed.
+	if err != nil {
+		return nil, s.fail(ctx, &payment, models.PaymentStatusFailed, err, apperrors.ErrPaymentUnavailable)
+	}
+
+	payment.AuthorizationId = authorization.Id
+	if err := s.transition(ctx, &payment, models.PaymentStatusAuthorized); err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+	return &payment, nil
+}
+
+func (s *paymentService) Capture(ctx context.Context, payment *models.Payment) error {
+	if payment == nil {
+		return nil
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, s.conf.Timeout)
+	defer cancel()
+
+	if err := s.provider.Capture(callCtx, payment.AuthorizationId, payment.Total()); err != nil {
+		return apperrors.ErrPaymentUnavailable.Wrap(err)
+	}
+
+	if err := s.transition(ctx, payment, models.PaymentStatusCaptured); err != nil {
+		return apperrors.ErrUnexpected.Wrap(err)
+	}
+	return nil
+}
+
+func (s *paymentService) Void(ctx context.Context, payment *models.Payment) error {
+	if payment == nil {
+		return nil
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, s.conf.Timeout)
+	defer cancel()
+
+	if err := s.provider.Void(callCtx, payment.AuthorizationId); err != nil {
+		return apperrors.ErrPaymentUnavailable.Wrap(err)
+	}
+
+	if err := s.transition(ctx, payment, models.PaymentStatusVoided); err != nil {
+		return apperrors.ErrUnexpected.Wrap(err)
+	}
+	return nil
+}
+
+func (s *paymentService) Refund(ctx context.Context, purchaseId string, amount money.Money) error {
+	if amount.IsZero() {
+		return nil
+	}
+
+	payment, err := s.paymentRepo.FindByPurchaseId(ctx, purchaseId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	return s.refund(ctx, payment, amount)
+}
+
+func (s *paymentService) Cancel(ctx context.Context, purchaseId string) error {
+	payment, err := s.paymentRepo.FindByPurchaseId(ctx, purchaseId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	switch payment.Status {
+	case models.PaymentStatusAuthorized:
+		return s.Void(ctx, payment)
+	case models.PaymentStatusCaptured:
+		return s.refund(ctx, payment, money.New(payment.Amount-payment.RefundedAmount, payment.Currency))
+	}
+	return nil
+}
+
+// refund records the refund before the provider is asked for it. The database checks the refunded amount atomically,
+// so concurrent refunds of the same payment can never return more than was captured, whatever the payment read
+// earlier says. The amount is released again when the provider fails.
+func (s *paymentService) refund(ctx context.Context, payment *models.Payment, amount money.Money) error {
+	if payment.Status != models.PaymentStatusCaptured {
+		return apperrors.ErrPaymentRefund
+	}
+
+	payment.UpdatedAt = timeNow()
+	err := s.paymentRepo.AddRefund(ctx, payment, amount.Amount)
+	if errors.Is(err, repositories.ErrPaymentStatusChanged) {
+		return apperrors.ErrPaymentRefund.Wrap(err)
+	}
+
+	if err != nil {
+		return apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, s.conf.Timeout)
+	defer cancel()
+
+	if err := s.provider.Refund(callCtx, payment.AuthorizationId, amount); err != nil {
+		payment.UpdatedAt = timeNow()
+		if releaseErr := s.paymentRepo.ReleaseRefund(ctx, payment, amount.Amount); releaseErr != nil {
+			log.Error("Error releasing the refund of payment ", payment.Id, ": ", releaseErr)
+		}
+		return apperrors.ErrPaymentRefund.Wrap(err)
+	}
+	return nil
+}
+
+// fail records why the provider refused the payment and returns the domain error for it
+func (s *paymentService) fail(ctx context.Context, payment *models.Payment, status string, cause error, appErr *apperrors.Error) error {
+	payment.FailureReason = cause.Error()
+	if err := s.transition(ctx, payment, status); err != nil {
+		return apperrors.ErrUnexpected.Wrap(err)
+	}
+	return appErr.Wrap(cause)
+}
+
+func (s *paymentService) transition(ctx context.Context, payment *models.Payment, status string) error {
+	return transitionPayment(ctx, s.paymentRepo, payment, status)
+}
+
+// transitionPayment moves the payment to the status and saves it, unless another request changed it in the meantime
+func transitionPayment(ctx context.Context, paymentRepo repositories.PaymentRepository, payment *models.Payment, status string) error {
+	if !payment.CanTransition(status) {
+		return fmt.Errorf("payment %s cannot move from %s to %s", payment.Id, payment.Status, status)
+	}
+
+	from := payment.Status
+	payment.Status = status
+	payment.UpdatedAt = timeNow()
+	return paymentRepo.Transition(ctx, payment, from)
+}
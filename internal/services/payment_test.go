@@ -0,0 +1,267 @@
+package services
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+	"testing"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/db/models"
+	dbRepositories "ticket-purchase/internal/db/repositories"
+	"ticket-purchase/internal/payments"
+	"ticket-purchase/pkg/money"
+	"time"
+)
+
+var paymentTestConf = config.PaymentConfig{
+	Provider: config.PaymentProviderFake,
+	Timeout:  time.Second,
+}
+
+// newTestPaymentService pays with the fake provider and the payment repository mock of setupTicketTest
+func newTestPaymentService() PaymentService {
+	return NewPaymentService(paymentRepo, paymentProvider, paymentTestConf)
+}
+
+// expectPaymentStatuses expects a payment to be created and moved through the statuses in order
+func expectPaymentStatuses(statuses ...string) {
+	calls := []any{
+		paymentRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, payment *models.Payment) error {
+			payment.Id = "payment-1"
+			return nil
+		}),
+	}
+	for _, status := range statuses {
+		calls = append(calls, paymentRepo.EXPECT().
+			Transition(gomock.Any(), gomock.Cond(func(x any) bool { return x.(*models.Payment).Status == status }), gomock.Any()).
+			Return(nil))
+	}
+	gomock.InOrder(calls...)
+}
+
+func pricedPurchase() *models.Purchase {
+	purchase := models.Purchase{
+		Id:       "purchase-1",
+		UserId:   "user-1",
+		Quantity: 2,
+	}
+	purchase.SetPrice(money.New(1250, "TRY"))
+	return &purchase
+}
+
+// capturedPayment returns a payment the fake provider authorized and captured
+func capturedPayment(t *testing.T, purchase *models.Purchase) *models.Payment {
+	authorization, err := paymentProvider.Authorize(context.Background(), payments.AuthorizeRequest{
+		PaymentId: "payment-1",
+		UserId:    purchase.UserId,
+		Amount:    money.New(purchase.Total, purchase.Currency),
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, paymentProvider.Capture(context.Background(), authorization.Id, money.New(purchase.Total, purchase.Currency)))
+
+	return &models.Payment{
+		Id:              "payment-1",
+		PurchaseId:      purchase.Id,
+		UserId:          purchase.UserId,
+		Amount:          purchase.Total,
+		Currency:        purchase.Currency,
+		Status:          models.PaymentStatusCaptured,
+		Provider:        paymentProvider.Name(),
+		AuthorizationId: authorization.Id,
+	}
+}
+
+func TestPaymentService_Authorize_Success(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	ps := newTestPaymentService()
+	purchase := pricedPurchase()
+
+	expectPaymentStatuses(models.PaymentStatusAuthorized)
+
+	payment, err := ps.Authorize(fiberCtx.Context(), purchase)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	assert.Equal(t, models.PaymentStatusAuthorized, payment.Status)
+	assert.Equal(t, "fake_auth_payment-1", payment.AuthorizationId)
+	assert.Equal(t, purchase.Id, payment.PurchaseId)
+	assert.Equal(t, money.New(2500, "TRY"), payment.Total())
+}
+
+func TestPaymentService_Authorize_Free(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	ps := newTestPaymentService()
+	paymentRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	payment, err := ps.Authorize(fiberCtx.Context(), &models.Purchase{Id: "purchase-1", Quantity: 1, Currency: "USD"})
+
+	assert.NoError(t, err)
+	assert.Nil(t, payment)
+	assert.NoError(t, ps.Capture(fiberCtx.Context(), payment))
+	assert.NoError(t, ps.Void(fiberCtx.Context(), payment))
+}
+
+func TestPaymentService_Authorize_Declined(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	paymentProvider, _ = payments.NewFakeProvider(payments.FakeDecline)
+	ps := newTestPaymentService()
+
+	expectPaymentStatuses(models.PaymentStatusDeclined)
+
+	payment, err := ps.Authorize(fiberCtx.Context(), pricedPurchase())
+	if err == nil {
+		t.Fatalf("Expected error to be not nil, got nil")
+	}
+
+	assert.Nil(t, payment)
+	assert.ErrorIs(t, err, apperrors.ErrPaymentDeclined)
+}
+
+func TestPaymentService_Authorize_Timeout(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	paymentProvider, _ = payments.NewFakeProvider(payments.FakeTimeout)
+	ps := NewPaymentService(paymentRepo, paymentProvider, config.PaymentConfig{Timeout: 10 * time.Millisecond})
+
+	expectPaymentStatuses(models.PaymentStatusFailed)
+
+	payment, err := ps.Authorize(fiberCtx.Context(), pricedPurchase())
+	if err == nil {
+		t.Fatalf("Expected error to be not nil, got nil")
+	}
+
+	assert.Nil(t, payment)
+	assert.ErrorIs(t, err, apperrors.ErrPaymentUnavailable)
+}
+
+func TestPaymentService_Capture_Changed_Concurrently(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	ps := newTestPaymentService()
+
+	expectPaymentStatuses(models.PaymentStatusAuthorized)
+	payment, err := ps.Authorize(fiberCtx.Context(), pricedPurchase())
+	assert.NoError(t, err)
+
+	paymentRepo.EXPECT().Transition(gomock.Any(), payment, models.PaymentStatusAuthorized).Return(gorm.ErrInvalidData)
+
+	err = ps.Capture(fiberCtx.Context(), payment)
+	assert.ErrorIs(t, err, apperrors.ErrUnexpected)
+}
+
+func TestPaymentService_Void_Captured(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	ps := newTestPaymentService()
+	payment := capturedPayment(t, pricedPurchase())
+
+	// A captured payment can only be refunded
+	err := ps.Void(fiberCtx.Context(), payment)
+	assert.ErrorIs(t, err, apperrors.ErrPaymentUnavailable)
+}
+
+func TestPaymentService_Refund_Success(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	ps := newTestPaymentService()
+	purchase := pricedPurchase()
+	payment := capturedPayment(t, purchase)
+
+	paymentRepo.EXPECT().FindByPurchaseId(fiberCtx.Context(), purchase.Id).Return(payment, nil)
+	paymentRepo.EXPECT().AddRefund(fiberCtx.Context(), payment, int64(1250)).Return(nil)
+
+	err := ps.Refund(fiberCtx.Context(), purchase.Id, purchase.Price())
+	assert.NoError(t, err)
+}
+
+func TestPaymentService_Refund_Exceeds_Captured(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	ps := newTestPaymentService()
+	purchase := pricedPurchase()
+	payment := capturedPayment(t, purchase)
+	payment.RefundedAmount = 1250
+
+	// The refunded amount is checked by the database, the payment read may already be stale
+	paymentRepo.EXPECT().FindByPurchaseId(fiberCtx.Context(), purchase.Id).Return(payment, nil)
+	paymentRepo.EXPECT().AddRefund(fiberCtx.Context(), payment, int64(2500)).Return(dbRepositories.ErrPaymentStatusChanged)
+
+	err := ps.Refund(fiberCtx.Context(), purchase.Id, purchase.Price().Multiply(2))
+	assert.ErrorIs(t, err, apperrors.ErrPaymentRefund)
+}
+
+func TestPaymentService_Refund_Provider_Failure_Releases_Refund(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	ps := newTestPaymentService()
+	purchase := pricedPurchase()
+	payment := capturedPayment(t, purchase)
+	payment.AuthorizationId = "unknown"
+
+	gomock.InOrder(
+		paymentRepo.EXPECT().FindByPurchaseId(fiberCtx.Context(), purchase.Id).Return(payment, nil),
+		paymentRepo.EXPECT().AddRefund(fiberCtx.Context(), payment, int64(1250)).Return(nil),
+		paymentRepo.EXPECT().ReleaseRefund(fiberCtx.Context(), payment, int64(1250)).Return(nil),
+	)
+
+	err := ps.Refund(fiberCtx.Context(), purchase.Id, purchase.Price())
+	assert.ErrorIs(t, err, apperrors.ErrPaymentRefund)
+}
+
+func TestPaymentService_Refund_Without_Payment(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	ps := newTestPaymentService()
+	paymentRepo.EXPECT().FindByPurchaseId(fiberCtx.Context(), "free").Return(nil, gorm.ErrRecordNotFound)
+
+	err := ps.Refund(fiberCtx.Context(), "free", money.New(1250, "USD"))
+	assert.NoError(t, err)
+}
+
+func TestPaymentService_Cancel(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	ps := newTestPaymentService()
+	purchase := pricedPurchase()
+
+	t.Run("Authorized", func(t *testing.T) {
+		expectPaymentStatuses(models.PaymentStatusAuthorized)
+		payment, err := ps.Authorize(fiberCtx.Context(), purchase)
+		assert.NoError(t, err)
+
+		paymentRepo.EXPECT().FindByPurchaseId(fiberCtx.Context(), purchase.Id).Return(payment, nil)
+		paymentRepo.EXPECT().Transition(gomock.Any(), payment, models.PaymentStatusAuthorized).Return(nil)
+
+		assert.NoError(t, ps.Cancel(fiberCtx.Context(), purchase.Id))
+		assert.Equal(t, models.PaymentStatusVoided, payment.Status)
+	})
+
+	t.Run("Captured", func(t *testing.T) {
+		paymentProvider, _ = payments.NewFakeProvider(payments.FakeSucceed)
+		ps = newTestPaymentService()
+		payment := capturedPayment(t, purchase)
+		payment.RefundedAmount = 1250
+
+		paymentRepo.EXPECT().FindByPurchaseId(fiberCtx.Context(), purchase.Id).Return(payment, nil)
+		paymentRepo.EXPECT().AddRefund(fiberCtx.Context(), payment, int64(1250)).Return(nil)
+
+		assert.NoError(t, ps.Cancel(fiberCtx.Context(), purchase.Id))
+	})
+}
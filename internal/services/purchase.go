@@ -0,0 +1,274 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v2/log"
+	"gorm.io/gorm"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/auth"
+	"ticket-purchase/internal/db/models"
+	"ticket-purchase/internal/db/repositories"
+	"ticket-purchase/internal/dto"
+	"ticket-purchase/pkg/pagination"
+)
+
+type PurchaseService interface {
+	// FindById finds a purchase visible to the actor
+	FindById(ctx context.Context, id string, actor auth.Actor) (*dto.PurchaseResponse, error)
+	// FindAll lists purchases of a user or a ticket page by page
+	FindAll(ctx context.Context, request *dto.PurchaseListRequest, actor auth.Actor) (*dto.PurchaseListResponse, error)
+	// Cancel refunds the whole remaining quantity of a purchase
+	Cancel(ctx context.Context, id string, request *dto.PurchaseCancelRequest, actor auth.Actor) (*dto.PurchaseResponse, error)
+	// Refund refunds part of a purchase
+	Refund(ctx context.Context, id string, request *dto.PurchaseRefundRequest, actor auth.Actor) (*dto.PurchaseResponse, error)
+}
+
+type purchaseService struct {
+	purchaseRepo repositories.PurchaseRepository
+	ticketRepo   repositories.TicketRepository
+	payments     PaymentService
+}
+
+func NewPurchaseService(
+	purchaseRepo repositories.PurchaseRepository,
+	ticketRepo repositories.TicketRepository,
+	payments PaymentService,
+) PurchaseService {
+	return &purchaseService{
+		purchaseRepo: purchaseRepo,
+		ticketRepo:   ticketRepo,
+		payments:     payments,
+	}
+}
+
+func (s *purchaseService) FindById(ctx context.Context, id string, actor auth.Actor) (*dto.PurchaseResponse, error) {
+	purchase, err := s.find(ctx, id, actor, auth.PermPurchaseRead)
+	if err != nil {
+		return nil, err
+	}
+
+	return purchaseResponse(purchase), nil
+}
+
+func (s *purchaseService) FindAll(ctx context.Context, request *dto.PurchaseListRequest, actor auth.Actor) (*dto.PurchaseListResponse, error) {
+	if err := s.checkListAccess(ctx, request, actor); err != nil {
+		return nil, err
+	}
+
+	sort, err := parseSort(request.Sort, request.Order, repositories.PurchaseSortColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to, err := parseDateRange(request.From, request.To)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := pagination.DecodeCursor(request.Cursor)
+	if err != nil {
+		return nil, apperrors.ErrInvalidCursor.Wrap(err)
+	}
+
+	limit := pagination.NormalizeLimit(request.Limit)
+
+	// One extra row tells whether there is a next page
+	purchases, err := s.purchaseRepo.FindAll(ctx, repositories.PurchaseFilter{
+		UserId:      request.UserId,
+		TicketId:    request.TicketId,
+		CreatedFrom: from,
+		CreatedTo:   to,
+		Sort:        sort,
+		Cursor:      cursor,
+		Limit:       limit + 1,
+	})
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		return nil, apperrors.ErrInvalidCursor.Wrap(err)
+	}
+
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	response := dto.PurchaseListResponse{
+		Items:      make([]dto.PurchaseResponse, 0, len(purchases)),
+		Pagination: dto.PageInfo{Limit: limit},
+	}
+
+	if len(purchases) > limit {
+		purchases = purchases[:limit]
+		response.Pagination.HasMore = true
+		response.Pagination.NextCursor = repositories.PurchaseCursor(&purchases[limit-1], sort).Encode()
+	}
+
+	for i := range purchases {
+		response.Items = append(response.Items, *purchaseResponse(&purchases[i]))
+	}
+
+	return &response, nil
+}
+
+func (s *purchaseService) Cancel(ctx context.Context, id string, request *dto.PurchaseCancelRequest, actor auth.Actor) (*dto.PurchaseResponse, error) {
+	purchase, err := s.findRefundable(ctx, id, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.refund(ctx, purchase, purchase.RemainingQuantity(), actor.UserId, request.Reason)
+}
+
+func (s *purchaseService) Refund(ctx context.Context, id string, request *dto.PurchaseRefundRequest, actor auth.Actor) (*dto.PurchaseResponse, error) {
+	if request.Quantity <= 0 {
+		return nil, apperrors.ErrBadRequest
+	}
+
+	purchase, err := s.findRefundable(ctx, id, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Quantity > purchase.RemainingQuantity() {
+		return nil, apperrors.ErrRefundExceedsQuantity
+	}
+
+	return s.refund(ctx, purchase, request.Quantity, actor.UserId, request.Reason)
+}
+
+// checkListAccess allows users to list their own purchases, organizers to list purchases of their own tickets and
+// staff with the purchase read permission to list anything
+func (s *purchaseService) checkListAccess(ctx context.Context, request *dto.PurchaseListRequest, actor auth.Actor) error {
+	if actor.Can(auth.PermPurchaseRead) {
+		return nil
+	}
+
+	if request.UserId != "" {
+		if !actor.Owns(request.UserId) {
+			return apperrors.ErrForbidden
+		}
+
+		return nil
+	}
+
+	ticket, err := s.ticketRepo.FindById(ctx, request.TicketId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperrors.ErrNotFound.Wrap(err)
+	}
+
+	if err != nil {
+		return apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	if !canManage(actor, ticket) {
+		return apperrors.ErrForbidden
+	}
+
+	return nil
+}
+
+// find loads a purchase and checks that it belongs to the actor or that the actor has the given permission
+func (s *purchaseService) find(ctx context.Context, id string, actor auth.Actor, permission auth.Permission) (*models.Purchase, error) {
+	purchase, err := s.purchaseRepo.FindById(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.ErrNotFound.Wrap(err)
+	}
+
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	if !actor.Owns(purchase.UserId) && !actor.Can(permission) {
+		return nil, apperrors.ErrForbidden
+	}
+
+	return purchase, nil
+}
+
+// findRefundable loads an active purchase the actor may refund and checks the refund policy of its ticket
+func (s *purchaseService) findRefundable(ctx context.Context, id string, actor auth.Actor) (*models.Purchase, error) {
+	purchase, err := s.find(ctx, id, actor, auth.PermPurchaseRefund)
+	if err != nil {
+		return nil, err
+	}
+
+	if !purchase.IsActive {
+		return nil, apperrors.ErrPurchaseNotActive
+	}
+
+	if !purchase.Ticket.IsRefundable(timeNow()) {
+		return nil, apperrors.ErrRefundWindowClosed
+	}
+
+	return purchase, nil
+}
+
+func (s *purchaseService) refund(ctx context.Context, purchase *models.Purchase, quantity int, userId string, reason string) (*dto.PurchaseResponse, error) {
+	purchase.CancelReason = reason
+	purchase.UpdatedBy = userId
+	purchase.UpdatedAt = timeNow()
+
+	// The quantity is taken off the purchase before the money is returned, so that concurrent refunds of the same
+	// purchase cannot both reach the provider
+	err := s.purchaseRepo.ReserveRefund(ctx, purchase, quantity)
+	if errors.Is(err, repositories.ErrRefundExceedsQuantity) {
+		return nil, apperrors.ErrRefundExceedsQuantity.Wrap(err)
+	}
+
+	if err != nil {
+		return nil, apperrors.ErrPurchaseRefund.Wrap(err)
+	}
+
+	// A provider that is down leaves the purchase as it was
+	if err := s.payments.Refund(ctx, purchase.Id, purchase.Price().Multiply(quantity)); err != nil {
+		if cancelErr := s.purchaseRepo.CancelRefund(ctx, purchase, quantity); cancelErr != nil {
+			log.Error("Error cancelling the refund of purchase ", purchase.Id, ": ", cancelErr)
+		}
+		return nil, err
+	}
+
+	// The money is back with the buyer, a failure here only keeps the quantity off sale
+	if err := s.purchaseRepo.CompleteRefund(ctx, purchase, quantity); err != nil {
+		log.Error("Error returning the refunded quantity of purchase ", purchase.Id, " to the allocation: ", err)
+	}
+
+	return purchaseResponse(purchase), nil
+}
+
+func purchaseResponse(purchase *models.Purchase) *dto.PurchaseResponse {
+	response := dto.PurchaseResponse{
+		Id:               purchase.Id,
+		TicketId:         purchase.TicketId,
+		UserId:           purchase.UserId,
+		Quantity:         purchase.Quantity,
+		RefundedQuantity: purchase.RefundedQuantity,
+		UnitPrice:        purchase.UnitPrice,
+		Total:            purchase.Total,
+		Currency:         purchase.Currency,
+		CancelReason:     purchase.CancelReason,
+		IsActive:         purchase.IsActive,
+		CreatedAt:        purchase.CreatedAt,
+		UpdatedAt:        purchase.UpdatedAt,
+	}
+
+	if purchase.OrderId != nil {
+		response.OrderId = *purchase.OrderId
+	}
+
+	response.Ticket = ticketSummary(&purchase.Ticket)
+	return &response
+}
+
+// ticketSummary returns the summary of the ticket of a purchase. The ticket is only present when the relationship
+// was preloaded.
+func ticketSummary(ticket *models.Ticket) *dto.TicketSummary {
+	if ticket.Id == "" {
+		return nil
+	}
+
+	return &dto.TicketSummary{
+		Id:            ticket.Id,
+		Name:          ticket.Name,
+		Description:   ticket.Description,
+		EventStartsAt: ticket.EventStartsAt,
+	}
+}
@@ -0,0 +1,385 @@
+package services
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+	"testing"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/auth"
+	"ticket-purchase/internal/db/models"
+	dbRepositories "ticket-purchase/internal/db/repositories"
+	"ticket-purchase/internal/dto"
+	"ticket-purchase/pkg/money"
+	"ticket-purchase/pkg/pagination"
+	"time"
+)
+
+var ps PurchaseService
+var purchaseMockTime = time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+const purchaseOwnerId = "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4c"
+
+var purchaseOwner = auth.Actor{UserId: purchaseOwnerId, Role: auth.RoleCustomer}
+var supportActor = auth.Actor{UserId: "support", Role: auth.RoleSupport}
+
+func setupPurchaseTest(t *testing.T) func() {
+	teardown := setupTicketTest(t)
+
+	timeNow = func() time.Time {
+		return purchaseMockTime
+	}
+
+	ps = NewPurchaseService(purchaseRepo, ticketRepo, newTestPaymentService())
+	return func() {
+		ps = nil
+		timeNow = time.Now
+		teardown()
+	}
+}
+
+func activePurchase(eventStartsAt *time.Time, cutoffHours int) *models.Purchase {
+	return &models.Purchase{
+		Id:               "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		TicketId:         "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		UserId:           purchaseOwnerId,
+		Quantity:         4,
+		RefundedQuantity: 1,
+		IsActive:         true,
+		Ticket: models.Ticket{
+			Id:                "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+			EventStartsAt:     eventStartsAt,
+			RefundCutoffHours: cutoffHours,
+		},
+	}
+}
+
+// reserveRefund takes the quantity off the purchase like the repository does
+func reserveRefund(_ context.Context, purchase *models.Purchase, quantity int) error {
+	purchase.RefundedQuantity += quantity
+	purchase.IsActive = purchase.RemainingQuantity() > 0
+	return nil
+}
+
+func TestPurchaseService_Cancel_Success(t *testing.T) {
+	teardown := setupPurchaseTest(t)
+	defer teardown()
+
+	purchase := activePurchase(nil, 0)
+	request := dto.PurchaseCancelRequest{Reason: "customer request"}
+
+	purchaseRepo.EXPECT().FindById(fiberCtx.Context(), purchase.Id).Return(purchase, nil)
+	purchaseRepo.EXPECT().ReserveRefund(fiberCtx.Context(), purchase, 3).DoAndReturn(reserveRefund)
+	purchaseRepo.EXPECT().CompleteRefund(fiberCtx.Context(), purchase, 3).Return(nil)
+
+	response, err := ps.Cancel(fiberCtx.Context(), purchase.Id, &request, purchaseOwner)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	assert.False(t, response.IsActive)
+	assert.Equal(t, 4, response.RefundedQuantity)
+	assert.Equal(t, "customer request", response.CancelReason)
+	assert.Equal(t, purchaseOwnerId, purchase.UpdatedBy)
+	assert.Equal(t, purchaseMockTime, purchase.UpdatedAt)
+}
+
+func TestPurchaseService_Refund_Partial(t *testing.T) {
+	teardown := setupPurchaseTest(t)
+	defer teardown()
+
+	eventStartsAt := purchaseMockTime.Add(72 * time.Hour)
+	purchase := activePurchase(&eventStartsAt, 48)
+	request := dto.PurchaseRefundRequest{Quantity: 2, Reason: "partial"}
+
+	purchaseRepo.EXPECT().FindById(fiberCtx.Context(), purchase.Id).Return(purchase, nil)
+	purchaseRepo.EXPECT().ReserveRefund(fiberCtx.Context(), purchase, 2).DoAndReturn(reserveRefund)
+	purchaseRepo.EXPECT().CompleteRefund(fiberCtx.Context(), purchase, 2).Return(nil)
+
+	response, err := ps.Refund(fiberCtx.Context(), purchase.Id, &request, purchaseOwner)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	assert.True(t, response.IsActive)
+	assert.Equal(t, 3, response.RefundedQuantity)
+}
+
+func TestPurchaseService_Refund_Returns_Payment(t *testing.T) {
+	teardown := setupPurchaseTest(t)
+	defer teardown()
+
+	purchase := activePurchase(nil, 0)
+	purchase.SetPrice(money.New(1250, "TRY"))
+	payment := capturedPayment(t, purchase)
+	request := dto.PurchaseRefundRequest{Quantity: 2}
+
+	purchaseRepo.EXPECT().FindById(fiberCtx.Context(), purchase.Id).Return(purchase, nil)
+	gomock.InOrder(
+		purchaseRepo.EXPECT().ReserveRefund(fiberCtx.Context(), purchase, 2).DoAndReturn(reserveRefund),
+		paymentRepo.EXPECT().FindByPurchaseId(fiberCtx.Context(), purchase.Id).Return(payment, nil),
+		paymentRepo.EXPECT().AddRefund(fiberCtx.Context(), payment, int64(2500)).Return(nil),
+		purchaseRepo.EXPECT().CompleteRefund(fiberCtx.Context(), purchase, 2).Return(nil),
+	)
+
+	_, err := ps.Refund(fiberCtx.Context(), purchase.Id, &request, purchaseOwner)
+	assert.NoError(t, err)
+}
+
+func TestPurchaseService_Refund_Payment_Failure_Keeps_Purchase(t *testing.T) {
+	teardown := setupPurchaseTest(t)
+	defer teardown()
+
+	purchase := activePurchase(nil, 0)
+	purchase.SetPrice(money.New(1250, "TRY"))
+	payment := capturedPayment(t, purchase)
+	payment.Status = models.PaymentStatusRefunded
+	request := dto.PurchaseRefundRequest{Quantity: 2}
+
+	purchaseRepo.EXPECT().FindById(fiberCtx.Context(), purchase.Id).Return(purchase, nil)
+	gomock.InOrder(
+		purchaseRepo.EXPECT().ReserveRefund(fiberCtx.Context(), purchase, 2).DoAndReturn(reserveRefund),
+		paymentRepo.EXPECT().FindByPurchaseId(fiberCtx.Context(), purchase.Id).Return(payment, nil),
+		purchaseRepo.EXPECT().CancelRefund(fiberCtx.Context(), purchase, 2).Return(nil),
+	)
+	purchaseRepo.EXPECT().CompleteRefund(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	response, err := ps.Refund(fiberCtx.Context(), purchase.Id, &request, purchaseOwner)
+
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, apperrors.ErrPaymentRefund)
+}
+
+func TestPurchaseService_Refund_Concurrent_Refund_Does_Not_Reach_Provider(t *testing.T) {
+	teardown := setupPurchaseTest(t)
+	defer teardown()
+
+	purchase := activePurchase(nil, 0)
+	purchase.SetPrice(money.New(1250, "TRY"))
+	request := dto.PurchaseRefundRequest{Quantity: 3}
+
+	// Another refund took the quantity after the purchase was read
+	purchaseRepo.EXPECT().FindById(fiberCtx.Context(), purchase.Id).Return(purchase, nil)
+	purchaseRepo.EXPECT().ReserveRefund(fiberCtx.Context(), purchase, 3).Return(dbRepositories.ErrRefundExceedsQuantity)
+	paymentRepo.EXPECT().FindByPurchaseId(gomock.Any(), gomock.Any()).Times(0)
+
+	response, err := ps.Refund(fiberCtx.Context(), purchase.Id, &request, purchaseOwner)
+
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, apperrors.ErrRefundExceedsQuantity)
+}
+
+func TestPurchaseService_Refund_Exceeds_Quantity(t *testing.T) {
+	teardown := setupPurchaseTest(t)
+	defer teardown()
+
+	purchase := activePurchase(nil, 0)
+	request := dto.PurchaseRefundRequest{Quantity: 4}
+
+	purchaseRepo.EXPECT().FindById(fiberCtx.Context(), purchase.Id).Return(purchase, nil)
+
+	response, err := ps.Refund(fiberCtx.Context(), purchase.Id, &request, purchaseOwner)
+	if err == nil {
+		t.Fatalf("Expected error to be not nil, got nil")
+	}
+
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, apperrors.ErrRefundExceedsQuantity)
+}
+
+func TestPurchaseService_Refund_Window_Closed(t *testing.T) {
+	teardown := setupPurchaseTest(t)
+	defer teardown()
+
+	eventStartsAt := purchaseMockTime.Add(24 * time.Hour)
+	purchase := activePurchase(&eventStartsAt, 48)
+	request := dto.PurchaseRefundRequest{Quantity: 1}
+
+	purchaseRepo.EXPECT().FindById(fiberCtx.Context(), purchase.Id).Return(purchase, nil)
+
+	response, err := ps.Refund(fiberCtx.Context(), purchase.Id, &request, purchaseOwner)
+	if err == nil {
+		t.Fatalf("Expected error to be not nil, got nil")
+	}
+
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, apperrors.ErrRefundWindowClosed)
+}
+
+func TestPurchaseService_Cancel_Not_Active(t *testing.T) {
+	teardown := setupPurchaseTest(t)
+	defer teardown()
+
+	purchase := activePurchase(nil, 0)
+	purchase.IsActive = false
+
+	purchaseRepo.EXPECT().FindById(fiberCtx.Context(), purchase.Id).Return(purchase, nil)
+
+	response, err := ps.Cancel(fiberCtx.Context(), purchase.Id, &dto.PurchaseCancelRequest{}, purchaseOwner)
+	if err == nil {
+		t.Fatalf("Expected error to be not nil, got nil")
+	}
+
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, apperrors.ErrPurchaseNotActive)
+}
+
+func TestPurchaseService_Cancel_Not_Found(t *testing.T) {
+	teardown := setupPurchaseTest(t)
+	defer teardown()
+
+	purchaseRepo.EXPECT().FindById(fiberCtx.Context(), "missing").Return(nil, gorm.ErrRecordNotFound)
+
+	response, err := ps.Cancel(fiberCtx.Context(), "missing", &dto.PurchaseCancelRequest{}, purchaseOwner)
+	if err == nil {
+		t.Fatalf("Expected error to be not nil, got nil")
+	}
+
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+}
+
+func TestPurchaseService_Cancel_Other_User(t *testing.T) {
+	teardown := setupPurchaseTest(t)
+	defer teardown()
+
+	purchase := activePurchase(nil, 0)
+
+	purchaseRepo.EXPECT().FindById(fiberCtx.Context(), purchase.Id).Return(purchase, nil)
+
+	someoneElse := auth.Actor{UserId: "someone-else", Role: auth.RoleCustomer}
+	response, err := ps.Cancel(fiberCtx.Context(), purchase.Id, &dto.PurchaseCancelRequest{}, someoneElse)
+	if err == nil {
+		t.Fatalf("Expected error to be not nil, got nil")
+	}
+
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, apperrors.ErrForbidden)
+}
+
+func TestPurchaseService_Cancel_By_Support(t *testing.T) {
+	teardown := setupPurchaseTest(t)
+	defer teardown()
+
+	purchase := activePurchase(nil, 0)
+
+	purchaseRepo.EXPECT().FindById(fiberCtx.Context(), purchase.Id).Return(purchase, nil)
+	purchaseRepo.EXPECT().ReserveRefund(fiberCtx.Context(), purchase, 3).DoAndReturn(reserveRefund)
+	purchaseRepo.EXPECT().CompleteRefund(fiberCtx.Context(), purchase, 3).Return(nil)
+
+	response, err := ps.Cancel(fiberCtx.Context(), purchase.Id, &dto.PurchaseCancelRequest{}, supportActor)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	assert.False(t, response.IsActive)
+	assert.Equal(t, supportActor.UserId, purchase.UpdatedBy)
+}
+
+func TestPurchaseService_FindById_Embeds_Ticket(t *testing.T) {
+	teardown := setupPurchaseTest(t)
+	defer teardown()
+
+	purchase := activePurchase(nil, 0)
+	purchase.Ticket.Name = "Ticket 1"
+
+	purchaseRepo.EXPECT().FindById(fiberCtx.Context(), purchase.Id).Return(purchase, nil)
+
+	response, err := ps.FindById(fiberCtx.Context(), purchase.Id, purchaseOwner)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	assert.NotNil(t, response.Ticket)
+	assert.Equal(t, "Ticket 1", response.Ticket.Name)
+}
+
+func TestPurchaseService_FindAll_Paginates(t *testing.T) {
+	teardown := setupPurchaseTest(t)
+	defer teardown()
+
+	request := dto.PurchaseListRequest{
+		UserId: "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		Limit:  2,
+		From:   "2020-01-01T00:00:00Z",
+	}
+	from := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	sort := dbRepositories.Sort{Column: "created_at", Descending: true}
+
+	purchases := []models.Purchase{mockPurchaseData[0], mockPurchaseData[1], mockPurchaseData[0]}
+
+	purchaseRepo.EXPECT().FindAll(fiberCtx.Context(), dbRepositories.PurchaseFilter{
+		UserId:      request.UserId,
+		CreatedFrom: &from,
+		Sort:        sort,
+		Limit:       3,
+	}).Return(purchases, nil)
+
+	response, err := ps.FindAll(fiberCtx.Context(), &request, supportActor)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	assert.Len(t, response.Items, 2)
+	assert.True(t, response.Pagination.HasMore)
+	assert.Equal(t, 2, response.Pagination.Limit)
+
+	cursor, err := pagination.DecodeCursor(response.Pagination.NextCursor)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	assert.Equal(t, mockPurchaseData[1].Id, cursor.Id)
+	assert.Equal(t, "2020-01-01T12:00:00Z", cursor.Value)
+}
+
+func TestPurchaseService_FindAll_Last_Page(t *testing.T) {
+	teardown := setupPurchaseTest(t)
+	defer teardown()
+
+	request := dto.PurchaseListRequest{TicketId: "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b", Sort: "quantity", Order: "asc"}
+
+	ticket := mockTicketData[0]
+	ticket.OwnerId = organizerActor.UserId
+
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), request.TicketId).Return(&ticket, nil)
+	purchaseRepo.EXPECT().FindAll(fiberCtx.Context(), gomock.Any()).Return(mockPurchaseData[:1], nil)
+
+	response, err := ps.FindAll(fiberCtx.Context(), &request, organizerActor)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	assert.Len(t, response.Items, 1)
+	assert.False(t, response.Pagination.HasMore)
+	assert.Empty(t, response.Pagination.NextCursor)
+}
+
+func TestPurchaseService_FindAll_Forbidden(t *testing.T) {
+	teardown := setupPurchaseTest(t)
+	defer teardown()
+
+	_, err := ps.FindAll(fiberCtx.Context(), &dto.PurchaseListRequest{UserId: "someone-else"}, purchaseOwner)
+	assert.ErrorIs(t, err, apperrors.ErrForbidden)
+
+	ticket := mockTicketData[0]
+	ticket.OwnerId = "another-organizer"
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), ticket.Id).Return(&ticket, nil)
+
+	_, err = ps.FindAll(fiberCtx.Context(), &dto.PurchaseListRequest{TicketId: ticket.Id}, organizerActor)
+	assert.ErrorIs(t, err, apperrors.ErrForbidden)
+}
+
+func TestPurchaseService_FindAll_Invalid_Request(t *testing.T) {
+	teardown := setupPurchaseTest(t)
+	defer teardown()
+
+	_, err := ps.FindAll(fiberCtx.Context(), &dto.PurchaseListRequest{Sort: "user_id"}, adminActor)
+	assert.ErrorIs(t, err, apperrors.ErrBadRequest)
+
+	_, err = ps.FindAll(fiberCtx.Context(), &dto.PurchaseListRequest{From: "yesterday"}, adminActor)
+	assert.ErrorIs(t, err, apperrors.ErrBadRequest)
+
+	_, err = ps.FindAll(fiberCtx.Context(), &dto.PurchaseListRequest{Cursor: "broken"}, adminActor)
+	assert.ErrorIs(t, err, apperrors.ErrInvalidCursor)
+}
@@ -3,10 +3,16 @@ package services
 import (
 	"context"
 	"errors"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/auth"
 	"ticket-purchase/internal/db/models"
 	"ticket-purchase/internal/db/repositories"
 	"ticket-purchase/internal/dto"
-	"ticket-purchase/internal/i18n/messages"
+	"ticket-purchase/pkg/money"
+	"ticket-purchase/pkg/pagination"
 	"time"
 )
 
@@ -15,69 +21,362 @@ var timeNow = time.Now
 type TicketService interface {
 	// Create creates a new ticket
 	Create(ctx context.Context, request *dto.TicketCreateRequest) (*dto.TicketResponse, error)
-	FindById(ctx context.Context, id string) (*dto.TicketResponse, error)
+	// FindById finds a ticket. Soft deleted tickets are only returned when includeInactive is set.
+	FindById(ctx context.Context, id string, includeInactive bool) (*dto.TicketResponse, error)
+	// FindAll lists the ticket catalog page by page
+	FindAll(ctx context.Context, request *dto.TicketListRequest) (*dto.TicketListResponse, error)
+	// Update changes the details and the total allocation of an active ticket managed by the actor
+	Update(ctx context.Context, id string, request *dto.TicketUpdateRequest, actor auth.Actor) (*dto.TicketResponse, error)
+	// Delete soft deletes a ticket managed by the actor
+	Delete(ctx context.Context, id string, actor auth.Actor) error
+	// Restore reactivates a soft deleted ticket managed by the actor
+	Restore(ctx context.Context, id string, actor auth.Actor) (*dto.TicketResponse, error)
 	TicketPurchase(ctx context.Context, request *dto.TicketPurchaseRequest) error
 }
 
 type ticketService struct {
 	ticketRepo   repositories.TicketRepository
 	purchaseRepo repositories.PurchaseRepository
+	holdRepo     repositories.HoldRepository
+	payments     PaymentService
 }
 
 func NewTicketService(
 	ticketRepo repositories.TicketRepository,
 	purchaseRepo repositories.PurchaseRepository,
+	holdRepo repositories.HoldRepository,
+	payments PaymentService,
 ) TicketService {
 	return &ticketService{
 		ticketRepo:   ticketRepo,
 		purchaseRepo: purchaseRepo,
+		holdRepo:     holdRepo,
+		payments:     payments,
 	}
 }
 
 func (s *ticketService) Create(ctx context.Context, request *dto.TicketCreateRequest) (*dto.TicketResponse, error) {
+	currency := request.Currency
+	if currency == "" {
+		currency = money.DefaultCurrency
+	}
+
 	ticket := models.Ticket{
-		Name:        request.Name,
-		Description: request.Description,
-		Allocation:  request.Allocation,
+		Name:              request.Name,
+		Description:       request.Description,
+		Allocation:        request.Allocation,
+		Price:             request.Price,
+		Currency:          currency,
+		OwnerId:           request.UserId,
+		EventStartsAt:     request.EventStartsAt,
+		RefundCutoffHours: request.RefundCutoffHours,
+		SaleStartsAt:      request.SaleStartsAt,
+		SaleEndsAt:        request.SaleEndsAt,
+		MaxPerOrder:       request.MaxPerOrder,
+		MaxPerUser:        request.MaxPerUser,
+		WaitingRoom:       request.WaitingRoom,
+		CreatedBy:         request.UserId,
+		UpdatedBy:         request.UserId,
 	}
 
 	data, err := s.ticketRepo.Create(ctx, &ticket)
 	if err != nil {
-		return nil, errors.New(messages.ErrorTicketCreate)
+		return nil, apperrors.ErrTicketCreate.Wrap(err)
 	}
 
-	response := dto.TicketResponse{
-		Id:          data.Id,
-		Name:        data.Name,
-		Description: data.Description,
-		Allocation:  data.Allocation,
+	return ticketResponse(data, 0), nil
+}
+
+func (s *ticketService) FindById(ctx context.Context, id string, includeInactive bool) (*dto.TicketResponse, error) {
+	data, err := s.ticketRepo.FindById(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.ErrNotFound.Wrap(err)
 	}
 
-	return &response, nil
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	if !data.IsActive && !includeInactive {
+		return nil, apperrors.ErrNotFound
+	}
+
+	held, err := s.holdRepo.SumActiveQuantity(ctx, id)
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	return ticketResponse(data, held), nil
 }
 
-func (s *ticketService) FindById(ctx context.Context, id string) (*dto.TicketResponse, error) {
-	data, err := s.ticketRepo.FindById(ctx, id)
-	if err != nil && err.Error() == "record not found" {
-		return nil, errors.New(messages.NotFound)
+func (s *ticketService) FindAll(ctx context.Context, request *dto.TicketListRequest) (*dto.TicketListResponse, error) {
+	sort, err := parseSort(request.Sort, request.Order, repositories.TicketSortColumns)
+	if err != nil {
+		return nil, err
 	}
 
+	from, to, err := parseDateRange(request.From, request.To)
 	if err != nil {
-		return nil, errors.New(messages.UnexpectedError)
+		return nil, err
 	}
 
-	response := dto.TicketResponse{
-		Id:          data.Id,
-		Name:        data.Name,
-		Description: data.Description,
-		Allocation:  data.Allocation,
+	cursor, err := pagination.DecodeCursor(request.Cursor)
+	if err != nil {
+		return nil, apperrors.ErrInvalidCursor.Wrap(err)
+	}
+
+	limit := pagination.NormalizeLimit(request.Limit)
+
+	// Soft deleted tickets are hidden unless they are asked for
+	isActive := request.Active
+	if !request.IncludeInactive {
+		active := true
+		isActive = &active
+	}
+
+	// One extra row tells whether there is a next page
+	tickets, err := s.ticketRepo.FindAll(ctx, repositories.TicketFilter{
+		Name:          request.Name,
+		MinAllocation: request.MinAllocation,
+		MaxAllocation: request.MaxAllocation,
+		IsActive:      isActive,
+		CreatedFrom:   from,
+		CreatedTo:     to,
+		SaleStatus:    request.Sale,
+		Now:           timeNow(),
+		Sort:          sort,
+		Cursor:        cursor,
+		Limit:         limit + 1,
+	})
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		return nil, apperrors.ErrInvalidCursor.Wrap(err)
+	}
+
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	response := dto.TicketListResponse{
+		Items:      make([]dto.TicketResponse, 0, len(tickets)),
+		Pagination: dto.PageInfo{Limit: limit},
+	}
+
+	if len(tickets) > limit {
+		tickets = tickets[:limit]
+		response.Pagination.HasMore = true
+		response.Pagination.NextCursor = repositories.TicketCursor(&tickets[limit-1], sort).Encode()
+	}
+
+	if len(tickets) == 0 {
+		return &response, nil
+	}
+
+	ids := make([]string, 0, len(tickets))
+	for _, ticket := range tickets {
+		ids = append(ids, ticket.Id)
+	}
+
+	held, err := s.holdRepo.SumActiveQuantities(ctx, ids)
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	for i := range tickets {
+		response.Items = append(response.Items, *ticketResponse(&tickets[i], held[tickets[i].Id]))
 	}
 
 	return &response, nil
 }
 
+func (s *ticketService) Update(ctx context.Context, id string, request *dto.TicketUpdateRequest, actor auth.Actor) (*dto.TicketResponse, error) {
+	if (request.Name != nil && *request.Name == "") || (request.Allocation != nil && *request.Allocation < 0) {
+		return nil, apperrors.ErrBadRequest
+	}
+
+	ticket, err := s.ticketRepo.FindById(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.ErrNotFound.Wrap(err)
+	}
+
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	if !canManage(actor, ticket) {
+		return nil, apperrors.ErrForbidden
+	}
+
+	if !ticket.IsActive {
+		return nil, apperrors.ErrTicketInactive
+	}
+
+	if request.Name != nil {
+		ticket.Name = *request.Name
+	}
+	if request.Description != nil {
+		ticket.Description = *request.Description
+	}
+	if request.Price != nil {
+		ticket.Price = *request.Price
+	}
+	if request.Currency != nil {
+		ticket.Currency = *request.Currency
+	}
+	if request.EventStartsAt != nil {
+		ticket.EventStartsAt = request.EventStartsAt
+	}
+	if request.SaleStartsAt != nil {
+		ticket.SaleStartsAt = request.SaleStartsAt
+	}
+	if request.SaleEndsAt != nil {
+		ticket.SaleEndsAt = request.SaleEndsAt
+	}
+	if request.MaxPerOrder != nil {
+		ticket.MaxPerOrder = *request.MaxPerOrder
+	}
+	if request.MaxPerUser != nil {
+		ticket.MaxPerUser = *request.MaxPerUser
+	}
+	if request.WaitingRoom != nil {
+		ticket.WaitingRoom = *request.WaitingRoom
+	}
+
+	// Only one bound may be given, so the window is checked against the stored one
+	if ticket.SaleStartsAt != nil && ticket.SaleEndsAt != nil && !ticket.SaleEndsAt.After(*ticket.SaleStartsAt) {
+		return nil, apperrors.ErrSaleWindowInvalid
+	}
+	ticket.UpdatedBy = actor.UserId
+	ticket.UpdatedAt = timeNow()
+
+	data, err := s.ticketRepo.UpdateDetails(ctx, ticket, request.Allocation)
+	if errors.Is(err, repositories.ErrAllocationBelowSold) {
+		return nil, apperrors.ErrAllocationBelowSold.Wrap(err)
+	}
+
+	if err != nil {
+		return nil, apperrors.ErrTicketUpdate.Wrap(err)
+	}
+
+	held, err := s.holdRepo.SumActiveQuantity(ctx, id)
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	return ticketResponse(data, held), nil
+}
+
+func (s *ticketService) Delete(ctx context.Context, id string, actor auth.Actor) error {
+	_, err := s.setActive(ctx, id, false, actor)
+	return err
+}
+
+func (s *ticketService) Restore(ctx context.Context, id string, actor auth.Actor) (*dto.TicketResponse, error) {
+	ticket, err := s.setActive(ctx, id, true, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	held, err := s.holdRepo.SumActiveQuantity(ctx, id)
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	return ticketResponse(ticket, held), nil
+}
+
+func (s *ticketService) setActive(ctx context.Context, id string, active bool, actor auth.Actor) (*models.Ticket, error) {
+	ticket, err := s.ticketRepo.FindById(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.ErrNotFound.Wrap(err)
+	}
+
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	if !canManage(actor, ticket) {
+		return nil, apperrors.ErrForbidden
+	}
+
+	ticket.IsActive = active
+	ticket.UpdatedBy = actor.UserId
+	ticket.UpdatedAt = timeNow()
+	if err := s.ticketRepo.SetActive(ctx, ticket); err != nil {
+		return nil, apperrors.ErrTicketUpdate.Wrap(err)
+	}
+
+	return ticket, nil
+}
+
+// checkOrder returns the ticket of an order. It rejects tickets outside of their sale window and orders above the per
+// order limit. Inactive tickets and the per user limit are checked by the allocation update in its transaction.
+func checkOrder(ctx context.Context, ticketRepo repositories.TicketRepository, ticketId string, quantity int) (*models.Ticket, error) {
+	ticket, err := ticketRepo.FindById(ctx, ticketId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.ErrNotFound.Wrap(err)
+	}
+
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	switch ticket.SaleStatus(timeNow()) {
+	case models.SaleStatusUpcoming:
+		return nil, apperrors.ErrSaleNotStarted
+	case models.SaleStatusEnded:
+		return nil, apperrors.ErrSaleEnded
+	}
+
+	if ticket.MaxPerOrder > 0 && quantity > ticket.MaxPerOrder {
+		return nil, apperrors.ErrMaxPerOrderExceeded
+	}
+	return ticket, nil
+}
+
+// canManage reports whether the actor can change the ticket. Organizers can only change their own tickets.
+func canManage(actor auth.Actor, ticket *models.Ticket) bool {
+	return actor.Can(auth.PermTicketManageAny) || (actor.Can(auth.PermTicketManage) && actor.Owns(ticket.OwnerId))
+}
+
+// ticketResponse builds the ticket response. Allocation held by open carts is not available
+// for sale, but it is not sold yet either.
+func ticketResponse(ticket *models.Ticket, held int) *dto.TicketResponse {
+	return &dto.TicketResponse{
+		Id:          ticket.Id,
+		Name:        ticket.Name,
+		Description: ticket.Description,
+		Allocation:  ticket.Allocation + held,
+		Held:        held,
+		Available:   ticket.Allocation,
+		OwnerId:     ticket.OwnerId,
+
+		Price:    ticket.Price,
+		Currency: ticket.Currency,
+
+		EventStartsAt:     ticket.EventStartsAt,
+		RefundCutoffHours: ticket.RefundCutoffHours,
+		SaleStartsAt:      ticket.SaleStartsAt,
+		SaleEndsAt:        ticket.SaleEndsAt,
+		SaleStatus:        ticket.SaleStatus(timeNow()),
+		MaxPerOrder:       ticket.MaxPerOrder,
+		MaxPerUser:        ticket.MaxPerUser,
+		WaitingRoom:       ticket.WaitingRoom,
+		IsActive:          ticket.IsActive,
+	}
+}
+
 func (s *ticketService) TicketPurchase(ctx context.Context, request *dto.TicketPurchaseRequest) error {
+	if request.Quantity <= 0 {
+		return apperrors.ErrBadRequest
+	}
+
+	ticket, err := checkOrder(ctx, s.ticketRepo, request.TicketId, request.Quantity)
+	if err != nil {
+		return err
+	}
+
 	ticketPurchase := models.Purchase{
+		Id:        uuid.New().String(),
 		TicketId:  request.TicketId,
 		UserId:    request.UserId,
 		Quantity:  request.Quantity,
@@ -85,32 +384,51 @@ func (s *ticketService) TicketPurchase(ctx context.Context, request *dto.TicketP
 		UpdatedBy: request.UserId,
 		CreatedAt: timeNow(),
 		UpdatedAt: timeNow(),
+		IsActive:  true,
 	}
+	ticketPurchase.SetPrice(ticket.UnitPrice())
 
-	err := s.purchaseRepo.Create(ctx, &ticketPurchase)
+	// The payment is authorized before the allocation is taken, so a declined card never holds tickets
+	payment, err := s.payments.Authorize(ctx, &ticketPurchase)
 	if err != nil {
-		return errors.New(messages.ErrorPurchase)
+		return err
 	}
 
-	// Update ticket allocation
-	ticket, err := s.ticketRepo.FindById(ctx, request.TicketId)
-	if err != nil && err.Error() == "record not found" {
-		return errors.New(messages.NotFound)
+	// Insert the purchase and decrement the ticket allocation atomically
+	err = s.purchaseRepo.CreateWithAllocation(ctx, &ticketPurchase)
+	if err != nil {
+		if voidErr := s.payments.Void(ctx, payment); voidErr != nil {
+			log.Error("Error voiding the payment of purchase ", ticketPurchase.Id, ": ", voidErr)
+		}
+		return purchaseError(err)
 	}
 
-	if err != nil {
-		return errors.New(messages.UnexpectedError)
+	// The tickets are sold at this point. An authorization that could not be captured stays authorized and is
+	// settled by hand.
+	if err := s.payments.Capture(ctx, payment); err != nil {
+		log.Error("Error capturing the payment of purchase ", ticketPurchase.Id, ": ", err)
 	}
 
-	if ticket.Allocation < request.Quantity {
-		return errors.New(messages.ErrorTicketAllocations)
+	return nil
+}
+
+// purchaseError maps the errors of taking allocation for a purchase to domain errors
+func purchaseError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperrors.ErrNotFound.Wrap(err)
 	}
 
-	ticket.Allocation -= request.Quantity
-	_, err = s.ticketRepo.Update(ctx, ticket)
-	if err != nil {
-		return errors.New(messages.ErrorTicketUpdate)
+	if errors.Is(err, repositories.ErrInsufficientAllocation) {
+		return apperrors.ErrTicketAllocations.Wrap(err)
 	}
 
-	return nil
+	if errors.Is(err, repositories.ErrTicketInactive) {
+		return apperrors.ErrTicketInactive.Wrap(err)
+	}
+
+	if errors.Is(err, repositories.ErrMaxPerUserExceeded) {
+		return apperrors.ErrMaxPerUserExceeded.Wrap(err)
+	}
+
+	return apperrors.ErrPurchase.Wrap(err)
 }
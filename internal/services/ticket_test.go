@@ -5,11 +5,18 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/valyala/fasthttp"
 	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
 	"testing"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/auth"
 	"ticket-purchase/internal/db/models"
+	dbRepositories "ticket-purchase/internal/db/repositories"
 	"ticket-purchase/internal/dto"
 	"ticket-purchase/internal/i18n"
 	"ticket-purchase/internal/mocks/repositories"
+	"ticket-purchase/internal/payments"
+	"ticket-purchase/pkg/money"
+	"ticket-purchase/pkg/pagination"
 	"time"
 )
 
@@ -19,12 +26,14 @@ var mockTicketData = []models.Ticket{
 		Name:        "Ticket 1",
 		Description: "Description 1",
 		Allocation:  100,
+		IsActive:    true,
 	},
 	{
 		Id:          "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4c",
 		Name:        "Ticket 2",
 		Description: "Description 2",
 		Allocation:  200,
+		IsActive:    true,
 	},
 }
 
@@ -51,17 +60,26 @@ var mockPurchaseData = []models.Purchase{
 	},
 }
 
+var adminActor = auth.Actor{UserId: "admin", Role: auth.RoleAdmin}
+var organizerActor = auth.Actor{UserId: "organizer", Role: auth.RoleOrganizer}
+
 var fiberCtx *fiber.Ctx
 var s TicketService
 var ticketRepo *repositories.MockTicketRepository
 var purchaseRepo *repositories.MockPurchaseRepository
+var holdRepo *repositories.MockHoldRepository
+var paymentRepo *repositories.MockPaymentRepository
+var paymentProvider *payments.FakeProvider
 
 func setupTicketTest(t *testing.T) func() {
 	ct := gomock.NewController(t)
 	defer ct.Finish()
 
 	app := fiber.New()
-	fiberCtx = app.AcquireCtx(&fasthttp.RequestCtx{})
+	// Init gives the request context a server, timeouts derived from it need its Done channel
+	requestCtx := &fasthttp.RequestCtx{}
+	requestCtx.Init(&fasthttp.Request{}, nil, nil)
+	fiberCtx = app.AcquireCtx(requestCtx)
 
 	// Assign language to fiber context header
 	fiberCtx.Request().Header.Set("Accept-Language", "en")
@@ -69,8 +87,11 @@ func setupTicketTest(t *testing.T) func() {
 	i18n.InitBundle("./../i18n/languages")
 	ticketRepo = repositories.NewMockTicketRepository(ct)
 	purchaseRepo = repositories.NewMockPurchaseRepository(ct)
+	holdRepo = repositories.NewMockHoldRepository(ct)
+	paymentRepo = repositories.NewMockPaymentRepository(ct)
+	paymentProvider, _ = payments.NewFakeProvider(payments.FakeSucceed)
 
-	s = NewTicketService(ticketRepo, purchaseRepo)
+	s = NewTicketService(ticketRepo, purchaseRepo, holdRepo, newTestPaymentService())
 	return func() {
 		s = nil
 		defer ct.Finish()
@@ -82,15 +103,23 @@ func TestTicketService_Create_Success(t *testing.T) {
 	defer teardown()
 
 	request := dto.TicketCreateRequest{
+		UserId:      "organizer",
 		Name:        "Ticket 3",
 		Description: "Description 3",
 		Allocation:  100,
+		Price:       2500,
+		Currency:    "EUR",
 	}
 
 	ticket := models.Ticket{
 		Name:        request.Name,
 		Description: request.Description,
 		Allocation:  request.Allocation,
+		Price:       request.Price,
+		Currency:    request.Currency,
+		OwnerId:     request.UserId,
+		CreatedBy:   request.UserId,
+		UpdatedBy:   request.UserId,
 	}
 
 	ticketRepo.EXPECT().Create(fiberCtx.Context(), &ticket).Return(&ticket, nil)
@@ -104,6 +133,8 @@ func TestTicketService_Create_Success(t *testing.T) {
 	assert.Equal(t, request.Name, response.Name)
 	assert.Equal(t, request.Description, response.Description)
 	assert.Equal(t, request.Allocation, response.Allocation)
+	assert.Equal(t, int64(2500), response.Price)
+	assert.Equal(t, "EUR", response.Currency)
 }
 
 func TestTicketService_Create_Failure(t *testing.T) {
@@ -120,6 +151,7 @@ func TestTicketService_Create_Failure(t *testing.T) {
 		Name:        request.Name,
 		Description: request.Description,
 		Allocation:  request.Allocation,
+		Currency:    money.DefaultCurrency,
 	}
 
 	ticketRepo.EXPECT().Create(fiberCtx.Context(), &ticket).Return(nil, assert.AnError)
@@ -140,8 +172,9 @@ func TestTicketService_FindById_Success(t *testing.T) {
 	ticket := mockTicketData[0]
 
 	ticketRepo.EXPECT().FindById(fiberCtx.Context(), id).Return(&ticket, nil)
+	holdRepo.EXPECT().SumActiveQuantity(fiberCtx.Context(), id).Return(0, nil)
 
-	response, err := s.FindById(fiberCtx.Context(), id)
+	response, err := s.FindById(fiberCtx.Context(), id, false)
 	if err != nil {
 		t.Fatalf("Expected error to be nil, got %v", err)
 	}
@@ -152,6 +185,26 @@ func TestTicketService_FindById_Success(t *testing.T) {
 	assert.Equal(t, ticket.Allocation, response.Allocation)
 }
 
+func TestTicketService_FindById_With_Holds(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	id := "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b"
+	ticket := mockTicketData[0]
+
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), id).Return(&ticket, nil)
+	holdRepo.EXPECT().SumActiveQuantity(fiberCtx.Context(), id).Return(3, nil)
+
+	response, err := s.FindById(fiberCtx.Context(), id, false)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	assert.Equal(t, ticket.Allocation+3, response.Allocation)
+	assert.Equal(t, 3, response.Held)
+	assert.Equal(t, ticket.Allocation, response.Available)
+}
+
 func TestTicketService_FindById_Record_Not_Found(t *testing.T) {
 	teardown := setupTicketTest(t)
 	defer teardown()
@@ -160,7 +213,7 @@ func TestTicketService_FindById_Record_Not_Found(t *testing.T) {
 
 	ticketRepo.EXPECT().FindById(fiberCtx.Context(), id).Return(&models.Ticket{}, assert.AnError)
 
-	response, err := s.FindById(fiberCtx.Context(), id)
+	response, err := s.FindById(fiberCtx.Context(), id, false)
 	if err == nil {
 		t.Fatalf("Expected error to be not nil, got nil")
 
@@ -177,7 +230,7 @@ func TestTicketService_FindById_Failure(t *testing.T) {
 
 	ticketRepo.EXPECT().FindById(fiberCtx.Context(), id).Return(nil, assert.AnError)
 
-	response, err := s.FindById(fiberCtx.Context(), id)
+	response, err := s.FindById(fiberCtx.Context(), id, false)
 	if err == nil {
 		t.Fatalf("Expected error to be not nil, got nil")
 	}
@@ -209,18 +262,513 @@ func TestTicketService_TicketPurchase_Success(t *testing.T) {
 		UpdatedBy: request.UserId,
 		CreatedAt: mockTime,
 		UpdatedAt: mockTime,
+		IsActive:  true,
+	}
+
+	ticket := mockTicketData[0]
+	ticket.Price = 1250
+	ticket.Currency = "TRY"
+	purchase.UnitPrice = 1250
+	purchase.Total = 1250
+	purchase.Currency = "TRY"
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), request.TicketId).Return(&ticket, nil)
+	expectPaymentStatuses(models.PaymentStatusAuthorized, models.PaymentStatusCaptured)
+	purchaseRepo.EXPECT().CreateWithAllocation(fiberCtx.Context(), gomock.Any()).DoAndReturn(func(_ any, created *models.Purchase) error {
+		// The purchase id is generated before the payment is authorized
+		assert.NotEmpty(t, created.Id)
+		purchase.Id = created.Id
+		assert.Equal(t, &purchase, created)
+		return nil
+	})
+
+	err := s.TicketPurchase(fiberCtx.Context(), &request)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+}
+
+func TestTicketService_TicketPurchase_Payment_Declined(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	paymentProvider, _ = payments.NewFakeProvider(payments.FakeDecline)
+	s = NewTicketService(ticketRepo, purchaseRepo, holdRepo, newTestPaymentService())
+
+	request := dto.TicketPurchaseRequest{
+		TicketId: "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		UserId:   "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		Quantity: 1,
+	}
+
+	ticket := mockTicketData[0]
+	ticket.Price = 1250
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), request.TicketId).Return(&ticket, nil)
+	expectPaymentStatuses(models.PaymentStatusDeclined)
+	purchaseRepo.EXPECT().CreateWithAllocation(gomock.Any(), gomock.Any()).Times(0)
+
+	err := s.TicketPurchase(fiberCtx.Context(), &request)
+	if err == nil {
+		t.Fatalf("Expected error to be not nil, got nil")
+	}
+
+	assert.ErrorIs(t, err, apperrors.ErrPaymentDeclined)
+}
+
+func TestTicketService_TicketPurchase_Sold_Out_Voids_Payment(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	request := dto.TicketPurchaseRequest{
+		TicketId: "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		UserId:   "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		Quantity: 1,
+	}
+
+	ticket := mockTicketData[0]
+	ticket.Price = 1250
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), request.TicketId).Return(&ticket, nil)
+	expectPaymentStatuses(models.PaymentStatusAuthorized, models.PaymentStatusVoided)
+	purchaseRepo.EXPECT().CreateWithAllocation(fiberCtx.Context(), gomock.Any()).Return(dbRepositories.ErrInsufficientAllocation)
+
+	err := s.TicketPurchase(fiberCtx.Context(), &request)
+	if err == nil {
+		t.Fatalf("Expected error to be not nil, got nil")
+	}
+
+	assert.ErrorIs(t, err, apperrors.ErrTicketAllocations)
+}
+
+func TestTicketService_TicketPurchase_Record_Not_Found(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	request := dto.TicketPurchaseRequest{
+		TicketId: "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4d",
+		UserId:   "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		Quantity: 1,
+	}
+
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), request.TicketId).Return(nil, gorm.ErrRecordNotFound)
+	purchaseRepo.EXPECT().CreateWithAllocation(gomock.Any(), gomock.Any()).Times(0)
+
+	err := s.TicketPurchase(fiberCtx.Context(), &request)
+	if err == nil {
+		t.Fatalf("Expected error to be not nil, got nil")
+	}
+
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+}
+
+func TestTicketService_TicketPurchase_Insufficient_Allocation(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	request := dto.TicketPurchaseRequest{
+		TicketId: "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		UserId:   "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		Quantity: 1000,
+	}
+
+	ticket := mockTicketData[0]
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), request.TicketId).Return(&ticket, nil)
+	purchaseRepo.EXPECT().CreateWithAllocation(fiberCtx.Context(), gomock.Any()).Return(dbRepositories.ErrInsufficientAllocation)
+
+	err := s.TicketPurchase(fiberCtx.Context(), &request)
+	if err == nil {
+		t.Fatalf("Expected error to be not nil, got nil")
+	}
+
+	assert.ErrorIs(t, err, apperrors.ErrTicketAllocations)
+}
+
+func TestTicketService_TicketPurchase_Invalid_Quantity(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	request := dto.TicketPurchaseRequest{
+		TicketId: "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		UserId:   "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		Quantity: -1,
+	}
+
+	err := s.TicketPurchase(fiberCtx.Context(), &request)
+	if err == nil {
+		t.Fatalf("Expected error to be not nil, got nil")
+	}
+
+	assert.ErrorIs(t, err, apperrors.ErrBadRequest)
+}
+
+func TestTicketService_FindAll_Paginates(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	minAllocation := 10
+	active := true
+	request := dto.TicketListRequest{
+		Name:          "Ticket",
+		MinAllocation: &minAllocation,
+		Active:        &active,
+		Sort:          "name",
+		Order:         "asc",
+		Limit:         1,
+	}
+	sort := dbRepositories.Sort{Column: "name"}
+
+	mockTime := time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time {
+		return mockTime
+	}
+	defer func() { timeNow = time.Now }()
+
+	ticketRepo.EXPECT().FindAll(fiberCtx.Context(), dbRepositories.TicketFilter{
+		Name:          "Ticket",
+		MinAllocation: &minAllocation,
+		IsActive:      &active,
+		Sort:          sort,
+		Limit:         2,
+		Now:           mockTime,
+	}).Return(mockTicketData, nil)
+	holdRepo.EXPECT().SumActiveQuantities(fiberCtx.Context(), []string{mockTicketData[0].Id}).Return(map[string]int{mockTicketData[0].Id: 5}, nil)
+
+	response, err := s.FindAll(fiberCtx.Context(), &request)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	assert.Len(t, response.Items, 1)
+	assert.Equal(t, 5, response.Items[0].Held)
+	assert.Equal(t, mockTicketData[0].Allocation, response.Items[0].Available)
+	assert.True(t, response.Pagination.HasMore)
+
+	cursor, err := pagination.DecodeCursor(response.Pagination.NextCursor)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	assert.Equal(t, pagination.Cursor{Value: mockTicketData[0].Name, Id: mockTicketData[0].Id}, *cursor)
+}
+
+func TestTicketService_FindAll_Empty(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	ticketRepo.EXPECT().FindAll(fiberCtx.Context(), gomock.Any()).Return([]models.Ticket{}, nil)
+
+	response, err := s.FindAll(fiberCtx.Context(), &dto.TicketListRequest{})
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	assert.Empty(t, response.Items)
+	assert.False(t, response.Pagination.HasMore)
+	assert.Equal(t, pagination.DefaultLimit, response.Pagination.Limit)
+}
+
+func TestTicketService_FindAll_Invalid_Sort(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	response, err := s.FindAll(fiberCtx.Context(), &dto.TicketListRequest{Sort: "description"})
+	if err == nil {
+		t.Fatalf("Expected error to be not nil, got nil")
+	}
+
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, apperrors.ErrBadRequest)
+}
+
+func TestTicketService_FindById_Inactive(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	ticket := mockTicketData[0]
+	ticket.IsActive = false
+
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), ticket.Id).Return(&ticket, nil).Times(2)
+	holdRepo.EXPECT().SumActiveQuantity(fiberCtx.Context(), ticket.Id).Return(0, nil)
+
+	response, err := s.FindById(fiberCtx.Context(), ticket.Id, false)
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	response, err = s.FindById(fiberCtx.Context(), ticket.Id, true)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	assert.False(t, response.IsActive)
+}
+
+func TestTicketService_Update_Success(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	ticket := mockTicketData[1]
+	ticket.OwnerId = organizerActor.UserId
+	name := "Ticket 2 Updated"
+	allocation := 150
+	request := dto.TicketUpdateRequest{Name: &name, Allocation: &allocation}
+
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), ticket.Id).Return(&ticket, nil)
+	ticketRepo.EXPECT().UpdateDetails(fiberCtx.Context(), gomock.Any(), &allocation).DoAndReturn(
+		func(_ any, updated *models.Ticket, _ *int) (*models.Ticket, error) {
+			assert.Equal(t, name, updated.Name)
+			assert.Equal(t, "organizer", updated.UpdatedBy)
+			assert.Equal(t, ticket.Description, updated.Description)
+			updated.Allocation = 140
+			return updated, nil
+		})
+	holdRepo.EXPECT().SumActiveQuantity(fiberCtx.Context(), ticket.Id).Return(2, nil)
+
+	response, err := s.Update(fiberCtx.Context(), ticket.Id, &request, organizerActor)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
 	}
 
-	purchaseRepo.EXPECT().Create(fiberCtx.Context(), &purchase).Return(nil)
-	ticketRepo.EXPECT().FindById(fiberCtx.Context(), request.TicketId).Return(&mockTicketData[0], nil)
-	ticketRepo.EXPECT().Update(fiberCtx.Context(), &mockTicketData[0]).Return(&mockTicketData[0], nil)
+	assert.Equal(t, name, response.Name)
+	assert.Equal(t, 140, response.Available)
+	assert.Equal(t, 2, response.Held)
+}
+
+func TestTicketService_Update_Allocation_Below_Sold(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	ticket := mockTicketData[1]
+	allocation := 1
+	request := dto.TicketUpdateRequest{Allocation: &allocation}
+
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), ticket.Id).Return(&ticket, nil)
+	ticketRepo.EXPECT().UpdateDetails(fiberCtx.Context(), gomock.Any(), &allocation).Return(nil, dbRepositories.ErrAllocationBelowSold)
+
+	response, err := s.Update(fiberCtx.Context(), ticket.Id, &request, adminActor)
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, apperrors.ErrAllocationBelowSold)
+}
+
+func TestTicketService_Update_Inactive(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	ticket := mockTicketData[1]
+	ticket.IsActive = false
+	name := "Ticket 2 Updated"
+
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), ticket.Id).Return(&ticket, nil)
+
+	response, err := s.Update(fiberCtx.Context(), ticket.Id, &dto.TicketUpdateRequest{Name: &name}, adminActor)
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, apperrors.ErrTicketInactive)
+}
+
+func TestTicketService_Update_Other_Organizer(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	ticket := mockTicketData[1]
+	ticket.OwnerId = "another-organizer"
+	name := "Ticket 2 Updated"
+
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), ticket.Id).Return(&ticket, nil)
+
+	response, err := s.Update(fiberCtx.Context(), ticket.Id, &dto.TicketUpdateRequest{Name: &name}, organizerActor)
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, apperrors.ErrForbidden)
+}
+
+func TestTicketService_Delete_Customer(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	ticket := mockTicketData[1]
+	customer := auth.Actor{UserId: "customer", Role: auth.RoleCustomer}
+
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), ticket.Id).Return(&ticket, nil)
+
+	err := s.Delete(fiberCtx.Context(), ticket.Id, customer)
+	assert.ErrorIs(t, err, apperrors.ErrForbidden)
+}
+
+func TestTicketService_Delete_And_Restore(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	ticket := mockTicketData[1]
+
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), ticket.Id).Return(&ticket, nil).Times(2)
+	gomock.InOrder(
+		ticketRepo.EXPECT().SetActive(fiberCtx.Context(), gomock.Any()).DoAndReturn(func(_ any, updated *models.Ticket) error {
+			assert.False(t, updated.IsActive)
+			assert.Equal(t, "admin", updated.UpdatedBy)
+			return nil
+		}),
+		ticketRepo.EXPECT().SetActive(fiberCtx.Context(), gomock.Any()).DoAndReturn(func(_ any, updated *models.Ticket) error {
+			assert.True(t, updated.IsActive)
+			return nil
+		}),
+	)
+	holdRepo.EXPECT().SumActiveQuantity(fiberCtx.Context(), ticket.Id).Return(0, nil)
+
+	if err := s.Delete(fiberCtx.Context(), ticket.Id, adminActor); err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	response, err := s.Restore(fiberCtx.Context(), ticket.Id, adminActor)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+	assert.True(t, response.IsActive)
+}
+
+func TestTicketService_TicketPurchase_Inactive_Ticket(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	request := dto.TicketPurchaseRequest{
+		TicketId: "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		UserId:   "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		Quantity: 1,
+	}
+
+	ticket := mockTicketData[0]
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), request.TicketId).Return(&ticket, nil)
+	purchaseRepo.EXPECT().CreateWithAllocation(fiberCtx.Context(), gomock.Any()).Return(dbRepositories.ErrTicketInactive)
 
 	err := s.TicketPurchase(fiberCtx.Context(), &request)
+	assert.ErrorIs(t, err, apperrors.ErrTicketInactive)
+}
+
+func TestTicketService_TicketPurchase_Outside_Sale_Window(t *testing.T) {
+	mockTime := time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+	startsAt := mockTime.Add(time.Hour)
+	endsAt := mockTime
+
+	tests := []struct {
+		name   string
+		ticket func(ticket *models.Ticket)
+		want   error
+	}{
+		{name: "not started", ticket: func(ticket *models.Ticket) { ticket.SaleStartsAt = &startsAt }, want: apperrors.ErrSaleNotStarted},
+		{name: "ended", ticket: func(ticket *models.Ticket) { ticket.SaleEndsAt = &endsAt }, want: apperrors.ErrSaleEnded},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			teardown := setupTicketTest(t)
+			defer teardown()
+
+			timeNow = func() time.Time {
+				return mockTime
+			}
+			defer func() { timeNow = time.Now }()
+
+			request := dto.TicketPurchaseRequest{
+				TicketId: mockTicketData[0].Id,
+				UserId:   "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+				Quantity: 1,
+			}
+
+			ticket := mockTicketData[0]
+			test.ticket(&ticket)
+			ticketRepo.EXPECT().FindById(fiberCtx.Context(), ticket.Id).Return(&ticket, nil)
+			purchaseRepo.EXPECT().CreateWithAllocation(gomock.Any(), gomock.Any()).Times(0)
+
+			err := s.TicketPurchase(fiberCtx.Context(), &request)
+
+			assert.ErrorIs(t, err, test.want)
+		})
+	}
+}
+
+func TestTicketService_FindAll_Sale_Status(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	mockTime := time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time {
+		return mockTime
+	}
+	defer func() { timeNow = time.Now }()
+
+	startsAt := mockTime.Add(time.Hour)
+	ticket := mockTicketData[0]
+	ticket.SaleStartsAt = &startsAt
+	request := dto.TicketListRequest{Sale: models.SaleStatusUpcoming, Limit: 10}
+
+	ticketRepo.EXPECT().FindAll(fiberCtx.Context(), gomock.Any()).DoAndReturn(
+		func(_ any, filter dbRepositories.TicketFilter) ([]models.Ticket, error) {
+			assert.Equal(t, models.SaleStatusUpcoming, filter.SaleStatus)
+			assert.Equal(t, mockTime, filter.Now)
+			return []models.Ticket{ticket}, nil
+		})
+	holdRepo.EXPECT().SumActiveQuantities(fiberCtx.Context(), []string{ticket.Id}).Return(map[string]int{}, nil)
+
+	response, err := s.FindAll(fiberCtx.Context(), &request)
 	if err != nil {
 		t.Fatalf("Expected error to be nil, got %v", err)
 	}
 
-	assert.Equal(t, 99, mockTicketData[0].Allocation)
-	assert.Equal(t, mockTime, mockTicketData[0].UpdatedAt)
-	assert.Equal(t, 1, len(mockPurchaseData))
+	assert.Len(t, response.Items, 1)
+	assert.Equal(t, models.SaleStatusUpcoming, response.Items[0].SaleStatus)
+	assert.Equal(t, &startsAt, response.Items[0].SaleStartsAt)
+}
+
+func TestTicketService_Update_Invalid_Sale_Window(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	startsAt := time.Date(2020, time.February, 1, 12, 0, 0, 0, time.UTC)
+	endsAt := startsAt.Add(-time.Hour)
+	ticket := mockTicketData[1]
+	ticket.SaleStartsAt = &startsAt
+	request := dto.TicketUpdateRequest{SaleEndsAt: &endsAt}
+
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), ticket.Id).Return(&ticket, nil)
+	ticketRepo.EXPECT().UpdateDetails(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	response, err := s.Update(fiberCtx.Context(), ticket.Id, &request, adminActor)
+
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, apperrors.ErrSaleWindowInvalid)
+}
+
+func TestTicketService_TicketPurchase_Max_Per_Order(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	request := dto.TicketPurchaseRequest{
+		TicketId: mockTicketData[0].Id,
+		UserId:   "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		Quantity: 5,
+	}
+
+	ticket := mockTicketData[0]
+	ticket.MaxPerOrder = 4
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), ticket.Id).Return(&ticket, nil)
+	purchaseRepo.EXPECT().CreateWithAllocation(gomock.Any(), gomock.Any()).Times(0)
+
+	err := s.TicketPurchase(fiberCtx.Context(), &request)
+
+	assert.ErrorIs(t, err, apperrors.ErrMaxPerOrderExceeded)
+}
+
+func TestTicketService_TicketPurchase_Max_Per_User(t *testing.T) {
+	teardown := setupTicketTest(t)
+	defer teardown()
+
+	request := dto.TicketPurchaseRequest{
+		TicketId: mockTicketData[0].Id,
+		UserId:   "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b",
+		Quantity: 2,
+	}
+
+	ticket := mockTicketData[0]
+	ticket.MaxPerOrder = 2
+	ticket.MaxPerUser = 3
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), ticket.Id).Return(&ticket, nil)
+	purchaseRepo.EXPECT().CreateWithAllocation(fiberCtx.Context(), gomock.Any()).Return(dbRepositories.ErrMaxPerUserExceeded)
+
+	err := s.TicketPurchase(fiberCtx.Context(), &request)
+
+	assert.ErrorIs(t, err, apperrors.ErrMaxPerUserExceeded)
 }
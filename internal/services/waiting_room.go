@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"gorm.io/gorm"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/auth"
+	"ticket-purchase/internal/db/repositories"
+	"ticket-purchase/internal/dto"
+)
+
+type WaitingRoomService interface {
+	// Join queues the actor for a ticket with a waiting room and returns their queue token
+	Join(ctx context.Context, ticketId string, actor auth.Actor) (*dto.QueueTokenResponse, error)
+	// Status returns the place of a queue token of the actor
+	Status(ctx context.Context, ticketId string, token string, actor auth.Actor) (*dto.QueueTokenResponse, error)
+	// CheckAdmission lets the actor buy a ticket without a waiting room, or one they were admitted to with the token.
+	// An admission allows a single purchase, so the token is used up. It reports whether it was.
+	CheckAdmission(ctx context.Context, ticketId string, token string, actor auth.Actor) (bool, error)
+	// ReleaseAdmission gives back a token used up by a purchase that failed, so that it can be used again
+	// for the rest of its admission
+	ReleaseAdmission(ctx context.Context, ticketId string, token string) error
+	// Admit admits the next users of every waiting room and returns how many were admitted
+	Admit(ctx context.Context) (int, error)
+}
+
+type waitingRoomService struct {
+	waitingRoomRepo repositories.WaitingRoomRepository
+	ticketRepo      repositories.TicketRepository
+	conf            config.WaitingRoomConfig
+}
+
+func NewWaitingRoomService(
+	waitingRoomRepo repositories.WaitingRoomRepository,
+	ticketRepo repositories.TicketRepository,
+	conf config.WaitingRoomConfig,
+) WaitingRoomService {
+	return &waitingRoomService{
+		waitingRoomRepo: waitingRoomRepo,
+		ticketRepo:      ticketRepo,
+		conf:            conf,
+	}
+}
+
+func (s *waitingRoomService) Join(ctx context.Context, ticketId string, actor auth.Actor) (*dto.QueueTokenResponse, error) {
+	ticket, err := s.ticketRepo.FindById(ctx, ticketId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.ErrNotFound.Wrap(err)
+	}
+
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	if !ticket.IsActive {
+		return nil, apperrors.ErrTicketInactive
+	}
+
+	if !ticket.WaitingRoom {
+		return nil, apperrors.ErrWaitingRoomDisabled
+	}
+
+	token, err := newQueueToken()
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	entry, err := s.waitingRoomRepo.Join(ctx, ticketId, actor.UserId, token, timeNow())
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	return s.queueTokenResponse(entry), nil
+}
+
+func (s *waitingRoomService) Status(ctx context.Context, ticketId string, token string, actor auth.Actor) (*dto.QueueTokenResponse, error) {
+	entry, err := s.find(ctx, ticketId, token, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.queueTokenResponse(entry), nil
+}
+
+func (s *waitingRoomService) CheckAdmission(ctx context.Context, ticketId string, token string, actor auth.Actor) (bool, error) {
+	ticket, err := s.ticketRepo.FindById(ctx, ticketId)
+
+	// Missing tickets are reported by the purchase itself
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	if !ticket.WaitingRoom {
+		return false, nil
+	}
+
+	if token == "" {
+		return false, apperrors.ErrQueueTokenRequired
+	}
+
+	entry, err := s.find(ctx, ticketId, token, actor)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return false, apperrors.ErrQueueTokenRequired
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	if entry.Status == repositories.QueueStatusUsed {
+		return false, apperrors.ErrQueueTokenUsed
+	}
+
+	if entry.Status != repositories.QueueStatusAdmitted {
+		return false, apperrors.ErrQueueNotAdmitted.WithData(s.queueTokenResponse(entry))
+	}
+
+	// Concurrent requests with the same token race here and only one of them can use it
+	err = s.waitingRoomRepo.Use(ctx, ticketId, token, timeNow())
+	if errors.Is(err, repositories.ErrQueueTokenNotUsable) {
+		return false, apperrors.ErrQueueTokenUsed
+	}
+
+	if err != nil {
+		return false, apperrors.ErrUnexpected.Wrap(err)
+	}
+	return true, nil
+}
+
+func (s *waitingRoomService) ReleaseAdmission(ctx context.Context, ticketId string, token string) error {
+	return s.waitingRoomRepo.Release(ctx, ticketId, token)
+}
+
+func (s *waitingRoomService) Admit(ctx context.Context) (int, error) {
+	ticketIds, err := s.waitingRoomRepo.TicketIds(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	admitted := 0
+	for _, ticketId := range ticketIds {
+		count, err := s.waitingRoomRepo.Admit(ctx, ticketId, timeNow(), s.conf.AdmissionsPerSecond, s.conf.AdmissionWindow)
+		if err != nil {
+			return admitted, err
+		}
+		admitted += count
+	}
+	return admitted, nil
+}
+
+// find returns the queue entry of a token. Tokens of other users are reported as not found.
+func (s *waitingRoomService) find(ctx context.Context, ticketId string, token string, actor auth.Actor) (*repositories.QueueEntry, error) {
+	entry, err := s.waitingRoomRepo.Find(ctx, ticketId, token, timeNow())
+	if errors.Is(err, repositories.ErrQueueTokenNotFound) {
+		return nil, apperrors.ErrNotFound.Wrap(err)
+	}
+
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	if !actor.Owns(entry.UserId) {
+		return nil, apperrors.ErrNotFound
+	}
+	return entry, nil
+}
+
+func (s *waitingRoomService) queueTokenResponse(entry *repositories.QueueEntry) *dto.QueueTokenResponse {
+	response := dto.QueueTokenResponse{
+		Token:         entry.Token,
+		TicketId:      entry.TicketId,
+		Status:        entry.Status,
+		Position:      entry.Position,
+		AdmittedUntil: entry.AdmittedUntil,
+	}
+
+	// AdmissionsPerSecond users are admitted every second
+	if entry.Status == repositories.QueueStatusWaiting {
+		response.EstimatedWaitSeconds = (entry.Position + s.conf.AdmissionsPerSecond - 1) / s.conf.AdmissionsPerSecond
+	}
+	return &response
+}
+
+func newQueueToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
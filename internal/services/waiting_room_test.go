@@ -0,0 +1,191 @@
+package services
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+	"testing"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/auth"
+	"ticket-purchase/internal/db/models"
+	dbRepositories "ticket-purchase/internal/db/repositories"
+	"ticket-purchase/internal/dto"
+	"ticket-purchase/internal/mocks/repositories"
+	"time"
+)
+
+var ws WaitingRoomService
+var waitingRoomRepo *repositories.MockWaitingRoomRepository
+var waitingRoomMockTime = time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+var queuedBuyer = auth.Actor{UserId: "buyer", Role: auth.RoleCustomer}
+
+func setupWaitingRoomTest(t *testing.T) func() {
+	teardown := setupTicketTest(t)
+
+	timeNow = func() time.Time {
+		return waitingRoomMockTime
+	}
+
+	waitingRoomRepo = repositories.NewMockWaitingRoomRepository(gomock.NewController(t))
+	ws = NewWaitingRoomService(waitingRoomRepo, ticketRepo, config.WaitingRoomConfig{
+		AdmissionsPerSecond: 10,
+		AdmissionWindow:     5 * time.Minute,
+	})
+	return func() {
+		ws = nil
+		timeNow = time.Now
+		teardown()
+	}
+}
+
+func queuedTicket() models.Ticket {
+	ticket := mockTicketData[0]
+	ticket.WaitingRoom = true
+	return ticket
+}
+
+func queueEntry(status string, position int) *dbRepositories.QueueEntry {
+	entry := dbRepositories.QueueEntry{
+		Token:    "token",
+		TicketId: mockTicketData[0].Id,
+		UserId:   queuedBuyer.UserId,
+		Status:   status,
+		Position: position,
+	}
+	if status != dbRepositories.QueueStatusWaiting {
+		admittedUntil := waitingRoomMockTime.Add(time.Minute)
+		entry.AdmittedUntil = &admittedUntil
+	}
+	return &entry
+}
+
+func TestWaitingRoomService_Join_Success(t *testing.T) {
+	teardown := setupWaitingRoomTest(t)
+	defer teardown()
+
+	ticket := queuedTicket()
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), ticket.Id).Return(&ticket, nil)
+	waitingRoomRepo.EXPECT().Join(fiberCtx.Context(), ticket.Id, queuedBuyer.UserId, gomock.Any(), waitingRoomMockTime).
+		Return(queueEntry(dbRepositories.QueueStatusWaiting, 25), nil)
+
+	response, err := ws.Join(fiberCtx.Context(), ticket.Id, queuedBuyer)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	assert.Equal(t, dto.QueueTokenResponse{
+		Token:                "token",
+		TicketId:             ticket.Id,
+		Status:               dbRepositories.QueueStatusWaiting,
+		Position:             25,
+		EstimatedWaitSeconds: 3,
+	}, *response)
+}
+
+func TestWaitingRoomService_Join_Without_Waiting_Room(t *testing.T) {
+	teardown := setupWaitingRoomTest(t)
+	defer teardown()
+
+	ticket := mockTicketData[0]
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), ticket.Id).Return(&ticket, nil)
+	waitingRoomRepo.EXPECT().Join(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := ws.Join(fiberCtx.Context(), ticket.Id, queuedBuyer)
+
+	assert.ErrorIs(t, err, apperrors.ErrWaitingRoomDisabled)
+}
+
+func TestWaitingRoomService_Status_Of_Other_User(t *testing.T) {
+	teardown := setupWaitingRoomTest(t)
+	defer teardown()
+
+	waitingRoomRepo.EXPECT().Find(fiberCtx.Context(), mockTicketData[0].Id, "token", waitingRoomMockTime).
+		Return(queueEntry(dbRepositories.QueueStatusWaiting, 1), nil)
+
+	_, err := ws.Status(fiberCtx.Context(), mockTicketData[0].Id, "token", auth.Actor{UserId: "other", Role: auth.RoleCustomer})
+
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+}
+
+func TestWaitingRoomService_CheckAdmission_Without_Waiting_Room(t *testing.T) {
+	teardown := setupWaitingRoomTest(t)
+	defer teardown()
+
+	ticket := mockTicketData[0]
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), ticket.Id).Return(&ticket, nil)
+
+	used, err := ws.CheckAdmission(fiberCtx.Context(), ticket.Id, "", queuedBuyer)
+	assert.NoError(t, err)
+	assert.False(t, used)
+}
+
+func TestWaitingRoomService_CheckAdmission_Ticket_Not_Found(t *testing.T) {
+	teardown := setupWaitingRoomTest(t)
+	defer teardown()
+
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), "missing").Return(nil, gorm.ErrRecordNotFound)
+
+	used, err := ws.CheckAdmission(fiberCtx.Context(), "missing", "", queuedBuyer)
+	assert.NoError(t, err)
+	assert.False(t, used)
+}
+
+func TestWaitingRoomService_CheckAdmission(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		entry  *dbRepositories.QueueEntry
+		err    error
+		useErr error
+		want   error
+	}{
+		{name: "missing token", want: apperrors.ErrQueueTokenRequired},
+		{name: "unknown token", token: "token", err: dbRepositories.ErrQueueTokenNotFound, want: apperrors.ErrQueueTokenRequired},
+		{name: "waiting", token: "token", entry: queueEntry(dbRepositories.QueueStatusWaiting, 3), want: apperrors.ErrQueueNotAdmitted},
+		{name: "expired", token: "token", entry: queueEntry(dbRepositories.QueueStatusExpired, 0), want: apperrors.ErrQueueNotAdmitted},
+		{name: "used", token: "token", entry: queueEntry(dbRepositories.QueueStatusUsed, 0), want: apperrors.ErrQueueTokenUsed},
+		{name: "used concurrently", token: "token", entry: queueEntry(dbRepositories.QueueStatusAdmitted, 0), useErr: dbRepositories.ErrQueueTokenNotUsable, want: apperrors.ErrQueueTokenUsed},
+		{name: "admitted", token: "token", entry: queueEntry(dbRepositories.QueueStatusAdmitted, 0)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			teardown := setupWaitingRoomTest(t)
+			defer teardown()
+
+			ticket := queuedTicket()
+			ticketRepo.EXPECT().FindById(fiberCtx.Context(), ticket.Id).Return(&ticket, nil)
+			if test.token != "" {
+				waitingRoomRepo.EXPECT().Find(fiberCtx.Context(), ticket.Id, test.token, waitingRoomMockTime).Return(test.entry, test.err)
+			}
+			if test.entry != nil && test.entry.Status == dbRepositories.QueueStatusAdmitted {
+				waitingRoomRepo.EXPECT().Use(fiberCtx.Context(), ticket.Id, test.token, waitingRoomMockTime).Return(test.useErr)
+			}
+
+			used, err := ws.CheckAdmission(fiberCtx.Context(), ticket.Id, test.token, queuedBuyer)
+
+			if test.want == nil {
+				assert.NoError(t, err)
+				assert.True(t, used)
+				return
+			}
+			assert.ErrorIs(t, err, test.want)
+			assert.False(t, used)
+		})
+	}
+}
+
+func TestWaitingRoomService_Admit(t *testing.T) {
+	teardown := setupWaitingRoomTest(t)
+	defer teardown()
+
+	waitingRoomRepo.EXPECT().TicketIds(fiberCtx.Context()).Return([]string{"ticket-1", "ticket-2"}, nil)
+	waitingRoomRepo.EXPECT().Admit(fiberCtx.Context(), "ticket-1", waitingRoomMockTime, 10, 5*time.Minute).Return(10, nil)
+	waitingRoomRepo.EXPECT().Admit(fiberCtx.Context(), "ticket-2", waitingRoomMockTime, 10, 5*time.Minute).Return(4, nil)
+
+	admitted, err := ws.Admit(fiberCtx.Context())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 14, admitted)
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gofiber/fiber/v2/log"
+	"gorm.io/gorm"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/db/models"
+	"ticket-purchase/internal/db/repositories"
+	"ticket-purchase/internal/payments"
+)
+
+type WebhookService interface {
+	// ReceivePayment verifies a payment callback of the provider and applies it to the payment and its purchase.
+	// Events that were already applied are acknowledged without changes.
+	ReceivePayment(ctx context.Context, signature string, body []byte) error
+}
+
+type webhookService struct {
+	webhookEventRepo repositories.WebhookEventRepository
+	paymentRepo      repositories.PaymentRepository
+	purchaseRepo     repositories.PurchaseRepository
+	conf             config.PaymentConfig
+}
+
+func NewWebhookService(
+	webhookEventRepo repositories.WebhookEventRepository,
+	paymentRepo repositories.PaymentRepository,
+	purchaseRepo repositories.PurchaseRepository,
+	conf config.PaymentConfig,
+) WebhookService {
+	return &webhookService{
+		webhookEventRepo: webhookEventRepo,
+		paymentRepo:      paymentRepo,
+		purchaseRepo:     purchaseRepo,
+		conf:             conf,
+	}
+}
+
+func (s *webhookService) ReceivePayment(ctx context.Context, signature string, body []byte) error {
+	err := payments.VerifyWebhook(signature, body, s.conf.WebhookSecrets, s.conf.WebhookTolerance, timeNow())
+	if errors.Is(err, payments.ErrStaleWebhook) {
+		return apperrors.ErrStaleWebhook.Wrap(err)
+	}
+
+	if err != nil {
+		return apperrors.ErrInvalidWebhookSignature.Wrap(err)
+	}
+
+	var event payments.Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		return apperrors.ErrBadRequest.Wrap(err)
+	}
+
+	if event.Id == "" || event.PaymentId == "" {
+		return apperrors.ErrBadRequest
+	}
+
+	// Payments are recorded before the provider hears of them, events of unknown payments are not retried
+	payment, err := s.paymentRepo.FindById(ctx, event.PaymentId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Warn("Webhook event ", event.Id, " is for unknown payment ", event.PaymentId)
+		return nil
+	}
+
+	if err != nil {
+		return apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	created, err := s.webhookEventRepo.CreateIfNotExists(ctx, &models.WebhookEvent{
+		Id:        event.Id,
+		Provider:  payment.Provider,
+		Type:      event.Type,
+		CreatedAt: timeNow(),
+	})
+	if err != nil {
+		return apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	if !created {
+		return nil
+	}
+
+	// The event is forgotten when it could not be applied, so the provider can deliver it again
+	if err := s.apply(ctx, payment, event); err != nil {
+		if deleteErr := s.webhookEventRepo.Delete(ctx, event.Id); deleteErr != nil {
+			log.Error("Error forgetting webhook event ", event.Id, ": ", deleteErr)
+		}
+		return apperrors.ErrUnexpected.Wrap(err)
+	}
+	return nil
+}
+
+// apply advances the payment to the status the event reports. Events that arrive after the payment moved past them
+// are ignored.
+func (s *webhookService) apply(ctx context.Context, payment *models.Payment, event payments.Event) error {
+	switch event.Type {
+	case payments.EventAuthorized:
+		if payment.Status != models.PaymentStatusPending {
+			return nil
+		}
+		payment.AuthorizationId = event.AuthorizationId
+		return transitionPayment(ctx, s.paymentRepo, payment, models.PaymentStatusAuthorized)
+
+	case payments.EventCaptured:
+		if payment.Status != models.PaymentStatusAuthorized {
+			return nil
+		}
+		return transitionPayment(ctx, s.paymentRepo, payment, models.PaymentStatusCaptured)
+
+	case payments.EventRefunded:
+		if payment.Status != models.PaymentStatusCaptured || event.RefundedAmount <= payment.RefundedAmount {
+			return nil
+		}
+
+		payment.UpdatedAt = timeNow()
+		if err := s.paymentRepo.SyncRefund(ctx, payment, event.RefundedAmount); err != nil {
+			return err
+		}
+
+		if payment.Status == models.PaymentStatusRefunded {
+			return s.releasePurchase(ctx, payment, "payment refunded")
+		}
+		return nil
+
+	case payments.EventFailed:
+		if !payment.CanTransition(models.PaymentStatusFailed) {
+			return nil
+		}
+
+		wasAuthorized := payment.Status == models.PaymentStatusAuthorized
+		payment.FailureReason = event.Reason
+		if err := transitionPayment(ctx, s.paymentRepo, payment, models.PaymentStatusFailed); err != nil {
+			return err
+		}
+
+		// A capture that failed after the purchase was written leaves tickets that were never paid for
+		if wasAuthorized {
+			return s.releasePurchase(ctx, payment, "payment failed")
+		}
+		return nil
+	}
+
+	log.Warn("Ignoring webhook event ", event.Id, " of unknown type ", event.Type)
+	return nil
+}
+
+// releasePurchase refunds the remaining quantity of the purchase of a payment whose money is gone
+func (s *webhookService) releasePurchase(ctx context.Context, payment *models.Payment, reason string) error {
+	purchase, err := s.purchaseRepo.FindById(ctx, payment.PurchaseId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !purchase.IsActive {
+		return nil
+	}
+
+	purchase.CancelReason = reason
+	purchase.UpdatedBy = "system"
+	purchase.UpdatedAt = timeNow()
+
+	// The buyer can refund the purchase at the same time
+	err = s.purchaseRepo.Refund(ctx, purchase, purchase.RemainingQuantity())
+	if errors.Is(err, repositories.ErrRefundExceedsQuantity) {
+		return nil
+	}
+
+	return err
+}
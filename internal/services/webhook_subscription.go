@@ -0,0 +1,453 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/gofiber/fiber/v2/log"
+	"gorm.io/gorm"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/db/models"
+	"ticket-purchase/internal/db/repositories"
+	"ticket-purchase/internal/dto"
+	"ticket-purchase/internal/events"
+	"ticket-purchase/internal/webhooks"
+	"ticket-purchase/pkg/pagination"
+)
+
+// webhookSecretPrefix marks subscription secrets, so that they are recognized when they leak
+const webhookSecretPrefix = "whsec_"
+
+// maxWebhookErrorLength limits the error kept of a failed attempt
+const maxWebhookErrorLength = 500
+
+// lookupIP resolves the hosts of subscriptions, tests replace it
+var lookupIP = net.DefaultResolver.LookupIP
+
+// webhookEvents are the outgoing webhook events of the domain events partner systems can subscribe to
+var webhookEvents = map[string]string{
+	events.TypeTicketCreated:     webhooks.EventTicketCreated,
+	events.TypeTicketUpdated:     webhooks.EventTicketUpdated,
+	events.TypeSoldOut:           webhooks.EventTicketSoldOut,
+	events.TypeTicketPurchased:   webhooks.EventPurchaseCompleted,
+	events.TypePurchaseCancelled: webhooks.EventPurchaseCancelled,
+}
+
+type WebhookSubscriptionService interface {
+	// HandleEvent queues a delivery of a domain event for every subscription of its webhook event, with the ticket
+	// or the purchase the event is about. It consumes the events of the outbox.
+	HandleEvent(ctx context.Context, event events.Event) error
+	// Create subscribes an endpoint to events. The response is the only one with the signing secret.
+	Create(ctx context.Context, request *dto.WebhookSubscriptionCreateRequest) (*dto.WebhookSubscriptionResponse, error)
+	// FindAll lists the subscriptions without their secrets
+	FindAll(ctx context.Context) (*dto.WebhookSubscriptionListResponse, error)
+	// Delete removes a subscription with its deliveries
+	Delete(ctx context.Context, id string) error
+	// FindDeliveries lists deliveries newest first page by page, dead letters are the ones with the dead status
+	FindDeliveries(ctx context.Context, request *dto.WebhookDeliveryListRequest) (*dto.WebhookDeliveryListResponse, error)
+	// Redeliver queues a delivered or dead delivery again with a fresh set of attempts
+	Redeliver(ctx context.Context, id string) (*dto.WebhookDeliveryResponse, error)
+	// DeliverDue sends the deliveries whose next attempt is due and returns how many were delivered
+	DeliverDue(ctx context.Context) (int, error)
+}
+
+type webhookSubscriptionService struct {
+	subscriptionRepo repositories.WebhookSubscriptionRepository
+	deliveryRepo     repositories.WebhookDeliveryRepository
+	ticketRepo       repositories.TicketRepository
+	holdRepo         repositories.HoldRepository
+	purchaseRepo     repositories.PurchaseRepository
+	sender           webhooks.Sender
+	conf             config.WebhookConfig
+}
+
+func NewWebhookSubscriptionService(
+	subscriptionRepo repositories.WebhookSubscriptionRepository,
+	deliveryRepo repositories.WebhookDeliveryRepository,
+	ticketRepo repositories.TicketRepository,
+	holdRepo repositories.HoldRepository,
+	purchaseRepo repositories.PurchaseRepository,
+	sender webhooks.Sender,
+	conf config.WebhookConfig,
+) WebhookSubscriptionService {
+	return &webhookSubscriptionService{
+		subscriptionRepo: subscriptionRepo,
+		deliveryRepo:     deliveryRepo,
+		ticketRepo:       ticketRepo,
+		holdRepo:         holdRepo,
+		purchaseRepo:     purchaseRepo,
+		sender:           sender,
+		conf:             conf,
+	}
+}
+
+func (s *webhookSubscriptionService) Create(ctx context.Context, request *dto.WebhookSubscriptionCreateRequest) (*dto.WebhookSubscriptionResponse, error) {
+	endpoint, err := url.Parse(request.Url)
+	if err != nil {
+		return nil, apperrors.ErrWebhookUrlInvalid.Wrap(err)
+	}
+
+	if (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, apperrors.ErrWebhookUrlInvalid
+	}
+
+	if err := checkPublicHost(ctx, endpoint.Hostname()); err != nil {
+		return nil, err
+	}
+
+	eventTypes := make([]string, 0, len(request.Events))
+	for _, event := range request.Events {
+		if !webhooks.IsEvent(event) {
+			return nil, apperrors.ErrWebhookEventUnknown
+		}
+
+		if !slices.Contains(eventTypes, event) {
+			eventTypes = append(eventTypes, event)
+		}
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	now := timeNow()
+	subscription := models.WebhookSubscription{
+		Url:       request.Url,
+		Secret:    secret,
+		Events:    strings.Join(eventTypes, ","),
+		CreatedBy: request.UserId,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := s.subscriptionRepo.Create(ctx, &subscription); err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	response := webhookSubscriptionResponse(&subscription)
+	response.Secret = subscription.Secret
+	return response, nil
+}
+
+// checkPublicHost makes sure that every address of host is public, so that subscriptions cannot make the API post to
+// itself or to the services next to it
+func checkPublicHost(ctx context.Context, host string) error {
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		var err error
+		ips, err = lookupIP(ctx, "ip", host)
+		if err != nil {
+			return apperrors.ErrWebhookUrlInvalid.Wrap(err)
+		}
+	}
+
+	for _, ip := range ips {
+		if !webhooks.IsPublicAddress(ip) {
+			return apperrors.ErrWebhookUrlNotPublic
+		}
+	}
+	return nil
+}
+
+func (s *webhookSubscriptionService) FindAll(ctx context.Context) (*dto.WebhookSubscriptionListResponse, error) {
+	subscriptions, err := s.subscriptionRepo.FindAll(ctx)
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	response := dto.WebhookSubscriptionListResponse{
+		Items: make([]dto.WebhookSubscriptionResponse, 0, len(subscriptions)),
+	}
+	for i := range subscriptions {
+		response.Items = append(response.Items, *webhookSubscriptionResponse(&subscriptions[i]))
+	}
+
+	return &response, nil
+}
+
+func (s *webhookSubscriptionService) Delete(ctx context.Context, id string) error {
+	err := s.subscriptionRepo.Delete(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperrors.ErrNotFound.Wrap(err)
+	}
+
+	if err != nil {
+		return apperrors.ErrUnexpected.Wrap(err)
+	}
+	return nil
+}
+
+func (s *webhookSubscriptionService) FindDeliveries(ctx context.Context, request *dto.WebhookDeliveryListRequest) (*dto.WebhookDeliveryListResponse, error) {
+	cursor, err := pagination.DecodeCursor(request.Cursor)
+	if err != nil {
+		return nil, apperrors.ErrInvalidCursor.Wrap(err)
+	}
+
+	limit := pagination.NormalizeLimit(request.Limit)
+
+	// One extra row tells whether there is a next page
+	deliveries, err := s.deliveryRepo.FindAll(ctx, repositories.WebhookDeliveryFilter{
+		SubscriptionId: request.SubscriptionId,
+		Status:         request.Status,
+		Cursor:         cursor,
+		Limit:          limit + 1,
+	})
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		return nil, apperrors.ErrInvalidCursor.Wrap(err)
+	}
+
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	response := dto.WebhookDeliveryListResponse{
+		Items:      make([]dto.WebhookDeliveryResponse, 0, len(deliveries)),
+		Pagination: dto.PageInfo{Limit: limit},
+	}
+
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+		response.Pagination.HasMore = true
+		response.Pagination.NextCursor = repositories.WebhookDeliveryCursor(&deliveries[limit-1]).Encode()
+	}
+
+	for i := range deliveries {
+		response.Items = append(response.Items, *webhookDeliveryResponse(&deliveries[i]))
+	}
+
+	return &response, nil
+}
+
+func (s *webhookSubscriptionService) Redeliver(ctx context.Context, id string) (*dto.WebhookDeliveryResponse, error) {
+	delivery, err := s.deliveryRepo.FindById(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.ErrNotFound.Wrap(err)
+	}
+
+	if err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	// Pending deliveries may be in flight, they are retried anyway
+	if delivery.Status == models.WebhookDeliveryPending {
+		return nil, apperrors.ErrWebhookDeliveryPending
+	}
+
+	now := timeNow()
+	delivery.Status = models.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = now
+	delivery.LastError = ""
+	delivery.LastStatus = 0
+	delivery.DeliveredAt = nil
+	delivery.UpdatedAt = now
+	if err := s.deliveryRepo.Save(ctx, delivery); err != nil {
+		return nil, apperrors.ErrUnexpected.Wrap(err)
+	}
+
+	return webhookDeliveryResponse(delivery), nil
+}
+
+func (s *webhookSubscriptionService) HandleEvent(ctx context.Context, event events.Event) error {
+	eventType, ok := webhookEvents[event.Type]
+	if !ok {
+		return nil
+	}
+
+	subscriptions, err := s.subscriptionRepo.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	subscribed := subscriptions[:0]
+	for _, subscription := range subscriptions {
+		if subscription.Subscribes(eventType) {
+			subscribed = append(subscribed, subscription)
+		}
+	}
+
+	if len(subscribed) == 0 {
+		return nil
+	}
+
+	data, err := s.eventData(ctx, event)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Warn("Dropping webhook event ", event.Id, " of type ", eventType, ", what it is about no longer exists")
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// The id of the domain event is kept, so an event relayed again is not delivered twice
+	body, err := json.Marshal(webhooks.Payload{
+		Id:        event.Id,
+		Type:      eventType,
+		CreatedAt: event.OccurredAt,
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+
+	now := timeNow()
+	deliveries := make([]models.WebhookDelivery, 0, len(subscribed))
+	for _, subscription := range subscribed {
+		deliveries = append(deliveries, models.WebhookDelivery{
+			SubscriptionId: subscription.Id,
+			EventId:        event.Id,
+			EventType:      eventType,
+			Payload:        string(body),
+			Status:         models.WebhookDeliveryPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		})
+	}
+
+	return s.deliveryRepo.CreateBatch(ctx, deliveries)
+}
+
+// eventData loads the ticket or the purchase of an event as the API returns it
+func (s *webhookSubscriptionService) eventData(ctx context.Context, event events.Event) (interface{}, error) {
+	if event.Type == events.TypeTicketPurchased || event.Type == events.TypePurchaseCancelled {
+		var payload struct {
+			PurchaseId string `json:"purchase_id"`
+		}
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return nil, err
+		}
+
+		purchase, err := s.purchaseRepo.FindById(ctx, payload.PurchaseId)
+		if err != nil {
+			return nil, err
+		}
+		return purchaseResponse(purchase), nil
+	}
+
+	ticket, err := s.ticketRepo.FindById(ctx, event.TicketId)
+	if err != nil {
+		return nil, err
+	}
+
+	held, err := s.holdRepo.SumActiveQuantity(ctx, ticket.Id)
+	if err != nil {
+		return nil, err
+	}
+	return ticketResponse(ticket, held), nil
+}
+
+func (s *webhookSubscriptionService) DeliverDue(ctx context.Context) (int, error) {
+	// The deliveries of a round are sent at the same time, so the lease only has to outlast one request
+	deliveries, err := s.deliveryRepo.ClaimDue(ctx, timeNow(), 2*s.conf.Timeout, s.conf.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	delivered := 0
+	for i := range deliveries {
+		wg.Add(1)
+		go func(delivery *models.WebhookDelivery) {
+			defer wg.Done()
+			if s.deliver(ctx, delivery) {
+				mu.Lock()
+				delivered++
+				mu.Unlock()
+			}
+		}(&deliveries[i])
+	}
+	wg.Wait()
+
+	return delivered, nil
+}
+
+// deliver makes one attempt of a delivery and records its outcome. Failed deliveries are retried with an
+// exponential backoff until they run out of attempts.
+func (s *webhookSubscriptionService) deliver(ctx context.Context, delivery *models.WebhookDelivery) bool {
+	status, err := s.sender.Send(ctx, webhooks.Request{
+		DeliveryId: delivery.Id,
+		EventType:  delivery.EventType,
+		Url:        delivery.Subscription.Url,
+		Secret:     delivery.Subscription.Secret,
+		Body:       []byte(delivery.Payload),
+	})
+
+	now := timeNow()
+	delivery.Attempts++
+	delivery.LastStatus = status
+	delivery.UpdatedAt = now
+
+	switch {
+	case err == nil:
+		delivery.Status = models.WebhookDeliveryDelivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= s.conf.MaxAttempts:
+		delivery.Status = models.WebhookDeliveryDead
+		delivery.LastError = truncate(err.Error(), maxWebhookErrorLength)
+		log.Warn("Webhook delivery ", delivery.Id, " is dead after ", delivery.Attempts, " attempts: ", err)
+	default:
+		delivery.LastError = truncate(err.Error(), maxWebhookErrorLength)
+		delivery.NextAttemptAt = now.Add(webhooks.Backoff(delivery.Attempts, s.conf.RetryDelay, s.conf.MaxRetryDelay))
+	}
+
+	// A delivery whose outcome is lost is sent again once its lease is over
+	if saveErr := s.deliveryRepo.Save(ctx, delivery); saveErr != nil {
+		log.Error("Error saving webhook delivery ", delivery.Id, ": ", saveErr)
+	}
+	return err == nil
+}
+
+func newWebhookSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// truncate shortens value to at most length bytes
+func truncate(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+	return value[:length]
+}
+
+func webhookSubscriptionResponse(subscription *models.WebhookSubscription) *dto.WebhookSubscriptionResponse {
+	return &dto.WebhookSubscriptionResponse{
+		Id:        subscription.Id,
+		Url:       subscription.Url,
+		Events:    subscription.EventList(),
+		CreatedBy: subscription.CreatedBy,
+		CreatedAt: subscription.CreatedAt,
+	}
+}
+
+func webhookDeliveryResponse(delivery *models.WebhookDelivery) *dto.WebhookDeliveryResponse {
+	return &dto.WebhookDeliveryResponse{
+		Id:             delivery.Id,
+		SubscriptionId: delivery.SubscriptionId,
+		EventId:        delivery.EventId,
+		EventType:      delivery.EventType,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		LastError:      delivery.LastError,
+		LastStatus:     delivery.LastStatus,
+		DeliveredAt:    delivery.DeliveredAt,
+		CreatedAt:      delivery.CreatedAt,
+	}
+}
@@ -0,0 +1,453 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/db/models"
+	dbRepositories "ticket-purchase/internal/db/repositories"
+	"ticket-purchase/internal/dto"
+	"ticket-purchase/internal/events"
+	"ticket-purchase/internal/mocks/repositories"
+	"ticket-purchase/internal/webhooks"
+	"time"
+)
+
+var subs WebhookSubscriptionService
+var subscriptionRepo *repositories.MockWebhookSubscriptionRepository
+var deliveryRepo *repositories.MockWebhookDeliveryRepository
+var sender *stubSender
+
+var webhookSubscriptionTestConf = config.WebhookConfig{
+	BatchSize:     10,
+	Timeout:       5 * time.Second,
+	MaxAttempts:   3,
+	RetryDelay:    time.Minute,
+	MaxRetryDelay: time.Hour,
+}
+
+// stubSender answers every delivery with the same outcome and keeps the requests
+type stubSender struct {
+	mu       sync.Mutex
+	status   int
+	err      error
+	requests []webhooks.Request
+}
+
+func (s *stubSender) Send(_ context.Context, request webhooks.Request) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, request)
+	return s.status, s.err
+}
+
+func setupWebhookSubscriptionTest(t *testing.T) func() {
+	teardown := setupTicketTest(t)
+
+	timeNow = func() time.Time {
+		return webhookMockTime
+	}
+	lookupIP = func(_ context.Context, _ string, host string) ([]net.IP, error) {
+		switch host {
+		case "partner.example.com":
+			return []net.IP{net.ParseIP("93.184.216.34")}, nil
+		case "localhost":
+			return []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")}, nil
+		case "split.example.com":
+			return []net.IP{net.ParseIP("93.184.216.34"), net.ParseIP("10.0.0.5")}, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	ct := gomock.NewController(t)
+	subscriptionRepo = repositories.NewMockWebhookSubscriptionRepository(ct)
+	deliveryRepo = repositories.NewMockWebhookDeliveryRepository(ct)
+	sender = &stubSender{status: 200}
+	subs = NewWebhookSubscriptionService(subscriptionRepo, deliveryRepo, ticketRepo, holdRepo, purchaseRepo, sender, webhookSubscriptionTestConf)
+	return func() {
+		subs = nil
+		timeNow = time.Now
+		lookupIP = net.DefaultResolver.LookupIP
+		teardown()
+	}
+}
+
+func dueDelivery(attempts int) models.WebhookDelivery {
+	return models.WebhookDelivery{
+		Id:             "delivery-1",
+		SubscriptionId: "subscription-1",
+		EventId:        "event-1",
+		EventType:      webhooks.EventTicketCreated,
+		Payload:        `{"id":"event-1"}`,
+		Status:         models.WebhookDeliveryPending,
+		Attempts:       attempts,
+		NextAttemptAt:  webhookMockTime,
+		Subscription: models.WebhookSubscription{
+			Id:     "subscription-1",
+			Url:    "https://partner.example.com/hooks",
+			Secret: "whsec_test",
+		},
+	}
+}
+
+func TestWebhookSubscriptionService_Create_Success(t *testing.T) {
+	teardown := setupWebhookSubscriptionTest(t)
+	defer teardown()
+
+	request := dto.WebhookSubscriptionCreateRequest{
+		Url:    "https://partner.example.com/hooks",
+		Events: []string{webhooks.EventTicketSoldOut, webhooks.EventPurchaseCompleted, webhooks.EventTicketSoldOut},
+		UserId: "admin",
+	}
+
+	subscriptionRepo.EXPECT().Create(fiberCtx.Context(), gomock.Any()).DoAndReturn(func(_ any, subscription *models.WebhookSubscription) error {
+		assert.Equal(t, "ticket.sold_out,purchase.completed", subscription.Events)
+		assert.Equal(t, "admin", subscription.CreatedBy)
+		subscription.Id = "subscription-1"
+		return nil
+	})
+
+	response, err := subs.Create(fiberCtx.Context(), &request)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	assert.Equal(t, "subscription-1", response.Id)
+	assert.Equal(t, []string{webhooks.EventTicketSoldOut, webhooks.EventPurchaseCompleted}, response.Events)
+	assert.True(t, strings.HasPrefix(response.Secret, "whsec_"))
+}
+
+func TestWebhookSubscriptionService_Create_Invalid(t *testing.T) {
+	teardown := setupWebhookSubscriptionTest(t)
+	defer teardown()
+
+	subscriptionRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := subs.Create(fiberCtx.Context(), &dto.WebhookSubscriptionCreateRequest{
+		Url:    "ftp://partner.example.com/hooks",
+		Events: []string{webhooks.EventTicketCreated},
+	})
+	assert.ErrorIs(t, err, apperrors.ErrWebhookUrlInvalid)
+
+	_, err = subs.Create(fiberCtx.Context(), &dto.WebhookSubscriptionCreateRequest{
+		Url:    "https://partner.example.com/hooks",
+		Events: []string{"ticket.deleted"},
+	})
+	assert.ErrorIs(t, err, apperrors.ErrWebhookEventUnknown)
+}
+
+func TestWebhookSubscriptionService_Create_Not_Public(t *testing.T) {
+	teardown := setupWebhookSubscriptionTest(t)
+	defer teardown()
+
+	subscriptionRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	for _, endpoint := range []string{
+		"http://127.0.0.1:8000/v1/tickets",
+		"http://[::1]/hooks",
+		"http://169.254.169.254/latest/meta-data",
+		"https://10.0.0.5/hooks",
+		"http://localhost:8000/hooks",
+		"https://split.example.com/hooks",
+	} {
+		_, err := subs.Create(fiberCtx.Context(), &dto.WebhookSubscriptionCreateRequest{
+			Url:    endpoint,
+			Events: []string{webhooks.EventTicketCreated},
+		})
+		assert.ErrorIs(t, err, apperrors.ErrWebhookUrlNotPublic, endpoint)
+	}
+
+	_, err := subs.Create(fiberCtx.Context(), &dto.WebhookSubscriptionCreateRequest{
+		Url:    "https://unknown.example.com/hooks",
+		Events: []string{webhooks.EventTicketCreated},
+	})
+	assert.ErrorIs(t, err, apperrors.ErrWebhookUrlInvalid)
+}
+
+// domainEvent is an event of the outbox as the relay hands it to the bus
+func domainEvent(t *testing.T, eventType string, payload interface{}) events.Event {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	return events.Event{
+		Id:         "event-1",
+		Type:       eventType,
+		TicketId:   mockTicketData[0].Id,
+		Payload:    body,
+		OccurredAt: webhookMockTime.Add(-time.Minute),
+	}
+}
+
+func TestWebhookSubscriptionService_HandleEvent_Sold_Out(t *testing.T) {
+	teardown := setupWebhookSubscriptionTest(t)
+	defer teardown()
+
+	ticket := mockTicketData[0]
+	ticket.Allocation = 0
+	event := domainEvent(t, events.TypeSoldOut, events.SoldOut{TicketId: ticket.Id})
+
+	subscriptionRepo.EXPECT().FindAll(fiberCtx.Context()).Return([]models.WebhookSubscription{
+		{Id: "subscription-1", Events: "ticket.created,ticket.sold_out"},
+		{Id: "subscription-2", Events: "purchase.completed"},
+		{Id: "subscription-3", Events: "ticket.sold_out"},
+	}, nil)
+	ticketRepo.EXPECT().FindById(fiberCtx.Context(), ticket.Id).Return(&ticket, nil)
+	holdRepo.EXPECT().SumActiveQuantity(fiberCtx.Context(), ticket.Id).Return(3, nil)
+	deliveryRepo.EXPECT().CreateBatch(fiberCtx.Context(), gomock.Any()).DoAndReturn(func(_ any, deliveries []models.WebhookDelivery) error {
+		assert.Len(t, deliveries, 2)
+		assert.Equal(t, "subscription-1", deliveries[0].SubscriptionId)
+		assert.Equal(t, "subscription-3", deliveries[1].SubscriptionId)
+
+		// Every subscription gets the event under the id of the domain event
+		assert.Equal(t, event.Id, deliveries[0].EventId)
+		assert.Equal(t, event.Id, deliveries[1].EventId)
+		assert.Equal(t, deliveries[0].Payload, deliveries[1].Payload)
+		assert.Equal(t, models.WebhookDeliveryPending, deliveries[0].Status)
+		assert.Equal(t, webhookMockTime, deliveries[0].NextAttemptAt)
+
+		var payload struct {
+			Id        string    `json:"id"`
+			Type      string    `json:"type"`
+			CreatedAt time.Time `json:"created_at"`
+			Data      struct {
+				Id        string `json:"id"`
+				Available int    `json:"available"`
+				Held      int    `json:"held"`
+			} `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(deliveries[0].Payload), &payload))
+		assert.Equal(t, event.Id, payload.Id)
+		assert.Equal(t, webhooks.EventTicketSoldOut, payload.Type)
+		assert.True(t, event.OccurredAt.Equal(payload.CreatedAt))
+		assert.Equal(t, ticket.Id, payload.Data.Id)
+		assert.Equal(t, 0, payload.Data.Available)
+		assert.Equal(t, 3, payload.Data.Held)
+		return nil
+	})
+
+	assert.NoError(t, subs.HandleEvent(fiberCtx.Context(), event))
+}
+
+func TestWebhookSubscriptionService_HandleEvent_Purchase_Cancelled(t *testing.T) {
+	teardown := setupWebhookSubscriptionTest(t)
+	defer teardown()
+
+	purchase := models.Purchase{Id: "purchase-1", TicketId: mockTicketData[0].Id, Quantity: 2, RefundedQuantity: 2, CancelReason: "payment refunded"}
+	event := domainEvent(t, events.TypePurchaseCancelled, events.PurchaseCancelled{TicketId: purchase.TicketId, PurchaseId: purchase.Id})
+
+	subscriptionRepo.EXPECT().FindAll(fiberCtx.Context()).Return([]models.WebhookSubscription{
+		{Id: "subscription-1", Events: "purchase.cancelled"},
+	}, nil)
+	purchaseRepo.EXPECT().FindById(fiberCtx.Context(), purchase.Id).Return(&purchase, nil)
+	deliveryRepo.EXPECT().CreateBatch(fiberCtx.Context(), gomock.Any()).DoAndReturn(func(_ any, deliveries []models.WebhookDelivery) error {
+		assert.Len(t, deliveries, 1)
+		assert.Equal(t, webhooks.EventPurchaseCancelled, deliveries[0].EventType)
+
+		var payload struct {
+			Data dto.PurchaseResponse `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(deliveries[0].Payload), &payload))
+		assert.Equal(t, purchase.Id, payload.Data.Id)
+		assert.Equal(t, "payment refunded", payload.Data.CancelReason)
+		assert.False(t, payload.Data.IsActive)
+		return nil
+	})
+
+	assert.NoError(t, subs.HandleEvent(fiberCtx.Context(), event))
+}
+
+func TestWebhookSubscriptionService_HandleEvent_Without_Subscribers(t *testing.T) {
+	teardown := setupWebhookSubscriptionTest(t)
+	defer teardown()
+
+	subscriptionRepo.EXPECT().FindAll(fiberCtx.Context()).Return([]models.WebhookSubscription{
+		{Id: "subscription-1", Events: "purchase.completed"},
+	}, nil)
+	ticketRepo.EXPECT().FindById(gomock.Any(), gomock.Any()).Times(0)
+	deliveryRepo.EXPECT().CreateBatch(gomock.Any(), gomock.Any()).Times(0)
+
+	event := domainEvent(t, events.TypeTicketCreated, events.TicketCreated{TicketId: mockTicketData[0].Id})
+	assert.NoError(t, subs.HandleEvent(fiberCtx.Context(), event))
+}
+
+func TestWebhookSubscriptionService_HandleEvent_Ignores_Other_Events(t *testing.T) {
+	teardown := setupWebhookSubscriptionTest(t)
+	defer teardown()
+
+	subscriptionRepo.EXPECT().FindAll(gomock.Any()).Times(0)
+
+	event := domainEvent(t, events.TypeAllocationChanged, events.AllocationChanged{TicketId: mockTicketData[0].Id})
+	assert.NoError(t, subs.HandleEvent(fiberCtx.Context(), event))
+}
+
+func TestWebhookSubscriptionService_HandleEvent_Deleted_Purchase(t *testing.T) {
+	teardown := setupWebhookSubscriptionTest(t)
+	defer teardown()
+
+	subscriptionRepo.EXPECT().FindAll(fiberCtx.Context()).Return([]models.WebhookSubscription{
+		{Id: "subscription-1", Events: "purchase.completed"},
+	}, nil)
+	purchaseRepo.EXPECT().FindById(fiberCtx.Context(), "purchase-1").Return(nil, gorm.ErrRecordNotFound)
+	deliveryRepo.EXPECT().CreateBatch(gomock.Any(), gomock.Any()).Times(0)
+
+	event := domainEvent(t, events.TypeTicketPurchased, events.TicketPurchased{TicketId: mockTicketData[0].Id, PurchaseId: "purchase-1"})
+	assert.NoError(t, subs.HandleEvent(fiberCtx.Context(), event))
+}
+
+func TestWebhookSubscriptionService_HandleEvent_Queue_Fails(t *testing.T) {
+	teardown := setupWebhookSubscriptionTest(t)
+	defer teardown()
+
+	failure := errors.New("connection reset")
+	purchase := models.Purchase{Id: "purchase-1", TicketId: mockTicketData[0].Id, Quantity: 1, IsActive: true}
+	subscriptionRepo.EXPECT().FindAll(fiberCtx.Context()).Return([]models.WebhookSubscription{
+		{Id: "subscription-1", Events: "purchase.completed"},
+	}, nil)
+	purchaseRepo.EXPECT().FindById(fiberCtx.Context(), purchase.Id).Return(&purchase, nil)
+	deliveryRepo.EXPECT().CreateBatch(fiberCtx.Context(), gomock.Any()).Return(failure)
+
+	// The relay stops at the event and publishes it again
+	event := domainEvent(t, events.TypeTicketPurchased, events.TicketPurchased{TicketId: purchase.TicketId, PurchaseId: purchase.Id})
+	assert.ErrorIs(t, subs.HandleEvent(fiberCtx.Context(), event), failure)
+}
+
+func TestWebhookSubscriptionService_DeliverDue_Delivered(t *testing.T) {
+	teardown := setupWebhookSubscriptionTest(t)
+	defer teardown()
+
+	deliveryRepo.EXPECT().ClaimDue(fiberCtx.Context(), webhookMockTime, 10*time.Second, 10).Return([]models.WebhookDelivery{dueDelivery(0)}, nil)
+	deliveryRepo.EXPECT().Save(fiberCtx.Context(), gomock.Any()).DoAndReturn(func(_ any, delivery *models.WebhookDelivery) error {
+		assert.Equal(t, models.WebhookDeliveryDelivered, delivery.Status)
+		assert.Equal(t, 1, delivery.Attempts)
+		assert.Equal(t, 200, delivery.LastStatus)
+		assert.Equal(t, webhookMockTime, *delivery.DeliveredAt)
+		return nil
+	})
+
+	delivered, err := subs.DeliverDue(fiberCtx.Context())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	assert.Equal(t, []webhooks.Request{{
+		DeliveryId: "delivery-1",
+		EventType:  webhooks.EventTicketCreated,
+		Url:        "https://partner.example.com/hooks",
+		Secret:     "whsec_test",
+		Body:       []byte(`{"id":"event-1"}`),
+	}}, sender.requests)
+}
+
+func TestWebhookSubscriptionService_DeliverDue_Retries_With_Backoff(t *testing.T) {
+	teardown := setupWebhookSubscriptionTest(t)
+	defer teardown()
+
+	sender.status = 503
+	sender.err = errors.New("webhook answered 503")
+
+	deliveryRepo.EXPECT().ClaimDue(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]models.WebhookDelivery{dueDelivery(1)}, nil)
+	deliveryRepo.EXPECT().Save(fiberCtx.Context(), gomock.Any()).DoAndReturn(func(_ any, delivery *models.WebhookDelivery) error {
+		assert.Equal(t, models.WebhookDeliveryPending, delivery.Status)
+		assert.Equal(t, 2, delivery.Attempts)
+		assert.Equal(t, 503, delivery.LastStatus)
+		assert.Equal(t, "webhook answered 503", delivery.LastError)
+		// The second failed attempt waits twice the retry delay
+		assert.Equal(t, webhookMockTime.Add(2*time.Minute), delivery.NextAttemptAt)
+		return nil
+	})
+
+	delivered, err := subs.DeliverDue(fiberCtx.Context())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+}
+
+func TestWebhookSubscriptionService_DeliverDue_Dead_Letter(t *testing.T) {
+	teardown := setupWebhookSubscriptionTest(t)
+	defer teardown()
+
+	sender.status = 0
+	sender.err = errors.New("connection refused")
+
+	deliveryRepo.EXPECT().ClaimDue(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]models.WebhookDelivery{dueDelivery(2)}, nil)
+	deliveryRepo.EXPECT().Save(fiberCtx.Context(), gomock.Any()).DoAndReturn(func(_ any, delivery *models.WebhookDelivery) error {
+		assert.Equal(t, models.WebhookDeliveryDead, delivery.Status)
+		assert.Equal(t, 3, delivery.Attempts)
+		assert.Equal(t, "connection refused", delivery.LastError)
+		return nil
+	})
+
+	_, err := subs.DeliverDue(fiberCtx.Context())
+	assert.NoError(t, err)
+}
+
+func TestWebhookSubscriptionService_Redeliver(t *testing.T) {
+	teardown := setupWebhookSubscriptionTest(t)
+	defer teardown()
+
+	delivery := dueDelivery(3)
+	delivery.Status = models.WebhookDeliveryDead
+	delivery.LastError = "connection refused"
+
+	deliveryRepo.EXPECT().FindById(fiberCtx.Context(), delivery.Id).Return(&delivery, nil)
+	deliveryRepo.EXPECT().Save(fiberCtx.Context(), &delivery).Return(nil)
+
+	response, err := subs.Redeliver(fiberCtx.Context(), delivery.Id)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	assert.Equal(t, models.WebhookDeliveryPending, response.Status)
+	assert.Equal(t, 0, response.Attempts)
+	assert.Equal(t, webhookMockTime, response.NextAttemptAt)
+	assert.Empty(t, response.LastError)
+}
+
+func TestWebhookSubscriptionService_Redeliver_Pending(t *testing.T) {
+	teardown := setupWebhookSubscriptionTest(t)
+	defer teardown()
+
+	delivery := dueDelivery(1)
+	deliveryRepo.EXPECT().FindById(fiberCtx.Context(), delivery.Id).Return(&delivery, nil)
+	deliveryRepo.EXPECT().Save(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := subs.Redeliver(fiberCtx.Context(), delivery.Id)
+	assert.ErrorIs(t, err, apperrors.ErrWebhookDeliveryPending)
+}
+
+func TestWebhookSubscriptionService_FindDeliveries_Dead_Letters(t *testing.T) {
+	teardown := setupWebhookSubscriptionTest(t)
+	defer teardown()
+
+	first := dueDelivery(3)
+	first.Status = models.WebhookDeliveryDead
+	first.CreatedAt = webhookMockTime
+	second := first
+	second.Id = "delivery-2"
+
+	deliveryRepo.EXPECT().FindAll(fiberCtx.Context(), gomock.Any()).DoAndReturn(func(_ any, filter dbRepositories.WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+		assert.Equal(t, models.WebhookDeliveryDead, filter.Status)
+		assert.Equal(t, 2, filter.Limit)
+		return []models.WebhookDelivery{first, second}, nil
+	})
+
+	response, err := subs.FindDeliveries(fiberCtx.Context(), &dto.WebhookDeliveryListRequest{Status: models.WebhookDeliveryDead, Limit: 1})
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	assert.Len(t, response.Items, 1)
+	assert.Equal(t, "delivery-1", response.Items[0].Id)
+	assert.True(t, response.Pagination.HasMore)
+	assert.Equal(t, dbRepositories.WebhookDeliveryCursor(&first).Encode(), response.Pagination.NextCursor)
+}
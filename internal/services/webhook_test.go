@@ -0,0 +1,260 @@
+package services
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+	"testing"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/apperrors"
+	"ticket-purchase/internal/db/models"
+	dbRepositories "ticket-purchase/internal/db/repositories"
+	"ticket-purchase/internal/mocks/repositories"
+	"ticket-purchase/internal/payments"
+	"time"
+)
+
+var whs WebhookService
+var webhookEventRepo *repositories.MockWebhookEventRepository
+var webhookMockTime = time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+var webhookTestConf = config.PaymentConfig{
+	WebhookSecrets:   []string{"new-secret", "old-secret"},
+	WebhookTolerance: 5 * time.Minute,
+}
+
+func setupWebhookTest(t *testing.T) func() {
+	teardown := setupTicketTest(t)
+
+	timeNow = func() time.Time {
+		return webhookMockTime
+	}
+
+	webhookEventRepo = repositories.NewMockWebhookEventRepository(gomock.NewController(t))
+	whs = NewWebhookService(webhookEventRepo, paymentRepo, purchaseRepo, webhookTestConf)
+	return func() {
+		whs = nil
+		timeNow = time.Now
+		teardown()
+	}
+}
+
+// signedEvent returns a callback body and its signature made with the current secret
+func signedEvent(body string) (string, []byte) {
+	return payments.SignWebhook("new-secret", webhookMockTime, []byte(body)), []byte(body)
+}
+
+func webhookPayment(status string) *models.Payment {
+	return &models.Payment{
+		Id:              "payment-1",
+		PurchaseId:      "purchase-1",
+		Amount:          2500,
+		Currency:        "TRY",
+		Status:          status,
+		Provider:        "fake",
+		AuthorizationId: "fake_auth_payment-1",
+	}
+}
+
+func expectNewWebhookEvent(id string) {
+	webhookEventRepo.EXPECT().CreateIfNotExists(fiberCtx.Context(), gomock.Any()).DoAndReturn(func(_ any, event *models.WebhookEvent) (bool, error) {
+		return event.Id == id, nil
+	})
+}
+
+func TestWebhookService_ReceivePayment_Captured(t *testing.T) {
+	teardown := setupWebhookTest(t)
+	defer teardown()
+
+	payment := webhookPayment(models.PaymentStatusAuthorized)
+	signature, body := signedEvent(`{"id":"evt_1","type":"payment.captured","payment_id":"payment-1"}`)
+
+	paymentRepo.EXPECT().FindById(fiberCtx.Context(), "payment-1").Return(payment, nil)
+	expectNewWebhookEvent("evt_1")
+	paymentRepo.EXPECT().Transition(fiberCtx.Context(), payment, models.PaymentStatusAuthorized).Return(nil)
+
+	err := whs.ReceivePayment(fiberCtx.Context(), signature, body)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.PaymentStatusCaptured, payment.Status)
+}
+
+func TestWebhookService_ReceivePayment_Rotated_Secret(t *testing.T) {
+	teardown := setupWebhookTest(t)
+	defer teardown()
+
+	payment := webhookPayment(models.PaymentStatusPending)
+	body := []byte(`{"id":"evt_1","type":"payment.authorized","payment_id":"payment-1","authorization_id":"auth_1"}`)
+	signature := payments.SignWebhook("old-secret", webhookMockTime, body)
+
+	paymentRepo.EXPECT().FindById(fiberCtx.Context(), "payment-1").Return(payment, nil)
+	expectNewWebhookEvent("evt_1")
+	paymentRepo.EXPECT().Transition(fiberCtx.Context(), payment, models.PaymentStatusPending).Return(nil)
+
+	err := whs.ReceivePayment(fiberCtx.Context(), signature, body)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.PaymentStatusAuthorized, payment.Status)
+	assert.Equal(t, "auth_1", payment.AuthorizationId)
+}
+
+func TestWebhookService_ReceivePayment_Invalid_Signature(t *testing.T) {
+	teardown := setupWebhookTest(t)
+	defer teardown()
+
+	body := []byte(`{"id":"evt_1","type":"payment.captured","payment_id":"payment-1"}`)
+	signature := payments.SignWebhook("unknown-secret", webhookMockTime, body)
+
+	paymentRepo.EXPECT().FindById(gomock.Any(), gomock.Any()).Times(0)
+
+	err := whs.ReceivePayment(fiberCtx.Context(), signature, body)
+	assert.ErrorIs(t, err, apperrors.ErrInvalidWebhookSignature)
+}
+
+func TestWebhookService_ReceivePayment_Stale(t *testing.T) {
+	teardown := setupWebhookTest(t)
+	defer teardown()
+
+	body := []byte(`{"id":"evt_1","type":"payment.captured","payment_id":"payment-1"}`)
+	signature := payments.SignWebhook("new-secret", webhookMockTime.Add(-time.Hour), body)
+
+	paymentRepo.EXPECT().FindById(gomock.Any(), gomock.Any()).Times(0)
+
+	err := whs.ReceivePayment(fiberCtx.Context(), signature, body)
+	assert.ErrorIs(t, err, apperrors.ErrStaleWebhook)
+}
+
+func TestWebhookService_ReceivePayment_Duplicate(t *testing.T) {
+	teardown := setupWebhookTest(t)
+	defer teardown()
+
+	signature, body := signedEvent(`{"id":"evt_1","type":"payment.captured","payment_id":"payment-1"}`)
+
+	paymentRepo.EXPECT().FindById(fiberCtx.Context(), "payment-1").Return(webhookPayment(models.PaymentStatusAuthorized), nil)
+	webhookEventRepo.EXPECT().CreateIfNotExists(fiberCtx.Context(), gomock.Any()).Return(false, nil)
+	paymentRepo.EXPECT().Transition(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	err := whs.ReceivePayment(fiberCtx.Context(), signature, body)
+	assert.NoError(t, err)
+}
+
+func TestWebhookService_ReceivePayment_Unknown_Payment(t *testing.T) {
+	teardown := setupWebhookTest(t)
+	defer teardown()
+
+	signature, body := signedEvent(`{"id":"evt_1","type":"payment.captured","payment_id":"unknown"}`)
+
+	paymentRepo.EXPECT().FindById(fiberCtx.Context(), "unknown").Return(nil, gorm.ErrRecordNotFound)
+	webhookEventRepo.EXPECT().CreateIfNotExists(gomock.Any(), gomock.Any()).Times(0)
+
+	err := whs.ReceivePayment(fiberCtx.Context(), signature, body)
+	assert.NoError(t, err)
+}
+
+func TestWebhookService_ReceivePayment_Out_Of_Order(t *testing.T) {
+	teardown := setupWebhookTest(t)
+	defer teardown()
+
+	// The capture was already recorded when the authorization arrives
+	signature, body := signedEvent(`{"id":"evt_1","type":"payment.authorized","payment_id":"payment-1"}`)
+
+	paymentRepo.EXPECT().FindById(fiberCtx.Context(), "payment-1").Return(webhookPayment(models.PaymentStatusCaptured), nil)
+	expectNewWebhookEvent("evt_1")
+	paymentRepo.EXPECT().Transition(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	err := whs.ReceivePayment(fiberCtx.Context(), signature, body)
+	assert.NoError(t, err)
+}
+
+func TestWebhookService_ReceivePayment_Refunded_Releases_Purchase(t *testing.T) {
+	teardown := setupWebhookTest(t)
+	defer teardown()
+
+	payment := webhookPayment(models.PaymentStatusCaptured)
+	payment.RefundedAmount = 1250
+	purchase := &models.Purchase{Id: "purchase-1", TicketId: "ticket-1", Quantity: 2, RefundedQuantity: 1, IsActive: true}
+	signature, body := signedEvent(`{"id":"evt_1","type":"payment.refunded","payment_id":"payment-1","refunded_amount":2500}`)
+
+	paymentRepo.EXPECT().FindById(fiberCtx.Context(), "payment-1").Return(payment, nil)
+	expectNewWebhookEvent("evt_1")
+	paymentRepo.EXPECT().SyncRefund(fiberCtx.Context(), payment, int64(2500)).DoAndReturn(func(_ any, payment *models.Payment, refundedAmount int64) error {
+		payment.RefundedAmount = refundedAmount
+		payment.Status = models.PaymentStatusRefunded
+		return nil
+	})
+	purchaseRepo.EXPECT().FindById(fiberCtx.Context(), "purchase-1").Return(purchase, nil)
+	purchaseRepo.EXPECT().Refund(fiberCtx.Context(), purchase, 1).Return(nil)
+
+	err := whs.ReceivePayment(fiberCtx.Context(), signature, body)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "payment refunded", purchase.CancelReason)
+	assert.Equal(t, "system", purchase.UpdatedBy)
+}
+
+func TestWebhookService_ReceivePayment_Refund_Already_Recorded(t *testing.T) {
+	teardown := setupWebhookTest(t)
+	defer teardown()
+
+	// Refunds made through the API are recorded before the provider reports them
+	payment := webhookPayment(models.PaymentStatusCaptured)
+	payment.RefundedAmount = 1250
+	signature, body := signedEvent(`{"id":"evt_1","type":"payment.refunded","payment_id":"payment-1","refunded_amount":1250}`)
+
+	paymentRepo.EXPECT().FindById(fiberCtx.Context(), "payment-1").Return(payment, nil)
+	expectNewWebhookEvent("evt_1")
+	paymentRepo.EXPECT().SyncRefund(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	err := whs.ReceivePayment(fiberCtx.Context(), signature, body)
+	assert.NoError(t, err)
+}
+
+func TestWebhookService_ReceivePayment_Failed_Capture_Releases_Purchase(t *testing.T) {
+	teardown := setupWebhookTest(t)
+	defer teardown()
+
+	payment := webhookPayment(models.PaymentStatusAuthorized)
+	purchase := &models.Purchase{Id: "purchase-1", TicketId: "ticket-1", Quantity: 2, IsActive: true}
+	signature, body := signedEvent(`{"id":"evt_1","type":"payment.failed","payment_id":"payment-1","reason":"card expired"}`)
+
+	paymentRepo.EXPECT().FindById(fiberCtx.Context(), "payment-1").Return(payment, nil)
+	expectNewWebhookEvent("evt_1")
+	paymentRepo.EXPECT().Transition(fiberCtx.Context(), payment, models.PaymentStatusAuthorized).Return(nil)
+	purchaseRepo.EXPECT().FindById(fiberCtx.Context(), "purchase-1").Return(purchase, nil)
+	purchaseRepo.EXPECT().Refund(fiberCtx.Context(), purchase, 2).Return(nil)
+
+	err := whs.ReceivePayment(fiberCtx.Context(), signature, body)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.PaymentStatusFailed, payment.Status)
+	assert.Equal(t, "card expired", payment.FailureReason)
+}
+
+func TestWebhookService_ReceivePayment_Failure_Forgets_Event(t *testing.T) {
+	teardown := setupWebhookTest(t)
+	defer teardown()
+
+	payment := webhookPayment(models.PaymentStatusAuthorized)
+	signature, body := signedEvent(`{"id":"evt_1","type":"payment.captured","payment_id":"payment-1"}`)
+
+	paymentRepo.EXPECT().FindById(fiberCtx.Context(), "payment-1").Return(payment, nil)
+	expectNewWebhookEvent("evt_1")
+	paymentRepo.EXPECT().Transition(fiberCtx.Context(), payment, models.PaymentStatusAuthorized).Return(dbRepositories.ErrPaymentStatusChanged)
+	webhookEventRepo.EXPECT().Delete(fiberCtx.Context(), "evt_1").Return(nil)
+
+	err := whs.ReceivePayment(fiberCtx.Context(), signature, body)
+
+	assert.ErrorIs(t, err, apperrors.ErrUnexpected)
+	assert.True(t, errors.Is(err, dbRepositories.ErrPaymentStatusChanged))
+}
+
+func TestWebhookService_ReceivePayment_Malformed_Body(t *testing.T) {
+	teardown := setupWebhookTest(t)
+	defer teardown()
+
+	signature, body := signedEvent(`{"type":"payment.captured"}`)
+
+	err := whs.ReceivePayment(fiberCtx.Context(), signature, body)
+	assert.ErrorIs(t, err, apperrors.ErrBadRequest)
+}
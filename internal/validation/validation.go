@@ -0,0 +1,106 @@
+package validation
+
+import (
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"reflect"
+	"strings"
+	"ticket-purchase/internal/i18n"
+	"ticket-purchase/internal/i18n/messages"
+)
+
+// FieldError describes why a single request field is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+var validate = newValidator()
+
+// localizedCodes are the validation codes that have their own message, other codes use messages.ValidationInvalid
+var localizedCodes = map[string]string{
+	"required":     messages.ValidationRequired,
+	"gt":           messages.ValidationGt,
+	"gte":          messages.ValidationGte,
+	"gtfield":      messages.ValidationGt,
+	"gtefield":     messages.ValidationGte,
+	"lt":           messages.ValidationLt,
+	"lte":          messages.ValidationLte,
+	"min":          messages.ValidationMin,
+	"max":          messages.ValidationMax,
+	"oneof":        messages.ValidationOneOf,
+	"datetime":     messages.ValidationDatetime,
+	"email":        messages.ValidationEmail,
+	"at_least_one": messages.ValidationAtLeastOne,
+}
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// Report fields with the names clients send them with
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "query", "params"} {
+			name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
+
+	return v
+}
+
+// RegisterStructValidation adds a rule that checks several fields of a request type together
+func RegisterStructValidation(fn validator.StructLevelFunc, types ...interface{}) {
+	validate.RegisterStructValidation(fn, types...)
+}
+
+// Struct validates the request against its validate tags and returns every field error,
+// localized for the language of the request. It returns nil when the request is valid.
+func Struct(ctx *fiber.Ctx, request interface{}) []FieldError {
+	err := validate.Struct(request)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return []FieldError{{Code: "invalid", Message: i18n.CreateMsg(ctx, messages.BadRequest)}}
+	}
+
+	fieldErrors := make([]FieldError, 0, len(validationErrors))
+	for _, fieldError := range validationErrors {
+		field := fieldPath(fieldError)
+
+		messageId, ok := localizedCodes[fieldError.Tag()]
+		if !ok {
+			messageId = messages.ValidationInvalid
+		}
+
+		fieldErrors = append(fieldErrors, FieldError{
+			Field: field,
+			Code:  fieldError.Tag(),
+			Message: i18n.CreateMsg(ctx, messageId, map[string]string{
+				"Field": field,
+				"Param": strings.ReplaceAll(fieldError.Param(), " ", ", "),
+			}),
+		})
+	}
+
+	return fieldErrors
+}
+
+// fieldPath returns the field name without the request type prefix, e.g. "quantity" instead of "TicketPurchaseRequest.quantity"
+func fieldPath(fieldError validator.FieldError) string {
+	namespace := fieldError.Namespace()
+	if index := strings.Index(namespace, "."); index >= 0 {
+		return namespace[index+1:]
+	}
+	return fieldError.Field()
+}
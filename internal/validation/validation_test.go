@@ -0,0 +1,71 @@
+package validation
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	"testing"
+	"ticket-purchase/internal/dto"
+	"ticket-purchase/internal/i18n"
+)
+
+func newFiberCtx(t *testing.T, language string) *fiber.Ctx {
+	app := fiber.New()
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	ctx.Request().Header.Set("Accept-Language", language)
+	t.Cleanup(func() { app.ReleaseCtx(ctx) })
+
+	i18n.InitBundle("./../i18n/languages")
+	return ctx
+}
+
+func TestStruct_Valid(t *testing.T) {
+	ctx := newFiberCtx(t, "en")
+
+	fieldErrors := Struct(ctx, &dto.TicketPurchaseRequest{UserId: "user", Quantity: 1})
+
+	assert.Nil(t, fieldErrors)
+}
+
+func TestStruct_Returns_Every_Field_Error(t *testing.T) {
+	ctx := newFiberCtx(t, "en")
+
+	fieldErrors := Struct(ctx, &dto.TicketCreateRequest{Allocation: -1})
+
+	assert.Equal(t, []FieldError{
+		{Field: "name", Code: "required", Message: "name is required"},
+		{Field: "allocation", Code: "gte", Message: "allocation must be greater than or equal to 0"},
+	}, fieldErrors)
+}
+
+func TestStruct_Localized(t *testing.T) {
+	ctx := newFiberCtx(t, "tr")
+
+	fieldErrors := Struct(ctx, &dto.TicketPurchaseRequest{UserId: "user", Quantity: 0})
+
+	assert.Equal(t, []FieldError{
+		{Field: "quantity", Code: "gt", Message: "quantity 0 değerinden büyük olmalıdır"},
+	}, fieldErrors)
+}
+
+func TestStruct_Query_Fields(t *testing.T) {
+	ctx := newFiberCtx(t, "en")
+
+	fieldErrors := Struct(ctx, &dto.PurchaseListRequest{Sort: "user_id", From: "yesterday"})
+
+	assert.Equal(t, []FieldError{
+		{Field: "sort", Code: "oneof", Message: "sort must be one of created_at, quantity"},
+		{Field: "from", Code: "datetime", Message: "from must be a date in RFC 3339 format"},
+	}, fieldErrors)
+}
+
+func TestStruct_Pointer_Fields(t *testing.T) {
+	ctx := newFiberCtx(t, "en")
+	name := ""
+
+	fieldErrors := Struct(ctx, &dto.TicketUpdateRequest{Name: &name})
+
+	assert.Equal(t, []FieldError{
+		{Field: "name", Code: "min", Message: "name must be at least 1 characters long"},
+	}, fieldErrors)
+}
@@ -0,0 +1,198 @@
+// Package webhooks delivers ticket and purchase events to the subscriptions of partner systems
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"ticket-purchase/pkg/signature"
+	"time"
+)
+
+// Headers of deliveries. The signature is made with the secret of the subscription in the format of the signature
+// package. The delivery id stays the same when a delivery is retried, so receivers can drop duplicates.
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// Event types
+const (
+	EventTicketCreated     = "ticket.created"
+	EventTicketUpdated     = "ticket.updated"
+	EventTicketSoldOut     = "ticket.sold_out"
+	EventPurchaseCompleted = "purchase.completed"
+	EventPurchaseCancelled = "purchase.cancelled"
+)
+
+// Events are all event types subscriptions can receive
+var Events = []string{
+	EventTicketCreated,
+	EventTicketUpdated,
+	EventTicketSoldOut,
+	EventPurchaseCompleted,
+	EventPurchaseCancelled,
+}
+
+// Payload is the body of a delivery
+type Payload struct {
+	// Id is unique per event, all subscriptions get the same id for an event
+	Id        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Request is a delivery to send
+type Request struct {
+	DeliveryId string
+	EventType  string
+	Url        string
+	Secret     string
+	Body       []byte
+}
+
+// Sender posts deliveries to subscribers
+type Sender interface {
+	// Send posts the delivery and returns the response status. Responses other than 2xx are errors.
+	Send(ctx context.Context, request Request) (int, error)
+}
+
+// ErrNonPublicAddress is returned when a delivery would connect to an address that is not public
+var ErrNonPublicAddress = errors.New("webhook address is not public")
+
+type httpSender struct {
+	client *http.Client
+}
+
+// NewHTTPSender returns a sender whose requests are cancelled after timeout. It only connects to public addresses,
+// checked when it connects so that a host resolving to another address since the subscription was made is refused.
+// Redirects are not followed, the redirect is the answer of the delivery.
+func NewHTTPSender(timeout time.Duration) Sender {
+	return newHTTPSender(timeout, IsPublicAddress)
+}
+
+func newHTTPSender(timeout time.Duration, allow func(ip net.IP) bool) *httpSender {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !allow(ip) {
+				return ErrNonPublicAddress
+			}
+			return nil
+		},
+	}
+
+	// Without a proxy every connection goes through the dialer above
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &httpSender{client: &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+func (s *httpSender) Send(ctx context.Context, request Request) (int, error) {
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, request.Url, bytes.NewReader(request.Body))
+	if err != nil {
+		return 0, err
+	}
+
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpRequest.Header.Set(SignatureHeader, signature.Sign(request.Secret, time.Now(), request.Body))
+	httpRequest.Header.Set(EventHeader, request.EventType)
+	httpRequest.Header.Set(DeliveryHeader, request.DeliveryId)
+
+	response, err := s.client.Do(httpRequest)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	// The body is drained so that the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("webhook answered %d", response.StatusCode)
+	}
+	return response.StatusCode, nil
+}
+
+// IsEvent reports whether eventType is a known event type
+func IsEvent(eventType string) bool {
+	for _, event := range Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// nonPublicNetworks are reserved ranges the checks of net.IP do not cover: "this network", carrier-grade NAT, IETF
+// protocol assignments, benchmarking, reserved and broadcast, and local-use NAT64
+var nonPublicNetworks = parseNetworks("0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "198.18.0.0/15", "240.0.0.0/4", "64:ff9b:1::/48")
+
+// nat64Network embeds IPv4 addresses in its last 4 bytes, they are checked instead
+var nat64Network = parseNetworks("64:ff9b::/96")[0]
+
+// IsPublicAddress reports whether ip can be reached from the internet. Loopback, private, carrier-grade NAT,
+// link-local, unspecified, multicast and reserved addresses are not, deliveries to them would reach the services next
+// to the API. IPv4-mapped and NAT64 addresses are checked by the IPv4 address they embed.
+func IsPublicAddress(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	} else if nat64Network.Contains(ip) {
+		return IsPublicAddress(ip[net.IPv6len-net.IPv4len:])
+	}
+
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsUnspecified() &&
+		!ip.IsMulticast()
+}
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// Backoff returns how long to wait before the next attempt after attempts failed ones. The delay doubles with every
+// attempt and is capped at maxDelay.
+func Backoff(attempts int, baseDelay time.Duration, maxDelay time.Duration) time.Duration {
+	delay := baseDelay
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
@@ -0,0 +1,129 @@
+package webhooks
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"ticket-purchase/pkg/signature"
+	"time"
+)
+
+// allowAll lets the tests deliver to their servers on loopback
+func allowAll(net.IP) bool {
+	return true
+}
+
+func TestHTTPSender_Send(t *testing.T) {
+	body := []byte(`{"id":"evt_1","type":"ticket.created"}`)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ := io.ReadAll(r.Body)
+		assert.Equal(t, body, received)
+		assert.Equal(t, EventTicketCreated, r.Header.Get(EventHeader))
+		assert.Equal(t, "delivery-1", r.Header.Get(DeliveryHeader))
+		assert.NoError(t, signature.Verify(r.Header.Get(SignatureHeader), received, []string{"secret"}, time.Minute, time.Now()))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	status, err := newHTTPSender(time.Second, allowAll).Send(context.Background(), Request{
+		DeliveryId: "delivery-1",
+		EventType:  EventTicketCreated,
+		Url:        server.URL,
+		Secret:     "secret",
+		Body:       body,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, status)
+}
+
+func TestHTTPSender_Send_Error_Status(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	status, err := newHTTPSender(time.Second, allowAll).Send(context.Background(), Request{Url: server.URL, Secret: "secret"})
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadGateway, status)
+}
+
+func TestHTTPSender_Send_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	status, err := newHTTPSender(10*time.Millisecond, allowAll).Send(context.Background(), Request{Url: server.URL, Secret: "secret"})
+
+	assert.Error(t, err)
+	assert.Equal(t, 0, status)
+}
+
+func TestHTTPSender_Refuses_Non_Public_Address(t *testing.T) {
+	reached := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	defer server.Close()
+
+	status, err := NewHTTPSender(time.Second).Send(context.Background(), Request{Url: server.URL, Secret: "secret"})
+
+	assert.ErrorIs(t, err, ErrNonPublicAddress)
+	assert.Equal(t, 0, status)
+	assert.False(t, reached)
+}
+
+func TestHTTPSender_Does_Not_Follow_Redirects(t *testing.T) {
+	reached := false
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	defer internal.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL, http.StatusFound)
+	}))
+	defer server.Close()
+
+	// The subscriber is trusted here, the redirect to loopback is still not followed
+	status, err := newHTTPSender(time.Second, allowAll).Send(context.Background(), Request{Url: server.URL, Secret: "secret"})
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusFound, status)
+	assert.False(t, reached)
+}
+
+func TestBackoff(t *testing.T) {
+	base := 30 * time.Second
+	maxDelay := time.Hour
+
+	assert.Equal(t, 30*time.Second, Backoff(1, base, maxDelay))
+	assert.Equal(t, time.Minute, Backoff(2, base, maxDelay))
+	assert.Equal(t, 4*time.Minute, Backoff(4, base, maxDelay))
+	assert.Equal(t, time.Hour, Backoff(20, base, maxDelay))
+}
+
+func TestIsEvent(t *testing.T) {
+	assert.True(t, IsEvent(EventPurchaseCancelled))
+	assert.False(t, IsEvent("purchase.deleted"))
+}
+
+func TestIsPublicAddress(t *testing.T) {
+	assert.True(t, IsPublicAddress(net.ParseIP("93.184.216.34")))
+	assert.True(t, IsPublicAddress(net.ParseIP("2606:2800:220:1::1")))
+	assert.True(t, IsPublicAddress(net.ParseIP("64:ff9b::5db8:d822")))
+	assert.True(t, IsPublicAddress(net.ParseIP("100.128.0.1")))
+
+	for _, address := range []string{"127.0.0.1", "::1", "10.0.0.5", "172.16.0.1", "192.168.1.1", "fd00::1", "169.254.169.254", "fe80::1", "0.0.0.0", "::", "224.0.0.1", "::ffff:127.0.0.1",
+		"100.64.0.1", "100.127.255.254", "198.18.0.1", "255.255.255.255", "::ffff:169.254.169.254", "64:ff9b::a9fe:a9fe",
+		"64:ff9b::7f00:1", "64:ff9b:1::1"} {
+		assert.False(t, IsPublicAddress(net.ParseIP(address)), address)
+	}
+}
@@ -0,0 +1,42 @@
+package workers
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2/log"
+	"ticket-purchase/internal/services"
+	"time"
+)
+
+// HoldSweeper periodically releases expired holds back to ticket inventory
+type HoldSweeper struct {
+	holdService services.HoldService
+	interval    time.Duration
+}
+
+func NewHoldSweeper(holdService services.HoldService, interval time.Duration) *HoldSweeper {
+	return &HoldSweeper{
+		holdService: holdService,
+		interval:    interval,
+	}
+}
+
+// Run sweeps on every interval until the context is cancelled
+func (w *HoldSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			released, err := w.holdService.ReleaseExpired(ctx)
+			if err != nil {
+				log.Error("Error releasing expired holds: ", err)
+			}
+			if released > 0 {
+				log.Infof("Released %d expired holds", released)
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package workers
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2/log"
+	"ticket-purchase/internal/services"
+	"time"
+)
+
+// IdempotencySweeper periodically deletes expired idempotency keys. Expired keys are also taken over when they are
+// reused, the sweeper removes the ones that never are.
+type IdempotencySweeper struct {
+	idempotencyService services.IdempotencyService
+	interval           time.Duration
+}
+
+func NewIdempotencySweeper(idempotencyService services.IdempotencyService, interval time.Duration) *IdempotencySweeper {
+	return &IdempotencySweeper{
+		idempotencyService: idempotencyService,
+		interval:           interval,
+	}
+}
+
+// Run sweeps on every interval until the context is cancelled
+func (w *IdempotencySweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := w.idempotencyService.DeleteExpired(ctx)
+			if err != nil {
+				log.Error("Error deleting expired idempotency keys: ", err)
+			}
+			if deleted > 0 {
+				log.Infof("Deleted %d expired idempotency keys", deleted)
+			}
+		}
+	}
+}
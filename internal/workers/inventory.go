@@ -0,0 +1,71 @@
+package workers
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2/log"
+	"ticket-purchase/internal/services"
+	"time"
+)
+
+// InventoryPersister writes the sales taken from the Redis counters to the database
+type InventoryPersister struct {
+	inventoryService services.InventoryService
+	consumer         string
+}
+
+// NewInventoryPersister creates a persister. The consumer name must be unique per instance.
+func NewInventoryPersister(inventoryService services.InventoryService, consumer string) *InventoryPersister {
+	return &InventoryPersister{
+		inventoryService: inventoryService,
+		consumer:         consumer,
+	}
+}
+
+// Run persists sales until the context is cancelled
+func (w *InventoryPersister) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		_, err := w.inventoryService.PersistSales(ctx, w.consumer)
+		if err != nil && ctx.Err() == nil {
+			log.Error("Error persisting inventory sales: ", err)
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+		}
+	}
+}
+
+// InventoryReconciler periodically repairs Redis counters that drifted from the ticket allocations
+type InventoryReconciler struct {
+	inventoryService services.InventoryService
+	interval         time.Duration
+}
+
+func NewInventoryReconciler(inventoryService services.InventoryService, interval time.Duration) *InventoryReconciler {
+	return &InventoryReconciler{
+		inventoryService: inventoryService,
+		interval:         interval,
+	}
+}
+
+// Run reconciles on every interval until the context is cancelled
+func (w *InventoryReconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			repaired, err := w.inventoryService.Reconcile(ctx)
+			if err != nil {
+				log.Error("Error reconciling inventory: ", err)
+			}
+			if repaired > 0 {
+				log.Infof("Repaired %d inventory counters", repaired)
+			}
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package workers
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2/log"
+	"sync"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/mail"
+	"time"
+)
+
+// MailDispatcher delivers queued emails in the background and retries failed deliveries with exponential backoff
+type MailDispatcher struct {
+	sender      mail.Sender
+	queue       chan mail.Message
+	workers     int
+	maxAttempts int
+	retryDelay  time.Duration
+}
+
+func NewMailDispatcher(sender mail.Sender, conf config.MailConfig) *MailDispatcher {
+	return &MailDispatcher{
+		sender:      sender,
+		queue:       make(chan mail.Message, max(conf.QueueSize, 1)),
+		workers:     max(conf.Workers, 1),
+		maxAttempts: max(conf.MaxAttempts, 1),
+		retryDelay:  conf.RetryDelay,
+	}
+}
+
+// Enqueue queues an email without blocking. It returns false when the queue is full and the email is dropped.
+func (d *MailDispatcher) Enqueue(message mail.Message) bool {
+	select {
+	case d.queue <- message:
+		return true
+	default:
+		log.Warnf("Mail queue is full, dropping email to %v", message.To)
+		return false
+	}
+}
+
+// Run delivers emails until the context is cancelled. Emails still in the queue at that point are not sent.
+func (d *MailDispatcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < d.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case message := <-d.queue:
+					d.deliver(ctx, message)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if pending := len(d.queue); pending > 0 {
+		log.Warnf("Mail dispatcher stopped with %d unsent emails", pending)
+	}
+}
+
+func (d *MailDispatcher) deliver(ctx context.Context, message mail.Message) {
+	delay := d.retryDelay
+	for attempt := 1; ; attempt++ {
+		err := d.sender.Send(message)
+		if err == nil {
+			return
+		}
+
+		if attempt >= d.maxAttempts {
+			log.Errorf("Giving up sending email to %v after %d attempts: %v", message.To, attempt, err)
+			return
+		}
+
+		log.Warnf("Sending email to %v failed, retrying in %s: %v", message.To, delay, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
@@ -0,0 +1,65 @@
+package workers
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"ticket-purchase/cmd/config"
+	"ticket-purchase/internal/mail"
+	"ticket-purchase/internal/mail/mailtest"
+	"time"
+)
+
+func TestMailDispatcher_Retries_Until_Delivered(t *testing.T) {
+	server := mailtest.NewServer(t)
+	server.FailNext(2)
+
+	conf := config.MailConfig{
+		Host:        server.Host(),
+		Port:        server.Port(),
+		Username:    "tickets@example.com",
+		Workers:     1,
+		QueueSize:   10,
+		MaxAttempts: 3,
+		RetryDelay:  time.Millisecond,
+	}
+	dispatcher := NewMailDispatcher(mail.NewSMTPSender(conf), conf)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go dispatcher.Run(ctx)
+
+	assert.True(t, dispatcher.Enqueue(mail.Message{To: []string{"user@example.com"}, Subject: "Hello", HTML: "<p>Hello</p>"}))
+
+	select {
+	case <-server.Received():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the email to be delivered")
+	}
+	assert.Len(t, server.Messages(), 1)
+}
+
+func TestMailDispatcher_Gives_Up_After_Max_Attempts(t *testing.T) {
+	sender := &failingSender{}
+	dispatcher := NewMailDispatcher(sender, config.MailConfig{MaxAttempts: 3, RetryDelay: time.Millisecond})
+
+	dispatcher.deliver(context.Background(), mail.Message{To: []string{"user@example.com"}})
+
+	assert.Equal(t, 3, sender.attempts)
+}
+
+func TestMailDispatcher_Enqueue_Full_Queue(t *testing.T) {
+	dispatcher := NewMailDispatcher(&failingSender{}, config.MailConfig{QueueSize: 1})
+
+	assert.True(t, dispatcher.Enqueue(mail.Message{}))
+	assert.False(t, dispatcher.Enqueue(mail.Message{}))
+}
+
+type failingSender struct {
+	attempts int
+}
+
+func (s *failingSender) Send(mail.Message) error {
+	s.attempts++
+	return assert.AnError
+}
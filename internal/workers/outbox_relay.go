@@ -0,0 +1,56 @@
+package workers
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2/log"
+	"ticket-purchase/internal/services"
+	"time"
+)
+
+// outboxCleanupInterval is how often published events past their retention are removed
+const outboxCleanupInterval = time.Hour
+
+// OutboxRelay periodically publishes the events of the outbox to the event bus
+type OutboxRelay struct {
+	outboxService services.OutboxService
+	interval      time.Duration
+}
+
+func NewOutboxRelay(outboxService services.OutboxService, interval time.Duration) *OutboxRelay {
+	return &OutboxRelay{
+		outboxService: outboxService,
+		interval:      interval,
+	}
+}
+
+// Run relays events on every interval and removes old published events every hour until the context is cancelled
+func (w *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	cleanup := time.NewTicker(outboxCleanupInterval)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			published, err := w.outboxService.Relay(ctx)
+			if err != nil {
+				log.Error("Error relaying outbox events: ", err)
+			}
+			if published > 0 {
+				log.Infof("Published %d outbox events", published)
+			}
+		case <-cleanup.C:
+			removed, err := w.outboxService.Cleanup(ctx)
+			if err != nil {
+				log.Error("Error removing published outbox events: ", err)
+			}
+			if removed > 0 {
+				log.Infof("Removed %d published outbox events", removed)
+			}
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package workers
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2/log"
+	"ticket-purchase/internal/services"
+	"time"
+)
+
+// waitingRoomAdmitInterval is shorter than a second, because admissions not taken within a second are dropped
+const waitingRoomAdmitInterval = 250 * time.Millisecond
+
+// WaitingRoomAdmitter admits the next users of the waiting rooms at the configured rate
+type WaitingRoomAdmitter struct {
+	waitingRoomService services.WaitingRoomService
+}
+
+func NewWaitingRoomAdmitter(waitingRoomService services.WaitingRoomService) *WaitingRoomAdmitter {
+	return &WaitingRoomAdmitter{
+		waitingRoomService: waitingRoomService,
+	}
+}
+
+// Run admits users until the context is cancelled
+func (w *WaitingRoomAdmitter) Run(ctx context.Context) {
+	ticker := time.NewTicker(waitingRoomAdmitInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := w.waitingRoomService.Admit(ctx); err != nil {
+				log.Error("Error admitting waiting room users: ", err)
+			}
+		}
+	}
+}
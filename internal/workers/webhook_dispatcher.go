@@ -0,0 +1,42 @@
+package workers
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2/log"
+	"ticket-purchase/internal/services"
+	"time"
+)
+
+// WebhookDispatcher periodically sends the webhook deliveries that are due
+type WebhookDispatcher struct {
+	webhookService services.WebhookSubscriptionService
+	interval       time.Duration
+}
+
+func NewWebhookDispatcher(webhookService services.WebhookSubscriptionService, interval time.Duration) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		webhookService: webhookService,
+		interval:       interval,
+	}
+}
+
+// Run sends due deliveries on every interval until the context is cancelled
+func (w *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			delivered, err := w.webhookService.DeliverDue(ctx)
+			if err != nil {
+				log.Error("Error sending webhook deliveries: ", err)
+			}
+			if delivered > 0 {
+				log.Infof("Sent %d webhook deliveries", delivered)
+			}
+		}
+	}
+}
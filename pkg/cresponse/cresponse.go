@@ -21,14 +21,15 @@ func SuccessResponse(ctx *fiber.Ctx, status int, data interface{}, msg ...string
 }
 
 func ErrorResponse(ctx *fiber.Ctx, status int, msg string, data ...interface{}) error {
-	if len(data) == 0 {
-		data = nil
+	var body interface{}
+	if len(data) > 0 {
+		body = data[0]
 	}
 
 	return ctx.Status(status).JSON(BaseResponse{
 		Success: false,
 		Message: msg,
-		Data:    data,
+		Data:    body,
 	})
 }
 
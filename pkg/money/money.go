@@ -0,0 +1,60 @@
+package money
+
+import (
+	"errors"
+	"golang.org/x/text/currency"
+	"math"
+)
+
+// DefaultCurrency is used for prices given without a currency
+const DefaultCurrency = "USD"
+
+// ErrCurrencyMismatch is returned when amounts of different currencies are added
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+// Money is an amount in the minor unit of its currency, e.g. cents for USD. Amounts are integers, so they never
+// pick up floating point rounding errors.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// New returns the amount in minor units of the ISO 4217 currency
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Multiply returns the price of quantity items
+func (m Money) Multiply(quantity int) Money {
+	return Money{Amount: m.Amount * int64(quantity), Currency: m.Currency}
+}
+
+// Add returns the sum of two amounts of the same currency
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Digits returns the number of minor unit digits of the currency, e.g. 2 for USD and 0 for JPY.
+// Unknown currencies have 2.
+func (m Money) Digits() int {
+	unit, err := currency.ParseISO(m.Currency)
+	if err != nil {
+		return 2
+	}
+
+	digits, _ := currency.Standard.Rounding(unit)
+	return digits
+}
+
+// Major returns the amount in major units, e.g. dollars for USD. It is only meant for display.
+func (m Money) Major() float64 {
+	return float64(m.Amount) / math.Pow10(m.Digits())
+}
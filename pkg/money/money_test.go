@@ -0,0 +1,25 @@
+package money
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMoney_Multiply(t *testing.T) {
+	assert.Equal(t, New(3750, "USD"), New(1250, "USD").Multiply(3))
+}
+
+func TestMoney_Add(t *testing.T) {
+	sum, err := New(100, "EUR").Add(New(250, "EUR"))
+	assert.NoError(t, err)
+	assert.Equal(t, New(350, "EUR"), sum)
+
+	_, err = New(100, "EUR").Add(New(100, "USD"))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+}
+
+func TestMoney_Major(t *testing.T) {
+	assert.Equal(t, 12.5, New(1250, "TRY").Major())
+	assert.Equal(t, 1250.0, New(1250, "JPY").Major())
+	assert.Equal(t, 1.25, New(1250, "KWD").Major())
+}
@@ -0,0 +1,58 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// ErrInvalidCursor is returned when a cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor points at the last item of a page. Value is the sort column value of that item
+// and Id breaks ties between items with the same value.
+type Cursor struct {
+	Value string `json:"v"`
+	Id    string `json:"id"`
+}
+
+// Encode returns an opaque, URL safe representation of the cursor
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor created by Cursor.Encode. An empty string returns a nil cursor.
+func DecodeCursor(value string) (*Cursor, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Id == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
+
+// NormalizeLimit keeps the page size between 1 and MaxLimit
+func NormalizeLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultLimit
+	}
+	if limit > MaxLimit {
+		return MaxLimit
+	}
+	return limit
+}
@@ -0,0 +1,38 @@
+package pagination
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCursor_Encode_Decode(t *testing.T) {
+	cursor := Cursor{Value: "2020-01-01T12:00:00Z", Id: "4a4b3b3b-1b4b-4b3b-8b3b-3b4b3b4b3b4b"}
+
+	decoded, err := DecodeCursor(cursor.Encode())
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %v", err)
+	}
+
+	assert.Equal(t, cursor, *decoded)
+}
+
+func TestDecodeCursor_Empty(t *testing.T) {
+	decoded, err := DecodeCursor("")
+
+	assert.Nil(t, err)
+	assert.Nil(t, decoded)
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	_, err := DecodeCursor("not a cursor")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	_, err = DecodeCursor(Cursor{Value: "1"}.Encode())
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestNormalizeLimit(t *testing.T) {
+	assert.Equal(t, DefaultLimit, NormalizeLimit(0))
+	assert.Equal(t, 5, NormalizeLimit(5))
+	assert.Equal(t, MaxLimit, NormalizeLimit(MaxLimit+1))
+}
@@ -0,0 +1,78 @@
+// Package signature signs webhook bodies as "t=<unix seconds>,v1=<hex HMAC-SHA256>". The HMAC is taken over
+// "<t>.<body>", so the timestamp cannot be changed without the secret. A header can carry several v1 signatures while
+// the sender rotates its secret.
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalid is returned when no signature of a header matches any of the secrets
+	ErrInvalid = errors.New("invalid signature")
+	// ErrStale is returned when the timestamp of a header is outside the tolerance
+	ErrStale = errors.New("stale signature timestamp")
+)
+
+// Sign returns the signature header of a body sent at timestamp
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + unix + ",v1=" + mac(secret, unix, body)
+}
+
+// Verify checks that the header was made with one of the secrets and that its timestamp is within tolerance of now
+func Verify(header string, body []byte, secrets []string, tolerance time.Duration, now time.Time) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return fmt.Errorf("%w: malformed header", ErrInvalid)
+	}
+
+	if !matchesAny(signatures, secrets, timestamp, body) {
+		return ErrInvalid
+	}
+
+	age := now.Sub(time.Unix(unix, 0))
+	if age > tolerance || age < -tolerance {
+		return ErrStale
+	}
+	return nil
+}
+
+func matchesAny(signatures []string, secrets []string, timestamp string, body []byte) bool {
+	for _, secret := range secrets {
+		expected := mac(secret, timestamp, body)
+		for _, signature := range signatures {
+			if hmac.Equal([]byte(signature), []byte(expected)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func mac(secret string, timestamp string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,44 @@
+package signature
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var signTime = time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":"evt_1","type":"payment.captured","payment_id":"payment-1"}`)
+	secrets := []string{"new-secret", "old-secret"}
+
+	tests := []struct {
+		name   string
+		header string
+		body   []byte
+		err    error
+	}{
+		{"Current secret", Sign("new-secret", signTime, body), body, nil},
+		{"Previous secret", Sign("old-secret", signTime, body), body, nil},
+		{"Several signatures", Sign("unknown", signTime, body) + ",v1=" + mac("old-secret", "1577880000", body), body, nil},
+		{"Unknown secret", Sign("unknown", signTime, body), body, ErrInvalid},
+		{"Changed body", Sign("new-secret", signTime, body), []byte(`{"id":"evt_2"}`), ErrInvalid},
+		{"Changed timestamp", "t=1577880060," + Sign("new-secret", signTime, body)[13:], body, ErrInvalid},
+		{"Missing signature", "t=1577880000", body, ErrInvalid},
+		{"Malformed header", "garbage", body, ErrInvalid},
+		{"Old timestamp", Sign("new-secret", signTime.Add(-6*time.Minute), body), body, ErrStale},
+		{"Future timestamp", Sign("new-secret", signTime.Add(6*time.Minute), body), body, ErrStale},
+		{"Within tolerance", Sign("new-secret", signTime.Add(-4*time.Minute), body), body, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Verify(test.header, test.body, secrets, 5*time.Minute, signTime)
+			if test.err == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, test.err)
+		})
+	}
+}